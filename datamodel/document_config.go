@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/url"
 	"os"
+	"time"
 )
 
 // DocumentConfiguration is used to configure the document creation process. It was added in v0.6.0 to allow
@@ -27,6 +28,9 @@ type DocumentConfiguration struct {
 	// The remote handler is only used if the BaseURL is set. If the BaseURL is not set, then the remote handler
 	// will not be used, as there will be nothing to use it against.
 	//
+	// A custom handler can not be used with a RemotePolicy, documents using both fail with
+	// ErrRemotePolicyCustomHandler.
+	//
 	// Resolves [#132]: https://github.com/pb33f/libopenapi/issues/132
 	RemoteURLHandler utils.RemoteURLHandler
 
@@ -114,6 +118,26 @@ type DocumentConfiguration struct {
 	// BundleInlineRefs is used by the bundler module. If set to true, all references will be inlined, including
 	// local references (to the root document) as well as all external references. This is false by default.
 	BundleInlineRefs bool
+
+	// RemoteCacheDirectory enables an on-disk cache of remote documents when set. Every remote document fetched
+	// by the rolodex is persisted to this directory (along with its ETag and Last-Modified values), so it does not
+	// need to be downloaded again the next time a document is created.
+	RemoteCacheDirectory string
+
+	// RemoteCacheTTL is how long a cached remote document is served without revalidation. Once expired, a conditional
+	// request is used to check if the document has changed. A value of zero will revalidate every document, every time.
+	// Only used when RemoteCacheDirectory is set.
+	RemoteCacheTTL time.Duration
+
+	// RemoteCacheOffline will serve remote documents only from the cache, the network is never used. Any remote
+	// document that is not cached will fail to load. Only used when RemoteCacheDirectory is set.
+	RemoteCacheOffline bool
+
+	// RemotePolicy restricts which remote references can be fetched, and how much can be fetched. It should always
+	// be set when parsing untrusted specifications with remote references enabled. Violations are reported as
+	// *RemotePolicyError errors. The policy is enforced by the default remote handler, so it can not be used with a
+	// RemoteURLHandler (ErrRemotePolicyCustomHandler is returned).
+	RemotePolicy *RemoteReferencePolicy
 }
 
 func NewDocumentConfiguration() *DocumentConfiguration {
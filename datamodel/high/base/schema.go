@@ -486,8 +486,8 @@ func (s *Schema) MarshalYAML() (interface{}, error) {
 	nb := high.NewNodeBuilder(s, s.low)
 
 	// determine index version
-	idx := s.GoLow().Index
-	if idx != nil {
+	if s.low != nil && s.low.Index != nil {
+		idx := s.low.Index
 		if idx.GetConfig().SpecInfo != nil {
 			nb.Version = idx.GetConfig().SpecInfo.VersionNumeric
 		}
@@ -4,6 +4,7 @@
 package v3
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/pb33f/libopenapi/datamodel"
@@ -169,3 +170,43 @@ func (c *Components) MarshalYAML() (interface{}, error) {
 	nb := high.NewNodeBuilder(c, c.low)
 	return nb.Render(), nil
 }
+
+// deleteComponent removes a component using its definition (for example '#/components/schemas/Pet'), returning
+// true if the component existed.
+func (c *Components) deleteComponent(definition, name string) bool {
+	parts := strings.Split(definition, "/")
+	if len(parts) != 4 || parts[1] != "components" {
+		return false
+	}
+	switch parts[2] {
+	case "schemas":
+		return deleteComponent(c.Schemas, name)
+	case "responses":
+		return deleteComponent(c.Responses, name)
+	case "parameters":
+		return deleteComponent(c.Parameters, name)
+	case "examples":
+		return deleteComponent(c.Examples, name)
+	case "requestBodies":
+		return deleteComponent(c.RequestBodies, name)
+	case "headers":
+		return deleteComponent(c.Headers, name)
+	case "securitySchemes":
+		return deleteComponent(c.SecuritySchemes, name)
+	case "links":
+		return deleteComponent(c.Links, name)
+	case "callbacks":
+		return deleteComponent(c.Callbacks, name)
+	case "pathItems":
+		return deleteComponent(c.PathItems, name)
+	}
+	return false
+}
+
+func deleteComponent[T any](components *orderedmap.Map[string, T], name string) bool {
+	if components == nil {
+		return false
+	}
+	_, present := components.Delete(name)
+	return present
+}
@@ -11,6 +11,7 @@ package v3
 
 import (
 	"bytes"
+	"errors"
 
 	"github.com/pb33f/libopenapi/datamodel/high"
 	"github.com/pb33f/libopenapi/datamodel/high/base"
@@ -199,3 +200,25 @@ func (d *Document) MarshalYAMLInline() (interface{}, error) {
 	nb.Resolve = true
 	return nb.Render(), nil
 }
+
+// PruneUnusedComponents removes every component that cannot be reached from a path, a webhook or a security
+// requirement (see index.SpecIndex.FindUnusedComponents) from the document, then renders the cleaned document.
+// The components that were removed are returned.
+//
+// Only components declared in the root document are removed, unused components in other files of the rolodex are
+// reported by FindUnusedComponents but cannot be removed from this document.
+func (d *Document) PruneUnusedComponents() ([]byte, []*index.Reference, error) {
+	if d.Index == nil {
+		return nil, nil, errors.New("the document has no index, unused components cannot be found")
+	}
+	var pruned []*index.Reference
+	if d.Components != nil {
+		for _, ref := range d.Index.FindUnusedComponents() {
+			if ref.Index == d.Index && d.Components.deleteComponent(ref.Definition, ref.Name) {
+				pruned = append(pruned, ref)
+			}
+		}
+	}
+	rendered, err := d.Render()
+	return rendered, pruned, err
+}
@@ -0,0 +1,295 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	lowv3 "github.com/pb33f/libopenapi/datamodel/low/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+var operationLabels = []string{
+	lowv3.GetLabel, lowv3.PutLabel, lowv3.PostLabel, lowv3.DeleteLabel,
+	lowv3.OptionsLabel, lowv3.HeadLabel, lowv3.PatchLabel, lowv3.TraceLabel,
+}
+
+// DocumentFilter selects the operations kept by ExtractSubDocument.
+//
+// An operation is kept when it matches at least one value of every Include criteria that has been set, and no values
+// of any Exclude criteria. An empty filter keeps everything.
+type DocumentFilter struct {
+	// IncludeTags and ExcludeTags match the tags of an operation.
+	IncludeTags []string
+	ExcludeTags []string
+
+	// IncludePaths and ExcludePaths are globs (see path.Match) matched against the path of an operation, or the name
+	// of a webhook. A glob ending in '/**' matches the path and everything beneath it.
+	IncludePaths []string
+	ExcludePaths []string
+
+	// IncludeOperationIds and ExcludeOperationIds match the operationId of an operation.
+	IncludeOperationIds []string
+	ExcludeOperationIds []string
+
+	// IncludeMethods and ExcludeMethods match the HTTP method of an operation, in any case.
+	IncludeMethods []string
+	ExcludeMethods []string
+
+	// IncludeExtensions and ExcludeExtensions match extension values of an operation, or the path item that holds it,
+	// for example 'x-internal: true'. An empty value matches any value, as long as the extension exists.
+	IncludeExtensions map[string]string
+	ExcludeExtensions map[string]string
+}
+
+// Matches returns true if the filter keeps the operation, found at a path (or webhook) in a path item.
+func (f *DocumentFilter) Matches(pathName, method string, pathItem *PathItem, operation *Operation) bool {
+	if f == nil {
+		return true
+	}
+	var tags []string
+	var operationId string
+	if operation != nil {
+		tags, operationId = operation.Tags, operation.OperationId
+	}
+	matchTag := func(tag string) bool { return slices.Contains(tags, tag) }
+	matchPath := func(glob string) bool { return matchPathGlob(glob, pathName) }
+	matchId := func(id string) bool { return id == operationId }
+	matchMethod := func(m string) bool { return strings.EqualFold(m, method) }
+
+	if !matchesInclude(f.IncludeTags, matchTag) || matchesExclude(f.ExcludeTags, matchTag) ||
+		!matchesInclude(f.IncludePaths, matchPath) || matchesExclude(f.ExcludePaths, matchPath) ||
+		!matchesInclude(f.IncludeOperationIds, matchId) || matchesExclude(f.ExcludeOperationIds, matchId) ||
+		!matchesInclude(f.IncludeMethods, matchMethod) || matchesExclude(f.ExcludeMethods, matchMethod) {
+		return false
+	}
+	if len(f.IncludeExtensions) > 0 && !matchExtensions(f.IncludeExtensions, pathItem, operation) {
+		return false
+	}
+	return !matchExtensions(f.ExcludeExtensions, pathItem, operation)
+}
+
+func matchesInclude(values []string, match func(string) bool) bool {
+	return len(values) == 0 || slices.ContainsFunc(values, match)
+}
+
+func matchesExclude(values []string, match func(string) bool) bool {
+	return slices.ContainsFunc(values, match)
+}
+
+func matchPathGlob(glob, p string) bool {
+	if prefix, ok := strings.CutSuffix(glob, "/**"); ok {
+		// match the path, or any of its parents.
+		for candidate := p; ; candidate = path.Dir(candidate) {
+			if m, _ := path.Match(prefix, candidate); m {
+				return true
+			}
+			if candidate == "/" || candidate == "." {
+				return false
+			}
+		}
+	}
+	m, _ := path.Match(glob, p)
+	return m
+}
+
+func matchExtensions(extensions map[string]string, pathItem *PathItem, operation *Operation) bool {
+	for name, value := range extensions {
+		var node *yaml.Node
+		if operation != nil && operation.Extensions != nil {
+			node = operation.Extensions.GetOrZero(name)
+		}
+		if node == nil && pathItem != nil && pathItem.Extensions != nil {
+			node = pathItem.Extensions.GetOrZero(name)
+		}
+		if node != nil && (value == "" || node.Value == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractSubDocument creates a new, self-consistent document that only holds the operations (from paths and
+// webhooks) kept by the filter, and the components, tags and security schemes those operations need. Path items
+// left without operations are removed. A path item that is a reference is shared, so it can't be split up, it is
+// kept whole if any of its operations are kept.
+//
+// The configuration is used to build the new document, it should allow the same references as the configuration
+// used to create this document. If it is nil, one is created from the configuration of the index of this document.
+//
+// The new document and its rendered YAML are returned. Errors building the new document (circular references, for
+// example) are returned along with it.
+func (d *Document) ExtractSubDocument(filter *DocumentFilter, config *datamodel.DocumentConfiguration,
+) (*Document, []byte, error) {
+	if config == nil {
+		config = d.documentConfiguration()
+	}
+
+	rendered, err := d.Render()
+	if err != nil {
+		return nil, nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(rendered, &root); err != nil {
+		return nil, nil, err
+	}
+	if len(root.Content) == 0 || !utils.IsNodeMap(root.Content[0]) {
+		return nil, nil, errors.New("unable to extract sub document, the document is empty")
+	}
+
+	// filter the operations, keeping track of the tags still in use.
+	usedTags := make(map[string]bool)
+	var pathItems *orderedmap.Map[string, *PathItem]
+	if d.Paths != nil {
+		pathItems = d.Paths.PathItems
+	}
+	filterPathItems(root.Content[0], "paths", pathItems, filter, usedTags)
+	filterPathItems(root.Content[0], "webhooks", d.Webhooks, filter, usedTags)
+	filterTags(root.Content[0], usedTags)
+
+	filtered, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// build the filtered document, so the components that are no longer used can be found and removed. Errors
+	// building it are kept, they may be about components that are pruned.
+	sub, buildErr := buildSubDocument(filtered, config)
+	if sub == nil {
+		return nil, nil, buildErr
+	}
+	pruned, _, err := sub.PruneUnusedComponents()
+	if err != nil {
+		return nil, nil, errors.Join(buildErr, err)
+	}
+	sub, err = buildSubDocument(pruned, config)
+	if sub == nil {
+		return nil, nil, errors.Join(buildErr, err)
+	}
+	return sub, pruned, errors.Join(buildErr, err)
+}
+
+// filterPathItems removes the operations that are not kept by the filter from the rendered path items held
+// by a key (paths or webhooks) of the root node.
+func filterPathItems(root *yaml.Node, key string, pathItems *orderedmap.Map[string, *PathItem],
+	filter *DocumentFilter, usedTags map[string]bool,
+) {
+	_, node := utils.FindKeyNodeTop(key, root.Content)
+	if !utils.IsNodeMap(node) || pathItems == nil {
+		return
+	}
+	var content []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, item := node.Content[i], node.Content[i+1]
+		pathItem := pathItems.GetOrZero(name.Value)
+		if pathItem == nil {
+			content = append(content, name, item)
+			continue
+		}
+		_, ref := utils.FindKeyNodeTop("$ref", item.Content)
+		kept := make(map[string]bool)
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			if filter.Matches(name.Value, method, pathItem, operation) {
+				kept[method] = true
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			if kept[method] || ref != nil {
+				for _, tag := range operation.Tags {
+					usedTags[tag] = true
+				}
+			}
+		}
+		if ref == nil {
+			var itemContent []*yaml.Node
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				method := strings.ToLower(item.Content[j].Value)
+				if slices.Contains(operationLabels, method) && !kept[method] {
+					continue
+				}
+				itemContent = append(itemContent, item.Content[j], item.Content[j+1])
+			}
+			item.Content = itemContent
+		}
+		content = append(content, name, item)
+	}
+	node.Content = content
+}
+
+// filterTags removes the tags that are no longer used by any operation from the rendered root node.
+func filterTags(root *yaml.Node, usedTags map[string]bool) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "tags" || !utils.IsNodeArray(root.Content[i+1]) {
+			continue
+		}
+		var tags []*yaml.Node
+		for _, tag := range root.Content[i+1].Content {
+			if _, name := utils.FindKeyNodeTop("name", tag.Content); name != nil && usedTags[name.Value] {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			root.Content = slices.Delete(root.Content, i, i+2)
+			return
+		}
+		root.Content[i+1].Content = tags
+		return
+	}
+}
+
+// documentConfiguration creates a document configuration from the configuration of the index (and the rolodex) of
+// the document, so the new document is built with the same references, remote policy and remote cache.
+func (d *Document) documentConfiguration() *datamodel.DocumentConfiguration {
+	config := datamodel.NewDocumentConfiguration()
+	if d.Index == nil || d.Index.GetConfig() == nil {
+		return config
+	}
+	ic := d.Index.GetConfig()
+	config.BaseURL = ic.BaseURL
+	config.BasePath = ic.BasePath
+	config.SpecFilePath = ic.SpecFilePath
+	config.RemoteURLHandler = ic.RemoteURLHandler
+	config.AllowFileReferences = ic.AllowFileLookup
+	config.AllowRemoteReferences = ic.AllowRemoteLookup
+	config.IgnorePolymorphicCircularReferences = ic.IgnorePolymorphicCircularReferences
+	config.IgnoreArrayCircularReferences = ic.IgnoreArrayCircularReferences
+	config.RemotePolicy = ic.RemotePolicy
+	if ic.Logger != nil {
+		config.Logger = ic.Logger
+	}
+	if ic.RemoteCache != nil {
+		config.RemoteCacheDirectory = ic.RemoteCache.GetDirectory()
+		config.RemoteCacheTTL = ic.RemoteCache.GetTTL()
+		config.RemoteCacheOffline = ic.RemoteCache.IsOffline()
+	}
+
+	// the type of a specification is only detected when the document check is not bypassed.
+	config.BypassDocumentCheck = ic.SpecInfo != nil && ic.SpecInfo.SpecType == ""
+	if rolodex := d.Index.GetRolodex(); rolodex != nil {
+		config.SkipCircularReferenceCheck = !rolodex.IsCircularReferenceChecked()
+	}
+	return config
+}
+
+func buildSubDocument(spec []byte, config *datamodel.DocumentConfiguration) (*Document, error) {
+	info, err := datamodel.ExtractSpecInfoWithDocumentCheck(spec, config.BypassDocumentCheck)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build sub document: %w", err)
+	}
+	lowDoc, err := lowv3.CreateDocumentFromConfig(info, config)
+	if lowDoc == nil {
+		return nil, err
+	}
+	return NewDocument(lowDoc), err
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	lowv3 "github.com/pb33f/libopenapi/datamodel/low/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filterSpec = `openapi: 3.1.0
+info:
+  title: filter
+  version: 1.0.0
+tags:
+  - name: pets
+  - name: store
+  - name: unused
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      operationId: listPets
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+    delete:
+      tags: [pets]
+      operationId: deletePets
+      x-internal: true
+      responses:
+        "204":
+          description: gone
+  /pets/{id}:
+    x-internal: true
+    get:
+      tags: [pets]
+      operationId: getPet
+      responses:
+        "200":
+          description: pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Owner'
+  /store/orders:
+    post:
+      tags: [store]
+      operationId: createOrder
+      security:
+        - oauth: [write]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Order'
+      responses:
+        "201":
+          description: created
+webhooks:
+  newPet:
+    post:
+      tags: [pets]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/NewPet'
+      responses:
+        "200":
+          description: ok
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+  schemas:
+    Pet:
+      type: object
+      properties:
+        tag:
+          $ref: '#/components/schemas/Tag'
+    Tag:
+      type: string
+    Owner:
+      type: object
+    Order:
+      type: object
+    NewPet:
+      type: object`
+
+func schemaNames(d *Document) []string {
+	var names []string
+	for name := range d.Components.Schemas.KeysFromOldest() {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestDocument_ExtractSubDocument(t *testing.T) {
+	doc := buildTestDocument(t, filterSpec)
+
+	sub, rendered, err := doc.ExtractSubDocument(&DocumentFilter{
+		IncludeTags:       []string{"pets"},
+		ExcludeExtensions: map[string]string{"x-internal": "true"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, orderedmap.Len(sub.Paths.PathItems))
+	pets := sub.Paths.PathItems.GetOrZero("/pets")
+	require.NotNil(t, pets)
+	assert.NotNil(t, pets.Get)
+	assert.Nil(t, pets.Delete)
+	assert.Equal(t, 1, orderedmap.Len(sub.Webhooks))
+	assert.Equal(t, []string{"Pet", "Tag", "NewPet"}, schemaNames(sub))
+	assert.Equal(t, 0, orderedmap.Len(sub.Components.SecuritySchemes))
+	require.Len(t, sub.Tags, 1)
+	assert.Equal(t, "pets", sub.Tags[0].Name)
+	assert.NotContains(t, string(rendered), "createOrder")
+
+	// the original document is not changed.
+	assert.Equal(t, 3, orderedmap.Len(doc.Paths.PathItems))
+	assert.Equal(t, 5, orderedmap.Len(doc.Components.Schemas))
+}
+
+func TestDocument_ExtractSubDocument_BuildErrors(t *testing.T) {
+	info, _ := datamodel.ExtractSpecInfo([]byte(`openapi: 3.1.0
+info:
+  title: filter
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      responses:
+        "200":
+          description: pets
+  /owners:
+    get:
+      tags: [owners]
+      responses:
+        "200":
+          description: owners
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Owner'
+components:
+  schemas:
+    Owner:
+      type: object
+      required: [owner]
+      properties:
+        owner:
+          $ref: '#/components/schemas/Owner'`))
+	lowDoc, err := lowv3.CreateDocumentFromConfig(info, datamodel.NewDocumentConfiguration())
+	require.Error(t, err)
+	doc := NewDocument(lowDoc)
+
+	// the errors building the filtered document are returned, even though the pruned document has none.
+	sub, _, err := doc.ExtractSubDocument(&DocumentFilter{IncludeTags: []string{"pets"}}, nil)
+	require.NotNil(t, sub)
+	assert.ErrorContains(t, err, "infinite circular reference detected: Owner")
+	assert.Empty(t, schemaNames(sub))
+}
+
+func TestDocument_ExtractSubDocument_PathsAndMethods(t *testing.T) {
+	doc := buildTestDocument(t, filterSpec)
+
+	sub, _, err := doc.ExtractSubDocument(&DocumentFilter{
+		IncludePaths:   []string{"/store/**"},
+		IncludeMethods: []string{"POST"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, orderedmap.Len(sub.Paths.PathItems))
+	assert.NotNil(t, sub.Paths.PathItems.GetOrZero("/store/orders").Post)
+	assert.Equal(t, 0, orderedmap.Len(sub.Webhooks))
+	assert.Equal(t, []string{"Order"}, schemaNames(sub))
+	assert.Equal(t, 1, orderedmap.Len(sub.Components.SecuritySchemes))
+	require.Len(t, sub.Tags, 1)
+	assert.Equal(t, "store", sub.Tags[0].Name)
+
+	sub, _, err = doc.ExtractSubDocument(&DocumentFilter{
+		IncludeOperationIds: []string{"getPet"},
+		IncludeExtensions:   map[string]string{"x-internal": ""},
+	}, datamodel.NewDocumentConfiguration())
+	require.NoError(t, err)
+	assert.Equal(t, 1, orderedmap.Len(sub.Paths.PathItems))
+	assert.Equal(t, []string{"Owner"}, schemaNames(sub))
+
+	// nothing is filtered out without a filter, but unused tags and components still go.
+	sub, _, err = doc.ExtractSubDocument(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, orderedmap.Len(sub.Paths.PathItems))
+	assert.Len(t, sub.Tags, 2)
+	assert.Equal(t, 5, orderedmap.Len(sub.Components.Schemas))
+}
+
+func TestDocumentFilter_Matches(t *testing.T) {
+	op := &Operation{Tags: []string{"a"}, OperationId: "op"}
+	assert.True(t, (*DocumentFilter)(nil).Matches("/a", "get", nil, op))
+	assert.True(t, (&DocumentFilter{ExcludeMethods: []string{"post"}}).Matches("/a", "get", nil, op))
+	assert.False(t, (&DocumentFilter{ExcludeTags: []string{"a"}}).Matches("/a", "get", nil, op))
+	assert.False(t, (&DocumentFilter{IncludeOperationIds: []string{"other"}}).Matches("/a", "get", nil, op))
+	assert.False(t, (&DocumentFilter{IncludeExtensions: map[string]string{"x-team": "pets"}}).Matches("/a", "get", nil, op))
+
+	assert.True(t, matchPathGlob("/pets/*", "/pets/{id}"))
+	assert.False(t, matchPathGlob("/pets/*", "/pets/{id}/toys"))
+	assert.True(t, matchPathGlob("/pets/**", "/pets"))
+	assert.True(t, matchPathGlob("/pets/**", "/pets/{id}/toys"))
+	assert.True(t, matchPathGlob("/*/orders/**", "/store/orders/{id}"))
+	assert.False(t, matchPathGlob("/pets/**", "/petshop"))
+	assert.False(t, matchPathGlob("/pets/**", "newPet"))
+}
+
+func TestDocument_ExtractSubDocument_KeepsConfiguration(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fetches++
+		_, _ = rw.Write([]byte("type: string"))
+	}))
+	defer server.Close()
+
+	info, _ := datamodel.ExtractSpecInfo([]byte(`openapi: 3.1.0
+info:
+  title: remote
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '` + server.URL + `/pet.yaml'`))
+	policy := &datamodel.RemoteReferencePolicy{AllowedHosts: []string{"127.0.0.1"}}
+	config := datamodel.NewDocumentConfiguration()
+	config.AllowRemoteReferences = true
+	config.RemotePolicy = policy
+	config.SkipCircularReferenceCheck = true
+	lowDoc, err := lowv3.CreateDocumentFromConfig(info, config)
+	require.NoError(t, err)
+	require.Equal(t, 1, fetches)
+	doc := NewDocument(lowDoc)
+
+	rebuilt := doc.documentConfiguration()
+	assert.Same(t, policy, rebuilt.RemotePolicy)
+	assert.True(t, rebuilt.SkipCircularReferenceCheck)
+	assert.False(t, rebuilt.BypassDocumentCheck)
+
+	// the policy still applies to the sub document, so a host that is no longer allowed is never fetched.
+	policy.AllowedHosts = []string{"pb33f.io"}
+	_, _, err = doc.ExtractSubDocument(&DocumentFilter{IncludeTags: []string{"pets"}}, nil)
+	var policyErr *datamodel.RemotePolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, 1, fetches)
+
+	// the remote cache is carried over too.
+	config = datamodel.NewDocumentConfiguration()
+	config.AllowRemoteReferences = true
+	config.RemoteCacheDirectory = t.TempDir()
+	config.RemoteCacheTTL = time.Hour
+	config.RemoteCacheOffline = true
+	lowDoc, _ = lowv3.CreateDocumentFromConfig(info, config)
+	rebuilt = NewDocument(lowDoc).documentConfiguration()
+	assert.Equal(t, config.RemoteCacheDirectory, rebuilt.RemoteCacheDirectory)
+	assert.Equal(t, time.Hour, rebuilt.RemoteCacheTTL)
+	assert.True(t, rebuilt.RemoteCacheOffline)
+	assert.False(t, rebuilt.SkipCircularReferenceCheck)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pb33f/libopenapi/orderedmap"
 	"github.com/pb33f/libopenapi/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 )
 
@@ -42,6 +43,14 @@ func initTest() {
 	}
 }
 
+// buildTestDocument builds the high-level model of a specification, failing the test if it has any errors.
+func buildTestDocument(t *testing.T, spec string) *Document {
+	info, _ := datamodel.ExtractSpecInfo([]byte(spec))
+	doc, err := lowv3.CreateDocumentFromConfig(info, datamodel.NewDocumentConfiguration())
+	require.NoError(t, err)
+	return NewDocument(doc)
+}
+
 func BenchmarkNewDocument(b *testing.B) {
 	initTest()
 	for i := 0; i < b.N; i++ {
@@ -901,3 +910,49 @@ func TestDocument_RenderJSONError(t *testing.T) {
 	assert.Error(t, e)
 	assert.Equal(t, "yaml: cannot decode !!float `-999.99` as a !!int", e.Error())
 }
+
+func TestDocument_PruneUnusedComponents(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: prune
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+components:
+  schemas:
+    Pet:
+      type: object
+    Orphan:
+      type: string
+  parameters:
+    Unused:
+      name: unused
+      in: query`
+
+	info, _ := datamodel.ExtractSpecInfo([]byte(spec))
+	lowDoc, err := lowv3.CreateDocumentFromConfig(info, datamodel.NewDocumentConfiguration())
+	assert.NoError(t, err)
+	highDoc := NewDocument(lowDoc)
+
+	rendered, pruned, err := highDoc.PruneUnusedComponents()
+	assert.NoError(t, err)
+	assert.Len(t, pruned, 2)
+	assert.Equal(t, "#/components/parameters/Unused", pruned[0].Definition)
+	assert.Equal(t, "#/components/schemas/Orphan", pruned[1].Definition)
+	assert.Equal(t, 1, orderedmap.Len(highDoc.Components.Schemas))
+	assert.Equal(t, 0, orderedmap.Len(highDoc.Components.Parameters))
+	assert.Contains(t, string(rendered), "Pet:")
+	assert.NotContains(t, string(rendered), "Orphan")
+	assert.NotContains(t, string(rendered), "unused")
+
+	_, _, err = (&Document{}).PruneUnusedComponents()
+	assert.EqualError(t, err, "the document has no index, unused components cannot be found")
+}
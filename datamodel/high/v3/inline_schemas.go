@@ -0,0 +1,333 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	lowbase "github.com/pb33f/libopenapi/datamodel/low/base"
+	"github.com/pb33f/libopenapi/index"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// InlineSchemaExtraction configures how ExtractInlineSchemas finds and names duplicate inline schemas.
+type InlineSchemaExtraction struct {
+	// MinOccurrences is the number of identical copies an inline schema needs before it's extracted, defaults to 2.
+	MinOccurrences int
+
+	// IncludeScalars extracts all duplicate inline schemas, by default only object and array schemas are extracted.
+	IncludeScalars bool
+
+	// Name returns the component name of an extracted schema. If it is nil, or returns an empty string, a name is
+	// generated from the title of the schema, the property that holds it, or the operation that uses it.
+	// Names are made unique by adding a number.
+	Name func(schema *ExtractedSchema) string
+
+	// DocumentConfiguration is used to build the new document. If it is nil, one is created from the configuration
+	// of the index of the document.
+	DocumentConfiguration *datamodel.DocumentConfiguration
+}
+
+// ExtractedSchema is a duplicated inline schema that has been moved into the schemas of the components.
+type ExtractedSchema struct {
+	// Name is the name of the new component schema.
+	Name string
+
+	// Hash is the hash of the low level schema shared by every copy.
+	Hash [32]byte
+
+	// Locations are the JSON pointers of every copy, which are now references to the new component.
+	Locations []string
+
+	// Node is the rendered schema.
+	Node *yaml.Node
+}
+
+// inlineSchema is a copy of an inline schema, found in the rendered document.
+type inlineSchema struct {
+	node    *yaml.Node
+	pointer []string
+	hash    [32]byte
+}
+
+// ExtractInlineSchemas finds inline schemas that are structurally identical, as decided by the Hash() of the low
+// level schema, moves each of them into 'components/schemas' and replaces every copy with a reference to it. Inline
+// schemas inside components (including the properties and items of component schemas) are extracted too, only the
+// component schemas themselves are not. Schemas that hold other duplicates are extracted first, and duplicates nested
+// inside them are left as they are, so every reference points at a schema with the same hash as the schema it
+// replaced.
+//
+// Only the root document is changed. The new document is built and returned with its rendered YAML and the schemas
+// that were extracted. Comparing the new document with this one (using what-changed) only reports the new components,
+// because references to identical schemas are not changes.
+func (d *Document) ExtractInlineSchemas(config *InlineSchemaExtraction) (*Document, []byte, []*ExtractedSchema, error) {
+	if config == nil {
+		config = &InlineSchemaExtraction{}
+	}
+	minOccurrences := config.MinOccurrences
+	if minOccurrences < 2 {
+		minOccurrences = 2
+	}
+	docConfig := config.DocumentConfiguration
+	if docConfig == nil {
+		docConfig = d.documentConfiguration()
+	}
+
+	rendered, err := d.Render()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(rendered, &root); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(root.Content) == 0 || !utils.IsNodeMap(root.Content[0]) {
+		return nil, nil, nil, errors.New("unable to extract inline schemas, the document is empty")
+	}
+
+	// index the rendered document, so the inline schemas are found in the nodes that will be changed.
+	idx := index.NewSpecIndexWithConfig(&root, index.CreateClosedAPIIndexConfig())
+	candidates := idx.GetAllInlineSchemaObjects()
+	if config.IncludeScalars {
+		candidates = idx.GetAllInlineSchemas()
+	}
+	pointers := make(map[*yaml.Node][]string)
+	mapPointers(root.Content[0], nil, pointers)
+
+	groups := make(map[[32]byte][]*inlineSchema)
+	var order [][32]byte
+	seen := make(map[*yaml.Node]bool)
+	for _, ref := range candidates {
+		pointer, ok := pointers[ref.Node]
+		if !ok || seen[ref.Node] || !utils.IsNodeMap(ref.Node) || len(pointer) == 0 || isComponentSchema(pointer) {
+			continue
+		}
+		seen[ref.Node] = true
+		schema := new(lowbase.Schema)
+		if bErr := schema.Build(context.Background(), ref.Node, idx); bErr != nil {
+			continue
+		}
+		s := &inlineSchema{node: ref.Node, pointer: pointer, hash: schema.Hash()}
+		if groups[s.hash] == nil {
+			order = append(order, s.hash)
+		}
+		groups[s.hash] = append(groups[s.hash], s)
+	}
+
+	// the shallowest schemas are extracted first, they may hold other duplicates.
+	depth := func(h [32]byte) int { return len(groups[h][0].pointer) }
+	sort.SliceStable(order, func(i, j int) bool { return depth(order[i]) < depth(order[j]) })
+
+	schemas := componentSchemasNode(root.Content[0])
+	names := make(map[string]bool)
+	for i := 0; i+1 < len(schemas.Content); i += 2 {
+		names[schemas.Content[i].Value] = true
+	}
+	detached := make(map[*yaml.Node]bool)
+	var extracted []*ExtractedSchema
+	for _, h := range order {
+		var copies []*inlineSchema
+		for _, s := range groups[h] {
+			if !detached[s.node] {
+				copies = append(copies, s)
+			}
+		}
+		if len(copies) < minOccurrences {
+			continue
+		}
+		e := &ExtractedSchema{Hash: h}
+		for _, s := range copies {
+			e.Locations = append(e.Locations, "#/"+strings.Join(escapePointer(s.pointer), "/"))
+		}
+
+		// the first copy becomes the component, nothing inside any of the copies is extracted again.
+		component := *copies[0].node
+		e.Node = &component
+		for _, s := range copies {
+			markDetached(s.node, detached)
+		}
+		if config.Name != nil {
+			e.Name = config.Name(e)
+		}
+		if e.Name == "" {
+			e.Name = inlineSchemaName(&component, copies[0].pointer, root.Content[0])
+		}
+		e.Name = uniqueName(e.Name, names)
+		schemas.Content = append(schemas.Content, utils.CreateStringNode(e.Name), e.Node)
+
+		for _, s := range copies {
+			*s.node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+				utils.CreateStringNode("$ref"),
+				utils.CreateStringNode("#/components/schemas/" + e.Name),
+			}}
+		}
+		extracted = append(extracted, e)
+	}
+
+	out, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	doc, err := buildSubDocument(out, docConfig)
+	if doc == nil {
+		return nil, nil, nil, err
+	}
+	return doc, out, extracted, err
+}
+
+// mapPointers records the JSON pointer segments (unescaped) of every node beneath a node.
+func mapPointers(node *yaml.Node, pointer []string, pointers map[*yaml.Node][]string) {
+	if node == nil {
+		return
+	}
+	if _, ok := pointers[node]; ok {
+		return
+	}
+	pointers[node] = pointer
+	switch {
+	case utils.IsNodeMap(node):
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			mapPointers(node.Content[i+1], append(pointer[:len(pointer):len(pointer)], node.Content[i].Value), pointers)
+		}
+	case utils.IsNodeArray(node):
+		for i, n := range node.Content {
+			mapPointers(n, append(pointer[:len(pointer):len(pointer)], fmt.Sprint(i)), pointers)
+		}
+	}
+}
+
+func escapePointer(segments []string) []string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+	}
+	return escaped
+}
+
+func markDetached(node *yaml.Node, detached map[*yaml.Node]bool) {
+	for _, n := range node.Content {
+		if !detached[n] {
+			detached[n] = true
+			markDetached(n, detached)
+		}
+	}
+}
+
+// isComponentSchema returns true if a pointer is a schema of the components, schemas inside it are inline schemas.
+func isComponentSchema(pointer []string) bool {
+	return len(pointer) == 3 && pointer[0] == "components" && pointer[1] == "schemas"
+}
+
+// componentSchemasNode returns the 'components/schemas' node of the rendered root, creating it if needed.
+func componentSchemasNode(root *yaml.Node) *yaml.Node {
+	_, components := utils.FindKeyNodeTop("components", root.Content)
+	if !utils.IsNodeMap(components) {
+		components = utils.CreateEmptyMapNode()
+		root.Content = append(root.Content, utils.CreateStringNode("components"), components)
+	}
+	for i := 0; i+1 < len(components.Content); i += 2 {
+		if components.Content[i].Value == "schemas" && utils.IsNodeMap(components.Content[i+1]) {
+			return components.Content[i+1]
+		}
+	}
+	schemas := utils.CreateEmptyMapNode()
+	components.Content = append(components.Content, utils.CreateStringNode("schemas"), schemas)
+	return schemas
+}
+
+// inlineSchemaName generates a component name for a schema from its title, the property that holds it, or the
+// operation that uses it.
+func inlineSchemaName(schema *yaml.Node, pointer []string, root *yaml.Node) string {
+	if _, title := utils.FindKeyNodeTop("title", schema.Content); utils.IsNodeStringValue(title) {
+		if name := pascalCase(title.Value); name != "" {
+			return name
+		}
+	}
+	last := len(pointer) - 1
+	switch {
+	case last > 0 && (pointer[last-1] == "properties" || pointer[last-1] == "patternProperties"):
+		if name := pascalCase(pointer[last]); name != "" {
+			return name
+		}
+	case last > 1 && pointer[last] == "items" && pointer[last-2] == "properties":
+		if name := pascalCase(pointer[last-1]); name != "" {
+			return name + "Item"
+		}
+	}
+
+	// look for the operation holding the schema.
+	for i := len(pointer) - 1; i > 0; i-- {
+		if pointer[0] != "paths" && pointer[0] != "webhooks" || i < 2 {
+			break
+		}
+		operation := nodeAt(root, pointer[:i+1])
+		_, id := utils.FindKeyNodeTop("operationId", operation.Content)
+		if !utils.IsNodeMap(operation) || !utils.IsNodeStringValue(id) {
+			continue
+		}
+		suffix := "Parameter"
+		if i+1 < len(pointer) {
+			switch pointer[i+1] {
+			case "requestBody":
+				suffix = "Request"
+			case "responses":
+				suffix = "Response"
+			}
+		}
+		return pascalCase(id.Value) + suffix
+	}
+	return "InlineSchema"
+}
+
+func nodeAt(node *yaml.Node, pointer []string) *yaml.Node {
+	for _, segment := range pointer {
+		var found *yaml.Node
+		if utils.IsNodeMap(node) {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					found = node.Content[i+1]
+					break
+				}
+			}
+		}
+		if found == nil {
+			return &yaml.Node{}
+		}
+		node = found
+	}
+	return node
+}
+
+func pascalCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func uniqueName(name string, names map[string]bool) string {
+	unique := name
+	for i := 2; names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	names[unique] = true
+	return unique
+}
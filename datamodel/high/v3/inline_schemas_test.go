@@ -0,0 +1,268 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"testing"
+
+	whatChanged "github.com/pb33f/libopenapi/what-changed"
+	"github.com/pb33f/libopenapi/what-changed/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inlineSchemaSpec = `openapi: 3.1.0
+info:
+  title: inline
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                    owner:
+                      type: object
+                      properties:
+                        id:
+                          type: integer
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+                owner:
+                  type: object
+                  properties:
+                    id:
+                      type: integer
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+                  owner:
+                    type: object
+                    properties:
+                      id:
+                        type: integer
+  /owners:
+    get:
+      operationId: listOwners
+      responses:
+        "200":
+          description: owners
+          content:
+            application/json:
+              schema:
+                type: object
+                title: owner list
+                properties:
+                  owner:
+                    type: object
+                    properties:
+                      id:
+                        type: integer
+                  other:
+                    type: object
+                    properties:
+                      id:
+                        type: integer
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        id:
+          type: string`
+
+func TestDocument_ExtractInlineSchemas(t *testing.T) {
+	doc := buildTestDocument(t, inlineSchemaSpec)
+
+	extracted, rendered, schemas, err := doc.ExtractInlineSchemas(nil)
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+
+	// the pet is extracted first, the owners nested in the pets are left inline.
+	assert.Equal(t, "ListPetsResponse", schemas[0].Name)
+	assert.Equal(t, []string{
+		"#/paths/~1pets/get/responses/200/content/application~1json/schema/items",
+		"#/paths/~1pets/post/requestBody/content/application~1json/schema",
+		"#/paths/~1pets/post/responses/201/content/application~1json/schema",
+	}, schemas[0].Locations)
+
+	// the name of the property clashes with an existing component.
+	assert.Equal(t, "Owner2", schemas[1].Name)
+	assert.Equal(t, []string{
+		"#/paths/~1owners/get/responses/200/content/application~1json/schema/properties/owner",
+		"#/paths/~1owners/get/responses/200/content/application~1json/schema/properties/other",
+	}, schemas[1].Locations)
+	assert.Equal(t, []string{"Owner", "ListPetsResponse", "Owner2"}, schemaNames(extracted))
+	assert.Contains(t, string(rendered), "$ref: '#/components/schemas/ListPetsResponse'")
+
+	pet := extracted.Components.Schemas.GetOrZero("ListPetsResponse").Schema()
+	assert.False(t, pet.Properties.GetOrZero("owner").IsReference())
+	owners := extracted.Paths.PathItems.GetOrZero("/owners").Get.Responses.Codes.GetOrZero("200")
+	assert.False(t, owners.Content.GetOrZero("application/json").Schema.IsReference())
+
+	// the only changes are the new components, the references point to identical schemas.
+	changes := whatChanged.CompareOpenAPIDocuments(doc.GoLow(), extracted.GoLow())
+	require.NotNil(t, changes)
+	for _, c := range changes.GetAllChanges() {
+		assert.Equal(t, model.ObjectAdded, c.ChangeType, c.Property)
+	}
+	assert.Equal(t, 2, changes.TotalChanges())
+	assert.Equal(t, 0, changes.TotalBreakingChanges())
+}
+
+func TestDocument_ExtractInlineSchemas_Config(t *testing.T) {
+	doc := buildTestDocument(t, inlineSchemaSpec)
+
+	// only schemas copied four times are extracted, and they are named by the caller.
+	_, _, schemas, err := doc.ExtractInlineSchemas(&InlineSchemaExtraction{
+		MinOccurrences: 4,
+		Name: func(schema *ExtractedSchema) string {
+			return "Identity"
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "Identity", schemas[0].Name)
+	assert.Len(t, schemas[0].Locations, 5)
+
+	// scalars can be extracted too, an empty name falls back to a generated one.
+	doc = buildTestDocument(t, `openapi: 3.1.0
+info:
+  title: scalars
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    get:
+      operationId: getPet
+      parameters:
+        - name: id
+          in: path
+          schema:
+            type: string
+            format: uuid
+  /owners/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          schema:
+            type: string
+            format: uuid`)
+	_, _, schemas, err = doc.ExtractInlineSchemas(nil)
+	require.NoError(t, err)
+	assert.Empty(t, schemas)
+
+	extracted, _, schemas, err := doc.ExtractInlineSchemas(&InlineSchemaExtraction{
+		IncludeScalars: true,
+		Name:           func(*ExtractedSchema) string { return "" },
+	})
+	require.NoError(t, err)
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "GetPetParameter", schemas[0].Name)
+	assert.Equal(t, "uuid", extracted.Components.Schemas.GetOrZero("GetPetParameter").Schema().Format)
+}
+
+func TestDocument_ExtractInlineSchemas_Components(t *testing.T) {
+	doc := buildTestDocument(t, `openapi: 3.1.0
+info:
+  title: components
+  version: 1.0.0
+components:
+  responses:
+    Problem:
+      description: a problem
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              "@@":
+                type: object
+                properties:
+                  code:
+                    type: integer
+  schemas:
+    Pet:
+      type: object
+      properties:
+        address:
+          type: object
+          properties:
+            street:
+              type: string
+        codes:
+          type: object
+          properties:
+            code:
+              type: integer
+    Owner:
+      type: object
+      properties:
+        address:
+          type: object
+          properties:
+            street:
+              type: string
+    Street:
+      type: object
+      properties:
+        street:
+          type: string`)
+
+	// inline schemas nested in components are extracted, the component schemas are not.
+	extracted, _, schemas, err := doc.ExtractInlineSchemas(nil)
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+	assert.Equal(t, "Address", schemas[0].Name)
+	assert.Equal(t, []string{
+		"#/components/schemas/Pet/properties/address",
+		"#/components/schemas/Owner/properties/address",
+	}, schemas[0].Locations)
+
+	// a property name without letters or digits falls back to a generated name.
+	assert.Equal(t, "InlineSchema", schemas[1].Name)
+	assert.Equal(t, []string{
+		"#/components/responses/Problem/content/application~1json/schema/properties/@@",
+		"#/components/schemas/Pet/properties/codes",
+	}, schemas[1].Locations)
+
+	assert.Equal(t, []string{"Pet", "Owner", "Street", "Address", "InlineSchema"}, schemaNames(extracted))
+	assert.True(t, extracted.Components.Schemas.GetOrZero("Owner").Schema().Properties.GetOrZero("address").IsReference())
+}
+
+func TestDocument_ExtractInlineSchemas_Nothing(t *testing.T) {
+	doc := buildTestDocument(t, `openapi: 3.1.0
+info:
+  title: nothing
+  version: 1.0.0`)
+	extracted, _, schemas, err := doc.ExtractInlineSchemas(nil)
+	require.NoError(t, err)
+	assert.Empty(t, schemas)
+	assert.Empty(t, schemaNames(extracted))
+}
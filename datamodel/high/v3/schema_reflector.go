@@ -0,0 +1,591 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	packageQualifier  = regexp.MustCompile(`(?:[\w.\-]+/)*\w+\.`)
+)
+
+// SchemaReflector builds schemas from Go types, so documents can be written code-first. Named struct types are added
+// to the schemas of the components, and referenced using $ref, every other type is described inline.
+//
+// Struct fields follow the rules of encoding/json: exported fields are properties, named by their json tag, fields
+// tagged with '-' are skipped, and the fields of embedded structs are promoted into the struct embedding them. Fields
+// are required unless they are pointers, or are tagged with 'omitempty' or 'omitzero'. time.Time is a 'date-time'
+// string, []byte is a 'byte' string, types implementing encoding.TextMarshaler are strings, and types implementing
+// json.Marshaler, interfaces and json.RawMessage can hold any value.
+//
+// Schemas can be refined with an 'openapi' tag, holding comma separated options (values can not contain commas):
+//
+//	ID   string `json:"id" openapi:"format=uuid,readOnly"`
+//	Name string `json:"name" openapi:"minLength=3,maxLength=64,pattern=^[a-z]+$" description:"The name of the pet."`
+//	Kind string `json:"kind,omitempty" openapi:"enum=cat|dog,default=cat,required"`
+//
+// Supported flags are required, optional, nullable, deprecated, readOnly, writeOnly and uniqueItems. Supported values
+// are format, pattern, title, description, minLength, maxLength, minItems, maxItems, minProperties, maxProperties,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, enum (separated by '|'), default, example and
+// const. Nullable schemas use 'null' as a type, as OpenAPI 3.1 does.
+type SchemaReflector struct {
+	// Name returns the component name of a struct type. If it is nil, or returns an empty string, the name of the
+	// type is used, including the type arguments of generic types ('Page[pets.Pet]' is named 'PagePet'). Names are
+	// made unique by adding a number.
+	Name func(t reflect.Type) string
+
+	components *Components
+	names      map[reflect.Type]string
+	used       map[string]bool
+	added      []reflect.Type
+	reflecting map[reflect.Type]bool
+}
+
+// reflectedField is a property of a struct, found by structFields.
+type reflectedField struct {
+	name      string
+	field     reflect.StructField
+	depth     int
+	tagged    bool
+	omitEmpty bool
+	asString  bool
+}
+
+// NewSchemaReflector creates a SchemaReflector that adds the schemas of struct types to the components supplied.
+func NewSchemaReflector(components *Components) *SchemaReflector {
+	return &SchemaReflector{
+		components: components,
+		names:      make(map[reflect.Type]string),
+		used:       make(map[string]bool),
+		reflecting: make(map[reflect.Type]bool),
+	}
+}
+
+// Reflect builds the schema of the type of a value. The value can also be a reflect.Type.
+func (r *SchemaReflector) Reflect(value any) (*highbase.SchemaProxy, error) {
+	if t, ok := value.(reflect.Type); ok {
+		return r.ReflectType(t)
+	}
+	return r.ReflectType(reflect.TypeOf(value))
+}
+
+// ReflectType builds the schema of a type. Named struct types, and the named struct types they use, are added to the
+// schemas of the components (if they have not been already) and a reference to the component is returned. Named
+// slice, array and map types that contain themselves are added to the components too. Any other type returns an
+// inline schema. If an error is returned, no schemas are added.
+func (r *SchemaReflector) ReflectType(t reflect.Type) (*highbase.SchemaProxy, error) {
+	if r.components == nil {
+		return nil, errors.New("unable to reflect schema, no components supplied")
+	}
+	if t == nil {
+		return nil, errors.New("unable to reflect schema, no type supplied")
+	}
+	if r.components.Schemas == nil {
+		r.components.Schemas = orderedmap.New[string, *highbase.SchemaProxy]()
+	}
+	for name := range r.components.Schemas.KeysFromOldest() {
+		r.used[name] = true
+	}
+
+	r.added = nil
+	schema, ref, err := r.typeSchema(t)
+	if err != nil {
+		for _, added := range r.added {
+			r.components.Schemas.Delete(r.names[added])
+			delete(r.used, r.names[added])
+			delete(r.names, added)
+		}
+		return nil, err
+	}
+	if ref != "" {
+		return highbase.CreateSchemaProxyRef(ref), nil
+	}
+	return highbase.CreateSchemaProxy(schema), nil
+}
+
+// typeSchema returns the inline schema of a type, or a reference to the component holding it.
+func (r *SchemaReflector) typeSchema(t reflect.Type) (*highbase.Schema, string, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &highbase.Schema{Type: []string{"string"}, Format: "date-time"}, "", nil
+	case t == rawMessageType:
+		return &highbase.Schema{}, "", nil
+	case implements(t, jsonMarshalerType):
+		return &highbase.Schema{}, "", nil
+	case implements(t, textMarshalerType):
+		return &highbase.Schema{Type: []string{"string"}}, "", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &highbase.Schema{Type: []string{"boolean"}}, "", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &highbase.Schema{Type: []string{"integer"}, Format: "int32"}, "", nil
+	case reflect.Int, reflect.Int64:
+		return &highbase.Schema{Type: []string{"integer"}, Format: "int64"}, "", nil
+	case reflect.Uint8, reflect.Uint16:
+		minimum := float64(0)
+		return &highbase.Schema{Type: []string{"integer"}, Format: "int32", Minimum: &minimum}, "", nil
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		minimum := float64(0)
+		return &highbase.Schema{Type: []string{"integer"}, Format: "int64", Minimum: &minimum}, "", nil
+	case reflect.Float32:
+		return &highbase.Schema{Type: []string{"number"}, Format: "float"}, "", nil
+	case reflect.Float64:
+		return &highbase.Schema{Type: []string{"number"}, Format: "double"}, "", nil
+	case reflect.String:
+		return &highbase.Schema{Type: []string{"string"}}, "", nil
+	case reflect.Interface:
+		return &highbase.Schema{}, "", nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if t.Name() == "" {
+			schema, err := r.containerSchema(t)
+			return schema, "", err
+		}
+		if name, ok := r.names[t]; ok {
+			return nil, "#/components/schemas/" + name, nil
+		}
+		if r.reflecting[t] {
+			// the type contains itself, so it becomes a component, which is set once it has been reflected.
+			name := r.componentName(t)
+			r.components.Schemas.Set(name, nil)
+			return nil, "#/components/schemas/" + name, nil
+		}
+		r.reflecting[t] = true
+		schema, err := r.containerSchema(t)
+		delete(r.reflecting, t)
+		if err != nil {
+			return nil, "", err
+		}
+		if name, ok := r.names[t]; ok {
+			r.components.Schemas.Set(name, highbase.CreateSchemaProxy(schema))
+			return nil, "#/components/schemas/" + name, nil
+		}
+		return schema, "", nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			schema, err := r.structSchema(t)
+			return schema, "", err
+		}
+		if name, ok := r.names[t]; ok {
+			return nil, "#/components/schemas/" + name, nil
+		}
+		name := r.componentName(t)
+
+		// the component is added before its properties are reflected, so that it's before the schemas it uses, and
+		// so that recursive types reference it.
+		r.components.Schemas.Set(name, nil)
+		schema, err := r.structSchema(t)
+		if err != nil {
+			return nil, "", err
+		}
+		r.components.Schemas.Set(name, highbase.CreateSchemaProxy(schema))
+		return nil, "#/components/schemas/" + name, nil
+	}
+	return nil, "", fmt.Errorf("unsupported type '%s'", t)
+}
+
+// componentName picks a unique component name for a type, and records it.
+func (r *SchemaReflector) componentName(t reflect.Type) string {
+	name := ""
+	if r.Name != nil {
+		name = r.Name(t)
+	}
+	if name == "" {
+		name = typeName(t)
+	}
+	name = uniqueName(name, r.used)
+	r.names[t] = name
+	r.added = append(r.added, t)
+	return name
+}
+
+// containerSchema builds the inline schema of a slice, array or map type.
+func (r *SchemaReflector) containerSchema(t reflect.Type) (*highbase.Schema, error) {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && !implements(t.Elem(), textMarshalerType) {
+			// encoding/json encodes byte slices as base64 strings.
+			return &highbase.Schema{Type: []string{"string"}, Format: "byte"}, nil
+		}
+		items, err := r.proxy(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := &highbase.Schema{
+			Type:  []string{"array"},
+			Items: &highbase.DynamicValue[*highbase.SchemaProxy, bool]{A: items},
+		}
+		if t.Kind() == reflect.Array {
+			length := int64(t.Len())
+			schema.MinItems, schema.MaxItems = &length, &length
+		}
+		return schema, nil
+	case reflect.Map:
+		key := t.Key()
+		if key.Kind() != reflect.String && !isInteger(key.Kind()) && !implements(key, textMarshalerType) {
+			return nil, fmt.Errorf("unsupported map key type '%s'", key)
+		}
+		values, err := r.proxy(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &highbase.Schema{
+			Type:                 []string{"object"},
+			AdditionalProperties: &highbase.DynamicValue[*highbase.SchemaProxy, bool]{A: values},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type '%s'", t)
+}
+
+// proxy returns the schema of a type as a proxy, holding a reference or an inline schema.
+func (r *SchemaReflector) proxy(t reflect.Type) (*highbase.SchemaProxy, error) {
+	schema, ref, err := r.typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	if ref != "" {
+		return highbase.CreateSchemaProxyRef(ref), nil
+	}
+	return highbase.CreateSchemaProxy(schema), nil
+}
+
+// structSchema builds the object schema of a struct.
+func (r *SchemaReflector) structSchema(t reflect.Type) (*highbase.Schema, error) {
+	schema := &highbase.Schema{Type: []string{"object"}}
+	for _, f := range structFields(t) {
+		fieldType := f.field.Type
+		var property *highbase.Schema
+		ref := ""
+		var err error
+		if f.asString && isStringable(fieldType) {
+			property = &highbase.Schema{Type: []string{"string"}}
+		} else {
+			property, ref, err = r.typeSchema(fieldType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to reflect field '%s.%s': %w", t.Name(), f.field.Name, err)
+		}
+
+		required := !f.omitEmpty && fieldType.Kind() != reflect.Pointer
+		options, _ := f.field.Tag.Lookup("openapi")
+		description := f.field.Tag.Get("description")
+		if ref != "" && (options != "" || description != "") {
+			// a reference can not have siblings in OpenAPI 3.0, so it's wrapped to be refined.
+			property = &highbase.Schema{AllOf: []*highbase.SchemaProxy{highbase.CreateSchemaProxyRef(ref)}}
+			ref = ""
+		}
+		if property != nil {
+			property.Description = description
+			if required, err = applySchemaOptions(property, options, required); err != nil {
+				return nil, fmt.Errorf("unable to reflect field '%s.%s': %w", t.Name(), f.field.Name, err)
+			}
+		}
+
+		if schema.Properties == nil {
+			schema.Properties = orderedmap.New[string, *highbase.SchemaProxy]()
+		}
+		if ref != "" {
+			schema.Properties.Set(f.name, highbase.CreateSchemaProxyRef(ref))
+		} else {
+			schema.Properties.Set(f.name, highbase.CreateSchemaProxy(property))
+		}
+		if required {
+			schema.Required = append(schema.Required, f.name)
+		}
+	}
+	return schema, nil
+}
+
+// applySchemaOptions applies the options of an 'openapi' tag to a schema, returning if the property is required.
+func applySchemaOptions(schema *highbase.Schema, options string, required bool) (bool, error) {
+	if strings.TrimSpace(options) == "" {
+		return required, nil
+	}
+	flag := true
+	for _, option := range strings.Split(options, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(option), "=")
+		if key == "" {
+			continue
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "required":
+			required = true
+		case "optional":
+			required = false
+		case "nullable":
+			if len(schema.Type) > 0 {
+				schema.Type = append(schema.Type, "null")
+			} else {
+				// an allOf wrapping a reference can not add a type, so null is an alternative to it.
+				schema.OneOf = append(schema.AllOf,
+					highbase.CreateSchemaProxy(&highbase.Schema{Type: []string{"null"}}))
+				schema.AllOf = nil
+			}
+		case "deprecated":
+			schema.Deprecated = &flag
+		case "readonly":
+			schema.ReadOnly = &flag
+		case "writeonly":
+			schema.WriteOnly = &flag
+		case "uniqueitems":
+			schema.UniqueItems = &flag
+		case "format":
+			schema.Format = value
+		case "pattern":
+			if _, err = regexp.Compile(value); err == nil {
+				schema.Pattern = value
+			}
+		case "title":
+			schema.Title = value
+		case "description":
+			schema.Description = value
+		case "minlength":
+			schema.MinLength, err = parseOptionInt(value)
+		case "maxlength":
+			schema.MaxLength, err = parseOptionInt(value)
+		case "minitems":
+			schema.MinItems, err = parseOptionInt(value)
+		case "maxitems":
+			schema.MaxItems, err = parseOptionInt(value)
+		case "minproperties":
+			schema.MinProperties, err = parseOptionInt(value)
+		case "maxproperties":
+			schema.MaxProperties, err = parseOptionInt(value)
+		case "minimum":
+			schema.Minimum, err = parseOptionFloat(value)
+		case "maximum":
+			schema.Maximum, err = parseOptionFloat(value)
+		case "multipleof":
+			schema.MultipleOf, err = parseOptionFloat(value)
+		case "exclusiveminimum":
+			var f *float64
+			if f, err = parseOptionFloat(value); err == nil {
+				schema.ExclusiveMinimum = &highbase.DynamicValue[bool, float64]{N: 1, B: *f}
+			}
+		case "exclusivemaximum":
+			var f *float64
+			if f, err = parseOptionFloat(value); err == nil {
+				schema.ExclusiveMaximum = &highbase.DynamicValue[bool, float64]{N: 1, B: *f}
+			}
+		case "enum":
+			schema.Enum = nil
+			for _, v := range strings.Split(value, "|") {
+				var node *yaml.Node
+				if node, err = optionValue(schema, v); err != nil {
+					break
+				}
+				schema.Enum = append(schema.Enum, node)
+			}
+		case "default":
+			schema.Default, err = optionValue(schema, value)
+		case "example":
+			var node *yaml.Node
+			if node, err = optionValue(schema, value); err == nil {
+				schema.Examples = append(schema.Examples, node)
+			}
+		case "const":
+			schema.Const, err = optionValue(schema, value)
+		default:
+			return required, fmt.Errorf("unknown openapi option '%s'", key)
+		}
+		if !hasValue && !isFlagOption(key) {
+			err = errors.New("a value is required")
+		}
+		if err != nil {
+			return required, fmt.Errorf("invalid openapi option '%s': %w", option, err)
+		}
+	}
+	return required, nil
+}
+
+func isFlagOption(key string) bool {
+	switch strings.ToLower(key) {
+	case "required", "optional", "nullable", "deprecated", "readonly", "writeonly", "uniqueitems":
+		return true
+	}
+	return false
+}
+
+func parseOptionInt(value string) (*int64, error) {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, errors.New("not an integer")
+	}
+	return &i, nil
+}
+
+func parseOptionFloat(value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.New("not a number")
+	}
+	return &f, nil
+}
+
+// optionValue creates the node of a value (of an enum, default, example or const) for the type of a schema.
+func optionValue(schema *highbase.Schema, value string) (*yaml.Node, error) {
+	kind := ""
+	if len(schema.Type) > 0 {
+		kind = schema.Type[0]
+	}
+	switch kind {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("'%s' is not an integer", value)
+		}
+		return utils.CreateIntNode(value), nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", value)
+		}
+		return utils.CreateFloatNode(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a boolean", value)
+		}
+		return utils.CreateBoolNode(strconv.FormatBool(b)), nil
+	}
+	return utils.CreateStringNode(value), nil
+}
+
+// structFields returns the properties of a struct, using the rules of encoding/json. Fields of embedded structs are
+// promoted, and when several fields have the same name the shallowest wins, or the only tagged one at that depth. If
+// neither decides, none of them are used.
+func structFields(t reflect.Type) []*reflectedField {
+	var fields []*reflectedField
+	var visit func(t reflect.Type, depth int, visited map[reflect.Type]bool)
+	visit = func(t reflect.Type, depth int, visited map[reflect.Type]bool) {
+		visited[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if sf.Anonymous {
+				ft := sf.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && ft.Kind() != reflect.Struct {
+					continue
+				}
+				if name == "" && ft.Kind() == reflect.Struct {
+					if !visited[ft] {
+						visit(ft, depth+1, visited)
+					}
+					continue
+				}
+			} else if !sf.IsExported() {
+				continue
+			}
+			field := &reflectedField{name: name, field: sf, depth: depth, tagged: name != ""}
+			if name == "" {
+				field.name = sf.Name
+			}
+			for _, opt := range strings.Split(opts, ",") {
+				switch opt {
+				case "omitempty", "omitzero":
+					field.omitEmpty = true
+				case "string":
+					field.asString = true
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	visit(t, 0, make(map[reflect.Type]bool))
+
+	byName := make(map[string][]*reflectedField)
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var result []*reflectedField
+	for _, f := range fields {
+		if dominantField(byName[f.name]) == f {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// dominantField returns the field that wins amongst fields with the same name, or nil if none does.
+func dominantField(fields []*reflectedField) *reflectedField {
+	depth := fields[0].depth
+	for _, f := range fields {
+		depth = min(depth, f.depth)
+	}
+	var shallowest, tagged []*reflectedField
+	for _, f := range fields {
+		if f.depth == depth {
+			shallowest = append(shallowest, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	switch {
+	case len(shallowest) == 1:
+		return shallowest[0]
+	case len(tagged) == 1:
+		return tagged[0]
+	}
+	return nil
+}
+
+// typeName returns the name of a type, without the packages of its type arguments, in pascal case.
+func typeName(t reflect.Type) string {
+	return pascalCase(packageQualifier.ReplaceAllString(t.Name(), ""))
+}
+
+// implements returns true if a type, or a pointer to it, implements an interface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isStringable returns true if the ',string' option of encoding/json applies to a type.
+func isStringable(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return isInteger(t.Kind())
+}
@@ -0,0 +1,383 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package v3
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	lowv3 "github.com/pb33f/libopenapi/datamodel/low/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type reflectedAudit struct {
+	Created time.Time  `json:"created"`
+	Updated *time.Time `json:"updated,omitempty"`
+	Version int64      `json:"-"`
+}
+
+type reflectedOwner struct {
+	Name string            `json:"name" description:"The name of the owner, in full."`
+	Pets []*reflectedPet   `json:"pets,omitempty"`
+	Meta map[string]any    `json:"meta,omitempty"`
+	Tags map[int]string    `json:"tags,omitempty"`
+	Data json.RawMessage   `json:"data,omitempty"`
+	IP   net.IP            `json:"ip,omitempty"`
+	Self *reflectedOwner   `json:"self,omitempty" openapi:"nullable"`
+	Refs [2]reflectedAudit `json:"refs"`
+}
+
+type reflectedPet struct {
+	reflectedAudit
+	ID       string          `json:"id" openapi:"format=uuid,readOnly"`
+	Name     string          `json:"name" openapi:"minLength=3,maxLength=64,pattern=^[a-z]+$"`
+	Kind     string          `json:"kind,omitempty" openapi:"enum=cat|dog,default=cat,required"`
+	Age      uint8           `json:"age" openapi:"maximum=40,example=3"`
+	Weight   float64         `json:"weight,string"`
+	Photo    []byte          `json:"photo,omitempty"`
+	Owner    *reflectedOwner `json:"owner,omitempty"`
+	Sitter   reflectedOwner  `json:"sitter" openapi:"deprecated"`
+	Nickname *string         `json:"nickname" openapi:"nullable"`
+	Extra    struct {
+		Note string `json:"note,omitempty"`
+	} `json:"extra"`
+	Unnamed string
+	hidden  string
+}
+
+type reflectedPage[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+type (
+	reflectedTree map[string]reflectedTree
+	reflectedList []reflectedList
+)
+
+func renderReflected(t *testing.T, proxy *base.SchemaProxy) string {
+	b, err := yaml.Marshal(proxy)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(b))
+}
+
+func TestSchemaReflector_Reflect(t *testing.T) {
+	components := &Components{}
+	reflector := NewSchemaReflector(components)
+
+	proxy, err := reflector.Reflect(reflectedPet{})
+	require.NoError(t, err)
+	assert.Equal(t, "#/components/schemas/ReflectedPet", proxy.GetReference())
+
+	// the components are in the order they are first used, and referenced by each other.
+	var names []string
+	for name := range components.Schemas.KeysFromOldest() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"ReflectedPet", "ReflectedOwner", "ReflectedAudit"}, names)
+
+	assert.Equal(t, `type: object
+properties:
+    created:
+        type: string
+        format: date-time
+    updated:
+        type: string
+        format: date-time
+    id:
+        type: string
+        format: uuid
+        readOnly: true
+    name:
+        type: string
+        maxLength: 64
+        minLength: 3
+        pattern: ^[a-z]+$
+    kind:
+        type: string
+        enum:
+            - cat
+            - dog
+        default: cat
+    age:
+        type: integer
+        examples:
+            - 3
+        maximum: 40
+        format: int32
+    weight:
+        type: string
+    photo:
+        type: string
+        format: byte
+    owner:
+        $ref: '#/components/schemas/ReflectedOwner'
+    sitter:
+        allOf:
+            - $ref: '#/components/schemas/ReflectedOwner'
+        deprecated: true
+    nickname:
+        type:
+            - string
+            - "null"
+    extra:
+        type: object
+        properties:
+            note:
+                type: string
+    Unnamed:
+        type: string
+required:
+    - created
+    - id
+    - name
+    - kind
+    - age
+    - weight
+    - sitter
+    - extra
+    - Unnamed`, renderReflected(t, components.Schemas.GetOrZero("ReflectedPet")))
+
+	assert.Equal(t, `type: object
+properties:
+    name:
+        type: string
+        description: The name of the owner, in full.
+    pets:
+        type: array
+        items:
+            $ref: '#/components/schemas/ReflectedPet'
+    meta:
+        type: object
+        additionalProperties: {}
+    tags:
+        type: object
+        additionalProperties:
+            type: string
+    data: {}
+    ip:
+        type: string
+    self:
+        oneOf:
+            - $ref: '#/components/schemas/ReflectedOwner'
+            - type: "null"
+    refs:
+        type: array
+        items:
+            $ref: '#/components/schemas/ReflectedAudit'
+        maxItems: 2
+        minItems: 2
+required:
+    - name
+    - refs`, renderReflected(t, components.Schemas.GetOrZero("ReflectedOwner")))
+
+	// reflecting a type again reuses its component.
+	proxy, err = reflector.Reflect(&reflectedOwner{})
+	require.NoError(t, err)
+	assert.Equal(t, "#/components/schemas/ReflectedOwner", proxy.GetReference())
+	assert.Equal(t, 3, components.Schemas.Len())
+}
+
+func TestSchemaReflector_Inline(t *testing.T) {
+	reflector := NewSchemaReflector(&Components{})
+	for value, expected := range map[any]string{
+		true:                               "type: boolean",
+		int32(1):                           "type: integer\nformat: int32",
+		1:                                  "type: integer\nformat: int64",
+		uint(1):                            "type: integer\nformat: int64",
+		float32(1):                         "type: number\nformat: float",
+		"":                                 "type: string",
+		time.Second:                        "type: integer\nformat: int64",
+		reflect.TypeOf([]int{}):            "type: array\nitems:\n    type: integer\n    format: int64",
+		reflect.TypeOf((*any)(nil)).Elem(): "{}",
+	} {
+		proxy, err := reflector.Reflect(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, renderReflected(t, proxy), "%T", value)
+	}
+
+	// unsigned integers have a minimum of zero, which is not rendered for models built in code.
+	proxy, err := reflector.Reflect(uint8(1))
+	require.NoError(t, err)
+	require.NotNil(t, proxy.Schema().Minimum)
+	assert.Equal(t, 0.0, *proxy.Schema().Minimum)
+}
+
+func TestSchemaReflector_RecursiveContainers(t *testing.T) {
+	components := &Components{}
+	reflector := NewSchemaReflector(components)
+	proxy, err := reflector.Reflect(struct {
+		Children reflectedTree `json:"children"`
+		Nested   reflectedList `json:"nested"`
+		Tags     []string      `json:"tags"`
+	}{})
+	require.NoError(t, err)
+
+	// the containers holding themselves become components, other named and unnamed containers stay inline.
+	assert.Equal(t, []string{"ReflectedTree", "ReflectedList"}, slices.Collect(components.Schemas.KeysFromOldest()))
+	assert.Equal(t, "type: object\nadditionalProperties:\n    $ref: '#/components/schemas/ReflectedTree'",
+		renderReflected(t, components.Schemas.GetOrZero("ReflectedTree")))
+	assert.Equal(t, "type: array\nitems:\n    $ref: '#/components/schemas/ReflectedList'",
+		renderReflected(t, components.Schemas.GetOrZero("ReflectedList")))
+	assert.Contains(t, renderReflected(t, proxy), "children:\n        $ref: '#/components/schemas/ReflectedTree'")
+	assert.Contains(t, renderReflected(t, proxy), "tags:\n        type: array")
+
+	// reflecting a recursive container directly returns a reference to it.
+	proxy, err = reflector.Reflect(reflectedList{})
+	require.NoError(t, err)
+	assert.Equal(t, "#/components/schemas/ReflectedList", proxy.GetReference())
+	assert.Equal(t, 2, components.Schemas.Len())
+}
+
+func TestSchemaReflector_Names(t *testing.T) {
+	components := &Components{Schemas: orderedmap.New[string, *base.SchemaProxy]()}
+	components.Schemas.Set("ReflectedAudit", base.CreateSchemaProxy(&base.Schema{Type: []string{"string"}}))
+
+	reflector := NewSchemaReflector(components)
+	proxy, err := reflector.Reflect(reflectedPage[reflectedAudit]{})
+	require.NoError(t, err)
+
+	// generic types are named after their type arguments, and existing components are not replaced.
+	assert.Equal(t, "#/components/schemas/ReflectedPageReflectedAudit", proxy.GetReference())
+	assert.NotNil(t, components.Schemas.GetOrZero("ReflectedAudit2"))
+
+	reflector = NewSchemaReflector(components)
+	reflector.Name = func(t reflect.Type) string {
+		if t == reflect.TypeOf(reflectedOwner{}) {
+			return "Owner"
+		}
+		return ""
+	}
+	proxy, err = reflector.Reflect(reflectedOwner{})
+	require.NoError(t, err)
+	assert.Equal(t, "#/components/schemas/Owner", proxy.GetReference())
+	assert.NotNil(t, components.Schemas.GetOrZero("ReflectedPet"))
+}
+
+func TestSchemaReflector_EmbeddedConflicts(t *testing.T) {
+	type first struct {
+		Label string
+		Code  string
+		ID    string
+	}
+	type second struct {
+		Label string `json:"Label"`
+		Code  string
+		ID    string
+	}
+	type combined struct {
+		first
+		second
+		ID int `json:"ID"`
+	}
+	proxy, err := NewSchemaReflector(&Components{}).Reflect(struct{ combined }{})
+	require.NoError(t, err)
+
+	// the shallowest field wins, then the only tagged one, and other conflicts are dropped.
+	assert.Equal(t, `type: object
+properties:
+    Label:
+        type: string
+    ID:
+        type: integer
+        format: int64
+required:
+    - Label
+    - ID`, renderReflected(t, proxy))
+}
+
+func TestSchemaReflector_Errors(t *testing.T) {
+	type badKey struct {
+		Values map[struct{ A int }]string `json:"values"`
+	}
+	type badOption struct {
+		Name string `json:"name" openapi:"colour=red"`
+	}
+	type badValue struct {
+		Age int `json:"age" openapi:"enum=1|two"`
+	}
+	type badFlag struct {
+		Age int `json:"age" openapi:"minimum"`
+	}
+	type wrapper struct {
+		Inner badKey `json:"inner"`
+	}
+
+	components := &Components{}
+	reflector := NewSchemaReflector(components)
+	for _, tc := range []struct {
+		value    any
+		expected string
+	}{
+		{badKey{}, "unable to reflect field 'badKey.Values': unsupported map key type 'struct { A int }'"},
+		{badOption{}, "unable to reflect field 'badOption.Name': unknown openapi option 'colour'"},
+		{badValue{}, "unable to reflect field 'badValue.Age': invalid openapi option 'enum=1|two': 'two' is not an integer"},
+		{badFlag{}, "unable to reflect field 'badFlag.Age': invalid openapi option 'minimum': a value is required"},
+		{wrapper{}, "unable to reflect field 'wrapper.Inner': unable to reflect field 'badKey.Values'"},
+		{make(chan int), "unsupported type 'chan int'"},
+	} {
+		_, err := reflector.Reflect(tc.value)
+		assert.ErrorContains(t, err, tc.expected)
+	}
+
+	// nothing is added when reflection fails.
+	assert.Equal(t, 0, components.Schemas.Len())
+
+	_, err := reflector.Reflect(nil)
+	assert.EqualError(t, err, "unable to reflect schema, no type supplied")
+	_, err = NewSchemaReflector(nil).Reflect(reflectedPet{})
+	assert.EqualError(t, err, "unable to reflect schema, no components supplied")
+}
+
+func TestSchemaReflector_Render(t *testing.T) {
+	components := &Components{}
+	reflector := NewSchemaReflector(components)
+	pet, err := reflector.Reflect(reflectedPet{})
+	require.NoError(t, err)
+
+	doc := &Document{
+		Version:    "3.1.0",
+		Info:       &base.Info{Title: "Pets", Version: "1.0.0"},
+		Components: components,
+		Paths: &Paths{PathItems: orderedmap.ToOrderedMap(map[string]*PathItem{
+			"/pets": {Get: &Operation{Responses: &Responses{Codes: orderedmap.ToOrderedMap(map[string]*Response{
+				"200": {
+					Description: "A pet.",
+					Content:     orderedmap.ToOrderedMap(map[string]*MediaType{"application/json": {Schema: pet}}),
+				},
+			})}}},
+		})},
+	}
+	rendered, err := doc.Render()
+	require.NoError(t, err)
+
+	// the rendered document can be built again, and its references resolve.
+	info, err := datamodel.ExtractSpecInfo(rendered)
+	require.NoError(t, err)
+	// pets and owners reference each other, through optional properties and arrays.
+	config := datamodel.NewDocumentConfiguration()
+	config.IgnorePolymorphicCircularReferences = true
+	config.IgnoreArrayCircularReferences = true
+	lowDoc, err := lowv3.CreateDocumentFromConfig(info, config)
+	require.NoError(t, err)
+	built := NewDocument(lowDoc)
+
+	schema := built.Paths.PathItems.GetOrZero("/pets").Get.Responses.Codes.GetOrZero("200").
+		Content.GetOrZero("application/json").Schema.Schema()
+	require.NotNil(t, schema)
+	assert.Equal(t, "uuid", schema.Properties.GetOrZero("id").Schema().Format)
+	owner := schema.Properties.GetOrZero("owner").Schema()
+	require.NotNil(t, owner)
+	assert.Equal(t, "The name of the owner, in full.", owner.Properties.GetOrZero("name").Schema().Description)
+}
@@ -131,10 +131,24 @@ func (s *Swagger) GetExtensions() *orderedmap.Map[low.KeyReference[string], low.
 // CreateDocumentFromConfig will create a new Swagger document from the provided SpecInfo and DocumentConfiguration.
 func CreateDocumentFromConfig(info *datamodel.SpecInfo,
 	configuration *datamodel.DocumentConfiguration) (*Swagger, error) {
-	return createDocument(info, configuration)
+	return createDocument(context.Background(), info, configuration)
 }
 
-func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration) (*Swagger, error) {
+// CreateDocumentFromConfigWithContext is the same as CreateDocumentFromConfig, except indexing, circular reference
+// checking and model building stop as soon as the context is cancelled or reaches its deadline. If that happens,
+// no document is returned and the error is ctx.Err().
+func CreateDocumentFromConfigWithContext(ctx context.Context, info *datamodel.SpecInfo,
+	configuration *datamodel.DocumentConfiguration) (*Swagger, error) {
+	return createDocument(ctx, info, configuration)
+}
+
+func createDocument(ctx context.Context, info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration) (*Swagger, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if config.RemotePolicy != nil && config.RemoteURLHandler != nil {
+		return nil, datamodel.ErrRemotePolicyCustomHandler
+	}
 	doc := Swagger{Swagger: low.ValueReference[string]{Value: info.Version, ValueNode: info.RootNode}}
 	doc.Extensions = low.ExtractExtensions(info.RootNode.Content[0])
 
@@ -175,12 +189,28 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 	}
 
 	// if base url is provided, add a remote filesystem to the rolodex.
+	var remoteCacheErr error
 	if idxConfig.BaseURL != nil {
 
+		// if a cache directory is provided, persist remote documents to disk.
+		if config.RemoteCacheDirectory != "" {
+			remoteCache, cacheErr := index.NewRemoteCache(&index.RemoteCacheConfig{
+				Directory: config.RemoteCacheDirectory,
+				TTL:       config.RemoteCacheTTL,
+				Offline:   config.RemoteCacheOffline,
+			})
+			if cacheErr != nil {
+				remoteCacheErr = cacheErr
+			}
+			idxConfig.RemoteCache = remoteCache
+		}
+
+		idxConfig.RemotePolicy = config.RemotePolicy
+
 		// create a remote filesystem
 		remoteFS, _ := index.NewRemoteFSWithConfig(idxConfig)
 		if config.RemoteURLHandler != nil {
-			remoteFS.RemoteHandlerFunc = config.RemoteURLHandler
+			remoteFS.SetRemoteHandlerFunc(config.RemoteURLHandler)
 		}
 		idxConfig.AllowRemoteLookup = true
 
@@ -192,13 +222,21 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 	doc.Rolodex = rolodex
 
 	var errs []error
+	if remoteCacheErr != nil {
+		errs = append(errs, remoteCacheErr)
+	}
 
 	// index all the things!
-	_ = rolodex.IndexTheRolodex()
+	_ = rolodex.IndexTheRolodexWithContext(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// check for circular references
 	if !config.SkipCircularReferenceCheck {
-		rolodex.CheckForCircularReferences()
+		if err := rolodex.CheckForCircularReferencesWithContext(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	// extract errors
@@ -214,8 +252,6 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 	// build out swagger scalar variables.
 	_ = low.BuildModel(info.RootNode.Content[0], &doc)
 
-	ctx := context.Background()
-
 	// extract externalDocs
 	extDocs, err := low.ExtractObject[*base.ExternalDoc](ctx, base.ExternalDocsLabel, info.RootNode, rolodex.GetRootIndex())
 	if err != nil {
@@ -234,8 +270,9 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 		extractTags,
 		extractSecurity,
 	}
-	doneChan := make(chan bool)
-	errChan := make(chan error)
+	// buffered, so extractions never block if the context is done before they complete.
+	doneChan := make(chan bool, len(extractionFuncs))
+	errChan := make(chan error, len(extractionFuncs))
 	for i := range extractionFuncs {
 		go extractionFuncs[i](ctx, info.RootNode.Content[0], &doc, rolodex.GetRootIndex(), doneChan, errChan)
 	}
@@ -247,6 +284,8 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 		case e := <-errChan:
 			completedExtractions++
 			errs = append(errs, e)
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 
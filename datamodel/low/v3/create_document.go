@@ -20,15 +20,44 @@ import (
 // Deprecated: Use CreateDocumentFromConfig instead. This function will be removed in a later version, it
 // defaults to allowing file and remote references, and does not support relative file references.
 func CreateDocument(info *datamodel.SpecInfo) (*Document, error) {
-	return createDocument(info, datamodel.NewDocumentConfiguration())
+	return createDocument(context.Background(), info, datamodel.NewDocumentConfiguration())
 }
 
 // CreateDocumentFromConfig Create a new document from the provided SpecInfo and DocumentConfiguration pointer.
 func CreateDocumentFromConfig(info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration) (*Document, error) {
-	return createDocument(info, config)
+	return createDocument(context.Background(), info, config)
 }
 
-func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration) (*Document, error) {
+// CreateDocumentFromConfigWithContext is the same as CreateDocumentFromConfig, except indexing, circular reference
+// checking and model building stop as soon as the context is cancelled or reaches its deadline. If that happens,
+// no document is returned and the error is ctx.Err().
+func CreateDocumentFromConfigWithContext(ctx context.Context, info *datamodel.SpecInfo,
+	config *datamodel.DocumentConfiguration,
+) (*Document, error) {
+	return createDocument(ctx, info, config)
+}
+
+// CreateDocumentFromRolodex creates a new document from a rolodex that has already been indexed, for example after
+// changed files have been re-indexed using Rolodex.ReindexFiles(). The SpecInfo must hold the root document of
+// the rolodex. Building stops as soon as the context is cancelled or reaches its deadline.
+func CreateDocumentFromRolodex(ctx context.Context, info *datamodel.SpecInfo,
+	config *datamodel.DocumentConfiguration, rolodex *index.Rolodex,
+) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rolodex == nil || rolodex.GetRootIndex() == nil {
+		return nil, errors.New("the rolodex has not been indexed, cannot create document")
+	}
+	doc, err := newDocument(info)
+	if err != nil {
+		return nil, err
+	}
+	doc.Rolodex = rolodex
+	return buildDocument(ctx, info, config, doc, nil)
+}
+
+func newDocument(info *datamodel.SpecInfo) (*Document, error) {
 	_, labelNode, versionNode := utils.FindKeyNodeFull(OpenAPILabel, info.RootNode.Content)
 	var version low.NodeReference[string]
 	if versionNode == nil {
@@ -37,6 +66,20 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 	version = low.NodeReference[string]{Value: versionNode.Value, KeyNode: labelNode, ValueNode: versionNode}
 	doc := Document{Version: version}
 	doc.Nodes = low.ExtractNodes(nil, info.RootNode.Content[0])
+	return &doc, nil
+}
+
+func createDocument(loadCtx context.Context, info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration) (*Document, error) {
+	if err := loadCtx.Err(); err != nil {
+		return nil, err
+	}
+	if config.RemotePolicy != nil && config.RemoteURLHandler != nil {
+		return nil, datamodel.ErrRemotePolicyCustomHandler
+	}
+	doc, err := newDocument(info)
+	if err != nil {
+		return nil, err
+	}
 	// create an index config and shadow the document configuration.
 	idxConfig := index.CreateClosedAPIIndexConfig()
 	idxConfig.SpecInfo = info
@@ -77,12 +120,28 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 		}
 	}
 	// if base url is provided, add a remote filesystem to the rolodex.
+	var remoteCacheErr error
 	if idxConfig.BaseURL != nil || config.AllowRemoteReferences {
 
+		// if a cache directory is provided, persist remote documents to disk.
+		if config.RemoteCacheDirectory != "" {
+			remoteCache, cacheErr := index.NewRemoteCache(&index.RemoteCacheConfig{
+				Directory: config.RemoteCacheDirectory,
+				TTL:       config.RemoteCacheTTL,
+				Offline:   config.RemoteCacheOffline,
+			})
+			if cacheErr != nil {
+				remoteCacheErr = cacheErr
+			}
+			idxConfig.RemoteCache = remoteCache
+		}
+
+		idxConfig.RemotePolicy = config.RemotePolicy
+
 		// create a remote filesystem
 		remoteFS, _ := index.NewRemoteFSWithConfig(idxConfig)
 		if config.RemoteURLHandler != nil {
-			remoteFS.RemoteHandlerFunc = config.RemoteURLHandler
+			remoteFS.SetRemoteHandlerFunc(config.RemoteURLHandler)
 		}
 		idxConfig.AllowRemoteLookup = true
 
@@ -96,26 +155,43 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 
 	// index the rolodex
 	var errs []error
+	if remoteCacheErr != nil {
+		errs = append(errs, remoteCacheErr)
+	}
 
 	// index all the things.
 	if config.Logger != nil {
 		config.Logger.Debug("indexing rolodex")
 	}
 	now := time.Now()
-	_ = rolodex.IndexTheRolodex()
+	_ = rolodex.IndexTheRolodexWithContext(loadCtx)
+	if err := loadCtx.Err(); err != nil {
+		return nil, err
+	}
 	done := time.Duration(time.Since(now).Milliseconds())
 	if config.Logger != nil {
 		config.Logger.Debug("rolodex indexed", "ms", done)
 	}
+	return buildDocument(loadCtx, info, config, doc, errs)
+}
+
+// buildDocument checks the indexed rolodex of the document for circular references, and then extracts the model.
+func buildDocument(loadCtx context.Context, info *datamodel.SpecInfo, config *datamodel.DocumentConfiguration,
+	doc *Document, errs []error,
+) (*Document, error) {
+	rolodex := doc.Rolodex
+
 	// check for circular references
 	if config.Logger != nil {
 		config.Logger.Debug("checking for circular references")
 	}
-	now = time.Now()
+	now := time.Now()
 	if !config.SkipCircularReferenceCheck {
-		rolodex.CheckForCircularReferences()
+		if err := rolodex.CheckForCircularReferencesWithContext(loadCtx); err != nil {
+			return nil, err
+		}
 	}
-	done = time.Duration(time.Since(now).Milliseconds())
+	done := time.Duration(time.Since(now).Milliseconds())
 	if config.Logger != nil {
 		if !config.SkipCircularReferenceCheck {
 			config.Logger.Debug("circular check completed", "ms", done)
@@ -133,7 +209,7 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 
 	var cacheMap sync.Map
 	modelContext := base.ModelContext{SchemaCache: &cacheMap}
-	ctx := context.WithValue(context.Background(), "modelCtx", &modelContext)
+	ctx := context.WithValue(loadCtx, "modelCtx", &modelContext)
 
 	doc.Extensions = low.ExtractExtensions(info.RootNode.Content[0])
 	low.ExtractExtensionNodes(ctx, doc.Extensions, doc.Nodes)
@@ -173,14 +249,21 @@ func createDocument(info *datamodel.SpecInfo, config *datamodel.DocumentConfigur
 	}
 	now = time.Now()
 	for _, f := range extractionFuncs {
-		runExtraction(ctx, info, &doc, rolodex.GetRootIndex(), f, &errs, &wg)
+		if loadCtx.Err() != nil {
+			wg.Done()
+			continue
+		}
+		runExtraction(ctx, info, doc, rolodex.GetRootIndex(), f, &errs, &wg)
 	}
 	wg.Wait()
+	if err := loadCtx.Err(); err != nil {
+		return nil, err
+	}
 	done = time.Duration(time.Since(now).Milliseconds())
 	if config.Logger != nil {
 		config.Logger.Debug("extractions complete", "time", done)
 	}
-	return &doc, errors.Join(errs...)
+	return doc, errors.Join(errs...)
 }
 
 func extractInfo(ctx context.Context, info *datamodel.SpecInfo, doc *Document, idx *index.SpecIndex) error {
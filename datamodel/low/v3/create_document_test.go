@@ -1,6 +1,7 @@
 package v3
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -881,3 +882,41 @@ func ExampleCreateDocument() {
 	fmt.Print(document.Info.Value.Contact.Value.Email.Value)
 	// Output: apiteam@swagger.io
 }
+
+func TestCreateDocumentFromConfigWithContext(t *testing.T) {
+	data, _ := os.ReadFile("../../../test_specs/burgershop.openapi.yaml")
+	info, _ := datamodel.ExtractSpecInfo(data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d, err := CreateDocumentFromConfigWithContext(ctx, info, datamodel.NewDocumentConfiguration())
+	assert.Nil(t, d)
+	assert.Equal(t, context.Canceled, err)
+
+	d, err = CreateDocumentFromConfigWithContext(context.Background(), info, datamodel.NewDocumentConfiguration())
+	assert.NoError(t, err)
+	assert.Equal(t, "3.1.0", d.Version.Value)
+}
+
+func TestCreateDocumentFromRolodex(t *testing.T) {
+	data, _ := os.ReadFile("../../../test_specs/burgershop.openapi.yaml")
+	info, _ := datamodel.ExtractSpecInfo(data)
+	config := datamodel.NewDocumentConfiguration()
+
+	d, err := CreateDocumentFromConfig(info, config)
+	require.NoError(t, err)
+
+	// the same rolodex can be used to build the document again, without indexing anything.
+	rebuilt, err := CreateDocumentFromRolodex(context.Background(), info, config, d.Rolodex)
+	assert.NoError(t, err)
+	assert.Same(t, d.Rolodex.GetRootIndex(), rebuilt.Index)
+	assert.Equal(t, d.Components.Value.Schemas.Value.Len(), rebuilt.Components.Value.Schemas.Value.Len())
+
+	_, err = CreateDocumentFromRolodex(context.Background(), info, config, nil)
+	assert.EqualError(t, err, "the rolodex has not been indexed, cannot create document")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = CreateDocumentFromRolodex(ctx, info, config, d.Rolodex)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package datamodel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// RemotePolicyReason describes which rule of a RemoteReferencePolicy was violated.
+type RemotePolicyReason int
+
+const (
+	// RemotePolicySchemeDenied is used when the scheme of a remote reference is denied, or not allowed.
+	RemotePolicySchemeDenied RemotePolicyReason = iota
+	// RemotePolicyHostDenied is used when the host of a remote reference is denied, or not allowed.
+	RemotePolicyHostDenied
+	// RemotePolicyPrivateAddress is used when a remote reference resolves to a private, loopback or link-local address.
+	RemotePolicyPrivateAddress
+	// RemotePolicyResponseTooLarge is used when a single remote document is larger than the MaxResponseBytes limit.
+	RemotePolicyResponseTooLarge
+	// RemotePolicyTooManyRedirects is used when a remote fetch is redirected more than the MaxRedirects limit.
+	RemotePolicyTooManyRedirects
+	// RemotePolicyTotalBytesExceeded is used when all remote documents fetched exceed the MaxTotalBytes limit.
+	RemotePolicyTotalBytesExceeded
+	// RemotePolicyFetchDepthExceeded is used when a chain of remote references is deeper than the MaxFetchDepth limit.
+	RemotePolicyFetchDepthExceeded
+)
+
+// String returns a readable name for the reason.
+func (r RemotePolicyReason) String() string {
+	switch r {
+	case RemotePolicySchemeDenied:
+		return "scheme denied"
+	case RemotePolicyHostDenied:
+		return "host denied"
+	case RemotePolicyPrivateAddress:
+		return "private address"
+	case RemotePolicyResponseTooLarge:
+		return "response too large"
+	case RemotePolicyTooManyRedirects:
+		return "too many redirects"
+	case RemotePolicyTotalBytesExceeded:
+		return "total bytes exceeded"
+	case RemotePolicyFetchDepthExceeded:
+		return "fetch depth exceeded"
+	}
+	return "unknown"
+}
+
+// ErrRemotePolicyCustomHandler is returned when a RemoteReferencePolicy is used with a custom RemoteURLHandler. The
+// policy checks every address that is connected to (preventing DNS rebinding) and every redirect, using its own http
+// client, so it can not be enforced when remote documents are fetched by a custom handler.
+var ErrRemotePolicyCustomHandler = errors.New("a remote reference policy can not be enforced by a custom remote URL handler")
+
+// RemotePolicyError is returned when a remote reference violates a RemoteReferencePolicy.
+type RemotePolicyError struct {
+	Reason RemotePolicyReason
+	URL    string
+	Detail string
+}
+
+// Error returns a human-readable description of the violation.
+func (e *RemotePolicyError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("remote reference policy violation (%s) for '%s': %s", e.Reason, e.URL, e.Detail)
+	}
+	return fmt.Sprintf("remote reference policy violation (%s) for '%s'", e.Reason, e.URL)
+}
+
+// RemoteReferencePolicy restricts what remote references are allowed to be fetched. It's designed for
+// parsing untrusted specifications, where any remote $ref could be used to reach internal services, or
+// exhaust resources by serving enormous (or endless) documents.
+//
+// Zero values mean no restriction.
+type RemoteReferencePolicy struct {
+	// AllowedSchemes is a list of URL schemes that can be fetched (e.g. "https"). Empty allows all schemes.
+	AllowedSchemes []string
+
+	// DeniedSchemes is a list of URL schemes that can never be fetched. Checked before AllowedSchemes.
+	DeniedSchemes []string
+
+	// AllowedHosts is a list of host names that can be fetched. A leading wildcard (e.g. "*.pb33f.io") matches any
+	// subdomain. Empty allows all hosts.
+	AllowedHosts []string
+
+	// DeniedHosts is a list of host names that can never be fetched, supports the same wildcards as AllowedHosts.
+	// Checked before AllowedHosts.
+	DeniedHosts []string
+
+	// BlockPrivateNetworks will deny any host that is, or resolves to, a private, loopback, link-local or
+	// unspecified address.
+	BlockPrivateNetworks bool
+
+	// MaxResponseBytes is the largest a single remote document can be.
+	MaxResponseBytes int64
+
+	// MaxRedirects is the number of redirects that will be followed for a single fetch. A negative value blocks
+	// all redirects, zero uses the default of the http client.
+	MaxRedirects int
+
+	// MaxTotalBytes is the total number of bytes that can be fetched across all remote documents.
+	MaxTotalBytes int64
+
+	// MaxFetchDepth is the deepest a chain of remote references can go. A remote document referenced by the root
+	// document is at a depth of one, anything it references is at a depth of two, and so on.
+	MaxFetchDepth int
+}
+
+// CheckURL checks the scheme and host of a URL against the policy. Addresses are not resolved, use
+// CheckAddress for each address the host resolves to.
+func (p *RemoteReferencePolicy) CheckURL(u *url.URL) error {
+	if p == nil || u == nil {
+		return nil
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if containsFold(p.DeniedSchemes, scheme) ||
+		(len(p.AllowedSchemes) > 0 && !containsFold(p.AllowedSchemes, scheme)) {
+		return &RemotePolicyError{Reason: RemotePolicySchemeDenied, URL: u.String(),
+			Detail: fmt.Sprintf("scheme '%s' is not allowed", scheme)}
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchesHost(p.DeniedHosts, host) || (len(p.AllowedHosts) > 0 && !matchesHost(p.AllowedHosts, host)) {
+		return &RemotePolicyError{Reason: RemotePolicyHostDenied, URL: u.String(),
+			Detail: fmt.Sprintf("host '%s' is not allowed", host)}
+	}
+	if p.BlockPrivateNetworks {
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return &RemotePolicyError{Reason: RemotePolicyPrivateAddress, URL: u.String(),
+				Detail: fmt.Sprintf("host '%s' is a loopback address", host)}
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			return p.CheckAddress(u.String(), ip)
+		}
+	}
+	return nil
+}
+
+// CheckAddress checks an IP address against the policy, returning an error if private networks are blocked
+// and the address is private, loopback, link-local or unspecified.
+func (p *RemoteReferencePolicy) CheckAddress(location string, ip net.IP) error {
+	if p == nil || !p.BlockPrivateNetworks || ip == nil {
+		return nil
+	}
+	if IsPrivateAddress(ip) {
+		return &RemotePolicyError{Reason: RemotePolicyPrivateAddress, URL: location,
+			Detail: fmt.Sprintf("address '%s' is not publicly routable", ip.String())}
+	}
+	return nil
+}
+
+// IsPrivateAddress returns true if the IP address is private, loopback, link-local, unspecified or multicast.
+func IsPrivateAddress(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if strings.HasPrefix(p, "*.") {
+			if strings.HasSuffix(host, p[1:]) {
+				return true
+			}
+			continue
+		}
+		if p == host {
+			return true
+		}
+	}
+	return false
+}
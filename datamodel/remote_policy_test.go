@@ -0,0 +1,86 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package datamodel
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkPolicyURL(p *RemoteReferencePolicy, u string) error {
+	parsed, _ := url.Parse(u)
+	return p.CheckURL(parsed)
+}
+
+func TestRemoteReferencePolicy_CheckURL(t *testing.T) {
+	p := &RemoteReferencePolicy{
+		AllowedSchemes: []string{"HTTPS"},
+		AllowedHosts:   []string{"*.pb33f.io", "quobix.com"},
+		DeniedHosts:    []string{"evil.pb33f.io"},
+	}
+	assert.NoError(t, checkPolicyURL(p, "https://api.pb33f.io/spec.yaml"))
+	assert.NoError(t, checkPolicyURL(p, "https://quobix.com:8443/spec.yaml"))
+
+	var policyErr *RemotePolicyError
+	err := checkPolicyURL(p, "http://api.pb33f.io/spec.yaml")
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, RemotePolicySchemeDenied, policyErr.Reason)
+	assert.Equal(t, "remote reference policy violation (scheme denied) for 'http://api.pb33f.io/spec.yaml': "+
+		"scheme 'http' is not allowed", err.Error())
+
+	err = checkPolicyURL(p, "https://evil.pb33f.io/spec.yaml")
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, RemotePolicyHostDenied, policyErr.Reason)
+
+	err = checkPolicyURL(p, "https://pb33f.io.evil.com/spec.yaml")
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, RemotePolicyHostDenied, policyErr.Reason)
+
+	p = &RemoteReferencePolicy{DeniedSchemes: []string{"http"}}
+	assert.Error(t, checkPolicyURL(p, "http://pb33f.io/spec.yaml"))
+	assert.NoError(t, checkPolicyURL(p, "https://pb33f.io/spec.yaml"))
+
+	var nilPolicy *RemoteReferencePolicy
+	assert.NoError(t, checkPolicyURL(nilPolicy, "http://127.0.0.1/spec.yaml"))
+}
+
+func TestRemoteReferencePolicy_BlockPrivateNetworks(t *testing.T) {
+	p := &RemoteReferencePolicy{BlockPrivateNetworks: true}
+	for _, u := range []string{
+		"http://localhost/a.yaml",
+		"http://api.localhost/a.yaml",
+		"http://127.0.0.1:8080/a.yaml",
+		"http://10.1.2.3/a.yaml",
+		"http://192.168.0.1/a.yaml",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/a.yaml",
+		"http://[fe80::1]/a.yaml",
+		"http://0.0.0.0/a.yaml",
+	} {
+		var policyErr *RemotePolicyError
+		err := checkPolicyURL(p, u)
+		if assert.True(t, errors.As(err, &policyErr), u) {
+			assert.Equal(t, RemotePolicyPrivateAddress, policyErr.Reason, u)
+		}
+	}
+	assert.NoError(t, checkPolicyURL(p, "https://8.8.8.8/a.yaml"))
+	assert.NoError(t, checkPolicyURL(p, "https://pb33f.io/a.yaml"))
+
+	assert.Error(t, p.CheckAddress("x", net.ParseIP("172.16.0.1")))
+	assert.NoError(t, p.CheckAddress("x", net.ParseIP("1.1.1.1")))
+	assert.NoError(t, p.CheckAddress("x", nil))
+	assert.NoError(t, (&RemoteReferencePolicy{}).CheckAddress("x", net.ParseIP("127.0.0.1")))
+}
+
+func TestRemotePolicyReason_String(t *testing.T) {
+	assert.Equal(t, "too many redirects", RemotePolicyTooManyRedirects.String())
+	assert.Equal(t, "fetch depth exceeded", RemotePolicyFetchDepthExceeded.String())
+	assert.Equal(t, "unknown", RemotePolicyReason(99).String())
+	assert.Equal(t, "remote reference policy violation (response too large) for 'x'",
+		(&RemotePolicyError{Reason: RemotePolicyResponseTooLarge, URL: "x"}).Error())
+}
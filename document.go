@@ -14,8 +14,12 @@
 package libopenapi
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/pb33f/libopenapi/index"
 
@@ -104,6 +108,52 @@ type Document interface {
 	Serialize() ([]byte, error)
 }
 
+// DocumentWithContext is a Document that can build its models with a context, so building can be cancelled or
+// given a deadline. Every Document returned by this package implements it.
+//
+//	doc, _ := libopenapi.NewDocumentWithConfiguration(spec, config)
+//	model, errs := doc.(libopenapi.DocumentWithContext).BuildV3ModelWithContext(ctx)
+type DocumentWithContext interface {
+	Document
+
+	// BuildV2ModelWithContext is the same as BuildV2Model, except building stops as soon as the context is cancelled
+	// or reaches its deadline (including any remote fetches, indexing and circular reference checks). If that
+	// happens, no model is returned and the only error is ctx.Err().
+	BuildV2ModelWithContext(ctx context.Context) (*DocumentModel[v2high.Swagger], []error)
+
+	// BuildV3ModelWithContext is the same as BuildV3Model, except building stops as soon as the context is cancelled
+	// or reaches its deadline (including any remote fetches, indexing and circular reference checks). If that
+	// happens, no model is returned and the only error is ctx.Err().
+	BuildV3ModelWithContext(ctx context.Context) (*DocumentModel[v3high.Document], []error)
+}
+
+// ReindexableDocument is a Document whose model can be rebuilt from only the files that have changed. Every
+// Document returned by this package implements it.
+type ReindexableDocument interface {
+	Document
+
+	// ReindexFiles will re-index the supplied changed files in the rolodex (along with only the files that depend
+	// on them), and then rebuild the OpenAPI model from the rolodex. This is much faster than creating a new
+	// document when a few files of a large, exploded specification have changed. Changes to the root document
+	// must supply its new content. If no model has been built yet, it is built from scratch.
+	// **IMPORTANT** This method only supports OpenAPI Documents.
+	ReindexFiles(changes ...*index.RolodexFileChange) (*DocumentModel[v3high.Document], []error)
+}
+
+// ComponentRenamer is a Document that can rename its components. Every Document returned by this package
+// implements it.
+type ComponentRenamer interface {
+	Document
+
+	// RenameComponent renames a component (for example '#/components/schemas/Pet', or
+	// 'models/pet.yaml#/components/schemas/Pet' for a component in another file) and rewrites every reference to
+	// it in every local file of the rolodex, then rebuilds the OpenAPI model. Nothing is written to disk, the
+	// returned rename holds the new content of each changed file, which can be written back with WriteFiles.
+	// If no model has been built yet, it is built first.
+	// **IMPORTANT** This method only supports OpenAPI Documents.
+	RenameComponent(definition, newName string) (*DocumentModel[v3high.Document], *index.ComponentRename, []error)
+}
+
 type document struct {
 	rolodex           *index.Rolodex
 	version           string
@@ -113,6 +163,12 @@ type document struct {
 	highSwaggerModel  *DocumentModel[v2high.Swagger]
 }
 
+var (
+	_ DocumentWithContext = &document{}
+	_ ReindexableDocument = &document{}
+	_ ComponentRenamer    = &document{}
+)
+
 // DocumentModel represents either a Swagger document (version 2) or an OpenAPI document (version 3) that is
 // built from a parent Document.
 type DocumentModel[T v2high.Swagger | v3high.Document] struct {
@@ -240,6 +296,10 @@ func (d *document) Render() ([]byte, error) {
 }
 
 func (d *document) BuildV2Model() (*DocumentModel[v2high.Swagger], []error) {
+	return d.BuildV2ModelWithContext(context.Background())
+}
+
+func (d *document) BuildV2ModelWithContext(ctx context.Context) (*DocumentModel[v2high.Swagger], []error) {
 	if d.highSwaggerModel != nil {
 		return d.highSwaggerModel, nil
 	}
@@ -260,7 +320,13 @@ func (d *document) BuildV2Model() (*DocumentModel[v2high.Swagger], []error) {
 	}
 
 	var docErr error
-	lowDoc, docErr = v2low.CreateDocumentFromConfig(d.info, d.config)
+	lowDoc, docErr = v2low.CreateDocumentFromConfigWithContext(ctx, d.info, d.config)
+	if ctx.Err() != nil && errors.Is(docErr, ctx.Err()) {
+		return nil, []error{ctx.Err()}
+	}
+	if lowDoc == nil {
+		return nil, utils.UnwrapErrors(docErr)
+	}
 	d.rolodex = lowDoc.Rolodex
 
 	if docErr != nil {
@@ -287,6 +353,10 @@ func (d *document) BuildV2Model() (*DocumentModel[v2high.Swagger], []error) {
 }
 
 func (d *document) BuildV3Model() (*DocumentModel[v3high.Document], []error) {
+	return d.BuildV3ModelWithContext(context.Background())
+}
+
+func (d *document) BuildV3ModelWithContext(ctx context.Context) (*DocumentModel[v3high.Document], []error) {
 	if d.highOpenAPI3Model != nil {
 		return d.highOpenAPI3Model, nil
 	}
@@ -310,9 +380,110 @@ func (d *document) BuildV3Model() (*DocumentModel[v3high.Document], []error) {
 	}
 
 	var docErr error
-	lowDoc, docErr = v3low.CreateDocumentFromConfig(d.info, d.config)
+	lowDoc, docErr = v3low.CreateDocumentFromConfigWithContext(ctx, d.info, d.config)
+	if ctx.Err() != nil && errors.Is(docErr, ctx.Err()) {
+		return nil, []error{ctx.Err()}
+	}
+	if lowDoc == nil {
+		return nil, utils.UnwrapErrors(docErr)
+	}
 	d.rolodex = lowDoc.Rolodex
+	return d.buildV3HighModel(lowDoc, docErr, errs)
+}
+
+// ReindexFiles will re-index the supplied changed files in the rolodex, and then rebuild the OpenAPI model.
+func (d *document) ReindexFiles(changes ...*index.RolodexFileChange) (*DocumentModel[v3high.Document], []error) {
+	if d.highOpenAPI3Model == nil || d.rolodex == nil || d.rolodex.GetRootIndex() == nil {
+		return d.BuildV3Model()
+	}
+
+	// the rolodex is changed from here on, so a failed rebuild must not leave the previous model behind.
+	d.highOpenAPI3Model = nil
+
+	// the root document is parsed here, so the specification info stays in sync with the rolodex.
+	rootLocation := d.rolodex.GetRootIndex().GetSpecAbsolutePath()
+	rolodexChanges := make([]*index.RolodexFileChange, 0, len(changes))
+	for _, c := range changes {
+		if c == nil {
+			continue
+		}
+		location := c.Location
+		if !filepath.IsAbs(location) && !strings.HasPrefix(location, "http") {
+			location, _ = filepath.Abs(filepath.Join(d.rolodex.GetConfig().BasePath, location))
+		}
+		if location == rootLocation && c.Content != nil {
+			info, err := datamodel.ExtractSpecInfoWithDocumentCheck(c.Content, d.config.BypassDocumentCheck)
+			if err != nil {
+				return nil, []error{err}
+			}
+			if info.SpecFormat != datamodel.OAS3 && info.SpecFormat != datamodel.OAS31 {
+				return nil, []error{fmt.Errorf("unable to re-index openapi document, "+
+					"supplied spec is a different version (%v)", info.SpecFormat)}
+			}
+			d.info = info
+			d.rolodex.SetRootNode(info.RootNode)
+			d.rolodex.GetConfig().SpecInfo = info
+			c = &index.RolodexFileChange{Location: location}
+		}
+		rolodexChanges = append(rolodexChanges, c)
+	}
+
+	// errors caught while indexing are collected again when the model is built, anything else is a failure.
+	reindexErr := d.rolodex.ReindexFiles(rolodexChanges...)
+	caught := d.rolodex.GetCaughtErrors()
+	for _, err := range utils.UnwrapErrors(reindexErr) {
+		if !slices.Contains(caught, err) {
+			return nil, []error{err}
+		}
+	}
+
+	lowDoc, docErr := v3low.CreateDocumentFromRolodex(context.Background(), d.info, d.config, d.rolodex)
+	if lowDoc == nil {
+		return nil, utils.UnwrapErrors(docErr)
+	}
+	return d.buildV3HighModel(lowDoc, docErr, nil)
+}
+
+// RenameComponent renames a component in the rolodex, and then rebuilds the OpenAPI model from the rolodex.
+func (d *document) RenameComponent(definition, newName string,
+) (*DocumentModel[v3high.Document], *index.ComponentRename, []error) {
+	if d.highOpenAPI3Model == nil {
+		if _, errs := d.BuildV3Model(); d.highOpenAPI3Model == nil {
+			return nil, nil, errs
+		}
+	}
+	if d.rolodex == nil || d.rolodex.GetRootIndex() == nil {
+		return nil, nil, []error{errors.New("unable to rename component, the document has not been indexed")}
+	}
+	rootLocation := d.rolodex.GetRootIndex().GetSpecAbsolutePath()
+	rename, err := d.rolodex.RenameComponent(definition, newName)
+	if err != nil {
+		return nil, rename, []error{err}
+	}
+	d.highOpenAPI3Model = nil // the rolodex has changed, a failed rebuild must not leave the previous model behind.
+
+	// the rolodex has parsed the renamed root document, the specification info is kept in sync with it.
+	if content, ok := rename.Files[rootLocation]; ok {
+		info, iErr := datamodel.ExtractSpecInfoWithDocumentCheck(content, d.config.BypassDocumentCheck)
+		if iErr != nil {
+			return nil, rename, []error{iErr}
+		}
+		info.RootNode = d.rolodex.GetRootNode()
+		d.info = info
+		d.rolodex.GetConfig().SpecInfo = info
+	}
+
+	lowDoc, docErr := v3low.CreateDocumentFromRolodex(context.Background(), d.info, d.config, d.rolodex)
+	if lowDoc == nil {
+		return nil, rename, utils.UnwrapErrors(docErr)
+	}
+	m, errs := d.buildV3HighModel(lowDoc, docErr, nil)
+	return m, rename, errs
+}
 
+// buildV3HighModel builds the high-level model from a low-level document, and any errors creating it.
+func (d *document) buildV3HighModel(lowDoc *v3low.Document, docErr error, errs []error,
+) (*DocumentModel[v3high.Document], []error) {
 	if docErr != nil {
 		errs = append(errs, utils.UnwrapErrors(docErr)...)
 	}
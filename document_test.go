@@ -3,18 +3,26 @@
 package libopenapi
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pb33f/libopenapi/datamodel"
 	"github.com/pb33f/libopenapi/datamodel/high/base"
 	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/index"
 	"github.com/pb33f/libopenapi/orderedmap"
 	"github.com/pb33f/libopenapi/utils"
 	"github.com/pb33f/libopenapi/what-changed/model"
@@ -1388,3 +1396,308 @@ components:
 	_, errs := doc.BuildV3Model()
 	assert.Len(t, errs, 0)
 }
+
+func TestDocument_RemotePolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("components:\n  schemas:\n    Thing:\n      type: string"))
+	}))
+	defer server.Close()
+
+	spec := fmt.Sprintf(`openapi: 3.1.0
+info:
+  title: policy
+  version: 1.0.0
+components:
+  schemas:
+    Root:
+      $ref: '%s/thing.yaml#/components/schemas/Thing'`, server.URL)
+
+	config := datamodel.NewDocumentConfiguration()
+	config.AllowRemoteReferences = true
+	config.RemotePolicy = &datamodel.RemoteReferencePolicy{BlockPrivateNetworks: true}
+
+	doc, err := NewDocumentWithConfiguration([]byte(spec), config)
+	require.NoError(t, err)
+	_, errs := doc.BuildV3Model()
+
+	var policyErr *datamodel.RemotePolicyError
+	require.True(t, errors.As(errors.Join(errs...), &policyErr))
+	assert.Equal(t, datamodel.RemotePolicyPrivateAddress, policyErr.Reason)
+
+	// a custom handler would bypass the policy, so the combination is refused.
+	config.RemoteURLHandler = http.Get
+	doc, err = NewDocumentWithConfiguration([]byte(spec), config)
+	require.NoError(t, err)
+	_, errs = doc.BuildV3Model()
+	assert.ErrorIs(t, errors.Join(errs...), datamodel.ErrRemotePolicyCustomHandler)
+}
+
+func TestDocument_BuildV3ModelWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	spec := fmt.Sprintf(`openapi: 3.1.0
+info:
+  title: slow
+  version: 1.0.0
+components:
+  schemas:
+    Root:
+      $ref: '%s/slow.yaml#/components/schemas/Thing'`, server.URL)
+
+	config := datamodel.NewDocumentConfiguration()
+	config.AllowRemoteReferences = true
+
+	doc, err := NewDocumentWithConfiguration([]byte(spec), config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m, errs := doc.(DocumentWithContext).BuildV3ModelWithContext(ctx)
+	assert.Nil(t, m)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.DeadlineExceeded)
+}
+
+func TestDocument_WithContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	doc, err := NewDocument([]byte(`swagger: 2.0`))
+	require.NoError(t, err)
+	m, errs := doc.(DocumentWithContext).BuildV2ModelWithContext(ctx)
+	assert.Nil(t, m)
+	assert.Equal(t, []error{context.Canceled}, errs)
+
+	// the document can still be built afterwards.
+	v2, errs := doc.BuildV2Model()
+	assert.Empty(t, errs)
+	assert.NotNil(t, v2)
+
+	doc, err = NewDocument([]byte(`openapi: 3.1.0`))
+	require.NoError(t, err)
+	v3, errs := doc.(DocumentWithContext).BuildV3ModelWithContext(ctx)
+	assert.Nil(t, v3)
+	assert.Equal(t, []error{context.Canceled}, errs)
+	v3, errs = doc.(DocumentWithContext).BuildV3ModelWithContext(context.Background())
+	assert.Empty(t, errs)
+	assert.NotNil(t, v3)
+}
+
+func TestDocument_ArchiveFS(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"openapi.yaml": `openapi: 3.1.0
+info:
+  title: archived
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'schemas/pet.yaml'`,
+		"schemas/pet.yaml": `type: object
+properties:
+  name:
+    type: string`,
+	} {
+		f, _ := w.Create(name)
+		_, _ = f.Write([]byte(content))
+	}
+	require.NoError(t, w.Close())
+
+	archiveFS, err := index.NewArchiveFSWithConfig(&index.ArchiveFSConfig{
+		Reader:        bytes.NewReader(buf.Bytes()),
+		Size:          int64(buf.Len()),
+		BaseDirectory: "/registry/pets.zip",
+	})
+	require.NoError(t, err)
+	root, err := archiveFS.ReadFile("openapi.yaml")
+	require.NoError(t, err)
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = archiveFS.GetBaseDirectory()
+	config.SpecFilePath = "openapi.yaml"
+	config.LocalFS = archiveFS
+
+	doc, err := NewDocumentWithConfiguration(root, config)
+	require.NoError(t, err)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+
+	pet := model.Model.Components.Schemas.GetOrZero("Pet")
+	assert.Equal(t, []string{"object"}, pet.Schema().Type)
+	assert.Equal(t, "/registry/pets.zip/openapi.yaml", pet.GetReferenceOrigin().AbsoluteLocation)
+	origin := model.Model.Rolodex.FindNodeOrigin(pet.Schema().GoLow().Properties.ValueNode)
+	require.NotNil(t, origin)
+	assert.Equal(t, "/registry/pets.zip/schemas/pet.yaml", origin.AbsoluteLocation)
+}
+
+func TestDocument_ReindexFiles(t *testing.T) {
+	dir := t.TempDir()
+	root := `openapi: 3.1.0
+info:
+  title: reindex
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'pet.yaml#/components/schemas/Pet'`
+	pet := `components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(pet), 0o644))
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = dir
+	config.SpecFilePath = "root.yaml"
+
+	created, err := NewDocumentWithConfiguration([]byte(root), config)
+	require.NoError(t, err)
+	doc := created.(ReindexableDocument)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	assert.Equal(t, 1, orderedmap.Len(model.Model.Components.Schemas.GetOrZero("Pet").Schema().Properties))
+
+	// a property is added to the referenced file.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(pet+"\n        age:\n          type: integer"), 0o644))
+	model, errs = doc.ReindexFiles(&index.RolodexFileChange{Location: "pet.yaml"})
+	require.Empty(t, errs)
+	assert.Equal(t, 2, orderedmap.Len(model.Model.Components.Schemas.GetOrZero("Pet").Schema().Properties))
+
+	// and a schema is added to the root document.
+	model, errs = doc.ReindexFiles(&index.RolodexFileChange{
+		Location: "root.yaml",
+		Content:  []byte(root + "\n    Owner:\n      type: string"),
+	})
+	require.Empty(t, errs)
+	assert.Equal(t, 2, orderedmap.Len(model.Model.Components.Schemas))
+	assert.Equal(t, 2, orderedmap.Len(model.Model.Components.Schemas.GetOrZero("Pet").Schema().Properties))
+	assert.Contains(t, string(*doc.GetSpecInfo().SpecBytes), "Owner")
+
+	_, errs = doc.ReindexFiles(&index.RolodexFileChange{Location: "root.yaml", Content: []byte("swagger: 2.0")})
+	assert.Len(t, errs, 1)
+}
+
+func TestDocument_ReindexFiles_FailedRebuild(t *testing.T) {
+	dir := t.TempDir()
+	root := `openapi: 3.1.0
+info:
+  title: reindex
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'pet.yaml#/components/schemas/Pet'`
+	pet := `components:
+  schemas:
+    Pet:
+      type: object`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(pet), 0o644))
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = dir
+	config.SpecFilePath = "root.yaml"
+	created, err := NewDocumentWithConfiguration([]byte(root), config)
+	require.NoError(t, err)
+	doc := created.(ReindexableDocument)
+	_, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+
+	// the referenced schema is removed, so the model can't be rebuilt.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte("components:\n  schemas: {}"), 0o644))
+	model, errs := doc.ReindexFiles(&index.RolodexFileChange{Location: "pet.yaml"})
+	assert.Nil(t, model)
+	assert.NotEmpty(t, errs)
+
+	// the previous model is not returned, the document is built again instead.
+	model, errs = doc.BuildV3Model()
+	assert.Nil(t, model)
+	assert.NotEmpty(t, errs)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(pet+"\n      title: fixed"), 0o644))
+	model, errs = doc.BuildV3Model()
+	require.Empty(t, errs)
+	assert.Equal(t, "fixed", model.Model.Components.Schemas.GetOrZero("Pet").Schema().Title)
+}
+
+func TestDocument_RenameComponent(t *testing.T) {
+	dir := t.TempDir()
+	root := `openapi: 3.1.0
+info:
+  title: rename
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pets'
+components:
+  schemas:
+    Pets:
+      type: array
+      items:
+        $ref: 'pet.yaml#/components/schemas/Pet'`
+	pet := `components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.yaml"), []byte(root), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(pet), 0o644))
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = dir
+	config.SpecFilePath = "root.yaml"
+	created, err := NewDocumentWithConfiguration([]byte(root), config)
+	require.NoError(t, err)
+	doc := created.(ComponentRenamer)
+
+	// the model is built before the rename, then rebuilt with the new names.
+	model, rename, errs := doc.RenameComponent("#/components/schemas/Pets", "PetList")
+	require.Empty(t, errs)
+	assert.Equal(t, 1, rename.References)
+	assert.Nil(t, model.Model.Components.Schemas.GetOrZero("Pets"))
+	list := model.Model.Components.Schemas.GetOrZero("PetList")
+	require.NotNil(t, list)
+	assert.Equal(t, "#/components/schemas/PetList", model.Model.Paths.PathItems.GetOrZero("/pets").Get.Responses.
+		Codes.GetOrZero("200").Content.GetOrZero("application/json").Schema.GetReference())
+	assert.Contains(t, string(*doc.GetSpecInfo().SpecBytes), "PetList:")
+
+	// components in other files are renamed too, and the model can be rendered.
+	model, rename, errs = doc.RenameComponent("pet.yaml#/components/schemas/Pet", "Animal")
+	require.Empty(t, errs)
+	assert.Len(t, rename.Files, 2)
+	assert.Equal(t, 1, orderedmap.Len(model.Model.Components.Schemas.GetOrZero("PetList").Schema().Items.A.Schema().Properties))
+	rendered, err := model.Model.Render()
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "$ref: 'pet.yaml#/components/schemas/Animal'")
+
+	// nothing has been written to disk.
+	written, _ := os.ReadFile(filepath.Join(dir, "pet.yaml"))
+	assert.Equal(t, pet, string(written))
+
+	_, _, errs = doc.RenameComponent("#/components/schemas/Missing", "Found")
+	assert.Len(t, errs, 1)
+	swagger, _ := NewDocument([]byte("swagger: 2.0"))
+	_, _, errs = swagger.(ComponentRenamer).RenameComponent("#/definitions/Pet", "Animal")
+	assert.NotEmpty(t, errs)
+}
@@ -0,0 +1,398 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+// Package examples provides tools for checking the examples defined in an OpenAPI 3+ document against the schemas
+// they are supposed to be examples of.
+//
+// Specifications often ship with `example` and `examples` values that contradict their own schemas. The Checker
+// walks every MediaType, Parameter, Header and Schema example in a document (including component examples reached
+// through a $ref) and reports every mismatch, along with the file, line and column of both the example and the schema.
+//
+// ValidateValue is the small JSON Schema value checker used by the Checker, it only needs the high-level base.Schema
+// model.
+package examples
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/index"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// Mismatch represents an example that does not match its schema.
+type Mismatch struct {
+	// Path is the JSON path to where the example is used in the document, for example
+	// $.paths['/pets'].get.responses['200'].content['application/json'].examples['cat']
+	Path string `json:"path" yaml:"path"`
+
+	// ExampleName is the name of the example in an `examples` map. Empty for an `example` value.
+	ExampleName string `json:"exampleName,omitempty" yaml:"exampleName,omitempty"`
+
+	// Errors contains every way in which the example does not match the schema.
+	Errors []*ValidationError `json:"errors" yaml:"errors"`
+
+	// ExampleOrigin is where the example value is located, which may be in a different file to the document.
+	ExampleOrigin *index.NodeOrigin `json:"exampleOrigin,omitempty" yaml:"exampleOrigin,omitempty"`
+
+	// SchemaOrigin is where the schema is located, which may be in a different file to the document.
+	SchemaOrigin *index.NodeOrigin `json:"schemaOrigin,omitempty" yaml:"schemaOrigin,omitempty"`
+}
+
+// Error returns a string representation of the Mismatch, including the locations of the example and the schema.
+func (m *Mismatch) Error() string {
+	reasons := make([]string, len(m.Errors))
+	for i, e := range m.Errors {
+		reasons[i] = e.Error()
+	}
+	return fmt.Sprintf("example at %s (%s) does not match schema (%s): %s", m.Path,
+		describeOrigin(m.ExampleOrigin), describeOrigin(m.SchemaOrigin), strings.Join(reasons, "; "))
+}
+
+func describeOrigin(origin *index.NodeOrigin) string {
+	if origin == nil {
+		return "unknown location"
+	}
+	if origin.AbsoluteLocation != "" {
+		return fmt.Sprintf("%s:%d:%d", origin.AbsoluteLocation, origin.Line, origin.Column)
+	}
+	return fmt.Sprintf("line %d, column %d", origin.Line, origin.Column)
+}
+
+// UnsupportedPattern represents a schema `pattern` (or `patternProperties` key) that can't be compiled by Go's
+// regular expression engine (RE2), for example a valid ECMA 262 pattern that uses a lookahead. Examples are not
+// checked against these patterns.
+type UnsupportedPattern struct {
+	// Path is the JSON path to the schema that holds the pattern.
+	Path string `json:"path" yaml:"path"`
+
+	// Pattern is the pattern that could not be compiled.
+	Pattern string `json:"pattern" yaml:"pattern"`
+
+	// Reason is why the pattern could not be compiled.
+	Reason string `json:"reason" yaml:"reason"`
+
+	// SchemaOrigin is where the schema is located, which may be in a different file to the document.
+	SchemaOrigin *index.NodeOrigin `json:"schemaOrigin,omitempty" yaml:"schemaOrigin,omitempty"`
+}
+
+// Error returns a string representation of the UnsupportedPattern, including the location of the schema.
+func (u *UnsupportedPattern) Error() string {
+	return fmt.Sprintf("pattern '%s' at %s (%s) is not supported: %s", u.Pattern, u.Path,
+		describeOrigin(u.SchemaOrigin), u.Reason)
+}
+
+// Checker walks a high-level OpenAPI 3+ document and checks every example against its schema.
+// Use NewChecker to create a new Checker.
+type Checker struct {
+	document    *v3.Document
+	mismatches  []*Mismatch
+	unsupported []*UnsupportedPattern
+	checked     map[checkedKey]bool
+	schemas     map[*yaml.Node]bool
+}
+
+type checkedKey struct {
+	example *yaml.Node
+	schema  *yaml.Node
+}
+
+// NewChecker creates a new Checker for the supplied document. The Index and Rolodex of the document (set when
+// building a model) are used to locate the files that examples and schemas were defined in.
+func NewChecker(document *v3.Document) *Checker {
+	return &Checker{document: document}
+}
+
+// CheckDocument is a convenience function that creates a new Checker and runs it against the document.
+func CheckDocument(document *v3.Document) []*Mismatch {
+	return NewChecker(document).Check()
+}
+
+// Check walks the document and returns every example that does not match its schema. Examples that are shared
+// through a $ref are only reported once per schema, at the first location they are found. Components are checked
+// before paths and webhooks.
+func (c *Checker) Check() []*Mismatch {
+	c.mismatches = nil
+	c.unsupported = nil
+	c.checked = make(map[checkedKey]bool)
+	c.schemas = make(map[*yaml.Node]bool)
+	if c.document == nil {
+		return nil
+	}
+
+	if comp := c.document.Components; comp != nil {
+		for name, sp := range comp.Schemas.FromOldest() {
+			c.checkSchemaProxy(fmt.Sprintf("$.components.schemas['%s']", name), sp)
+		}
+		for name, p := range comp.Parameters.FromOldest() {
+			c.checkParameter(fmt.Sprintf("$.components.parameters['%s']", name), p)
+		}
+		for name, h := range comp.Headers.FromOldest() {
+			c.checkHeader(fmt.Sprintf("$.components.headers['%s']", name), h)
+		}
+		for name, rb := range comp.RequestBodies.FromOldest() {
+			if rb != nil {
+				c.checkContent(fmt.Sprintf("$.components.requestBodies['%s']", name), rb.Content)
+			}
+		}
+		for name, r := range comp.Responses.FromOldest() {
+			c.checkResponse(fmt.Sprintf("$.components.responses['%s']", name), r)
+		}
+		for name, pi := range comp.PathItems.FromOldest() {
+			c.checkPathItem(fmt.Sprintf("$.components.pathItems['%s']", name), pi)
+		}
+	}
+	if c.document.Paths != nil {
+		for path, pi := range c.document.Paths.PathItems.FromOldest() {
+			c.checkPathItem(fmt.Sprintf("$.paths['%s']", path), pi)
+		}
+	}
+	for name, pi := range c.document.Webhooks.FromOldest() {
+		c.checkPathItem(fmt.Sprintf("$.webhooks['%s']", name), pi)
+	}
+	return c.mismatches
+}
+
+// UnsupportedPatterns returns every pattern found by the last call to Check that could not be compiled, each schema
+// is only reported once.
+func (c *Checker) UnsupportedPatterns() []*UnsupportedPattern {
+	return c.unsupported
+}
+
+func (c *Checker) checkPathItem(path string, pathItem *v3.PathItem) {
+	if pathItem == nil {
+		return
+	}
+	for i, p := range pathItem.Parameters {
+		c.checkParameter(fmt.Sprintf("%s.parameters[%d]", path, i), p)
+	}
+	for method, op := range pathItem.GetOperations().FromOldest() {
+		c.checkOperation(fmt.Sprintf("%s.%s", path, method), op)
+	}
+}
+
+func (c *Checker) checkOperation(path string, op *v3.Operation) {
+	if op == nil {
+		return
+	}
+	for i, p := range op.Parameters {
+		c.checkParameter(fmt.Sprintf("%s.parameters[%d]", path, i), p)
+	}
+	if op.RequestBody != nil {
+		c.checkContent(path+".requestBody", op.RequestBody.Content)
+	}
+	if op.Responses != nil {
+		if op.Responses.Default != nil {
+			c.checkResponse(path+".responses.default", op.Responses.Default)
+		}
+		for code, r := range op.Responses.Codes.FromOldest() {
+			c.checkResponse(fmt.Sprintf("%s.responses['%s']", path, code), r)
+		}
+	}
+	for name, cb := range op.Callbacks.FromOldest() {
+		if cb == nil {
+			continue
+		}
+		for expression, pi := range cb.Expression.FromOldest() {
+			c.checkPathItem(fmt.Sprintf("%s.callbacks['%s']['%s']", path, name, expression), pi)
+		}
+	}
+}
+
+func (c *Checker) checkResponse(path string, response *v3.Response) {
+	if response == nil {
+		return
+	}
+	for name, h := range response.Headers.FromOldest() {
+		c.checkHeader(fmt.Sprintf("%s.headers['%s']", path, name), h)
+	}
+	c.checkContent(path, response.Content)
+}
+
+func (c *Checker) checkParameter(path string, param *v3.Parameter) {
+	if param == nil {
+		return
+	}
+	c.checkExamples(path, param.Example, param.Examples, param.Schema)
+	c.checkSchemaProxy(path+".schema", param.Schema)
+	c.checkContent(path, param.Content)
+}
+
+func (c *Checker) checkHeader(path string, header *v3.Header) {
+	if header == nil {
+		return
+	}
+	c.checkExamples(path, header.Example, header.Examples, header.Schema)
+	c.checkSchemaProxy(path+".schema", header.Schema)
+	c.checkContent(path, header.Content)
+}
+
+func (c *Checker) checkContent(path string, content *orderedmap.Map[string, *v3.MediaType]) {
+	for mediaType, mt := range content.FromOldest() {
+		if mt == nil {
+			continue
+		}
+		mtPath := fmt.Sprintf("%s.content['%s']", path, mediaType)
+		c.checkExamples(mtPath, mt.Example, mt.Examples, mt.Schema)
+		c.checkSchemaProxy(mtPath+".schema", mt.Schema)
+	}
+}
+
+// checkExamples checks an `example` value and an `examples` map against a schema.
+func (c *Checker) checkExamples(path string, example *yaml.Node,
+	examples *orderedmap.Map[string, *base.Example], proxy *base.SchemaProxy,
+) {
+	if proxy == nil {
+		return
+	}
+	schema := proxy.Schema()
+	if schema == nil {
+		return
+	}
+	if example != nil {
+		c.checkValue(path+".example", "", example, schema, proxy)
+	}
+	for name, ex := range examples.FromOldest() {
+		// external values can't be checked.
+		if ex == nil || ex.Value == nil {
+			continue
+		}
+		c.checkValue(fmt.Sprintf("%s.examples['%s']", path, name), name, ex.Value, schema, proxy)
+	}
+}
+
+// checkSchemaProxy checks the `example` and `examples` values of a schema, and every schema nested inside it.
+func (c *Checker) checkSchemaProxy(path string, proxy *base.SchemaProxy) {
+	if proxy == nil {
+		return
+	}
+	schema := proxy.Schema()
+	if schema == nil {
+		return
+	}
+	root := schemaNode(schema, proxy)
+	if root != nil {
+		if c.schemas[root] {
+			return
+		}
+		c.schemas[root] = true
+	}
+	c.checkPatterns(path, schema, proxy)
+
+	if schema.Example != nil {
+		c.checkValue(path+".example", "", schema.Example, schema, proxy)
+	}
+	for i, ex := range schema.Examples {
+		c.checkValue(fmt.Sprintf("%s.examples[%d]", path, i), "", ex, schema, proxy)
+	}
+
+	for name, prop := range schema.Properties.FromOldest() {
+		c.checkSchemaProxy(fmt.Sprintf("%s.properties['%s']", path, name), prop)
+	}
+	for name, prop := range schema.PatternProperties.FromOldest() {
+		c.checkSchemaProxy(fmt.Sprintf("%s.patternProperties['%s']", path, name), prop)
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		c.checkSchemaProxy(path+".items", schema.Items.A)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		c.checkSchemaProxy(path+".additionalProperties", schema.AdditionalProperties.A)
+	}
+	for i, sp := range schema.AllOf {
+		c.checkSchemaProxy(fmt.Sprintf("%s.allOf[%d]", path, i), sp)
+	}
+	for i, sp := range schema.AnyOf {
+		c.checkSchemaProxy(fmt.Sprintf("%s.anyOf[%d]", path, i), sp)
+	}
+	for i, sp := range schema.OneOf {
+		c.checkSchemaProxy(fmt.Sprintf("%s.oneOf[%d]", path, i), sp)
+	}
+	for i, sp := range schema.PrefixItems {
+		c.checkSchemaProxy(fmt.Sprintf("%s.prefixItems[%d]", path, i), sp)
+	}
+	if schema.Not != nil {
+		c.checkSchemaProxy(path+".not", schema.Not)
+	}
+}
+
+func (c *Checker) checkValue(path, name string, example *yaml.Node, schema *base.Schema, proxy *base.SchemaProxy) {
+	schemaRoot := schemaNode(schema, proxy)
+	key := checkedKey{example: example, schema: schemaRoot}
+	if c.checked[key] {
+		return
+	}
+	c.checked[key] = true
+
+	errs := ValidateValue(schema, NodeToValue(example))
+	if len(errs) == 0 {
+		return
+	}
+	c.mismatches = append(c.mismatches, &Mismatch{
+		Path:          path,
+		ExampleName:   name,
+		Errors:        errs,
+		ExampleOrigin: c.findOrigin(example, nil),
+		SchemaOrigin:  c.findOrigin(schemaRoot, schema),
+	})
+}
+
+// checkPatterns records the patterns of a schema that can't be compiled.
+func (c *Checker) checkPatterns(path string, schema *base.Schema, proxy *base.SchemaProxy) {
+	patterns := make([]string, 0, 1)
+	if schema.Pattern != "" {
+		patterns = append(patterns, schema.Pattern)
+	}
+	if schema.PatternProperties != nil {
+		patterns = slices.AppendSeq(patterns, schema.PatternProperties.KeysFromOldest())
+	}
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			c.unsupported = append(c.unsupported, &UnsupportedPattern{
+				Path:         path,
+				Pattern:      pattern,
+				Reason:       err.Error(),
+				SchemaOrigin: c.findOrigin(schemaNode(schema, proxy), schema),
+			})
+		}
+	}
+}
+
+// findOrigin locates the file, line and column of a node. The index that built the schema is checked first,
+// then the rolodex, then the document index. If none of those know about the node, the line and column
+// of the node are used on their own.
+func (c *Checker) findOrigin(node *yaml.Node, schema *base.Schema) *index.NodeOrigin {
+	if node == nil {
+		return nil
+	}
+	if schema != nil && schema.GoLow() != nil && schema.GoLow().Index != nil {
+		if origin := schema.GoLow().Index.FindNodeOrigin(node); origin != nil {
+			return origin
+		}
+	}
+	if c.document.Rolodex != nil && c.document.Rolodex.GetRootIndex() != nil {
+		if origin := c.document.Rolodex.FindNodeOrigin(node); origin != nil {
+			return origin
+		}
+	}
+	if c.document.Index != nil {
+		if origin := c.document.Index.FindNodeOrigin(node); origin != nil {
+			return origin
+		}
+	}
+	return &index.NodeOrigin{Node: node, Line: node.Line, Column: node.Column}
+}
+
+func schemaNode(schema *base.Schema, proxy *base.SchemaProxy) *yaml.Node {
+	if schema.GoLow() != nil && schema.GoLow().GetRootNode() != nil {
+		return schema.GoLow().GetRootNode()
+	}
+	if proxy != nil {
+		return proxy.GetValueNode()
+	}
+	return nil
+}
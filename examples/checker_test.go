@@ -0,0 +1,248 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package examples
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var checkerSpec = `openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        schema:
+          type: integer
+        example: abc
+    get:
+      parameters:
+        - $ref: '#/components/parameters/Limit'
+        - $ref: '#/components/parameters/Limit'
+      responses:
+        '200':
+          description: ok
+          headers:
+            X-Rate:
+              schema:
+                type: integer
+              examples:
+                good:
+                  value: 10
+                bad:
+                  value: ten
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+              examples:
+                good:
+                  value:
+                    name: Tip
+                bad:
+                  $ref: '#/components/examples/BadPet'
+                external:
+                  externalValue: https://pb33f.io/pet.json
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+            example:
+              name: 123
+      responses:
+        '200':
+          description: ok
+components:
+  examples:
+    BadPet:
+      value:
+        age: 3
+  parameters:
+    Limit:
+      name: limit
+      in: query
+      schema:
+        type: integer
+        maximum: 10
+      example: 11
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          example: Tip
+        age:
+          type: integer
+          example: old`
+
+// buildModel builds the high-level model of an OpenAPI 3+ specification, failing the test if it has any errors.
+func buildModel(t *testing.T, spec string, config *datamodel.DocumentConfiguration) *v3.Document {
+	doc, err := libopenapi.NewDocumentWithConfiguration([]byte(spec), config)
+	require.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	return &m.Model
+}
+
+func TestChecker_Check(t *testing.T) {
+	model := buildModel(t, checkerSpec, nil)
+	mismatches := CheckDocument(model)
+
+	var paths []string
+	for _, m := range mismatches {
+		paths = append(paths, m.Path)
+	}
+	assert.Equal(t, []string{
+		"$.components.schemas['Pet'].properties['age'].example",
+		"$.components.parameters['Limit'].example",
+		"$.paths['/pets/{id}'].parameters[0].example",
+		"$.paths['/pets/{id}'].get.responses['200'].headers['X-Rate'].examples['bad']",
+		"$.paths['/pets/{id}'].get.responses['200'].content['application/json'].examples['bad']",
+		"$.webhooks['newPet'].post.requestBody.content['application/json'].example",
+	}, paths)
+
+	age := mismatches[0]
+	assert.Equal(t, "expected integer, got string", age.Errors[0].Reason)
+	assert.Equal(t, 77, age.ExampleOrigin.Line)
+	assert.Equal(t, 76, age.SchemaOrigin.Line)
+
+	bad := mismatches[4]
+	assert.Equal(t, "bad", bad.ExampleName)
+	assert.Equal(t, "missing required property 'name'", bad.Errors[0].Reason)
+
+	// the example is reached through a $ref, so the origin is where the example is defined in components.
+	assert.Equal(t, 58, bad.ExampleOrigin.Line)
+	assert.Equal(t, 69, bad.SchemaOrigin.Line)
+
+	assert.Equal(t, "example at $.paths['/pets/{id}'].get.responses['200'].content['application/json'].examples['bad'] "+
+		"(line 58, column 9) does not match schema (line 69, column 7): missing required property 'name'", bad.Error())
+
+	webhook := mismatches[5]
+	assert.Equal(t, "/name: expected string, got integer", webhook.Errors[0].Error())
+}
+
+func TestChecker_Check_MultiFile(t *testing.T) {
+	dir := t.TempDir()
+	root := `openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: 'schemas.yaml#/components/schemas/Pet'
+              examples:
+                bad:
+                  $ref: 'examples.yaml#/components/examples/BadPet'`
+
+	schemas := `components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string`
+
+	examplesFile := `components:
+  examples:
+    BadPet:
+      value:
+        name: 42`
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(root), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas.yaml"), []byte(schemas), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "examples.yaml"), []byte(examplesFile), 0o600))
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = dir
+	config.SpecFilePath = "openapi.yaml"
+	model := buildModel(t, root, config)
+
+	mismatches := NewChecker(model).Check()
+	require.Len(t, mismatches, 1)
+
+	m := mismatches[0]
+	assert.Equal(t, "/name: expected string, got integer", m.Errors[0].Error())
+	assert.Equal(t, filepath.Join(dir, "examples.yaml"), m.ExampleOrigin.AbsoluteLocation)
+	assert.Equal(t, 5, m.ExampleOrigin.Line)
+	assert.Equal(t, filepath.Join(dir, "schemas.yaml"), m.SchemaOrigin.AbsoluteLocation)
+	assert.Equal(t, 4, m.SchemaOrigin.Line)
+	assert.Contains(t, m.Error(), filepath.Join(dir, "examples.yaml")+":5:9")
+}
+
+func TestChecker_Check_UnsupportedPatterns(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: Patterns
+  version: 1.0.0
+paths:
+  /codes:
+    get:
+      parameters:
+        - name: code
+          in: query
+          schema:
+            $ref: '#/components/schemas/Code'
+          examples:
+            one:
+              value: abc1
+            two:
+              value: def2
+components:
+  schemas:
+    Code:
+      type: string
+      pattern: '^(?=.*\d).+$'
+      examples: [ghi3]
+    Labels:
+      type: object
+      patternProperties:
+        '(?<!x)-label$':
+          type: string
+      additionalProperties: false
+      example:
+        a-label: b`
+	checker := NewChecker(buildModel(t, spec, nil))
+	assert.Empty(t, checker.Check())
+
+	// each pattern is reported once, rather than as a mismatch against every example.
+	unsupported := checker.UnsupportedPatterns()
+	require.Len(t, unsupported, 2)
+	assert.Equal(t, "$.components.schemas['Code']", unsupported[0].Path)
+	assert.Equal(t, `^(?=.*\d).+$`, unsupported[0].Pattern)
+	assert.Equal(t, 21, unsupported[0].SchemaOrigin.Line)
+	assert.Contains(t, unsupported[0].Error(), "pattern '^(?=.*\\d).+$' at $.components.schemas['Code'] "+
+		"(line 21, column 7) is not supported: error parsing regexp")
+	assert.Equal(t, "$.components.schemas['Labels']", unsupported[1].Path)
+	assert.Equal(t, "(?<!x)-label$", unsupported[1].Pattern)
+}
+
+func TestChecker_Check_Nil(t *testing.T) {
+	assert.Nil(t, NewChecker(nil).Check())
+	assert.Equal(t, "unknown location", describeOrigin(nil))
+}
@@ -0,0 +1,471 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package examples
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"gopkg.in/yaml.v3"
+)
+
+// maxDepth prevents runaway recursion through circular schemas that never consume any of the value.
+const maxDepth = 64
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidationError describes a single way in which a value does not match a schema.
+type ValidationError struct {
+	// Location is a JSON pointer to the part of the value that failed validation, empty for the root.
+	Location string `json:"location" yaml:"location"`
+
+	// Reason is a human-readable explanation of the failure.
+	Reason string `json:"reason" yaml:"reason"`
+
+	// Schema is the (sub) schema that the value failed to validate against.
+	Schema *base.Schema `json:"-" yaml:"-"`
+}
+
+// Error returns a string representation of the ValidationError.
+func (v *ValidationError) Error() string {
+	if v.Location == "" {
+		return v.Reason
+	}
+	return fmt.Sprintf("%s: %s", v.Location, v.Reason)
+}
+
+// NodeToValue converts a yaml.Node into a plain JSON compatible value. Unlike decoding a node using the yaml library,
+// timestamps are kept as strings (as they would be in JSON), and aliases are resolved.
+func NodeToValue(node *yaml.Node) any {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) > 0 {
+			return NodeToValue(node.Content[0])
+		}
+		return nil
+	case yaml.AliasNode:
+		return NodeToValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			m[node.Content[i].Value] = NodeToValue(node.Content[i+1])
+		}
+		return m
+	case yaml.SequenceNode:
+		s := make([]any, len(node.Content))
+		for i, n := range node.Content {
+			s[i] = NodeToValue(n)
+		}
+		return s
+	default:
+		switch node.ShortTag() {
+		case "!!null":
+			return nil
+		case "!!bool":
+			b, _ := strconv.ParseBool(node.Value)
+			return b
+		case "!!int":
+			if i, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+				return i
+			}
+			var v any
+			_ = node.Decode(&v)
+			if f, ok := toFloat(v); ok {
+				return f
+			}
+			return node.Value
+		case "!!float":
+			var f float64
+			if err := node.Decode(&f); err == nil {
+				return f
+			}
+			return node.Value
+		default:
+			return node.Value
+		}
+	}
+}
+
+// ValidateValue validates a plain value (as returned by NodeToValue or json.Unmarshal) against a schema, and returns
+// every validation error found. No errors means the value is valid.
+//
+// Types, enums, const, string, numeric, array and object constraints are checked, as are allOf, anyOf, oneOf and not.
+// OpenAPI 3.0 `nullable` and OpenAPI 3.1 `null` types are both supported. The following formats are checked when the
+// value is a string: date, date-time, time, email, uuid, ipv4, ipv6, uri and hostname. Patterns that Go's regular
+// expression engine can't compile are not checked, the Checker reports them as an UnsupportedPattern.
+func ValidateValue(schema *base.Schema, value any) []*ValidationError {
+	return validate(schema, value, "", 0)
+}
+
+func validate(schema *base.Schema, value any, location string, depth int) []*ValidationError {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	var errs []*ValidationError
+	fail := func(format string, args ...any) {
+		errs = append(errs, &ValidationError{Location: location, Reason: fmt.Sprintf(format, args...), Schema: schema})
+	}
+
+	if value == nil && schema.Nullable != nil && *schema.Nullable {
+		return nil
+	}
+
+	if len(schema.Type) > 0 {
+		if !slices.ContainsFunc(schema.Type, func(t string) bool { return matchesType(t, value) }) {
+			fail("expected %s, got %s", strings.Join(schema.Type, " or "), describeType(value))
+			return errs
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			if equal(NodeToValue(e), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value %v is not one of the allowed enum values", describeValue(value))
+		}
+	}
+	if schema.Const != nil && !equal(NodeToValue(schema.Const), value) {
+		fail("value %v does not match const value %v", describeValue(value), describeValue(NodeToValue(schema.Const)))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := int64(utf8.RuneCountInString(v))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("string length %d is less than minLength %d", length, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("string length %d is greater than maxLength %d", length, *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(v) {
+				fail("string '%s' does not match pattern '%s'", v, schema.Pattern)
+			}
+		}
+		if reason := checkFormat(schema.Format, v); reason != "" {
+			fail("string '%s' is not a valid %s: %s", v, schema.Format, reason)
+		}
+	case []any:
+		length := int64(len(v))
+		if schema.MinItems != nil && length < *schema.MinItems {
+			fail("array has %d items, fewer than minItems %d", length, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && length > *schema.MaxItems {
+			fail("array has %d items, more than maxItems %d", length, *schema.MaxItems)
+		}
+		if schema.UniqueItems != nil && *schema.UniqueItems {
+			for i := range v {
+				for j := i + 1; j < len(v); j++ {
+					if equal(v[i], v[j]) {
+						fail("array items %d and %d are not unique", i, j)
+					}
+				}
+			}
+		}
+		for i, item := range v {
+			itemLocation := fmt.Sprintf("%s/%d", location, i)
+			if i < len(schema.PrefixItems) {
+				errs = append(errs, validate(proxySchema(schema.PrefixItems[i]), item, itemLocation, depth+1)...)
+				continue
+			}
+			if schema.Items != nil {
+				if schema.Items.IsA() {
+					errs = append(errs, validate(proxySchema(schema.Items.A), item, itemLocation, depth+1)...)
+				} else if !schema.Items.B {
+					errs = append(errs, &ValidationError{
+						Location: itemLocation, Reason: "additional items are not allowed", Schema: schema,
+					})
+				}
+			}
+		}
+	case map[string]any:
+		count := int64(len(v))
+		if schema.MinProperties != nil && count < *schema.MinProperties {
+			fail("object has %d properties, fewer than minProperties %d", count, *schema.MinProperties)
+		}
+		if schema.MaxProperties != nil && count > *schema.MaxProperties {
+			fail("object has %d properties, more than maxProperties %d", count, *schema.MaxProperties)
+		}
+		for _, req := range schema.Required {
+			if _, ok := v[req]; !ok {
+				fail("missing required property '%s'", req)
+			}
+		}
+		// a key might match a pattern that can't be compiled, so additional properties are not checked if there is one.
+		patterns := make(map[string]*regexp.Regexp)
+		unsupported := false
+		if schema.PatternProperties != nil {
+			for pattern := range schema.PatternProperties.KeysFromOldest() {
+				if re, err := regexp.Compile(pattern); err == nil {
+					patterns[pattern] = re
+				} else {
+					unsupported = true
+				}
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			propLocation := location + "/" + escapePointer(key)
+			matched := false
+			if schema.Properties != nil {
+				if prop, ok := schema.Properties.Get(key); ok {
+					matched = true
+					errs = append(errs, validate(proxySchema(prop), v[key], propLocation, depth+1)...)
+				}
+			}
+			if schema.PatternProperties != nil {
+				for pattern, prop := range schema.PatternProperties.FromOldest() {
+					if re := patterns[pattern]; re != nil && re.MatchString(key) {
+						matched = true
+						errs = append(errs, validate(proxySchema(prop), v[key], propLocation, depth+1)...)
+					}
+				}
+			}
+			if matched || unsupported || schema.AdditionalProperties == nil {
+				continue
+			}
+			if schema.AdditionalProperties.IsA() {
+				errs = append(errs, validate(proxySchema(schema.AdditionalProperties.A), v[key], propLocation, depth+1)...)
+			} else if !schema.AdditionalProperties.B {
+				errs = append(errs, &ValidationError{
+					Location: propLocation, Reason: fmt.Sprintf("additional property '%s' is not allowed", key), Schema: schema,
+				})
+			}
+		}
+	default:
+		if f, ok := toFloat(value); ok {
+			errs = append(errs, validateNumber(schema, f, location)...)
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		errs = append(errs, validate(proxySchema(sub), value, location, depth+1)...)
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, sub := range schema.AnyOf {
+			if len(validate(proxySchema(sub), value, location, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value does not match any of the anyOf schemas")
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, sub := range schema.OneOf {
+			if len(validate(proxySchema(sub), value, location, depth+1)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("value matches %d of the oneOf schemas, it must match exactly one", matches)
+		}
+	}
+	if schema.Not != nil {
+		if len(validate(proxySchema(schema.Not), value, location, depth+1)) == 0 {
+			fail("value must not match the 'not' schema")
+		}
+	}
+	return errs
+}
+
+func validateNumber(schema *base.Schema, f float64, location string) []*ValidationError {
+	var errs []*ValidationError
+	fail := func(format string, args ...any) {
+		errs = append(errs, &ValidationError{Location: location, Reason: fmt.Sprintf(format, args...), Schema: schema})
+	}
+	exclusiveMin := schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsA() && schema.ExclusiveMinimum.A
+	exclusiveMax := schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsA() && schema.ExclusiveMaximum.A
+	if schema.Minimum != nil {
+		if exclusiveMin && f <= *schema.Minimum {
+			fail("%v is less than or equal to exclusive minimum %v", f, *schema.Minimum)
+		} else if f < *schema.Minimum {
+			fail("%v is less than minimum %v", f, *schema.Minimum)
+		}
+	}
+	if schema.Maximum != nil {
+		if exclusiveMax && f >= *schema.Maximum {
+			fail("%v is greater than or equal to exclusive maximum %v", f, *schema.Maximum)
+		} else if f > *schema.Maximum {
+			fail("%v is greater than maximum %v", f, *schema.Maximum)
+		}
+	}
+	if schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsB() && f <= schema.ExclusiveMinimum.B {
+		fail("%v is less than or equal to exclusive minimum %v", f, schema.ExclusiveMinimum.B)
+	}
+	if schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsB() && f >= schema.ExclusiveMaximum.B {
+		fail("%v is greater than or equal to exclusive maximum %v", f, schema.ExclusiveMaximum.B)
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		q := f / *schema.MultipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("%v is not a multiple of %v", f, *schema.MultipleOf)
+		}
+	}
+	return errs
+}
+
+func checkFormat(format, value string) string {
+	var err error
+	switch format {
+	case "date":
+		_, err = time.Parse(time.DateOnly, value)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, value)
+	case "time":
+		if _, e := time.Parse("15:04:05Z07:00", value); e != nil {
+			_, err = time.Parse(time.TimeOnly, value)
+		}
+	case "email":
+		_, err = mail.ParseAddress(value)
+	case "uuid":
+		if !uuidRegex.MatchString(value) {
+			return "invalid UUID"
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+			return "invalid IPv4 address"
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+			return "invalid IPv6 address"
+		}
+	case "uri":
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && !u.IsAbs() {
+			return "URI is not absolute"
+		}
+	case "hostname":
+		if value == "" || len(value) > 253 || strings.ContainsAny(value, " /:@") {
+			return "invalid hostname"
+		}
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func matchesType(t string, value any) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	}
+	return true
+}
+
+func describeType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if f, ok := toFloat(value); ok {
+		if f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func describeValue(value any) string {
+	if s, ok := value.(string); ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprint(value)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// equal compares two plain values, treating all numbers as equal if they hold the same value.
+func equal(a, b any) bool {
+	fa, aNum := toFloat(a)
+	fb, bNum := toFloat(b)
+	if aNum && bNum {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func proxySchema(proxy *base.SchemaProxy) *base.Schema {
+	if proxy == nil {
+		return nil
+	}
+	return proxy.Schema()
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
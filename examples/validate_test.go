@@ -0,0 +1,252 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package examples
+
+import (
+	"context"
+	"testing"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/datamodel/low"
+	lowbase "github.com/pb33f/libopenapi/datamodel/low/base"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func buildSchema(t *testing.T, schema string) *highbase.Schema {
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(schema), &node))
+	sp := new(lowbase.SchemaProxy)
+	assert.NoError(t, sp.Build(context.Background(), nil, node.Content[0], nil))
+	return highbase.NewSchemaProxy(&low.NodeReference[*lowbase.SchemaProxy]{
+		Value:     sp,
+		ValueNode: node.Content[0],
+	}).Schema()
+}
+
+func parseValue(t *testing.T, value string) any {
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal([]byte(value), &node))
+	return NodeToValue(&node)
+}
+
+func TestNodeToValue(t *testing.T) {
+	v := parseValue(t, `a: 1
+b: 1.5
+c: true
+d: ~
+e: 2021-01-01
+f: [x, 0x10]
+g: &anchor hello
+h: *anchor`)
+	assert.Equal(t, map[string]any{
+		"a": int64(1), "b": 1.5, "c": true, "d": nil, "e": "2021-01-01",
+		"f": []any{"x", int64(16)}, "g": "hello", "h": "hello",
+	}, v)
+	assert.Nil(t, NodeToValue(nil))
+	assert.Nil(t, NodeToValue(&yaml.Node{Kind: yaml.DocumentNode}))
+}
+
+func TestValidateValue_Valid(t *testing.T) {
+	schema := buildSchema(t, `type: object
+required: [id, name]
+properties:
+  id:
+    type: integer
+    minimum: 1
+  name:
+    type: string
+    minLength: 2
+    pattern: '^[A-Z]'
+  tags:
+    type: array
+    uniqueItems: true
+    items:
+      type: string
+      enum: [a, b]
+  email:
+    type: string
+    format: email
+  born:
+    type: string
+    format: date
+  score:
+    type: number
+    multipleOf: 0.5
+    exclusiveMaximum: 10
+  nick:
+    type: [string, 'null']
+  legacy:
+    type: string
+    nullable: true
+additionalProperties: false`)
+
+	v := parseValue(t, `id: 3
+name: Tip
+tags: [a, b]
+email: tip@pb33f.io
+born: 2020-02-02
+score: 9.5
+nick: null
+legacy: null`)
+	assert.Empty(t, ValidateValue(schema, v))
+}
+
+func TestValidateValue_Invalid(t *testing.T) {
+	schema := buildSchema(t, `type: object
+required: [id, name]
+properties:
+  id:
+    type: integer
+    minimum: 1
+  name:
+    type: string
+    maxLength: 3
+  tags:
+    type: array
+    maxItems: 1
+    uniqueItems: true
+    items:
+      type: string
+  id2:
+    type: string
+    format: uuid
+  ratio:
+    type: number
+    maximum: 1
+    exclusiveMinimum: true
+    minimum: 0
+additionalProperties: false`)
+
+	v := parseValue(t, `id: 0
+name: Tippy
+tags: [a, a]
+id2: nope
+ratio: 0
+extra: true`)
+	errs := ValidateValue(schema, v)
+
+	var reasons []string
+	for _, e := range errs {
+		reasons = append(reasons, e.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		"/extra: additional property 'extra' is not allowed",
+		"/id: 0 is less than minimum 1",
+		"/id2: string 'nope' is not a valid uuid: invalid UUID",
+		"/name: string length 5 is greater than maxLength 3",
+		"/ratio: 0 is less than or equal to exclusive minimum 0",
+		"/tags: array has 2 items, more than maxItems 1",
+		"/tags: array items 0 and 1 are not unique",
+	}, reasons)
+}
+
+func TestValidateValue_MissingAndWrongType(t *testing.T) {
+	schema := buildSchema(t, `type: object
+required: [id]
+properties:
+  id:
+    type: integer`)
+
+	errs := ValidateValue(schema, parseValue(t, `name: x`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "missing required property 'id'", errs[0].Error())
+
+	errs = ValidateValue(schema, parseValue(t, `[1]`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "expected object, got array", errs[0].Reason)
+
+	errs = ValidateValue(schema, parseValue(t, `id: 1.5`))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "expected integer, got number", errs[0].Reason)
+	assert.Equal(t, "/id", errs[0].Location)
+}
+
+func TestValidateValue_Composition(t *testing.T) {
+	schema := buildSchema(t, `oneOf:
+  - type: string
+  - type: integer
+  - type: number`)
+	assert.Empty(t, ValidateValue(schema, "x"))
+	errs := ValidateValue(schema, int64(1))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "value matches 2 of the oneOf schemas, it must match exactly one", errs[0].Reason)
+
+	schema = buildSchema(t, `anyOf:
+  - type: string
+  - type: boolean
+not:
+  const: nope`)
+	assert.Empty(t, ValidateValue(schema, true))
+	assert.Len(t, ValidateValue(schema, int64(1)), 1)
+	assert.Len(t, ValidateValue(schema, "nope"), 1)
+
+	schema = buildSchema(t, `allOf:
+  - type: object
+    required: [a]
+  - type: object
+    required: [b]`)
+	assert.Len(t, ValidateValue(schema, map[string]any{"a": 1}), 1)
+}
+
+func TestValidateValue_Arrays31(t *testing.T) {
+	schema := buildSchema(t, `type: array
+prefixItems:
+  - type: string
+  - type: integer
+items: false
+minItems: 2`)
+	assert.Empty(t, ValidateValue(schema, []any{"a", int64(1)}))
+	errs := ValidateValue(schema, []any{"a", "b", "c"})
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "/1: expected integer, got string", errs[0].Error())
+	assert.Equal(t, "/2: additional items are not allowed", errs[1].Error())
+	assert.Len(t, ValidateValue(schema, []any{"a"}), 1)
+}
+
+func TestValidateValue_Formats(t *testing.T) {
+	for format, cases := range map[string][2]string{
+		"date-time": {"2023-01-01T10:00:00Z", "yesterday"},
+		"time":      {"10:00:00", "10am"},
+		"ipv4":      {"10.0.0.1", "::1"},
+		"ipv6":      {"::1", "10.0.0.1"},
+		"uri":       {"https://pb33f.io", "/relative"},
+		"hostname":  {"pb33f.io", "not a host"},
+		"email":     {"a@b.com", "nope"},
+	} {
+		schema := &highbase.Schema{Type: []string{"string"}, Format: format}
+		assert.Empty(t, ValidateValue(schema, cases[0]), format)
+		assert.Len(t, ValidateValue(schema, cases[1]), 1, format)
+	}
+}
+
+func TestValidateValue_Objects(t *testing.T) {
+	schema := buildSchema(t, `type: object
+minProperties: 1
+maxProperties: 2
+patternProperties:
+  '^x-':
+    type: string
+additionalProperties:
+  type: integer`)
+	assert.Empty(t, ValidateValue(schema, map[string]any{"x-a": "b", "c": int64(1)}))
+	assert.Len(t, ValidateValue(schema, map[string]any{}), 1)
+	errs := ValidateValue(schema, map[string]any{"x-a": int64(1), "c": "d", "e": int64(1)})
+	assert.Len(t, errs, 3)
+	assert.Empty(t, ValidateValue(nil, "anything"))
+}
+
+func TestValidateValue_InvalidPatterns(t *testing.T) {
+	// patterns that can't be compiled are not checked.
+	schema := buildSchema(t, `type: string
+pattern: '^(?=.*\d).+$'`)
+	assert.Empty(t, ValidateValue(schema, "anything"))
+
+	schema = buildSchema(t, `type: object
+patternProperties:
+  '(x-':
+    type: string
+additionalProperties: false`)
+	assert.Empty(t, ValidateValue(schema, map[string]any{"x-a": int64(1), "x-b": int64(2)}))
+}
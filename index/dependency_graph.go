@@ -0,0 +1,435 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// DependencyNodeType is the type of node in a DependencyGraph.
+type DependencyNodeType string
+
+const (
+	// DependencyFile is a file in the rolodex, used when a reference points to a whole file, or when a reference
+	// is not inside a component or an operation.
+	DependencyFile DependencyNodeType = "file"
+	// DependencyComponent is a component, for example '#/components/schemas/Pet' or '#/definitions/Pet'.
+	DependencyComponent DependencyNodeType = "component"
+	// DependencyOperation is an operation, for example 'GET /pets', or a webhook operation, for example
+	// 'POST newPet (webhook)'.
+	DependencyOperation DependencyNodeType = "operation"
+)
+
+// DependencyNode is a component, operation or file in a DependencyGraph.
+type DependencyNode struct {
+	// ID is unique across the graph, it is the full definition of the node (the file and the JSON pointer).
+	ID         string             `json:"id"`
+	Type       DependencyNodeType `json:"type"`
+	Name       string             `json:"name"`
+	File       string             `json:"file"`
+	Definition string             `json:"definition,omitempty"`
+	Circular   bool               `json:"circular,omitempty"`
+}
+
+// DependencyEdge is a reference from one node in a DependencyGraph to another. The Pointer is the JSON pointer
+// of the $ref in the file of the referencing node.
+type DependencyEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Pointer  string `json:"pointer"`
+	Circular bool   `json:"circular,omitempty"`
+}
+
+// DependencyGraph is a typed graph of the references between the components, operations and files of a
+// specification. Circular references found by the resolver are highlighted, on the nodes and edges that form
+// the loops, and each loop is listed in CircularReferences (as a sequence of node IDs).
+//
+// Build one using BuildDependencyGraph on a SpecIndex or a Rolodex, then export it using RenderDOT, RenderMermaid
+// or RenderJSON.
+type DependencyGraph struct {
+	Nodes              []*DependencyNode `json:"nodes"`
+	Edges              []*DependencyEdge `json:"edges"`
+	CircularReferences [][]string        `json:"circularReferences,omitempty"`
+
+	basePath string
+	nodes    map[string]*DependencyNode
+}
+
+var dependencyComponentContainers = []string{"definitions", "parameters", "responses", "securityDefinitions"}
+
+// BuildDependencyGraph builds a DependencyGraph from every index in the rolodex. Circular references are only
+// highlighted once they have been checked, by indexing or resolving the rolodex.
+func (r *Rolodex) BuildDependencyGraph() *DependencyGraph {
+	indexes := slices.Clone(r.GetIndexes())
+	if r.rootIndex != nil {
+		indexes = append([]*SpecIndex{r.rootIndex}, indexes...)
+	}
+	return buildDependencyGraph(indexes)
+}
+
+// BuildDependencyGraph builds a DependencyGraph of the references in the index. If the index belongs to a rolodex,
+// the graph is built from every index in the rolodex.
+func (index *SpecIndex) BuildDependencyGraph() *DependencyGraph {
+	if index.rolodex != nil {
+		return index.rolodex.BuildDependencyGraph()
+	}
+	return buildDependencyGraph([]*SpecIndex{index})
+}
+
+func buildDependencyGraph(indexes []*SpecIndex) *DependencyGraph {
+	g := &DependencyGraph{nodes: make(map[string]*DependencyNode)}
+	if len(indexes) > 0 && indexes[0] != nil {
+		g.basePath = filepath.Dir(indexes[0].specAbsolutePath)
+	}
+
+	seen := make(map[*SpecIndex]bool)
+	var circular []*CircularReferenceResult
+	for _, idx := range indexes {
+		if idx == nil || seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		file := idx.specAbsolutePath
+		g.addNode(file, nil)
+
+		// every component and operation is a node, even if nothing references it.
+		pointers := make(map[*yaml.Node]string)
+		if idx.root != nil && len(idx.root.Content) > 0 {
+			g.addDeclaredNodes(file, idx.root.Content[0])
+			collectRefPointers(idx.root.Content[0], "#", pointers)
+		}
+
+		for _, ref := range idx.rawSequencedRefs {
+			if ref.FullDefinition == "" {
+				continue
+			}
+			pointer := pointers[ref.KeyNode]
+			from := g.addNode(file, splitPointer(pointer))
+			target, fragment, _ := strings.Cut(ref.FullDefinition, "#")
+			if target == "" {
+				target = file
+			}
+			to := g.addNode(target, splitPointer(fragment))
+			g.Edges = append(g.Edges, &DependencyEdge{From: from.ID, To: to.ID, Pointer: pointer})
+		}
+
+		circular = append(circular, idx.GetCircularReferences()...)
+		if idx.resolver != nil {
+			circular = append(circular, idx.resolver.circularReferences...)
+			circular = append(circular, idx.resolver.ignoredPolyReferences...)
+			circular = append(circular, idx.resolver.ignoredArrayReferences...)
+		}
+	}
+	g.markCircularReferences(circular)
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Pointer < b.Pointer
+	})
+	for _, n := range g.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	return g
+}
+
+// addDeclaredNodes adds a node for every component, operation and webhook operation declared in a file.
+func (g *DependencyGraph) addDeclaredNodes(file string, root *yaml.Node) {
+	if !utils.IsNodeMap(root) {
+		return
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		if !utils.IsNodeMap(value) {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			name, child := escapePointerSegment(value.Content[j].Value), value.Content[j+1]
+			switch {
+			case key == "components" && utils.IsNodeMap(child):
+				for k := 0; k+1 < len(child.Content); k += 2 {
+					g.addNode(file, []string{key, name, escapePointerSegment(child.Content[k].Value)})
+				}
+			case (key == "paths" || key == "webhooks") && utils.IsNodeMap(child):
+				for k := 0; k+1 < len(child.Content); k += 2 {
+					if isHttpMethod(child.Content[k].Value) {
+						g.addNode(file, []string{key, name, child.Content[k].Value})
+					}
+				}
+			case slices.Contains(dependencyComponentContainers, key):
+				g.addNode(file, []string{key, name})
+			}
+		}
+	}
+}
+
+// addNode adds (or returns the existing) node that owns the supplied JSON pointer segments in a file. References
+// inside a component or an operation belong to that node, anything else belongs to the file.
+func (g *DependencyGraph) addNode(file string, segments []string) *DependencyNode {
+	node := &DependencyNode{Type: DependencyFile, File: file, Name: g.displayFile(file)}
+	if definition, name, ok := componentDefinition(segments); ok {
+		node.Type = DependencyComponent
+		node.Definition = definition
+		node.Name = name
+	} else if len(segments) >= 3 && (segments[0] == "paths" || segments[0] == "webhooks") && isHttpMethod(segments[2]) {
+		node.Type = DependencyOperation
+		node.Definition = "#/" + strings.Join(segments[:3], "/")
+		node.Name = fmt.Sprintf("%s %s", strings.ToUpper(segments[2]), unescapePointerSegment(segments[1]))
+		if segments[0] == "webhooks" {
+			node.Name += " (webhook)"
+		}
+	}
+	node.ID = file + node.Definition
+	if existing, ok := g.nodes[node.ID]; ok {
+		return existing
+	}
+	g.nodes[node.ID] = node
+	return node
+}
+
+// componentDefinition returns the definition and name of the component that owns the supplied JSON pointer
+// segments, if they are inside a component ('#/components/schemas/Pet/properties/name' is owned by
+// '#/components/schemas/Pet').
+func componentDefinition(segments []string) (string, string, bool) {
+	switch {
+	case len(segments) >= 3 && segments[0] == "components":
+		return "#/" + strings.Join(segments[:3], "/"), unescapePointerSegment(segments[2]), true
+	case len(segments) >= 2 && slices.Contains(dependencyComponentContainers, segments[0]):
+		return "#/" + strings.Join(segments[:2], "/"), unescapePointerSegment(segments[1]), true
+	}
+	return "", "", false
+}
+
+// markCircularReferences highlights the nodes and edges that make up each loop, and records each loop once.
+func (g *DependencyGraph) markCircularReferences(results []*CircularReferenceResult) {
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result == nil || result.LoopIndex < 0 || result.LoopIndex >= len(result.Journey) {
+			continue
+		}
+		var loop []string
+		for _, ref := range result.Journey[result.LoopIndex:] {
+			target, fragment, _ := strings.Cut(ref.FullDefinition, "#")
+			if target == "" && ref.Index != nil {
+				target = ref.Index.specAbsolutePath
+			}
+			id := g.addNode(target, splitPointer(fragment)).ID
+			if len(loop) == 0 || loop[len(loop)-1] != id {
+				loop = append(loop, id)
+			}
+		}
+		// the journey ends where the loop starts.
+		if len(loop) > 1 && loop[len(loop)-1] == loop[0] {
+			loop = loop[:len(loop)-1]
+		}
+		if len(loop) == 0 {
+			continue
+		}
+
+		// rotate the loop so it starts at the lowest ID, so the same loop found from another start is the same.
+		lowest := 0
+		for i := range loop {
+			if loop[i] < loop[lowest] {
+				lowest = i
+			}
+		}
+		loop = append(loop[lowest:], loop[:lowest]...)
+		key := strings.Join(loop, "|")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		g.CircularReferences = append(g.CircularReferences, loop)
+
+		for i, id := range loop {
+			g.nodes[id].Circular = true
+			next := loop[(i+1)%len(loop)]
+			for _, edge := range g.Edges {
+				if edge.From == id && edge.To == next {
+					edge.Circular = true
+				}
+			}
+		}
+	}
+	sort.Slice(g.CircularReferences, func(i, j int) bool {
+		return strings.Join(g.CircularReferences[i], "|") < strings.Join(g.CircularReferences[j], "|")
+	})
+}
+
+// RenderJSON renders the graph as JSON.
+func (g *DependencyGraph) RenderJSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// RenderDOT renders the graph in the Graphviz DOT language. Nodes are grouped into a cluster for each file, and
+// circular references are drawn in red.
+func (g *DependencyGraph) RenderDOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n")
+	for i, file := range g.files() {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(g.displayFile(file)))
+		for _, n := range g.Nodes {
+			if n.File != file {
+				continue
+			}
+			attrs := []string{"label=" + dotQuote(n.Name)}
+			switch n.Type {
+			case DependencyFile:
+				attrs = append(attrs, "shape=note")
+			case DependencyOperation:
+				attrs = append(attrs, "shape=box", "style=\"rounded,filled\"", "fillcolor=\"#e8f0fe\"")
+			}
+			if n.Circular {
+				attrs = append(attrs, "color=red", "fontcolor=red")
+			}
+			fmt.Fprintf(&b, "    %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+		}
+		b.WriteString("  }\n")
+	}
+	for _, e := range g.Edges {
+		attrs := []string{"label=" + dotQuote(e.Pointer)}
+		if e.Circular {
+			attrs = append(attrs, "color=red", "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.From), dotQuote(e.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// RenderMermaid renders the graph as a Mermaid flowchart. Nodes are grouped into a subgraph for each file, and
+// circular references are drawn in red.
+func (g *DependencyGraph) RenderMermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, file := range g.files() {
+		fmt.Fprintf(&b, "  subgraph f%d[%s]\n", i, mermaidQuote(g.displayFile(file)))
+		for _, n := range g.Nodes {
+			if n.File != file {
+				continue
+			}
+			switch n.Type {
+			case DependencyFile:
+				fmt.Fprintf(&b, "    %s[/%s/]\n", ids[n.ID], mermaidQuote(n.Name))
+			case DependencyOperation:
+				fmt.Fprintf(&b, "    %s([%s])\n", ids[n.ID], mermaidQuote(n.Name))
+			default:
+				fmt.Fprintf(&b, "    %s[%s]\n", ids[n.ID], mermaidQuote(n.Name))
+			}
+		}
+		b.WriteString("  end\n")
+	}
+
+	var circularEdges, circularNodes []string
+	for i, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[e.From], mermaidQuote(e.Pointer), ids[e.To])
+		if e.Circular {
+			circularEdges = append(circularEdges, fmt.Sprint(i))
+		}
+	}
+	for _, n := range g.Nodes {
+		if n.Circular {
+			circularNodes = append(circularNodes, ids[n.ID])
+		}
+	}
+	if len(circularNodes) > 0 {
+		b.WriteString("  classDef circular stroke:#e00,stroke-width:2px,color:#e00\n")
+		fmt.Fprintf(&b, "  class %s circular\n", strings.Join(circularNodes, ","))
+	}
+	if len(circularEdges) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#e00,stroke-width:2px\n", strings.Join(circularEdges, ","))
+	}
+	return b.String()
+}
+
+// files returns every file in the graph, sorted.
+func (g *DependencyGraph) files() []string {
+	var files []string
+	for _, n := range g.Nodes {
+		if !slices.Contains(files, n.File) {
+			files = append(files, n.File)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// displayFile returns the location of a file relative to the directory of the root document, if possible.
+func (g *DependencyGraph) displayFile(file string) string {
+	if g.basePath == "" || strings.HasPrefix(file, "http") || !filepath.IsAbs(file) {
+		return file
+	}
+	if rel, err := filepath.Rel(g.basePath, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+// collectRefPointers walks a node tree, recording the JSON pointer of the value node of every $ref.
+func collectRefPointers(node *yaml.Node, pointer string, found map[*yaml.Node]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "$ref" && value.Kind == yaml.ScalarNode {
+				found[value] = pointer
+				continue
+			}
+			collectRefPointers(value, pointer+"/"+escapePointerSegment(key.Value), found)
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			collectRefPointers(n, fmt.Sprintf("%s/%d", pointer, i), found)
+		}
+	}
+}
+
+func splitPointer(pointer string) []string {
+	pointer = strings.TrimPrefix(strings.TrimPrefix(pointer, "#"), "/")
+	if pointer == "" {
+		return nil
+	}
+	return strings.Split(pointer, "/")
+}
+
+func escapePointerSegment(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1")
+}
+
+func unescapePointerSegment(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
+
+func dotQuote(value string) string {
+	return "\"" + strings.ReplaceAll(strings.ReplaceAll(value, "\\", "\\\\"), "\"", "\\\"") + "\""
+}
+
+func mermaidQuote(value string) string {
+	return "\"" + strings.ReplaceAll(value, "\"", "#quot;") + "\""
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func findDependencyNode(g *DependencyGraph, id string) *DependencyNode {
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func TestRolodex_BuildDependencyGraph(t *testing.T) {
+	dir := writeReindexFiles(t)
+	circular := `components:
+  schemas:
+    C:
+      type: object
+      properties:
+        next:
+          $ref: '#/components/schemas/C'
+        a:
+          $ref: 'a.yaml#/components/schemas/A'`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.yaml"), []byte(circular), 0o644))
+	rolo := buildReindexRolodex(t, dir, false)
+
+	g := rolo.BuildDependencyGraph()
+	a := filepath.Join(dir, "a.yaml") + "#/components/schemas/A"
+	c := filepath.Join(dir, "c.yaml") + "#/components/schemas/C"
+
+	// a file node, and a node for every component in each of the four files.
+	assert.Len(t, g.Nodes, 9)
+	assert.Len(t, g.Edges, 5)
+	node := findDependencyNode(g, c)
+	require.NotNil(t, node)
+	assert.Equal(t, DependencyComponent, node.Type)
+	assert.Equal(t, "C", node.Name)
+	assert.Equal(t, "#/components/schemas/C", node.Definition)
+	assert.True(t, node.Circular)
+	assert.False(t, findDependencyNode(g, filepath.Join(dir, "b.yaml")+"#/components/schemas/B").Circular)
+
+	// both loops are found, once each.
+	assert.Equal(t, [][]string{{a, c}, {c}}, g.CircularReferences)
+	for _, e := range g.Edges {
+		if e.From == a {
+			assert.Equal(t, c, e.To)
+			assert.Equal(t, "#/components/schemas/A/properties/c", e.Pointer)
+			assert.True(t, e.Circular)
+		}
+		if e.From == filepath.Join(dir, "root.yaml")+"#/components/schemas/B" {
+			assert.False(t, e.Circular)
+		}
+	}
+
+	// the same graph is built from the root index.
+	assert.Equal(t, g.Nodes, rolo.GetRootIndex().BuildDependencyGraph().Nodes)
+
+	dot := g.RenderDOT()
+	assert.Contains(t, dot, "digraph dependencies {")
+	assert.Contains(t, dot, "label=\"c.yaml\";")
+	assert.Contains(t, dot, "\""+c+"\" -> \""+c+"\" [label=\"#/components/schemas/C/properties/next\", color=red, penwidth=2];")
+
+	mermaid := g.RenderMermaid()
+	assert.Contains(t, mermaid, "flowchart LR\n")
+	assert.Contains(t, mermaid, "  n5 -->|\"#/components/schemas/C/properties/next\"| n5\n")
+	assert.Contains(t, mermaid, "  class n1,n5 circular\n")
+	assert.Contains(t, mermaid, "  linkStyle 0,1,2 stroke:#e00,stroke-width:2px\n")
+}
+
+func TestSpecIndex_BuildDependencyGraph(t *testing.T) {
+	spec := `openapi: 3.1.0
+paths:
+  /pets/{id}:
+    parameters:
+      - $ref: '#/components/parameters/Id'
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+components:
+  parameters:
+    Id:
+      name: id
+      in: path
+  schemas:
+    Pet:
+      type: object
+      properties:
+        tags:
+          type: array
+          items:
+            $ref: '#/components/schemas/Tag'
+    Tag:
+      type: string
+    "Say \"hi\"":
+      type: string`
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+	g := idx.BuildDependencyGraph()
+
+	root := idx.GetSpecAbsolutePath()
+	op := findDependencyNode(g, root+"#/paths/~1pets~1{id}/get")
+	require.NotNil(t, op)
+	assert.Equal(t, DependencyOperation, op.Type)
+	assert.Equal(t, "GET /pets/{id}", op.Name)
+	assert.Empty(t, g.CircularReferences)
+
+	edges := make(map[string]*DependencyEdge)
+	for _, e := range g.Edges {
+		edges[e.Pointer] = e
+	}
+	require.Len(t, edges, 3)
+	assert.Equal(t, op.ID, edges["#/paths/~1pets~1{id}/get/responses/200/content/application~1json/schema"].From)
+	assert.Equal(t, root+"#/components/schemas/Pet", edges["#/paths/~1pets~1{id}/get/responses/200/content/application~1json/schema"].To)
+	assert.Equal(t, root+"#/components/schemas/Tag", edges["#/components/schemas/Pet/properties/tags/items"].To)
+
+	// a parameter shared by the path item belongs to the file.
+	assert.Equal(t, root, edges["#/paths/~1pets~1{id}/parameters/0"].From)
+	assert.Equal(t, root+"#/components/parameters/Id", edges["#/paths/~1pets~1{id}/parameters/0"].To)
+
+	// labels are escaped for both formats.
+	assert.Contains(t, g.RenderDOT(), "[label=\"Say \\\"hi\\\"\"]")
+	assert.Contains(t, g.RenderMermaid(), "[\"Say #quot;hi#quot;\"]")
+
+	data, err := g.RenderJSON()
+	require.NoError(t, err)
+	var decoded DependencyGraph
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, g.Nodes, decoded.Nodes)
+	assert.Equal(t, g.Edges, decoded.Edges)
+}
+
+func TestSpecIndex_BuildDependencyGraph_Swagger(t *testing.T) {
+	spec := `swagger: 2.0
+paths:
+  /pets:
+    post:
+      parameters:
+        - in: body
+          schema:
+            $ref: '#/definitions/Pet'
+definitions:
+  Pet:
+    type: object
+    properties:
+      parent:
+        $ref: '#/definitions/Pet'`
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+	resolver := NewResolver(idx)
+	resolver.CheckForCircularReferences()
+
+	g := idx.BuildDependencyGraph()
+	root := idx.GetSpecAbsolutePath()
+	pet := findDependencyNode(g, root+"#/definitions/Pet")
+	require.NotNil(t, pet)
+	assert.Equal(t, "Pet", pet.Name)
+	assert.True(t, pet.Circular)
+	assert.NotNil(t, findDependencyNode(g, root+"#/paths/~1pets/post"))
+	assert.Equal(t, [][]string{{pet.ID}}, g.CircularReferences)
+}
+
+func TestSpecIndex_BuildDependencyGraph_Webhooks(t *testing.T) {
+	spec := `openapi: 3.1.0
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+components:
+  schemas:
+    Pet:
+      type: object`
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+	g := idx.BuildDependencyGraph()
+
+	root := idx.GetSpecAbsolutePath()
+	op := findDependencyNode(g, root+"#/webhooks/newPet/post")
+	require.NotNil(t, op)
+	assert.Equal(t, DependencyOperation, op.Type)
+	assert.Equal(t, "POST newPet (webhook)", op.Name)
+
+	// the schema is used by the webhook operation, not by the file.
+	require.Len(t, g.Edges, 1)
+	assert.Equal(t, op.ID, g.Edges[0].From)
+	assert.Equal(t, root+"#/components/schemas/Pet", g.Edges[0].To)
+	assert.Contains(t, g.RenderDOT(), "[label=\"POST newPet (webhook)\", shape=box")
+	assert.Contains(t, g.RenderMermaid(), "([\"POST newPet (webhook)\"])")
+}
@@ -122,7 +122,7 @@ func (index *SpecIndex) lookupRolodex(uri []string) *Reference {
 		idx := index
 		if ext != "" {
 			// extract the document from the rolodex.
-			rFile, rError := index.rolodex.Open(absoluteFileLocation)
+			rFile, rError := index.rolodex.openFromDepth(absoluteFileLocation, index.remoteDepth())
 
 			if rError != nil {
 				index.logger.Error("unable to open the rolodex file, check specification references and base path",
@@ -151,8 +151,18 @@ type SpecIndexConfig struct {
 	// to be bundled.
 	ExtractRefsSequentially bool
 
+	// RemoteCache is an optional on-disk cache of remote documents. When set, the RemoteFS will serve documents
+	// from the cache while they are fresh, revalidate stale documents using conditional requests and, if the cache
+	// is offline, never touch the network at all.
+	RemoteCache *RemoteCache
+
+	// RemotePolicy restricts which remote references can be fetched, and how much can be fetched. Use this when
+	// indexing untrusted specifications. Violations are reported as *datamodel.RemotePolicyError errors.
+	RemotePolicy *datamodel.RemoteReferencePolicy
+
 	// private fields
-	uri []string
+	uri         []string
+	remoteDepth int // how many remote references deep this index is.
 }
 
 // SetTheoreticalRoot sets the spec file paths to point to a theoretical spec file, which does not exist but is required
@@ -308,6 +318,14 @@ func (index *SpecIndex) GetConfig() *SpecIndexConfig {
 	return index.config
 }
 
+// remoteDepth returns how many remote references away from the root document this index is.
+func (index *SpecIndex) remoteDepth() int {
+	if index.config == nil {
+		return 0
+	}
+	return index.config.remoteDepth
+}
+
 func (index *SpecIndex) SetCache(sync *sync.Map) {
 	index.cache = sync
 }
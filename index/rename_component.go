@@ -0,0 +1,332 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pb33f/libopenapi/json"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// WritableFS is a file system that renamed files can be written back to.
+type WritableFS interface {
+	// WriteFile writes data to the named file (a slash separated path), creating it if necessary.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// LocalWritableFS is a WritableFS that writes files to a directory on disk.
+type LocalWritableFS struct {
+	baseDirectory string
+}
+
+// NewLocalWritableFS creates a WritableFS that writes files beneath a base directory.
+func NewLocalWritableFS(baseDirectory string) *LocalWritableFS {
+	return &LocalWritableFS{baseDirectory: baseDirectory}
+}
+
+// WriteFile writes data to the named file, relative to the base directory.
+func (l *LocalWritableFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	location := filepath.Join(l.baseDirectory, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(location, data, perm)
+}
+
+// ComponentRename is the result of renaming a component.
+type ComponentRename struct {
+	// From and To are the full definitions of the component, before and after it was renamed.
+	From string
+	To   string
+
+	// References is the number of references, discriminator mappings and security requirements that were rewritten.
+	References int
+
+	// Files holds the new content of every file that changed, keyed by its absolute location.
+	Files map[string][]byte
+}
+
+// RenameComponent renames a component (a schema, parameter, response, example or any other type in 'components', or
+// the Swagger 'definitions', 'parameters', 'responses' and 'securityDefinitions') and rewrites every reference to it,
+// or to anything inside it, in every local file of the rolodex. Discriminator mappings are rewritten too, and so are
+// security requirements when a security scheme of the root document is renamed.
+//
+// The definition identifies the component, for example '#/components/schemas/Pet' for a component of the root
+// document, or 'models/pet.yaml#/components/schemas/Pet' for a component in another file (relative locations are
+// resolved against the BasePath of the rolodex).
+//
+// The changed files are re-indexed, the new content of each one is returned so it can be written back with
+// WriteFiles. Models built from the rolodex before the rename still hold the old names, and should be built again.
+// The rolodex must not have been resolved.
+func (r *Rolodex) RenameComponent(definition, newName string) (*ComponentRename, error) {
+	if r.resolved {
+		return nil, errors.New("the rolodex has been resolved, components in resolved documents cannot be renamed")
+	}
+	indexes := r.allFileIndexes()
+	if r.rootIndex != nil {
+		indexes = append([]*SpecIndex{r.rootIndex}, indexes...)
+	}
+	rename, err := renameComponent(indexes, definition, newName, r.changeLocation)
+	if err != nil {
+		return nil, err
+	}
+	var changes []*RolodexFileChange
+	for location, content := range rename.Files {
+		changes = append(changes, &RolodexFileChange{Location: location, Content: content})
+	}
+	if err = r.ReindexFiles(changes...); err != nil {
+		return rename, fmt.Errorf("component renamed, but unable to re-index changed files: %w", err)
+	}
+	return rename, nil
+}
+
+// RenameComponent renames a component and rewrites every reference to it. If the index belongs to a rolodex,
+// references in every file of the rolodex are rewritten (see Rolodex.RenameComponent), otherwise only this index is
+// changed, and it must be built again to see the new name.
+func (index *SpecIndex) RenameComponent(definition, newName string) (*ComponentRename, error) {
+	if index.rolodex != nil {
+		return index.rolodex.RenameComponent(definition, newName)
+	}
+	return renameComponent([]*SpecIndex{index}, definition, newName, func(location string) string {
+		if location == "" || strings.HasPrefix(location, "http") || filepath.IsAbs(location) {
+			return location
+		}
+		abs, _ := filepath.Abs(filepath.Join(index.config.BasePath, location))
+		return abs
+	})
+}
+
+// WriteFiles writes every changed file to a writable file system. Locations are made relative to the base path,
+// files outside the base path cannot be written.
+func (c *ComponentRename) WriteFiles(target WritableFS, basePath string) error {
+	base, err := filepath.Abs(basePath)
+	if err != nil {
+		return err
+	}
+	locations := make([]string, 0, len(c.Files))
+	for location := range c.Files {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	for _, location := range locations {
+		rel, rErr := filepath.Rel(base, location)
+		if rErr != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("unable to write '%s', it is outside of the base path '%s'", location, base)
+		}
+		if err = target.WriteFile(filepath.ToSlash(rel), c.Files[location], 0o644); err != nil {
+			return fmt.Errorf("unable to write '%s': %w", location, err)
+		}
+	}
+	return nil
+}
+
+type componentRenamer struct {
+	from, to     string // definitions, without the file.
+	file         string
+	name         string
+	newName      string
+	securityName bool
+	refs         map[*yaml.Node]*Reference
+	edits        map[*yaml.Node]string // new values, nothing is changed until every file has been checked.
+	changed      map[*SpecIndex]bool
+	count        int
+}
+
+func renameComponent(indexes []*SpecIndex, definition, newName string, locate func(string) string,
+) (*ComponentRename, error) {
+	if len(indexes) == 0 || indexes[0] == nil {
+		return nil, errors.New("there are no indexes, unable to rename component")
+	}
+	if newName == "" {
+		return nil, errors.New("the new name of the component is empty")
+	}
+	file, fragment, _ := strings.Cut(definition, "#")
+	if file == "" {
+		file = indexes[0].specAbsolutePath
+	} else {
+		file = locate(file)
+	}
+	var owner *SpecIndex
+	for _, idx := range indexes {
+		if idx != nil && idx.specAbsolutePath == file {
+			owner = idx
+			break
+		}
+	}
+	if owner == nil {
+		return nil, fmt.Errorf("unable to rename component, '%s' is not part of the rolodex", file)
+	}
+	if strings.HasPrefix(file, "http") {
+		return nil, fmt.Errorf("unable to rename component, '%s' is a remote document", file)
+	}
+	segments := splitPointer(fragment)
+	from, name, ok := componentDefinition(segments)
+	if !ok || from != "#/"+strings.Join(segments, "/") {
+		return nil, fmt.Errorf("unable to rename '%s', it is not a component", definition)
+	}
+
+	// rename the key of the component.
+	container := nodeAtPointer(documentContent(owner), segments[:len(segments)-1])
+	var key *yaml.Node
+	for i := 0; utils.IsNodeMap(container) && i+1 < len(container.Content); i += 2 {
+		switch container.Content[i].Value {
+		case name:
+			key = container.Content[i]
+		case newName:
+			return nil, fmt.Errorf("unable to rename '%s', '%s' already exists", definition, newName)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unable to rename '%s', the component cannot be found", definition)
+	}
+
+	rn := &componentRenamer{
+		from:    from,
+		to:      strings.TrimSuffix(from, segments[len(segments)-1]) + escapePointerSegment(newName),
+		file:    file,
+		name:    name,
+		newName: newName,
+		refs:    make(map[*yaml.Node]*Reference),
+		edits:   map[*yaml.Node]string{key: newName},
+		changed: map[*SpecIndex]bool{owner: true},
+	}
+	rn.securityName = owner == indexes[0] &&
+		(strings.HasPrefix(from, "#/components/securitySchemes/") || strings.HasPrefix(from, "#/securityDefinitions/"))
+	for _, idx := range indexes {
+		if idx == nil {
+			continue
+		}
+		for _, ref := range idx.rawSequencedRefs {
+			if ref.KeyNode != nil {
+				rn.refs[ref.KeyNode] = ref
+			}
+		}
+	}
+	for _, idx := range indexes {
+		if idx != nil && !strings.HasPrefix(idx.specAbsolutePath, "http") {
+			rn.walk(idx, documentContent(idx))
+		}
+	}
+
+	// apply the edits, and put the original values back if any changed file can't be rendered.
+	originals := make(map[*yaml.Node]string, len(rn.edits))
+	for node, value := range rn.edits {
+		originals[node] = node.Value
+		node.Value = value
+	}
+	rename := &ComponentRename{
+		From:       file + from,
+		To:         file + rn.to,
+		References: rn.count,
+		Files:      make(map[string][]byte),
+	}
+	for idx := range rn.changed {
+		content, err := renderIndexFile(idx)
+		if err != nil {
+			for node, value := range originals {
+				node.Value = value
+			}
+			return nil, fmt.Errorf("unable to render '%s': %w", idx.specAbsolutePath, err)
+		}
+		rename.Files[idx.specAbsolutePath] = content
+	}
+	return rename, nil
+}
+
+// edit records the new value of a node in an index, each node is only changed once.
+func (rn *componentRenamer) edit(idx *SpecIndex, node *yaml.Node, value string) {
+	if _, ok := rn.edits[node]; ok {
+		return
+	}
+	rn.edits[node] = value
+	rn.changed[idx] = true
+	rn.count++
+}
+
+// walk looks through a node for references, discriminator mappings and security requirements that point to the
+// renamed component, and records the edits that rewrite them.
+func (rn *componentRenamer) walk(idx *SpecIndex, node *yaml.Node) {
+	if node == nil {
+		return
+	}
+	if !utils.IsNodeMap(node) {
+		for _, n := range node.Content {
+			rn.walk(idx, n)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case key == "$ref" && utils.IsNodeStringValue(value):
+			if ref := rn.refs[value]; ref != nil {
+				rn.rewrite(idx, value, ref.FullDefinition)
+			}
+			continue
+		case key == "discriminator" && utils.IsNodeMap(value):
+			_, mapping := utils.FindKeyNodeTop("mapping", value.Content)
+			for j := 1; utils.IsNodeMap(mapping) && j < len(mapping.Content); j += 2 {
+				target := mapping.Content[j]
+				if !strings.ContainsAny(target.Value, "#/") && ExtractFileType(target.Value) == UNSUPPORTED {
+					if idx.specAbsolutePath == rn.file && rn.from == "#/components/schemas/"+
+						escapePointerSegment(target.Value) {
+						rn.edit(idx, target, rn.newName)
+					}
+					continue
+				}
+				rn.rewrite(idx, target, mappingDefinition(idx.specAbsolutePath, target.Value))
+			}
+		case key == "security" && rn.securityName && utils.IsNodeArray(value):
+			for _, requirement := range value.Content {
+				for j := 0; utils.IsNodeMap(requirement) && j < len(requirement.Content); j += 2 {
+					if requirement.Content[j].Value == rn.name {
+						rn.edit(idx, requirement.Content[j], rn.newName)
+					}
+				}
+			}
+		}
+		rn.walk(idx, value)
+	}
+}
+
+// rewrite records a change to the fragment of a reference (or mapping) value if its full definition points to the
+// renamed component, or anything inside it. The file part of the value is left as it is.
+func (rn *componentRenamer) rewrite(idx *SpecIndex, value *yaml.Node, fullDefinition string) {
+	file, fragment, _ := strings.Cut(fullDefinition, "#")
+	if file == "" {
+		file = idx.specAbsolutePath
+	}
+	fragment = "#" + fragment
+	if file != rn.file || (fragment != rn.from && !strings.HasPrefix(fragment, rn.from+"/")) {
+		return
+	}
+	location, original, _ := strings.Cut(value.Value, "#")
+	rn.edit(idx, value, location+rn.to+strings.TrimPrefix("#"+original, rn.from))
+}
+
+// renderIndexFile renders the root node of an index, as JSON if the file is JSON, otherwise as YAML.
+func renderIndexFile(idx *SpecIndex) ([]byte, error) {
+	if ExtractFileType(idx.specAbsolutePath) == JSON {
+		return json.YAMLNodeToJSON(documentContent(idx), "  ")
+	}
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(idx.root); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), encoder.Close()
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const renameRoot = `openapi: 3.1.0
+security:
+  - apiKey: []
+paths:
+  /pets:
+    get:
+      security:
+        - apiKey: []
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: 'models/pets.yaml#/components/schemas/Pet'
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      name: key
+      in: header
+  schemas:
+    Dog:
+      $ref: 'models/pets.yaml#/components/schemas/Dog'
+    Name:
+      $ref: 'names.json#/name'`
+
+var renameFiles = map[string]string{
+	"models/pets.yaml": `components:
+  schemas:
+    Pet:
+      type: object
+      discriminator:
+        propertyName: kind
+        mapping:
+          pet: Pet
+          dog: '#/components/schemas/Dog'
+      properties:
+        name:
+          type: string
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Pet'
+        - type: object
+          properties:
+            bark:
+              type: boolean
+    PetList:
+      type: array
+      items:
+        $ref: 'pets.yaml#/components/schemas/Pet'`,
+	"names.json": `{
+  "name": {
+    "$ref": "models/pets.yaml#/components/schemas/Pet/properties/name"
+  }
+}`,
+}
+
+func buildRenameRolodex(t *testing.T) (*Rolodex, string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.yaml"), []byte(renameRoot), 0o644))
+	for name, content := range renameFiles {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	rolo := buildRenameRolodexFrom(t, dir)
+	return rolo, dir
+}
+
+func buildRenameRolodexFrom(t *testing.T, dir string) *Rolodex {
+	spec, err := os.ReadFile(filepath.Join(dir, "root.yaml"))
+	require.NoError(t, err)
+	var rootNode yaml.Node
+	require.NoError(t, yaml.Unmarshal(spec, &rootNode))
+
+	cf := CreateOpenAPIIndexConfig()
+	cf.BasePath = dir
+	cf.SpecFilePath = "root.yaml"
+	fileFS, err := NewLocalFSWithConfig(&LocalFSConfig{BaseDirectory: dir, IndexConfig: cf})
+	require.NoError(t, err)
+
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	rolo.AddLocalFS(dir, fileFS)
+	require.NoError(t, rolo.IndexTheRolodex())
+	return rolo
+}
+
+func TestRolodex_RenameComponent(t *testing.T) {
+	rolo, dir := buildRenameRolodex(t)
+	pets := filepath.Join(dir, "models", "pets.yaml")
+
+	rename, err := rolo.RenameComponent("models/pets.yaml#/components/schemas/Pet", "Animal")
+	require.NoError(t, err)
+	assert.Equal(t, pets+"#/components/schemas/Pet", rename.From)
+	assert.Equal(t, pets+"#/components/schemas/Animal", rename.To)
+
+	// the root reference, the allOf chain, the reference to itself, the mapping, and the nested reference.
+	assert.Equal(t, 5, rename.References)
+	assert.Len(t, rename.Files, 3)
+	assert.Contains(t, string(rename.Files[filepath.Join(dir, "root.yaml")]),
+		"$ref: 'models/pets.yaml#/components/schemas/Animal'")
+	assert.Contains(t, string(rename.Files[pets]), "    Animal:\n      type: object")
+	assert.Contains(t, string(rename.Files[pets]), "pet: Animal")
+	assert.Contains(t, string(rename.Files[pets]), "dog: '#/components/schemas/Dog'")
+	assert.Contains(t, string(rename.Files[pets]), "- $ref: '#/components/schemas/Animal'")
+	assert.Contains(t, string(rename.Files[pets]), "$ref: 'pets.yaml#/components/schemas/Animal'")
+	assert.Equal(t, `{
+  "name": {
+    "$ref": "models/pets.yaml#/components/schemas/Animal/properties/name"
+  }
+}`, string(rename.Files[filepath.Join(dir, "names.json")]))
+
+	// the rolodex has been re-indexed with the new names.
+	assert.Empty(t, rolo.GetCaughtErrors())
+	assert.NotNil(t, rolo.GetRootIndex().FindComponent(pets+"#/components/schemas/Animal"))
+
+	// writing the files back gives a rolodex that indexes without errors.
+	require.NoError(t, rename.WriteFiles(NewLocalWritableFS(dir), dir))
+	written, err := os.ReadFile(pets)
+	require.NoError(t, err)
+	assert.Equal(t, rename.Files[pets], written)
+	rebuilt := buildRenameRolodexFrom(t, dir)
+	assert.Empty(t, rebuilt.GetCaughtErrors())
+	assert.Empty(t, rebuilt.GetRootIndex().GetReferenceIndexErrors())
+}
+
+func TestRolodex_RenameComponent_SecurityScheme(t *testing.T) {
+	rolo, dir := buildRenameRolodex(t)
+
+	rename, err := rolo.RenameComponent("#/components/securitySchemes/apiKey", "token")
+	require.NoError(t, err)
+	assert.Equal(t, 2, rename.References)
+	require.Len(t, rename.Files, 1)
+	root := string(rename.Files[filepath.Join(dir, "root.yaml")])
+	assert.NotContains(t, root, "apiKey:")
+	assert.Contains(t, root, "  - token: []")
+	assert.Contains(t, root, "    token:\n      type: apiKey")
+}
+
+type memoryWritableFS map[string][]byte
+
+func (m memoryWritableFS) WriteFile(name string, data []byte, _ fs.FileMode) error {
+	m[name] = data
+	return nil
+}
+
+func TestRolodex_RenameComponent_Errors(t *testing.T) {
+	rolo, dir := buildRenameRolodex(t)
+
+	_, err := rolo.RenameComponent("#/components/schemas/Dog", "Name")
+	assert.EqualError(t, err, "unable to rename '#/components/schemas/Dog', 'Name' already exists")
+	_, err = rolo.RenameComponent("#/components/schemas/Cat", "Animal")
+	assert.EqualError(t, err, "unable to rename '#/components/schemas/Cat', the component cannot be found")
+	_, err = rolo.RenameComponent("#/paths/~1pets", "Animal")
+	assert.EqualError(t, err, "unable to rename '#/paths/~1pets', it is not a component")
+	_, err = rolo.RenameComponent("#/components/schemas/Dog", "")
+	assert.EqualError(t, err, "the new name of the component is empty")
+	_, err = rolo.RenameComponent("missing.yaml#/components/schemas/Dog", "Animal")
+	assert.ErrorContains(t, err, "is not part of the rolodex")
+
+	// files are written relative to the base path, and nothing outside it.
+	rename, err := rolo.RenameComponent("#/components/schemas/Dog", "Hound")
+	require.NoError(t, err)
+	target := memoryWritableFS{}
+	require.NoError(t, rename.WriteFiles(target, dir))
+	assert.Contains(t, string(target["root.yaml"]), "Hound:")
+	assert.Error(t, rename.WriteFiles(target, filepath.Join(dir, "models")))
+
+	assert.Error(t, NewLocalWritableFS(dir).WriteFile("../escape.yaml", nil, 0o644))
+
+	// names that only start with dots are inside the base path.
+	dotted := &ComponentRename{Files: map[string][]byte{filepath.Join(dir, "..pets.yaml"): []byte("dots")}}
+	require.NoError(t, dotted.WriteFiles(target, dir))
+	assert.Equal(t, "dots", string(target["..pets.yaml"]))
+}
+
+func TestRolodex_RenameComponent_NothingChangedOnError(t *testing.T) {
+	rolo, dir := buildRenameRolodex(t)
+
+	// a value that can't be rendered as JSON stops the rename after every file has been checked.
+	for _, idx := range rolo.GetIndexes() {
+		if idx.GetSpecAbsolutePath() == filepath.Join(dir, "names.json") {
+			content := idx.GetRootNode().Content[0]
+			content.Content = append(content.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "broken"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "nope"})
+		}
+	}
+	_, err := rolo.RenameComponent("models/pets.yaml#/components/schemas/Pet", "Animal")
+	assert.ErrorContains(t, err, "unable to render '"+filepath.Join(dir, "names.json")+"'")
+
+	// no file has been partly renamed.
+	root, _ := yaml.Marshal(rolo.GetRootNode())
+	assert.Contains(t, string(root), "$ref: 'models/pets.yaml#/components/schemas/Pet'")
+	for _, idx := range rolo.GetIndexes() {
+		if idx.GetSpecAbsolutePath() == filepath.Join(dir, "models", "pets.yaml") {
+			rendered, _ := yaml.Marshal(idx.GetRootNode())
+			assert.Contains(t, string(rendered), "    Pet:\n")
+			assert.NotContains(t, string(rendered), "Animal")
+		}
+	}
+}
+
+func TestSpecIndex_RenameComponent(t *testing.T) {
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(`openapi: 3.1.0
+components:
+  schemas:
+    Pet:
+      type: object
+    Pets:
+      type: array
+      items:
+        $ref: '#/components/schemas/Pet'`), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+
+	rename, err := idx.RenameComponent("#/components/schemas/Pet", "Animal")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rename.References)
+	require.Len(t, rename.Files, 1)
+	for _, content := range rename.Files {
+		assert.Contains(t, string(content), "$ref: '#/components/schemas/Animal'")
+	}
+}
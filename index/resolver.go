@@ -4,6 +4,7 @@
 package index
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -67,6 +68,7 @@ type Resolver struct {
 	IgnorePoly             bool
 	IgnoreArray            bool
 	circChecked            bool
+	ctx                    context.Context
 }
 
 // NewResolver will create a new resolver from a *index.SpecIndex
@@ -190,7 +192,20 @@ func (resolver *Resolver) GetRelativesSeen() int {
 // re-organize the node tree. Make sure you have copied your original tree before running this (if you want to preserve
 // original data)
 func (resolver *Resolver) Resolve() []*ResolvingError {
+	errs, _ := resolver.ResolveWithContext(context.Background())
+	return errs
+}
+
+// ResolveWithContext is the same as Resolve, except resolving stops as soon as the context is cancelled or reaches
+// its deadline, returning ctx.Err(). A cancelled resolve will leave the node tree partially resolved.
+func (resolver *Resolver) ResolveWithContext(ctx context.Context) ([]*ResolvingError, error) {
+	resolver.ctx = ctx
+	defer func() { resolver.ctx = nil }()
+
 	visitIndex(resolver, resolver.specIndex)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	for _, circRef := range resolver.circularReferences {
 		// If the circular reference is not required, we can ignore it, as it's a terminable loop rather than an infinite one
@@ -211,12 +226,25 @@ func (resolver *Resolver) Resolve() []*ResolvingError {
 	resolver.specIndex.SetIgnoredArrayCircularReferences(resolver.ignoredArrayReferences)
 	resolver.specIndex.SetIgnoredPolymorphicCircularReferences(resolver.ignoredPolyReferences)
 	resolver.circChecked = true
-	return resolver.resolvingErrors
+	return resolver.resolvingErrors, nil
 }
 
 // CheckForCircularReferences Check for circular references, without resolving, a non-destructive run.
 func (resolver *Resolver) CheckForCircularReferences() []*ResolvingError {
+	errs, _ := resolver.CheckForCircularReferencesWithContext(context.Background())
+	return errs
+}
+
+// CheckForCircularReferencesWithContext is the same as CheckForCircularReferences, except the check stops as soon
+// as the context is cancelled or reaches its deadline, returning ctx.Err().
+func (resolver *Resolver) CheckForCircularReferencesWithContext(ctx context.Context) ([]*ResolvingError, error) {
+	resolver.ctx = ctx
+	defer func() { resolver.ctx = nil }()
+
 	visitIndexWithoutDamagingIt(resolver, resolver.specIndex)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	for _, circRef := range resolver.circularReferences {
 		// If the circular reference is not required, we can ignore it, as it's a terminable loop rather than an infinite one
 		if !circRef.IsInfiniteLoop {
@@ -236,7 +264,12 @@ func (resolver *Resolver) CheckForCircularReferences() []*ResolvingError {
 	resolver.specIndex.SetIgnoredArrayCircularReferences(resolver.ignoredArrayReferences)
 	resolver.specIndex.SetIgnoredPolymorphicCircularReferences(resolver.ignoredPolyReferences)
 	resolver.circChecked = true
-	return resolver.resolvingErrors
+	return resolver.resolvingErrors, nil
+}
+
+// cancelled returns true if the resolver is running with a context that is done.
+func (resolver *Resolver) cancelled() bool {
+	return resolver.ctx != nil && resolver.ctx.Err() != nil
 }
 
 func visitIndexWithoutDamagingIt(res *Resolver, idx *SpecIndex) {
@@ -244,6 +277,9 @@ func visitIndexWithoutDamagingIt(res *Resolver, idx *SpecIndex) {
 	mappedIndex := idx.GetMappedReferences()
 	res.indexesVisited++
 	for _, ref := range mapped {
+		if res.cancelled() {
+			return
+		}
 		seenReferences := make(map[string]bool)
 		var journey []*Reference
 		res.journeysTaken++
@@ -251,6 +287,9 @@ func visitIndexWithoutDamagingIt(res *Resolver, idx *SpecIndex) {
 	}
 	schemas := idx.GetAllComponentSchemas()
 	for s, schemaRef := range schemas {
+		if res.cancelled() {
+			return
+		}
 		if mappedIndex[s] == nil {
 			seenReferences := make(map[string]bool)
 			var journey []*Reference
@@ -272,6 +311,9 @@ func visitIndex(res *Resolver, idx *SpecIndex) {
 
 	var refs []refMap
 	for _, ref := range mapped {
+		if res.cancelled() {
+			return
+		}
 		seenReferences := make(map[string]bool)
 		var journey []*Reference
 		res.journeysTaken++
@@ -292,6 +334,9 @@ func visitIndex(res *Resolver, idx *SpecIndex) {
 
 	schemas := idx.GetAllComponentSchemas()
 	for s, schemaRef := range schemas {
+		if res.cancelled() {
+			return
+		}
 		if mappedIndex[s] == nil {
 			seenReferences := make(map[string]bool)
 			var journey []*Reference
@@ -302,6 +347,9 @@ func visitIndex(res *Resolver, idx *SpecIndex) {
 
 	schemas = idx.GetAllSecuritySchemes()
 	for s, schemaRef := range schemas {
+		if res.cancelled() {
+			return
+		}
 		if mappedIndex[s] == nil {
 			seenReferences := make(map[string]bool)
 			var journey []*Reference
@@ -324,6 +372,9 @@ func visitIndex(res *Resolver, idx *SpecIndex) {
 // VisitReference will visit a reference as part of a journey and will return resolved nodes.
 func (resolver *Resolver) VisitReference(ref *Reference, seen map[string]bool, journey []*Reference, resolve bool) []*yaml.Node {
 	resolver.referencesVisited++
+	if resolver.cancelled() {
+		return ref.Node.Content
+	}
 	if resolve && ref.Seen {
 		if ref.Resolved {
 			return ref.Node.Content
@@ -455,7 +506,7 @@ func (resolver *Resolver) extractRelatives(ref *Reference, node, parent *yaml.No
 	foundRelatives map[string]bool,
 	journey []*Reference, seen map[int]bool, resolve bool, depth int,
 ) []*Reference {
-	if len(journey) > 100 {
+	if len(journey) > 100 || resolver.cancelled() {
 		return nil
 	}
 
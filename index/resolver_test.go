@@ -2,6 +2,7 @@ package index
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/pb33f/libopenapi/datamodel"
@@ -1443,3 +1444,36 @@ components:
 	assert.Len(t, errs, 0)
 
 }
+
+func TestResolver_WithContext_Cancelled(t *testing.T) {
+	circular, _ := os.ReadFile("../test_specs/circular-tests.yaml")
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal(circular, &rootNode)
+
+	cf := CreateClosedAPIIndexConfig()
+	cf.AvoidCircularReferenceCheck = true
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	assert.NoError(t, rolo.IndexTheRolodex())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resolver := rolo.GetRootIndex().GetResolver()
+	errs, err := resolver.CheckForCircularReferencesWithContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, errs)
+	assert.Empty(t, resolver.GetCircularReferences())
+
+	errs, err = resolver.ResolveWithContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, errs)
+
+	assert.ErrorIs(t, rolo.CheckForCircularReferencesWithContext(ctx), context.Canceled)
+	assert.Empty(t, rolo.GetCaughtErrors())
+
+	// the context is not held on to, so the resolver still works afterwards.
+	errs, err = resolver.CheckForCircularReferencesWithContext(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, errs, 3)
+}
@@ -4,8 +4,10 @@
 package index
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/pb33f/libopenapi/datamodel"
 	"gopkg.in/yaml.v3"
 	"io"
 	"io/fs"
@@ -18,6 +20,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -73,6 +76,14 @@ type Rolodex struct {
 	infiniteCircularReferences []*CircularReferenceResult
 	ignoredCircularReferences  []*CircularReferenceResult
 	logger                     *slog.Logger
+	loadingCtx                 atomic.Pointer[contextHolder]
+	indexingErrors             map[string]error
+	reindexedFiles             []string
+}
+
+// contextHolder wraps a context so it can be stored atomically (contexts do not share a concrete type).
+type contextHolder struct {
+	ctx context.Context
 }
 
 // NewRolodex creates a new rolodex with the provided index configuration.
@@ -142,6 +153,11 @@ func (r *Rolodex) GetCaughtErrors() []error {
 	return r.caughtErrors
 }
 
+// IsCircularReferenceChecked returns true if the rolodex has been checked for circular references.
+func (r *Rolodex) IsCircularReferenceChecked() bool {
+	return r.circChecked
+}
+
 // AddLocalFS adds a local file system to the rolodex.
 func (r *Rolodex) AddLocalFS(baseDir string, fileSystem fs.FS) {
 	absBaseDir, _ := filepath.Abs(baseDir)
@@ -149,9 +165,27 @@ func (r *Rolodex) AddLocalFS(baseDir string, fileSystem fs.FS) {
 		f.rolodex = r
 		f.logger = r.logger
 	}
+	if f, ok := fileSystem.(*ArchiveFS); ok {
+		f.rolodex = r
+		f.logger = r.logger
+	}
 	r.localFS[absBaseDir] = fileSystem
 }
 
+// GetLocalFiles returns every file read from the local file systems of the rolodex, keyed by the full path
+// of the file. Files that failed to parse are included.
+func (r *Rolodex) GetLocalFiles() map[string]RolodexFile {
+	files := make(map[string]RolodexFile)
+	for _, v := range r.localFS {
+		if lfs, ok := v.(RolodexFS); ok {
+			for k, f := range lfs.GetFiles() {
+				files[k] = f
+			}
+		}
+	}
+	return files
+}
+
 // SetRootNode sets the root node of the rolodex (the entry point, the main document)
 func (r *Rolodex) SetRootNode(node *yaml.Node) {
 	r.rootNode = node
@@ -184,9 +218,19 @@ func (r *Rolodex) AddRemoteFS(baseURL string, fileSystem fs.FS) {
 
 // IndexTheRolodex indexes the rolodex, building out the indexes for each file, and then building the root index.
 func (r *Rolodex) IndexTheRolodex() error {
+	return r.IndexTheRolodexWithContext(context.Background())
+}
+
+// IndexTheRolodexWithContext is the same as IndexTheRolodex, except indexing (and any remote fetches made while
+// indexing) stops as soon as the context is cancelled or reaches its deadline, returning ctx.Err().
+func (r *Rolodex) IndexTheRolodexWithContext(ctx context.Context) error {
 	if r.indexed {
 		return nil
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.useLoadingContext(ctx)()
 
 	var caughtErrors []error
 
@@ -210,7 +254,11 @@ func (r *Rolodex) IndexTheRolodex() error {
 			idx, err := idxFile.Index(&copiedConfig)
 
 			if err != nil {
-				errChan <- err
+				r.recordIndexingError(fullPath, err)
+				select {
+				case errChan <- err:
+				case <-ctx.Done():
+				}
 			}
 
 			if err == nil {
@@ -224,7 +272,10 @@ func (r *Rolodex) IndexTheRolodex() error {
 				if copiedConfig.IgnorePolymorphicCircularReferences {
 					resolver.IgnorePolymorphicCircularReferences()
 				}
-				indexChan <- idx
+				select {
+				case indexChan <- idx:
+				case <-ctx.Done():
+				}
 			}
 
 		}
@@ -241,11 +292,21 @@ func (r *Rolodex) IndexTheRolodex() error {
 			if wait {
 				wg.Wait()
 			}
-			doneChan <- true
+			select {
+			case doneChan <- true:
+			case <-ctx.Done():
+			}
 			return
 		} else {
-			errChan <- errors.New("rolodex file system is not a RolodexFS")
-			doneChan <- true
+			select {
+			case errChan <- errors.New("rolodex file system is not a RolodexFS"):
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case doneChan <- true:
+			case <-ctx.Done():
+			}
 		}
 	}
 
@@ -273,6 +334,8 @@ func (r *Rolodex) IndexTheRolodex() error {
 			caughtErrors = append(caughtErrors, err)
 		case idx := <-indexChan:
 			indexBuildQueue = append(indexBuildQueue, idx)
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
@@ -284,11 +347,17 @@ func (r *Rolodex) IndexTheRolodex() error {
 	})
 
 	for _, idx := range indexBuildQueue {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		idx.BuildIndex()
 		if r.indexConfig.AvoidCircularReferenceCheck {
 			continue
 		}
-		errs := idx.resolver.CheckForCircularReferences()
+		errs, cErr := idx.resolver.CheckForCircularReferencesWithContext(ctx)
+		if cErr != nil {
+			return cErr
+		}
 		for e := range errs {
 			caughtErrors = append(caughtErrors, errs[e])
 		}
@@ -331,12 +400,21 @@ func (r *Rolodex) IndexTheRolodex() error {
 			resolver.IgnorePolymorphicCircularReferences()
 		}
 		r.rootIndex = index
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		r.logger.Debug("[rolodex] starting root index build")
 		index.BuildIndex()
 		r.logger.Debug("[rolodex] root index build completed")
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		if !r.indexConfig.AvoidCircularReferenceCheck {
-			resolvingErrors := resolver.CheckForCircularReferences()
+			resolvingErrors, cErr := resolver.CheckForCircularReferencesWithContext(ctx)
+			if cErr != nil {
+				return cErr
+			}
 			r.circChecked = true
 			for e := range resolvingErrors {
 				caughtErrors = append(caughtErrors, resolvingErrors[e])
@@ -353,6 +431,9 @@ func (r *Rolodex) IndexTheRolodex() error {
 			caughtErrors = append(caughtErrors, index.refErrors...)
 		}
 	}
+
+	// remote policy violations are always reported.
+	caughtErrors = append(caughtErrors, r.remotePolicyErrors()...)
 	r.indexingDuration = time.Since(started)
 	r.indexed = true
 	r.caughtErrors = caughtErrors
@@ -361,11 +442,54 @@ func (r *Rolodex) IndexTheRolodex() error {
 
 }
 
+// remotePolicyErrors returns any remote reference policy violations caught by the remote file systems.
+func (r *Rolodex) remotePolicyErrors() []error {
+	var errs []error
+	for _, v := range r.remoteFS {
+		if rfs, ok := v.(*RemoteFS); ok {
+			for _, e := range rfs.GetErrors() {
+				var policyErr *datamodel.RemotePolicyError
+				if errors.As(e, &policyErr) {
+					errs = append(errs, e)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// useLoadingContext makes the context available to file systems while the rolodex is loading, the returned
+// function restores the previous context.
+func (r *Rolodex) useLoadingContext(ctx context.Context) func() {
+	previous := r.loadingCtx.Swap(&contextHolder{ctx: ctx})
+	return func() {
+		r.loadingCtx.Store(previous)
+	}
+}
+
+// loadingContext returns the context the rolodex is currently loading with, or a background context.
+func (r *Rolodex) loadingContext() context.Context {
+	if h := r.loadingCtx.Load(); h != nil {
+		return h.ctx
+	}
+	return context.Background()
+}
+
 // CheckForCircularReferences checks for circular references in the rolodex.
 func (r *Rolodex) CheckForCircularReferences() {
+	_ = r.CheckForCircularReferencesWithContext(context.Background())
+}
+
+// CheckForCircularReferencesWithContext is the same as CheckForCircularReferences, except the check stops as soon
+// as the context is cancelled or reaches its deadline, returning ctx.Err().
+func (r *Rolodex) CheckForCircularReferencesWithContext(ctx context.Context) error {
 	if !r.circChecked {
 		if r.rootIndex != nil && r.rootIndex.resolver != nil {
-			resolvingErrors := r.rootIndex.resolver.CheckForCircularReferences()
+			defer r.useLoadingContext(ctx)()
+			resolvingErrors, err := r.rootIndex.resolver.CheckForCircularReferencesWithContext(ctx)
+			if err != nil {
+				return err
+			}
 			for e := range resolvingErrors {
 				r.caughtErrors = append(r.caughtErrors, resolvingErrors[e])
 			}
@@ -380,6 +504,7 @@ func (r *Rolodex) CheckForCircularReferences() {
 		}
 		r.circChecked = true
 	}
+	return nil
 }
 
 // Resolve resolves references in the rolodex.
@@ -458,6 +583,12 @@ func (r *Rolodex) GetAllMappedReferences() map[string]*Reference {
 
 // Open opens a file in the rolodex, and returns a RolodexFile.
 func (r *Rolodex) Open(location string) (RolodexFile, error) {
+	return r.openFromDepth(location, 0)
+}
+
+// openFromDepth opens a file referenced by a document that is the supplied number of remote references away from
+// the root document.
+func (r *Rolodex) openFromDepth(location string, depth int) (RolodexFile, error) {
 
 	if r == nil {
 		return nil, fmt.Errorf("rolodex has not been initialized, cannot open file '%s'", location)
@@ -538,7 +669,13 @@ func (r *Rolodex) Open(location string) (RolodexFile, error) {
 
 		for _, v := range r.remoteFS {
 
-			f, err := v.Open(fileLookup)
+			var f fs.File
+			var err error
+			if rfs, ok := v.(*RemoteFS); ok {
+				f, err = rfs.openAtDepth(r.loadingContext(), fileLookup, depth+1)
+			} else {
+				f, err = v.Open(fileLookup)
+			}
 			if err != nil {
 				r.logger.Warn("[rolodex] errors opening remote file", "location", fileLookup, "error", err)
 			}
@@ -0,0 +1,419 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat is the type of archive read by an ArchiveFS.
+type ArchiveFormat int
+
+const (
+	// ArchiveUnknown will detect the format of the archive from the file extension or the content.
+	ArchiveUnknown ArchiveFormat = iota
+	ArchiveZip
+	ArchiveTar
+	ArchiveTarGzip
+)
+
+const (
+	// DefaultArchiveMaxEntrySize is the largest entry read from an archive, when ArchiveFSConfig.MaxEntrySize is not set.
+	DefaultArchiveMaxEntrySize int64 = 64 << 20
+
+	// DefaultArchiveMaxTotalSize is the most read from all the entries of an archive, when
+	// ArchiveFSConfig.MaxTotalSize is not set.
+	DefaultArchiveMaxTotalSize int64 = 512 << 20
+)
+
+// String returns the name of the archive format.
+func (a ArchiveFormat) String() string {
+	switch a {
+	case ArchiveZip:
+		return "zip"
+	case ArchiveTar:
+		return "tar"
+	case ArchiveTarGzip:
+		return "tar.gz"
+	}
+	return "unknown"
+}
+
+// ArchiveFSConfig is the configuration for the ArchiveFS.
+type ArchiveFSConfig struct {
+	// Path is the location of a zip, tar or tar.gz archive on disk. Ignored if a Reader is supplied.
+	Path string
+
+	// Reader supplies the archive from anything that can be read at an offset (a byte slice reader, a file
+	// or a blob). Size must be set to the length of the archive when a Reader is used.
+	Reader io.ReaderAt
+	Size   int64
+
+	// Format is the type of archive, if not set it will be detected from the Path extension or the content.
+	Format ArchiveFormat
+
+	// BaseDirectory is the directory the archive is mounted at, every file in the archive is reported
+	// as living under this directory, in NodeOrigin values and errors. Defaults to the Path of the archive.
+	BaseDirectory string
+
+	// Root is an optional directory inside the archive that relative lookups are resolved from, useful when the
+	// archive wraps everything in a single top level folder. Only files beneath the root are read.
+	Root string
+
+	// supply a list of specific files (relative to the root) to index only
+	FileFilters []string
+
+	// MaxEntrySize is the largest (uncompressed) entry that can be read, and MaxTotalSize is the most that can be
+	// read from all the entries together. Reading an archive that exceeds either fails. Defaults to
+	// DefaultArchiveMaxEntrySize and DefaultArchiveMaxTotalSize, set a negative value for no limit.
+	MaxEntrySize int64
+	MaxTotalSize int64
+
+	// supply your own logger
+	Logger *slog.Logger
+
+	// supply an index configuration to use
+	IndexConfig *SpecIndexConfig
+}
+
+// ArchiveFS is a file system that indexes the JSON and YAML files held inside a zip, tar or tar.gz archive,
+// without extracting anything to disk. Files are read once, when the file system is created.
+type ArchiveFS struct {
+	fsConfig      *ArchiveFSConfig
+	indexConfig   *SpecIndexConfig
+	format        ArchiveFormat
+	archivePath   string
+	baseDirectory string
+	files         map[string]*LocalFile
+	logger        *slog.Logger
+	readingErrors []error
+	rolodex       *Rolodex
+	totalSize     int64
+}
+
+// NewArchiveFSWithConfig creates a new ArchiveFS with the supplied configuration, reading every
+// JSON and YAML file from the archive.
+func NewArchiveFSWithConfig(config *ArchiveFSConfig) (*ArchiveFS, error) {
+	if config == nil {
+		return nil, errors.New("an archive file system requires a configuration")
+	}
+	log := config.Logger
+	if log == nil {
+		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+			Level: slog.LevelError,
+		}))
+	}
+
+	reader, size := config.Reader, config.Size
+	archivePath := config.Path
+	if reader == nil {
+		if archivePath == "" {
+			return nil, errors.New("an archive file system requires a Path or a Reader")
+		}
+		data, err := os.ReadFile(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read archive '%s': %w", archivePath, err)
+		}
+		reader, size = bytes.NewReader(data), int64(len(data))
+	}
+	if archivePath == "" {
+		archivePath = config.BaseDirectory
+	}
+
+	base := config.BaseDirectory
+	if base == "" {
+		base = archivePath
+	}
+	if base == "" {
+		return nil, errors.New("an archive file system read from a Reader requires a BaseDirectory")
+	}
+	base, _ = filepath.Abs(base)
+	if config.Root != "" {
+		base = filepath.Join(base, filepath.FromSlash(strings.Trim(config.Root, "/")))
+	}
+
+	format := config.Format
+	if format == ArchiveUnknown {
+		format = detectArchiveFormat(archivePath, reader)
+	}
+
+	afs := &ArchiveFS{
+		fsConfig:      config,
+		indexConfig:   config.IndexConfig,
+		format:        format,
+		archivePath:   archivePath,
+		baseDirectory: base,
+		files:         make(map[string]*LocalFile),
+		logger:        log,
+	}
+
+	var err error
+	switch format {
+	case ArchiveZip:
+		err = afs.readZip(reader, size)
+	case ArchiveTar:
+		err = afs.readTar(io.NewSectionReader(reader, 0, size))
+	case ArchiveTarGzip:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(io.NewSectionReader(reader, 0, size))
+		if err == nil {
+			err = afs.readTar(gz)
+		}
+	default:
+		err = errors.New("unsupported archive format, only zip, tar and tar.gz archives can be read")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read archive '%s': %w", archivePath, err)
+	}
+	return afs, nil
+}
+
+func detectArchiveFormat(name string, reader io.ReaderAt) ArchiveFormat {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGzip
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar
+	}
+
+	// no extension to go on, check the magic bytes.
+	header := make([]byte, 262)
+	n, _ := reader.ReadAt(header, 0)
+	header = header[:n]
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveTarGzip
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return ArchiveTar
+	}
+	return ArchiveUnknown
+}
+
+func (a *ArchiveFS) readZip(reader io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(reader, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := a.entryName(f.Name)
+		if !ok {
+			continue
+		}
+		rc, oErr := f.Open()
+		if oErr != nil {
+			a.readingErrors = append(a.readingErrors, a.entryError(f.Name, oErr))
+			continue
+		}
+		data, rErr := a.readEntry(f.Name, rc)
+		_ = rc.Close()
+		var tooLarge *archiveSizeError
+		if errors.As(rErr, &tooLarge) {
+			return rErr
+		}
+		if rErr != nil {
+			a.readingErrors = append(a.readingErrors, a.entryError(f.Name, rErr))
+			continue
+		}
+		a.addFile(name, data, f.Modified)
+	}
+	return nil
+}
+
+func (a *ArchiveFS) readTar(reader io.Reader) error {
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok := a.entryName(hdr.Name)
+		if !ok {
+			continue
+		}
+		data, rErr := a.readEntry(hdr.Name, tr)
+		var tooLarge *archiveSizeError
+		if errors.As(rErr, &tooLarge) {
+			return rErr
+		}
+		if rErr != nil {
+			a.readingErrors = append(a.readingErrors, a.entryError(hdr.Name, rErr))
+			continue
+		}
+		a.addFile(name, data, hdr.ModTime)
+	}
+}
+
+// archiveSizeError is returned when an entry, or all the entries together, are larger than the configured limits.
+type archiveSizeError struct {
+	entry  string
+	detail string
+}
+
+func (e *archiveSizeError) Error() string {
+	return fmt.Sprintf("entry '%s' %s", e.entry, e.detail)
+}
+
+// readEntry reads the content of an entry, failing if the entry or the archive would exceed the size limits.
+func (a *ArchiveFS) readEntry(entry string, reader io.Reader) ([]byte, error) {
+	entryLimit, totalLimit := a.fsConfig.MaxEntrySize, a.fsConfig.MaxTotalSize
+	if entryLimit == 0 {
+		entryLimit = DefaultArchiveMaxEntrySize
+	}
+	if totalLimit == 0 {
+		totalLimit = DefaultArchiveMaxTotalSize
+	}
+	limit := int64(-1)
+	if entryLimit > 0 {
+		limit = entryLimit
+	}
+	if totalLimit > 0 && (limit < 0 || totalLimit-a.totalSize < limit) {
+		limit = totalLimit - a.totalSize
+	}
+	if limit >= 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(data))
+	if entryLimit > 0 && size > entryLimit {
+		return nil, &archiveSizeError{entry: entry,
+			detail: fmt.Sprintf("is larger than the maximum entry size of %d bytes", entryLimit)}
+	}
+	a.totalSize += size
+	if totalLimit > 0 && a.totalSize > totalLimit {
+		return nil, &archiveSizeError{entry: entry,
+			detail: fmt.Sprintf("takes the archive over the maximum total size of %d bytes", totalLimit)}
+	}
+	return data, nil
+}
+
+// entryName cleans up the name of an archive entry and returns it relative to the configured root. Entries that
+// are outside the root, are not JSON or YAML, or are not in the file filter are skipped.
+func (a *ArchiveFS) entryName(entry string) (string, bool) {
+	name := path.Clean(strings.TrimPrefix(filepath.ToSlash(entry), "/"))
+	if name == ".." || strings.HasPrefix(name, "../") {
+		a.readingErrors = append(a.readingErrors, a.entryError(entry,
+			errors.New("entry escapes the archive and has been skipped")))
+		return "", false
+	}
+	if a.fsConfig.Root != "" {
+		root := path.Clean(strings.Trim(filepath.ToSlash(a.fsConfig.Root), "/"))
+		if !strings.HasPrefix(name, root+"/") {
+			return "", false
+		}
+		name = strings.TrimPrefix(name, root+"/")
+	}
+	if strings.HasPrefix(path.Base(name), ".") {
+		return "", false
+	}
+	if ExtractFileType(name) == UNSUPPORTED {
+		a.logger.Debug("[rolodex archive loader]: skipping non JSON/YAML file", "archive", a.archivePath, "file", name)
+		return "", false
+	}
+	if len(a.fsConfig.FileFilters) > 0 && !slices.Contains(a.fsConfig.FileFilters, name) {
+		return "", false
+	}
+	return name, true
+}
+
+func (a *ArchiveFS) entryError(entry string, err error) error {
+	return fmt.Errorf("archive '%s', entry '%s': %w", a.archivePath, entry, err)
+}
+
+func (a *ArchiveFS) addFile(name string, data []byte, modTime time.Time) {
+	abs := filepath.Join(a.baseDirectory, filepath.FromSlash(name))
+	a.logger.Debug("[rolodex archive loader]: collecting file from archive", "archive", a.archivePath, "file", abs)
+	a.files[abs] = &LocalFile{
+		filename:     name,
+		name:         path.Base(name),
+		extension:    ExtractFileType(name),
+		data:         data,
+		fullPath:     abs,
+		lastModified: modTime,
+	}
+}
+
+// Open opens a file from the archive, returning it or an error. Names can be relative to the root, or absolute
+// paths beneath the directory the archive is mounted at. If the file is not found, the error is of type *PathError.
+func (a *ArchiveFS) Open(name string) (fs.File, error) {
+	if a.indexConfig != nil && !a.indexConfig.AllowFileLookup {
+		return nil, &fs.PathError{
+			Op: "open", Path: name,
+			Err: fmt.Errorf("file lookup for '%s' not allowed, set the index configuration "+
+				"to AllowFileLookup to be true", name),
+		}
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(a.baseDirectory, name)
+	}
+	if f, ok := a.files[filepath.Clean(name)]; ok {
+		return f, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name,
+		Err: fmt.Errorf("%w, no entry exists in archive '%s'", fs.ErrNotExist, a.archivePath)}
+}
+
+// ReadFile returns a copy of the content of a file in the archive, it implements fs.ReadFileFS.
+func (a *ArchiveFS) ReadFile(name string) ([]byte, error) {
+	f, err := a.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(f.(*LocalFile).data), nil
+}
+
+// GetFiles returns the files read from the archive. A map of RolodexFile objects keyed by the full path of the file.
+func (a *ArchiveFS) GetFiles() map[string]RolodexFile {
+	files := make(map[string]RolodexFile, len(a.files))
+	for k, v := range a.files {
+		files[k] = v
+	}
+	return files
+}
+
+// GetErrors returns any errors that occurred reading entries from the archive.
+func (a *ArchiveFS) GetErrors() []error {
+	return a.readingErrors
+}
+
+// GetFormat returns the format of the archive.
+func (a *ArchiveFS) GetFormat() ArchiveFormat {
+	return a.format
+}
+
+// GetBaseDirectory returns the directory files are resolved from (the mount directory joined with the root), this
+// is the base directory to use when adding the file system to a rolodex.
+func (a *ArchiveFS) GetBaseDirectory() string {
+	return a.baseDirectory
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var archiveTestFiles = map[string]string{
+	"api/openapi.yaml": `openapi: 3.1.0
+components:
+  schemas:
+    Pet:
+      $ref: 'schemas/pet.yaml'`,
+	"api/schemas/pet.yaml": `type: object
+properties:
+  owner:
+    $ref: '../common/owner.yaml'`,
+	"api/common/owner.yaml": `type: object
+properties:
+  name:
+    type: string`,
+	"api/README.md":       "# not a spec",
+	"api/.hidden.yaml":    "nope: true",
+	"../../etc/evil.yaml": "escape: true",
+}
+
+func sortedArchiveNames() []string {
+	var names []string
+	for k := range archiveTestFiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func buildTestZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range sortedArchiveNames() {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, _ = f.Write([]byte(archiveTestFiles[name]))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func buildTestTarGz(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	require.NoError(t, w.WriteHeader(&tar.Header{Name: "api/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, name := range sortedArchiveNames() {
+		content := archiveTestFiles[name]
+		require.NoError(t, w.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, _ = w.Write([]byte(content))
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestNewArchiveFSWithConfig_Zip(t *testing.T) {
+	data := buildTestZip(t)
+	afs, err := NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Reader:        bytes.NewReader(data),
+		Size:          int64(len(data)),
+		BaseDirectory: "/specs/pets.zip",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveZip, afs.GetFormat())
+	assert.Equal(t, "/specs/pets.zip", afs.GetBaseDirectory())
+
+	files := afs.GetFiles()
+	assert.Len(t, files, 3)
+	assert.Contains(t, files, "/specs/pets.zip/api/schemas/pet.yaml")
+
+	// the entry escaping the archive is reported
+	require.Len(t, afs.GetErrors(), 1)
+	assert.Equal(t, "archive '/specs/pets.zip', entry '../../etc/evil.yaml': "+
+		"entry escapes the archive and has been skipped", afs.GetErrors()[0].Error())
+
+	f, err := afs.Open("api/common/owner.yaml")
+	require.NoError(t, err)
+	stat, _ := f.Stat()
+	assert.Equal(t, "owner.yaml", stat.Name())
+
+	content, err := afs.ReadFile("/specs/pets.zip/api/openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, archiveTestFiles["api/openapi.yaml"], string(content))
+
+	_, err = afs.Open("api/nope.yaml")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Equal(t, "open /specs/pets.zip/api/nope.yaml: file does not exist, "+
+		"no entry exists in archive '/specs/pets.zip'", err.Error())
+}
+
+func TestNewArchiveFSWithConfig_TarGzPath(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "pets.tgz")
+	require.NoError(t, os.WriteFile(archive, buildTestTarGz(t), 0o644))
+
+	afs, err := NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Path:        archive,
+		Root:        "api/",
+		FileFilters: []string{"openapi.yaml", "schemas/pet.yaml"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveTarGzip, afs.GetFormat())
+	assert.Equal(t, "tar.gz", afs.GetFormat().String())
+
+	files := afs.GetFiles()
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, filepath.Join(archive, "api", "schemas", "pet.yaml"))
+	assert.NotContains(t, files, filepath.Join(archive, "api", "common", "owner.yaml"))
+	assert.Equal(t, filepath.Join(archive, "api"), afs.GetBaseDirectory())
+}
+
+func TestNewArchiveFSWithConfig_DetectFormat(t *testing.T) {
+	var tarBuf bytes.Buffer
+	w := tar.NewWriter(&tarBuf)
+	_ = w.WriteHeader(&tar.Header{Name: "a.yaml", Mode: 0o644, Size: 4})
+	_, _ = w.Write([]byte("a: b"))
+	_ = w.Close()
+
+	for _, tc := range []struct {
+		data   []byte
+		format ArchiveFormat
+	}{
+		{buildTestZip(t), ArchiveZip},
+		{buildTestTarGz(t), ArchiveTarGzip},
+		{tarBuf.Bytes(), ArchiveTar},
+	} {
+		afs, err := NewArchiveFSWithConfig(&ArchiveFSConfig{
+			Reader:        bytes.NewReader(tc.data),
+			Size:          int64(len(tc.data)),
+			BaseDirectory: "/specs/blob",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tc.format, afs.GetFormat())
+		assert.NotEmpty(t, afs.GetFiles())
+	}
+
+	_, err := NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Reader: bytes.NewReader([]byte("not an archive")), Size: 14, BaseDirectory: "/specs/blob",
+	})
+	assert.EqualError(t, err, "unable to read archive '/specs/blob': unsupported archive format, "+
+		"only zip, tar and tar.gz archives can be read")
+}
+
+func TestNewArchiveFSWithConfig_Errors(t *testing.T) {
+	_, err := NewArchiveFSWithConfig(nil)
+	assert.Error(t, err)
+	_, err = NewArchiveFSWithConfig(&ArchiveFSConfig{})
+	assert.EqualError(t, err, "an archive file system requires a Path or a Reader")
+	_, err = NewArchiveFSWithConfig(&ArchiveFSConfig{Reader: bytes.NewReader(nil)})
+	assert.EqualError(t, err, "an archive file system read from a Reader requires a BaseDirectory")
+	_, err = NewArchiveFSWithConfig(&ArchiveFSConfig{Path: "/no/such/archive.zip"})
+	assert.Error(t, err)
+	_, err = NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Reader: bytes.NewReader([]byte("PK\x03\x04")), Size: 4, BaseDirectory: "/specs/bad.zip",
+	})
+	assert.Error(t, err)
+
+	afs, _ := NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Reader:        bytes.NewReader(buildTestZip(t)),
+		Size:          int64(len(buildTestZip(t))),
+		BaseDirectory: "/specs/pets.zip",
+		IndexConfig:   &SpecIndexConfig{},
+	})
+	_, err = afs.Open("api/openapi.yaml")
+	assert.Error(t, err)
+}
+
+func TestNewArchiveFSWithConfig_SizeLimits(t *testing.T) {
+	zipData, tarData := buildTestZip(t), buildTestTarGz(t)
+	read := func(data []byte, maxEntry, maxTotal int64) (*ArchiveFS, error) {
+		return NewArchiveFSWithConfig(&ArchiveFSConfig{
+			Reader:        bytes.NewReader(data),
+			Size:          int64(len(data)),
+			BaseDirectory: "/specs/pets",
+			MaxEntrySize:  maxEntry,
+			MaxTotalSize:  maxTotal,
+		})
+	}
+
+	// entries are read in name order, api/common/owner.yaml (49 bytes) is the first.
+	for _, data := range [][]byte{zipData, tarData} {
+		_, err := read(data, 48, 0)
+		assert.EqualError(t, err, "unable to read archive '/specs/pets': entry 'api/common/owner.yaml' "+
+			"is larger than the maximum entry size of 48 bytes")
+
+		_, err = read(data, 0, 100)
+		assert.EqualError(t, err, "unable to read archive '/specs/pets': entry 'api/openapi.yaml' "+
+			"takes the archive over the maximum total size of 100 bytes")
+
+		afs, err := read(data, 77, -1)
+		require.NoError(t, err)
+		assert.Len(t, afs.GetFiles(), 3)
+	}
+}
+
+func TestRolodex_ArchiveFS(t *testing.T) {
+	data := buildTestZip(t)
+	base := "/specs/pets.zip/api"
+
+	cf := CreateOpenAPIIndexConfig()
+	cf.BasePath = base
+	cf.SpecFilePath = "openapi.yaml"
+
+	afs, err := NewArchiveFSWithConfig(&ArchiveFSConfig{
+		Reader:        bytes.NewReader(data),
+		Size:          int64(len(data)),
+		BaseDirectory: "/specs/pets.zip",
+		Root:          "api",
+		IndexConfig:   cf,
+	})
+	require.NoError(t, err)
+
+	root, err := afs.ReadFile("openapi.yaml")
+	require.NoError(t, err)
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal(root, &rootNode)
+
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	assert.Equal(t, base, afs.GetBaseDirectory())
+	rolo.AddLocalFS(base, afs)
+	require.NoError(t, rolo.IndexTheRolodex())
+
+	// relative references between entries resolve, and the origin of the nodes is the archive.
+	owner, err := rolo.Open("/specs/pets.zip/api/common/owner.yaml")
+	require.NoError(t, err)
+	node, _ := owner.GetContentAsYAMLNode()
+	origin := rolo.FindNodeOrigin(node.Content[0].Content[3])
+	require.NotNil(t, origin)
+	assert.Equal(t, "/specs/pets.zip/api/common/owner.yaml", origin.AbsoluteLocation)
+	assert.Len(t, rolo.GetIndexes(), 3)
+
+	_, err = rolo.Open("common/missing.yaml")
+	assert.ErrorContains(t, err, "no entry exists in archive '/specs/pets.zip'")
+}
@@ -0,0 +1,530 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RolodexFileChange describes a file in the rolodex that has changed, and needs to be re-indexed.
+type RolodexFileChange struct {
+	// Location is the absolute path of the file, relative paths are resolved against the BasePath of the rolodex.
+	// Remote documents are identified by their URL.
+	Location string
+
+	// Content is the new content of the file. If nil, the file is read again from the file system that owns it,
+	// and if it no longer exists, it is removed from the rolodex. Remote documents are always fetched again,
+	// content cannot be supplied for them.
+	Content []byte
+}
+
+// ReindexFiles re-parses and re-indexes the supplied changed files, along with every file that depends on them
+// (directly or through other files) and the root document. Everything else in the rolodex is left untouched.
+// Reference mappings, resolvers and circular reference results that depended on the changed files are rebuilt, so
+// the state of the rolodex is the same as if it had been indexed from scratch.
+//
+// The root document is re-indexed using the root node of the rolodex, unless new content is supplied for it.
+// The rolodex must have been indexed first, and must not have been resolved (resolving modifies the document tree).
+func (r *Rolodex) ReindexFiles(changes ...*RolodexFileChange) error {
+	return r.ReindexFilesWithContext(context.Background(), changes...)
+}
+
+// ReindexFilesWithContext is the same as ReindexFiles, except re-indexing stops as soon as the context is
+// cancelled or reaches its deadline, returning ctx.Err(). A cancelled re-index leaves the rolodex in an
+// incomplete state, it should be indexed again from scratch.
+func (r *Rolodex) ReindexFilesWithContext(ctx context.Context, changes ...*RolodexFileChange) error {
+	if !r.indexed {
+		return errors.New("the rolodex has not been indexed, cannot re-index files")
+	}
+	if r.resolved {
+		return errors.New("the rolodex has been resolved, resolved documents cannot be re-indexed")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	defer r.useLoadingContext(ctx)()
+	started := time.Now()
+
+	rootLocation := r.indexConfig.SpecAbsolutePath
+	if r.rootIndex != nil {
+		rootLocation = r.rootIndex.specAbsolutePath
+	}
+
+	// apply the changes to the files held by the file systems.
+	changed := make(map[string]bool)
+	for _, c := range changes {
+		if c == nil {
+			continue
+		}
+		location := r.changeLocation(c.Location)
+		changed[location] = true
+		if location == rootLocation && c.Content != nil {
+			var root yaml.Node
+			if err := yaml.Unmarshal(c.Content, &root); err != nil {
+				return fmt.Errorf("unable to parse root document '%s': %w", location, err)
+			}
+			r.rootNode = &root
+		}
+		if err := r.applyFileChange(location, c.Content, location == rootLocation); err != nil {
+			return err
+		}
+	}
+
+	// work out which indexes depend on the changed files, directly or through other files.
+	indexes := r.allFileIndexes()
+	dependencies := make(map[*SpecIndex][]string, len(indexes)+1)
+	dependents := make(map[string][]*SpecIndex)
+	for _, idx := range append(indexes, r.rootIndex) {
+		if idx == nil {
+			continue
+		}
+		dependencies[idx] = indexDependencies(idx)
+		for _, d := range dependencies[idx] {
+			dependents[d] = append(dependents[d], idx)
+		}
+	}
+	affected := make(map[*SpecIndex]bool)
+	queue := make([]string, 0, len(changed))
+	for location := range changed {
+		queue = append(queue, location)
+	}
+	for _, idx := range indexes {
+		if changed[idx.specAbsolutePath] {
+			affected[idx] = true
+		}
+	}
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		location := queue[0]
+		queue = queue[1:]
+		if seen[location] {
+			continue
+		}
+		seen[location] = true
+		for _, idx := range dependents[location] {
+			if !affected[idx] {
+				affected[idx] = true
+				queue = append(queue, idx.specAbsolutePath)
+			}
+		}
+	}
+	rebuildRoot := changed[rootLocation] || affected[r.rootIndex] || r.rootIndex == nil
+
+	// throw away the affected indexes, and reset the files they were built from.
+	var kept []*SpecIndex
+	rebuild := make(map[string]bool)
+	for _, idx := range indexes {
+		if affected[idx] {
+			r.removeIndex(idx)
+			if r.resetRolodexFile(idx.specAbsolutePath) {
+				rebuild[idx.specAbsolutePath] = true
+			}
+			continue
+		}
+		kept = append(kept, idx)
+	}
+	for location := range changed {
+		if location != rootLocation && r.resetRolodexFile(location) {
+			rebuild[location] = true
+		}
+		r.indexLock.Lock()
+		delete(r.indexingErrors, location)
+		r.indexLock.Unlock()
+	}
+
+	// lookups cached by the remaining indexes may point to nodes that no longer exist.
+	for _, idx := range kept {
+		idx.cache = new(sync.Map)
+	}
+	r.indexLock.Lock()
+	r.indexes = slices.DeleteFunc(r.indexes, func(idx *SpecIndex) bool { return affected[idx] })
+	r.indexLock.Unlock()
+
+	// rebuild the affected files, dependencies are indexed before the files that depend on them.
+	var rebuilt []*SpecIndex
+	checked := make(map[*SpecIndex]bool)
+	for _, location := range rebuildOrder(rebuild, dependencies, indexes) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		file, preloaded := r.findRolodexFile(location)
+		if file == nil {
+			continue
+		}
+		idx, err := r.indexRolodexFile(file, location)
+		if err != nil {
+			r.recordIndexingError(location, err)
+			continue
+		}
+		r.AddIndex(idx)
+		rebuilt = append(rebuilt, idx)
+
+		// indexing the rolodex only checks files that are read up front for circular references, files read
+		// on demand are checked as part of the root document.
+		checked[idx] = preloaded
+	}
+	for _, idx := range rebuilt {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		idx.BuildIndex()
+		if !r.indexConfig.AvoidCircularReferenceCheck && checked[idx] {
+			if _, err := idx.resolver.CheckForCircularReferencesWithContext(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if rebuildRoot && r.rootNode != nil {
+		index := NewSpecIndexWithConfig(r.rootNode, r.indexConfig)
+		resolver := NewResolver(index)
+		if r.indexConfig.IgnoreArrayCircularReferences {
+			resolver.IgnoreArrayCircularReferences()
+		}
+		if r.indexConfig.IgnorePolymorphicCircularReferences {
+			resolver.IgnorePolymorphicCircularReferences()
+		}
+		r.rootIndex = index
+		index.BuildIndex()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	// gather up the errors and circular references again, as a full indexing run would.
+	var caughtErrors []error
+	r.indexLock.Lock()
+	locations := make([]string, 0, len(r.indexingErrors))
+	for location := range r.indexingErrors {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	for _, location := range locations {
+		caughtErrors = append(caughtErrors, r.indexingErrors[location])
+	}
+	sort.Slice(r.indexes, func(i, j int) bool {
+		return r.indexes[i].specAbsolutePath < r.indexes[j].specAbsolutePath
+	})
+	r.indexLock.Unlock()
+
+	circChecked := r.circChecked
+	r.ignoredCircularReferences = nil
+	r.safeCircularReferences = nil
+	r.infiniteCircularReferences = nil
+	r.circChecked = false
+	if !r.indexConfig.AvoidCircularReferenceCheck {
+		for _, idx := range r.indexes {
+			if idx.resolver == nil {
+				continue
+			}
+			for _, e := range idx.resolver.GetResolvingErrors() {
+				caughtErrors = append(caughtErrors, e)
+			}
+			r.ignoredCircularReferences = append(r.ignoredCircularReferences, idx.resolver.GetIgnoredCircularPolyReferences()...)
+			r.ignoredCircularReferences = append(r.ignoredCircularReferences, idx.resolver.GetIgnoredCircularArrayReferences()...)
+		}
+	}
+	if r.rootIndex != nil && r.rootIndex.resolver != nil {
+
+		// the root is checked when indexing, or when the circular reference check had been run on the rolodex.
+		if !r.indexConfig.AvoidCircularReferenceCheck || circChecked {
+			resolver := r.rootIndex.resolver
+			resolvingErrors := resolver.GetResolvingErrors()
+			if rebuildRoot {
+				var err error
+				if resolvingErrors, err = resolver.CheckForCircularReferencesWithContext(ctx); err != nil {
+					return err
+				}
+			}
+			r.circChecked = true
+			for _, e := range resolvingErrors {
+				caughtErrors = append(caughtErrors, e)
+			}
+			r.ignoredCircularReferences = append(r.ignoredCircularReferences, resolver.GetIgnoredCircularPolyReferences()...)
+			r.ignoredCircularReferences = append(r.ignoredCircularReferences, resolver.GetIgnoredCircularArrayReferences()...)
+			if r.indexConfig.AvoidCircularReferenceCheck {
+				r.safeCircularReferences = append(r.safeCircularReferences, resolver.GetSafeCircularReferences()...)
+				r.infiniteCircularReferences = append(r.infiniteCircularReferences, resolver.GetInfiniteCircularReferences()...)
+			}
+		}
+		caughtErrors = append(caughtErrors, r.rootIndex.refErrors...)
+	}
+	caughtErrors = append(caughtErrors, r.remotePolicyErrors()...)
+	r.caughtErrors = caughtErrors
+
+	r.reindexedFiles = r.reindexedFiles[:0]
+	for _, idx := range rebuilt {
+		r.reindexedFiles = append(r.reindexedFiles, idx.specAbsolutePath)
+	}
+	if rebuildRoot && r.rootIndex != nil {
+		r.reindexedFiles = append(r.reindexedFiles, r.rootIndex.specAbsolutePath)
+	}
+	r.indexingDuration = time.Since(started)
+	return errors.Join(r.caughtErrors...)
+}
+
+// GetReindexedFiles returns the locations of the files that were re-indexed by the last call to ReindexFiles.
+func (r *Rolodex) GetReindexedFiles() []string {
+	return r.reindexedFiles
+}
+
+// changeLocation turns the location of a changed file into the location used by the rolodex.
+func (r *Rolodex) changeLocation(location string) string {
+	if strings.HasPrefix(location, "http") || filepath.IsAbs(location) {
+		return location
+	}
+	abs, _ := filepath.Abs(filepath.Join(r.indexConfig.BasePath, location))
+	return abs
+}
+
+// recordIndexingError records an error that occurred when indexing a file, so it can be reported again after
+// files have been re-indexed.
+func (r *Rolodex) recordIndexingError(location string, err error) {
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+	if r.indexingErrors == nil {
+		r.indexingErrors = make(map[string]error)
+	}
+	r.indexingErrors[location] = err
+}
+
+// allFileIndexes returns the index of every file in the rolodex (not including the root index).
+func (r *Rolodex) allFileIndexes() []*SpecIndex {
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+	seen := make(map[*SpecIndex]bool)
+	var indexes []*SpecIndex
+	for _, idx := range r.indexes {
+		if idx != nil && !seen[idx] {
+			seen[idx] = true
+			indexes = append(indexes, idx)
+		}
+	}
+	for _, idx := range r.indexMap {
+		if idx != nil && !seen[idx] {
+			seen[idx] = true
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].specAbsolutePath < indexes[j].specAbsolutePath
+	})
+	return indexes
+}
+
+func (r *Rolodex) removeIndex(idx *SpecIndex) {
+	r.indexLock.Lock()
+	defer r.indexLock.Unlock()
+	if r.indexMap[idx.specAbsolutePath] == idx {
+		delete(r.indexMap, idx.specAbsolutePath)
+	}
+}
+
+// indexDependencies returns the locations of every other file the index references.
+func indexDependencies(idx *SpecIndex) []string {
+	found := make(map[string]bool)
+	add := func(definition string) {
+		location, _, _ := strings.Cut(definition, "#")
+		if location != "" && location != idx.specAbsolutePath {
+			found[location] = true
+		}
+	}
+	for _, ref := range idx.rawSequencedRefs {
+		add(ref.FullDefinition)
+	}
+	for _, ref := range idx.polymorphicRefs {
+		add(ref.FullDefinition)
+	}
+	for _, ref := range idx.allMappedRefs {
+		add(ref.FullDefinition)
+		if ref.Index != nil {
+			add(ref.Index.specAbsolutePath)
+		}
+	}
+	locations := make([]string, 0, len(found))
+	for location := range found {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+	return locations
+}
+
+// rebuildOrder sorts the locations to rebuild, so files are rebuilt after the files they depend on.
+func rebuildOrder(rebuild map[string]bool, dependencies map[*SpecIndex][]string, indexes []*SpecIndex) []string {
+	deps := make(map[string][]string)
+	for _, idx := range indexes {
+		deps[idx.specAbsolutePath] = dependencies[idx]
+	}
+	locations := make([]string, 0, len(rebuild))
+	for location := range rebuild {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(location string)
+	visit = func(location string) {
+		if visited[location] {
+			return
+		}
+		visited[location] = true
+		for _, d := range deps[location] {
+			if rebuild[d] {
+				visit(d)
+			}
+		}
+		order = append(order, location)
+	}
+	for _, location := range locations {
+		visit(location)
+	}
+	return order
+}
+
+// indexRolodexFile indexes a file in the same way indexing the rolodex does.
+func (r *Rolodex) indexRolodexFile(file CanBeIndexed, fullPath string) (*SpecIndex, error) {
+	copiedConfig := *r.indexConfig
+	copiedConfig.SpecAbsolutePath = fullPath
+	copiedConfig.AvoidBuildIndex = true
+	idx, err := file.Index(&copiedConfig)
+	if err != nil {
+		return nil, err
+	}
+	resolver := NewResolver(idx)
+	if copiedConfig.IgnoreArrayCircularReferences {
+		resolver.IgnoreArrayCircularReferences()
+	}
+	if copiedConfig.IgnorePolymorphicCircularReferences {
+		resolver.IgnorePolymorphicCircularReferences()
+	}
+	idx.resolver = resolver
+	return idx, nil
+}
+
+// findRolodexFile returns a local file that has already been read into one of the local file systems, and
+// whether the file system reads all of its files up front (rather than on demand, as they are referenced).
+func (r *Rolodex) findRolodexFile(location string) (*LocalFile, bool) {
+	for _, v := range r.localFS {
+		switch lfs := v.(type) {
+		case *LocalFS:
+			if f, ok := lfs.Files.Load(location); ok {
+				return f.(*LocalFile), lfs.fsConfig != nil && lfs.fsConfig.DirFS != nil
+			}
+		case *ArchiveFS:
+			if f, ok := lfs.files[location]; ok {
+				return f, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// resetRolodexFile throws away the index of a file so it can be rebuilt, remote files are evicted, so they are
+// fetched again when they are next referenced. Returns true if the file needs to be rebuilt.
+func (r *Rolodex) resetRolodexFile(location string) bool {
+	if f, _ := r.findRolodexFile(location); f != nil {
+		f.index = nil
+		f.parsed = nil
+		f.offset = 0
+		return true
+	}
+	for _, v := range r.remoteFS {
+		if rfs, ok := v.(*RemoteFS); ok {
+			rfs.Files.Range(func(key, value any) bool {
+				if value.(*RemoteFile).fullPath == location {
+					rfs.Files.Delete(key)
+				}
+				return true
+			})
+		}
+	}
+	return false
+}
+
+// applyFileChange updates the content of a changed file held by one of the rolodex file systems.
+func (r *Rolodex) applyFileChange(location string, content []byte, root bool) error {
+	if strings.HasPrefix(location, "http") {
+		if content != nil {
+			return fmt.Errorf("cannot re-index remote document '%s' with new content, remote documents are "+
+				"fetched again when they change", location)
+		}
+		return nil
+	}
+	for base, v := range r.localFS {
+		switch lfs := v.(type) {
+		case *LocalFS:
+			existing, exists := lfs.Files.Load(location)
+			if content == nil {
+				if !exists {
+					continue
+				}
+				data, err := lfs.readFile(location)
+				if errors.Is(err, fs.ErrNotExist) {
+					lfs.Files.Delete(location)
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("unable to re-read '%s': %w", location, err)
+				}
+				content = data
+			}
+			if exists {
+				f := existing.(*LocalFile)
+				f.data = content
+				f.lastModified = time.Now()
+				return nil
+			}
+			if !root && strings.HasPrefix(location, base+string(filepath.Separator)) {
+				lfs.Files.Store(location, &LocalFile{
+					filename:     filepath.Base(location),
+					name:         filepath.Base(location),
+					extension:    ExtractFileType(location),
+					data:         content,
+					fullPath:     location,
+					lastModified: time.Now(),
+				})
+				return nil
+			}
+		case *ArchiveFS:
+			if f, ok := lfs.files[location]; ok {
+				if content == nil {
+					return fmt.Errorf("cannot re-read '%s', content must be supplied for files held in an archive",
+						location)
+				}
+				f.data = content
+				f.lastModified = time.Now()
+				return nil
+			}
+		}
+	}
+	if content != nil && !root {
+		return fmt.Errorf("cannot re-index '%s', it is not part of any local file system in the rolodex", location)
+	}
+	return nil
+}
+
+// readFile reads the current content of a file from the underlying file system.
+func (l *LocalFS) readFile(location string) ([]byte, error) {
+	if l.fsConfig != nil && l.fsConfig.DirFS != nil {
+		rel, err := filepath.Rel(l.baseDirectory, location)
+		if err != nil {
+			return nil, err
+		}
+		return fs.ReadFile(l.fsConfig.DirFS, filepath.ToSlash(rel))
+	}
+	return os.ReadFile(location)
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const reindexRoot = `openapi: 3.1.0
+components:
+  schemas:
+    A:
+      $ref: 'a.yaml#/components/schemas/A'
+    B:
+      $ref: 'b.yaml#/components/schemas/B'`
+
+var reindexFiles = map[string]string{
+	"a.yaml": `components:
+  schemas:
+    A:
+      type: object
+      properties:
+        c:
+          $ref: 'c.yaml#/components/schemas/C'`,
+	"b.yaml": `components:
+  schemas:
+    B:
+      type: string`,
+	"c.yaml": `components:
+  schemas:
+    C:
+      type: string`,
+}
+
+func writeReindexFiles(t *testing.T) string {
+	dir := t.TempDir()
+	for name, content := range reindexFiles {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func buildReindexRolodex(t *testing.T, dir string, dirFS bool) *Rolodex {
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(reindexRoot), &rootNode)
+
+	cf := CreateOpenAPIIndexConfig()
+	cf.BasePath = dir
+	cf.SpecFilePath = "root.yaml"
+
+	fsCfg := &LocalFSConfig{BaseDirectory: dir, IndexConfig: cf}
+	if dirFS {
+		fsCfg.DirFS = os.DirFS(dir)
+	}
+	fileFS, err := NewLocalFSWithConfig(fsCfg)
+	require.NoError(t, err)
+
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	rolo.AddLocalFS(dir, fileFS)
+	_ = rolo.IndexTheRolodex()
+	return rolo
+}
+
+func findIndexByPath(r *Rolodex, location string) *SpecIndex {
+	for _, idx := range r.GetIndexes() {
+		if idx.GetSpecAbsolutePath() == location {
+			return idx
+		}
+	}
+	return nil
+}
+
+func countCircularReferences(r *Rolodex) int {
+	count := len(r.GetRootIndex().GetCircularReferences())
+	for _, idx := range r.GetIndexes() {
+		count += len(idx.GetCircularReferences())
+	}
+	return count
+}
+
+func mappedReferenceKeys(r *Rolodex) []string {
+	var keys []string
+	for k := range r.GetRootIndex().GetMappedReferences() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestRolodex_ReindexFiles(t *testing.T) {
+	for _, dirFS := range []bool{false, true} {
+		dir := writeReindexFiles(t)
+		rolo := buildReindexRolodex(t, dir, dirFS)
+		require.Empty(t, rolo.GetCaughtErrors())
+		oldB := findIndexByPath(rolo, filepath.Join(dir, "b.yaml"))
+		require.NotNil(t, oldB)
+
+		// c.yaml now references itself.
+		circular := `components:
+  schemas:
+    C:
+      type: object
+      properties:
+        next:
+          $ref: '#/components/schemas/C'
+        b:
+          $ref: 'b.yaml#/components/schemas/B'`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "c.yaml"), []byte(circular), 0o644))
+
+		_ = rolo.ReindexFiles(&RolodexFileChange{Location: "c.yaml"})
+
+		// only c.yaml, the file that depends on it, and the root are rebuilt.
+		assert.ElementsMatch(t, []string{
+			filepath.Join(dir, "c.yaml"), filepath.Join(dir, "a.yaml"), filepath.Join(dir, "root.yaml"),
+		}, rolo.GetReindexedFiles())
+		assert.Same(t, oldB, findIndexByPath(rolo, filepath.Join(dir, "b.yaml")))
+
+		c, err := rolo.Open(filepath.Join(dir, "c.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, circular, c.GetContent())
+
+		// the result must be the same as indexing everything again.
+		full := buildReindexRolodex(t, dir, dirFS)
+		assert.Equal(t, mappedReferenceKeys(full), mappedReferenceKeys(rolo))
+		assert.Len(t, rolo.GetCaughtErrors(), len(full.GetCaughtErrors()))
+		assert.Len(t, rolo.GetIndexes(), len(full.GetIndexes()))
+		assert.Equal(t, countCircularReferences(full), countCircularReferences(rolo))
+		assert.NotZero(t, countCircularReferences(rolo))
+	}
+}
+
+func TestRolodex_ReindexFiles_Content(t *testing.T) {
+	dir := writeReindexFiles(t)
+	rolo := buildReindexRolodex(t, dir, true)
+
+	// new content for a file that does not exist on disk, and for the root.
+	require.NoError(t, rolo.ReindexFiles(
+		&RolodexFileChange{Location: filepath.Join(dir, "d.yaml"), Content: []byte("components:\n  schemas:\n    D:\n      type: integer")},
+		&RolodexFileChange{Location: filepath.Join(dir, "root.yaml"), Content: []byte(reindexRoot +
+			"\n    D:\n      $ref: 'd.yaml#/components/schemas/D'")},
+	))
+	assert.Contains(t, mappedReferenceKeys(rolo), filepath.Join(dir, "d.yaml")+"#/components/schemas/D")
+	assert.Contains(t, rolo.GetReindexedFiles(), filepath.Join(dir, "d.yaml"))
+	assert.Contains(t, rolo.GetLocalFiles(), filepath.Join(dir, "d.yaml"))
+
+	// a file that has been removed breaks the reference to it.
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.yaml")))
+	err := rolo.ReindexFiles(&RolodexFileChange{Location: filepath.Join(dir, "b.yaml")})
+	assert.Error(t, err)
+	assert.NotContains(t, mappedReferenceKeys(rolo), filepath.Join(dir, "b.yaml")+"#/components/schemas/B")
+
+	// and bringing it back fixes it.
+	err = rolo.ReindexFiles(&RolodexFileChange{
+		Location: filepath.Join(dir, "b.yaml"), Content: []byte(reindexFiles["b.yaml"]),
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, mappedReferenceKeys(rolo), filepath.Join(dir, "b.yaml")+"#/components/schemas/B")
+}
+
+func TestRolodex_ReindexFiles_Errors(t *testing.T) {
+	dir := writeReindexFiles(t)
+
+	rolo := NewRolodex(CreateOpenAPIIndexConfig())
+	assert.EqualError(t, rolo.ReindexFiles(), "the rolodex has not been indexed, cannot re-index files")
+
+	rolo = buildReindexRolodex(t, dir, false)
+	assert.EqualError(t, rolo.ReindexFiles(&RolodexFileChange{
+		Location: "https://pb33f.io/spec.yaml", Content: []byte("a: b"),
+	}), "cannot re-index remote document 'https://pb33f.io/spec.yaml' with new content, remote documents are "+
+		"fetched again when they change")
+	assert.EqualError(t, rolo.ReindexFiles(&RolodexFileChange{
+		Location: "/somewhere/else.yaml", Content: []byte("a: b"),
+	}), "cannot re-index '/somewhere/else.yaml', it is not part of any local file system in the rolodex")
+	assert.ErrorContains(t, rolo.ReindexFiles(&RolodexFileChange{
+		Location: filepath.Join(dir, "root.yaml"), Content: []byte("a: [b"),
+	}), "unable to parse root document")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, rolo.ReindexFilesWithContext(ctx), context.Canceled)
+
+	rolo.Resolve()
+	assert.EqualError(t, rolo.ReindexFiles(), "the rolodex has been resolved, resolved documents cannot be re-indexed")
+}
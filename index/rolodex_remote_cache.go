@@ -0,0 +1,191 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteCacheExtension is the file extension used for every entry written to the cache directory.
+const remoteCacheExtension = ".rcache"
+
+// ErrRemoteCacheMiss is returned (wrapped) by the RemoteFS when the cache is in offline mode and the requested
+// remote document has never been cached.
+var ErrRemoteCacheMiss = errors.New("remote document is not cached")
+
+// RemoteCacheConfig is used to configure a RemoteCache.
+type RemoteCacheConfig struct {
+	// Directory is where cached remote documents are persisted. It will be created if it does not exist.
+	Directory string
+
+	// TTL is how long a cached document is considered fresh. Fresh documents are served straight from the cache
+	// without touching the network. Once a document is stale, it is revalidated with a conditional request
+	// (using the ETag and Last-Modified values of the cached copy). A TTL of zero means every document is
+	// revalidated each time it's opened.
+	TTL time.Duration
+
+	// Offline will serve documents only from the cache, the network is never used. Any document that has not been
+	// cached will fail to open with an error wrapping ErrRemoteCacheMiss.
+	Offline bool
+}
+
+// RemoteCacheEntry is a single remote document held by the RemoteCache.
+type RemoteCacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Content      []byte    `json:"content"`
+}
+
+// RemoteCache is an on-disk cache of remote documents, keyed by URL. It's used by the RemoteFS to avoid
+// re-downloading every remote reference each time a process starts.
+type RemoteCache struct {
+	directory string
+	ttl       time.Duration
+	offline   bool
+	lock      sync.Mutex
+	now       func() time.Time
+}
+
+// NewRemoteCache creates a new RemoteCache using the supplied configuration. The cache directory will be created
+// if it does not already exist.
+func NewRemoteCache(config *RemoteCacheConfig) (*RemoteCache, error) {
+	if config == nil || config.Directory == "" {
+		return nil, errors.New("no remote cache directory provided")
+	}
+	if err := os.MkdirAll(config.Directory, 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create remote cache directory '%s': %w", config.Directory, err)
+	}
+	return &RemoteCache{
+		directory: config.Directory,
+		ttl:       config.TTL,
+		offline:   config.Offline,
+		now:       time.Now,
+	}, nil
+}
+
+// GetDirectory returns the directory the cache is persisted to.
+func (c *RemoteCache) GetDirectory() string {
+	return c.directory
+}
+
+// GetTTL returns how long a cached document is considered fresh.
+func (c *RemoteCache) GetTTL() time.Duration {
+	return c.ttl
+}
+
+// IsOffline returns true if the cache is only serving documents from disk.
+func (c *RemoteCache) IsOffline() bool {
+	return c.offline
+}
+
+// IsFresh returns true if the entry is younger than the TTL of the cache, and can be served without revalidation.
+func (c *RemoteCache) IsFresh(entry *RemoteCacheEntry) bool {
+	if entry == nil || c.ttl <= 0 {
+		return false
+	}
+	return c.now().Sub(entry.FetchedAt) < c.ttl
+}
+
+// Get returns the cached entry for a URL. If the URL has not been cached, nil is returned without an error.
+func (c *RemoteCache) Get(remoteURL string) (*RemoteCacheEntry, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data, err := os.ReadFile(c.entryPath(remoteURL))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entry RemoteCacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("remote cache entry for '%s' is corrupt: %w", remoteURL, err)
+	}
+	return &entry, nil
+}
+
+// Put writes an entry to the cache, replacing anything already cached for the same URL. If the entry has no
+// FetchedAt time, the current time is used.
+func (c *RemoteCache) Put(entry *RemoteCacheEntry) error {
+	if entry == nil || entry.URL == "" {
+		return errors.New("cannot cache an entry without a URL")
+	}
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = c.now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// write to a temporary file first, so a crash never leaves a half written entry behind.
+	tmp, err := os.CreateTemp(c.directory, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), c.entryPath(entry.URL)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Delete removes the cached entry for a URL, if there is one.
+func (c *RemoteCache) Delete(remoteURL string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	err := os.Remove(c.entryPath(remoteURL))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Clear removes every entry from the cache. The cache directory itself is left in place.
+func (c *RemoteCache) Clear() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entries, err := os.ReadDir(c.directory)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), remoteCacheExtension) {
+			continue
+		}
+		if rErr := os.Remove(filepath.Join(c.directory, e.Name())); rErr != nil {
+			errs = append(errs, rErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *RemoteCache) entryPath(remoteURL string) string {
+	sum := sha256.Sum256([]byte(remoteURL))
+	return filepath.Join(c.directory, hex.EncodeToString(sum[:])+remoteCacheExtension)
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cachedPet = `components:
+  schemas:
+    Pet:
+      type: object`
+
+type cacheTestServer struct {
+	*httptest.Server
+	fetches     atomic.Int32
+	notModified atomic.Int32
+}
+
+func buildCacheTestServer() *cacheTestServer {
+	s := &cacheTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/pet.yaml" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Header.Get("If-None-Match") == `"v1"` &&
+			req.Header.Get("If-Modified-Since") == "Wed, 21 Oct 2015 07:28:00 GMT" {
+			s.notModified.Add(1)
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.fetches.Add(1)
+		rw.Header().Set("ETag", `"v1"`)
+		rw.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		_, _ = rw.Write([]byte(cachedPet))
+	}))
+	return s
+}
+
+func newCachedRemoteFS(t *testing.T, dir string, ttl time.Duration, offline bool) *RemoteFS {
+	cache, err := NewRemoteCache(&RemoteCacheConfig{Directory: dir, TTL: ttl, Offline: offline})
+	require.NoError(t, err)
+	cfg := CreateOpenAPIIndexConfig()
+	cfg.RemoteCache = cache
+	rfs, err := NewRemoteFSWithConfig(cfg)
+	require.NoError(t, err)
+	return rfs
+}
+
+func TestRemoteCache_PersistsAcrossFileSystems(t *testing.T) {
+	server := buildCacheTestServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	rfs := newCachedRemoteFS(t, dir, time.Hour, false)
+	f, err := rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	b, _ := io.ReadAll(f)
+	assert.Equal(t, cachedPet, string(b))
+	assert.Equal(t, int32(1), server.fetches.Load())
+	assert.Equal(t, 2015, f.(*RemoteFile).GetLastModified().Year())
+
+	entry, err := rfs.GetCache().Get(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, entry.ETag)
+	assert.Equal(t, "Wed, 21 Oct 2015 07:28:00 GMT", entry.LastModified)
+
+	// a brand-new file system (a new process) serves the fresh copy from disk.
+	rfs = newCachedRemoteFS(t, dir, time.Hour, false)
+	f, err = rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	b, _ = io.ReadAll(f)
+	assert.Equal(t, cachedPet, string(b))
+	assert.Equal(t, int32(1), server.fetches.Load())
+	assert.Equal(t, int32(0), server.notModified.Load())
+}
+
+func TestRemoteCache_Revalidate(t *testing.T) {
+	server := buildCacheTestServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	rfs := newCachedRemoteFS(t, dir, 0, false)
+	_, err := rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+
+	rfs = newCachedRemoteFS(t, dir, 0, false)
+	f, err := rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	b, _ := io.ReadAll(f)
+	assert.Equal(t, cachedPet, string(b))
+	assert.Equal(t, int32(1), server.fetches.Load())
+	assert.Equal(t, int32(1), server.notModified.Load())
+}
+
+func TestRemoteCache_ExpiredTTL(t *testing.T) {
+	server := buildCacheTestServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	rfs := newCachedRemoteFS(t, dir, time.Minute, false)
+	_, err := rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+
+	rfs = newCachedRemoteFS(t, dir, time.Minute, false)
+	rfs.GetCache().now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, err = rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), server.notModified.Load())
+}
+
+func TestRemoteCache_Offline(t *testing.T) {
+	server := buildCacheTestServer()
+	dir := t.TempDir()
+	petURL := server.URL + "/pet.yaml"
+
+	rfs := newCachedRemoteFS(t, dir, 0, false)
+	_, err := rfs.Open(petURL)
+	require.NoError(t, err)
+	server.Close()
+
+	// the server has gone, but the document is cached.
+	rfs = newCachedRemoteFS(t, dir, 0, true)
+	assert.True(t, rfs.GetCache().IsOffline())
+	f, err := rfs.Open(petURL)
+	require.NoError(t, err)
+	b, _ := io.ReadAll(f)
+	assert.Equal(t, cachedPet, string(b))
+
+	// misses fail clearly.
+	_, err = rfs.Open(server.URL + "/missing.yaml")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRemoteCacheMiss))
+	assert.Contains(t, err.Error(), "offline mode")
+	assert.Contains(t, err.Error(), "/missing.yaml")
+}
+
+func TestRemoteCache_NotFound(t *testing.T) {
+	server := buildCacheTestServer()
+	defer server.Close()
+
+	rfs := newCachedRemoteFS(t, t.TempDir(), 0, false)
+	_, err := rfs.Open(server.URL + "/nope.yaml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error 404")
+
+	entry, err := rfs.GetCache().Get(server.URL + "/nope.yaml")
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestRemoteCache_CustomHandler(t *testing.T) {
+	server := buildCacheTestServer()
+	defer server.Close()
+	dir := t.TempDir()
+
+	var calls int
+	rfs := newCachedRemoteFS(t, dir, 0, false)
+	rfs.SetRemoteHandlerFunc(func(url string) (*http.Response, error) {
+		calls++
+		return http.Get(url)
+	})
+	assert.Nil(t, rfs.RemoteRequestHandlerFunc)
+
+	_, err := rfs.Open(server.URL + "/pet.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	entry, _ := rfs.GetCache().Get(server.URL + "/pet.yaml")
+	assert.NotNil(t, entry)
+}
+
+func TestRemoteCache_Management(t *testing.T) {
+	_, err := NewRemoteCache(nil)
+	assert.Error(t, err)
+	_, err = NewRemoteCache(&RemoteCacheConfig{})
+	assert.Error(t, err)
+
+	dir := filepath.Join(t.TempDir(), "nested", "cache")
+	cache, err := NewRemoteCache(&RemoteCacheConfig{Directory: dir, TTL: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, dir, cache.GetDirectory())
+
+	assert.Error(t, cache.Put(&RemoteCacheEntry{}))
+	require.NoError(t, cache.Put(&RemoteCacheEntry{URL: "https://pb33f.io/a.yaml", Content: []byte("a")}))
+	require.NoError(t, cache.Put(&RemoteCacheEntry{URL: "https://pb33f.io/b.yaml", Content: []byte("b")}))
+
+	entry, err := cache.Get("https://pb33f.io/a.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(entry.Content))
+	assert.True(t, cache.IsFresh(entry))
+	assert.False(t, cache.IsFresh(nil))
+
+	require.NoError(t, cache.Delete("https://pb33f.io/a.yaml"))
+	require.NoError(t, cache.Delete("https://pb33f.io/a.yaml"))
+	entry, _ = cache.Get("https://pb33f.io/a.yaml")
+	assert.Nil(t, entry)
+
+	// unrelated files are left alone.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keep.txt"), []byte("x"), 0o644))
+	require.NoError(t, cache.Clear())
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 1)
+
+	// corrupt entries are reported.
+	require.NoError(t, os.WriteFile(cache.entryPath("https://pb33f.io/c.yaml"), []byte("{"), 0o644))
+	_, err = cache.Get("https://pb33f.io/c.yaml")
+	assert.Error(t, err)
+}
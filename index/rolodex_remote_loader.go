@@ -4,6 +4,7 @@
 package index
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/pb33f/libopenapi/utils"
 	"gopkg.in/yaml.v3"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -34,20 +37,33 @@ type FileExtension int
 // RemoteFS is a file system that indexes remote files. It implements the fs.FS interface. Files are located remotely
 // and served via HTTP.
 type RemoteFS struct {
-	indexConfig       *SpecIndexConfig
-	rootURL           string
-	rootURLParsed     *url.URL
+	indexConfig   *SpecIndexConfig
+	rootURL       string
+	rootURLParsed *url.URL
+	// RemoteHandlerFunc fetches remote documents.
 	RemoteHandlerFunc utils.RemoteURLHandler
-	Files             sync.Map
-	ProcessingFiles   sync.Map
-	FetchTime         int64
-	FetchChannel      chan *RemoteFile
-	remoteErrors      []error
-	logger            *slog.Logger
-	extractedFiles    map[string]RolodexFile
-	rolodex           *Rolodex
+
+	// RemoteRequestHandlerFunc, if set, is used instead of RemoteHandlerFunc, it allows requests to carry a context
+	// (so they can be cancelled) and conditional headers when revalidating cached documents. When it's not set, and
+	// RemoteHandlerFunc has not been replaced, the default http client sends requests this way.
+	RemoteRequestHandlerFunc RemoteRequestHandler
+	Files                    sync.Map
+	ProcessingFiles          sync.Map
+	FetchTime                int64
+	FetchChannel             chan *RemoteFile
+	remoteErrors             []error
+	logger                   *slog.Logger
+	extractedFiles           map[string]RolodexFile
+	rolodex                  *Rolodex
+	cache                    *RemoteCache
+	totalBytes               atomic.Int64
+	defaultHandler           utils.RemoteURLHandler // the RemoteHandlerFunc of the default http client.
+	defaultRequestHandler    RemoteRequestHandler   // sends requests using the default http client.
 }
 
+// RemoteRequestHandler is a function that sends a fully formed request and returns the response.
+type RemoteRequestHandler func(req *http.Request) (*http.Response, error)
+
 // RemoteFile is a file that has been indexed by the RemoteFS. It implements the RolodexFile interface.
 type RemoteFile struct {
 	filename      string
@@ -215,20 +231,29 @@ func NewRemoteFSWithConfig(specIndexConfig *SpecIndexConfig) (*RemoteFS, error)
 		logger:        log,
 		rootURLParsed: remoteRootURL,
 		FetchChannel:  make(chan *RemoteFile),
+		cache:         specIndexConfig.RemoteCache,
 	}
 	if remoteRootURL != nil {
 		rfs.rootURL = remoteRootURL.String()
 	}
 	if specIndexConfig.RemoteURLHandler != nil {
+		if specIndexConfig.RemotePolicy != nil {
+			return nil, datamodel.ErrRemotePolicyCustomHandler
+		}
 		rfs.RemoteHandlerFunc = specIndexConfig.RemoteURLHandler
 	} else {
 		// default http client
 		client := &http.Client{
 			Timeout: time.Second * 120,
 		}
-		rfs.RemoteHandlerFunc = func(url string) (*http.Response, error) {
+		if specIndexConfig.RemotePolicy != nil {
+			client = newPolicyHTTPClient(specIndexConfig.RemotePolicy)
+		}
+		rfs.defaultHandler = func(url string) (*http.Response, error) {
 			return client.Get(url)
 		}
+		rfs.RemoteHandlerFunc = rfs.defaultHandler
+		rfs.defaultRequestHandler = client.Do
 	}
 	return rfs, nil
 }
@@ -244,9 +269,35 @@ func NewRemoteFSWithRootURL(rootURL string) (*RemoteFS, error) {
 	return NewRemoteFSWithConfig(config)
 }
 
-// SetRemoteHandlerFunc sets the remote handler function.
+// SetRemoteHandlerFunc sets the remote handler function. Any RemoteRequestHandlerFunc is cleared, so the
+// custom handler is always used. A custom handler can not enforce a remote policy, so if the index configuration
+// has a RemotePolicy, every remote fetch fails with datamodel.ErrRemotePolicyCustomHandler.
 func (i *RemoteFS) SetRemoteHandlerFunc(handlerFunc utils.RemoteURLHandler) {
 	i.RemoteHandlerFunc = handlerFunc
+	i.RemoteRequestHandlerFunc = nil
+}
+
+// requestHandler returns the handler used to send requests carrying a context and headers, which is nil when the
+// RemoteHandlerFunc has been replaced (and no RemoteRequestHandlerFunc has been set).
+func (i *RemoteFS) requestHandler() RemoteRequestHandler {
+	if i.RemoteRequestHandlerFunc != nil {
+		return i.RemoteRequestHandlerFunc
+	}
+	if i.defaultHandler != nil && i.RemoteHandlerFunc != nil &&
+		reflect.ValueOf(i.RemoteHandlerFunc).Pointer() == reflect.ValueOf(i.defaultHandler).Pointer() {
+		return i.defaultRequestHandler
+	}
+	return nil
+}
+
+// SetCache sets the on-disk cache used for remote documents, nil disables caching.
+func (i *RemoteFS) SetCache(cache *RemoteCache) {
+	i.cache = cache
+}
+
+// GetCache returns the on-disk cache used for remote documents, if one has been set.
+func (i *RemoteFS) GetCache() *RemoteCache {
+	return i.cache
 }
 
 // SetIndexConfig sets the index configuration.
@@ -272,13 +323,47 @@ func (i *RemoteFS) GetErrors() []error {
 
 type waiterRemote struct {
 	f         string
-	done      bool
+	done      chan struct{}
+	once      sync.Once
 	file      *RemoteFile
-	listeners int
+	listeners atomic.Int32
+}
+
+func newWaiterRemote(f string) *waiterRemote {
+	return &waiterRemote{f: f, done: make(chan struct{})}
+}
+
+// finish releases anyone waiting on the fetch, it's safe to call more than once.
+func (w *waiterRemote) finish() {
+	w.once.Do(func() { close(w.done) })
 }
 
 // Open opens a file, returning it or an error. If the file is not found, the error is of type *PathError.
+//
+// If the RemoteFS belongs to a rolodex that is being indexed with a context, that context is used for the fetch.
 func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
+	return i.openAtDepth(i.context(), remoteURL, 1)
+}
+
+// OpenWithContext is the same as Open, except the fetch is abandoned as soon as the context is cancelled or
+// reaches its deadline, returning ctx.Err().
+func (i *RemoteFS) OpenWithContext(ctx context.Context, remoteURL string) (fs.File, error) {
+	return i.openAtDepth(ctx, remoteURL, 1)
+}
+
+// context returns the context of the rolodex currently indexing, or a background context.
+func (i *RemoteFS) context() context.Context {
+	if i.rolodex != nil {
+		return i.rolodex.loadingContext()
+	}
+	return context.Background()
+}
+
+// openAtDepth opens a file that is the supplied number of remote references away from the root document.
+func (i *RemoteFS) openAtDepth(ctx context.Context, remoteURL string, depth int) (fs.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if i.indexConfig != nil && !i.indexConfig.AllowRemoteLookup {
 		return nil, fmt.Errorf("remote lookup for '%s' is not allowed, please set "+
 			"AllowRemoteLookup to true as part of the index configuration", remoteURL)
@@ -307,19 +392,23 @@ func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
 	if r, ok := i.ProcessingFiles.Load(remoteParsedURL.Path); ok {
 
 		wait := r.(*waiterRemote)
-		wait.listeners++
+		wait.listeners.Add(1)
+		defer wait.listeners.Add(-1)
 
 		i.logger.Debug("[rolodex remote loader] waiting for existing fetch to complete", "file", remoteURL,
 			"remoteURL", remoteParsedURL.String())
 
-		for !wait.done {
-			i.logger.Debug("[rolodex remote loader] sleeping, waiting for file to return", "file", remoteURL)
-			time.Sleep(500 * time.Nanosecond) // breathe for a few nanoseconds.
+		select {
+		case <-wait.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 
-		wait.listeners--
 		i.logger.Debug("[rolodex remote loader]: waiting done, remote completed, returning file", "file",
-			remoteParsedURL.String(), "listeners", wait.listeners)
+			remoteParsedURL.String(), "listeners", wait.listeners.Load())
+		if wait.file == nil {
+			return nil, nil
+		}
 		return wait.file, nil
 	}
 
@@ -333,7 +422,7 @@ func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
 		return nil, &fs.PathError{Op: "open", Path: remoteURL, Err: fs.ErrInvalid}
 	}
 
-	processingWaiter := &waiterRemote{f: remoteParsedURL.Path}
+	processingWaiter := newWaiterRemote(remoteParsedURL.Path)
 
 	// add to processing
 	i.ProcessingFiles.Store(remoteParsedURL.Path, processingWaiter)
@@ -351,61 +440,90 @@ func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
 	}
 
 	if remoteParsedURL.Scheme == "" {
-		processingWaiter.done = true
+		processingWaiter.finish()
 		i.ProcessingFiles.Delete(remoteParsedURL.Path)
 		return nil, nil // not a remote file, nothing wrong with that - just we can't keep looking here partner.
 	}
 
-	i.logger.Debug("[rolodex remote loader] loading remote file", "file", remoteURL, "remoteURL", remoteParsedURL.String())
-
-	response, clientErr := i.RemoteHandlerFunc(remoteParsedURL.String())
-	if clientErr != nil {
-
-		i.remoteErrors = append(i.remoteErrors, clientErr)
+	if policyErr := i.checkRemotePolicy(ctx, remoteParsedURL, depth); policyErr != nil {
+		i.remoteErrors = append(i.remoteErrors, policyErr)
 		// remove from processing
-		processingWaiter.done = true
+		processingWaiter.finish()
 		i.ProcessingFiles.Delete(remoteParsedURL.Path)
-		if response != nil {
-			i.logger.Error("client error", "error", clientErr, "status", response.StatusCode)
-		} else {
-			i.logger.Error("client error", "error", clientErr.Error())
-		}
-		return nil, clientErr
+		i.logger.Error("remote reference blocked by policy", "file", remoteParsedURL.Path, "error", policyErr.Error())
+		return nil, policyErr
 	}
-	if response == nil {
-		// remove from processing
-		processingWaiter.done = true
-		i.ProcessingFiles.Delete(remoteParsedURL.Path)
-		return nil, fmt.Errorf("empty response from remote URL: %s", remoteParsedURL.String())
-	}
-	responseBytes, readError := io.ReadAll(response.Body)
-	if readError != nil {
 
-		// remove from processing
-		processingWaiter.done = true
-		i.ProcessingFiles.Delete(remoteParsedURL.Path)
+	i.logger.Debug("[rolodex remote loader] loading remote file", "file", remoteURL, "remoteURL", remoteParsedURL.String())
 
-		return nil, fmt.Errorf("error reading bytes from remote file '%s': [%s]",
-			remoteParsedURL.String(), readError.Error())
-	}
+	var responseBytes []byte
+	var lastModified string
+	if i.cache != nil {
+		var cacheErr error
+		responseBytes, lastModified, cacheErr = i.fetchWithCache(ctx, remoteParsedURL.String())
+		if cacheErr != nil {
+			if ctx.Err() == nil { // cancellations are not a problem with the remote document.
+				i.remoteErrors = append(i.remoteErrors, cacheErr)
+			}
+			// remove from processing
+			processingWaiter.finish()
+			i.ProcessingFiles.Delete(remoteParsedURL.Path)
+			i.logger.Error("unable to fetch remote document", "file", remoteParsedURL.Path, "error", cacheErr.Error())
+			return nil, cacheErr
+		}
+	} else {
+		response, clientErr := i.sendRequest(ctx, remoteParsedURL.String(), nil)
+		if clientErr != nil {
+
+			if ctx.Err() == nil { // cancellations are not a problem with the remote document.
+				i.remoteErrors = append(i.remoteErrors, clientErr)
+			}
+			// remove from processing
+			processingWaiter.finish()
+			i.ProcessingFiles.Delete(remoteParsedURL.Path)
+			if response != nil {
+				i.logger.Error("client error", "error", clientErr, "status", response.StatusCode)
+			} else {
+				i.logger.Error("client error", "error", clientErr.Error())
+			}
+			return nil, clientErr
+		}
+		if response == nil {
+			// remove from processing
+			processingWaiter.finish()
+			i.ProcessingFiles.Delete(remoteParsedURL.Path)
+			return nil, fmt.Errorf("empty response from remote URL: %s", remoteParsedURL.String())
+		}
+		var readError error
+		responseBytes, readError = i.readRemoteBody(remoteParsedURL.String(), response)
+		if readError != nil {
 
-	if response.StatusCode >= 400 {
+			// remove from processing
+			processingWaiter.finish()
+			i.ProcessingFiles.Delete(remoteParsedURL.Path)
 
-		// remove from processing
-		processingWaiter.done = true
-		i.ProcessingFiles.Delete(remoteParsedURL.Path)
+			return nil, fmt.Errorf("error reading bytes from remote file '%s': [%w]",
+				remoteParsedURL.String(), readError)
+		}
 
-		i.logger.Error("unable to fetch remote document",
-			"file", remoteParsedURL.Path, "status", response.StatusCode, "resp", string(responseBytes))
-		return nil, fmt.Errorf("unable to fetch remote document '%s' (error %d)", remoteParsedURL.String(),
-			response.StatusCode)
+		if response.StatusCode >= 400 {
+
+			// remove from processing
+			processingWaiter.finish()
+			i.ProcessingFiles.Delete(remoteParsedURL.Path)
+
+			i.logger.Error("unable to fetch remote document",
+				"file", remoteParsedURL.Path, "status", response.StatusCode, "resp", string(responseBytes))
+			return nil, fmt.Errorf("unable to fetch remote document '%s' (error %d)", remoteParsedURL.String(),
+				response.StatusCode)
+		}
+
+		// extract last modified from response
+		lastModified = response.Header.Get("Last-Modified")
 	}
 
 	absolutePath := remoteParsedURL.Path
 
-	// extract last modified from response
-	lastModified := response.Header.Get("Last-Modified")
-
 	// parse the last modified date into a time object
 	lastModifiedTime, parseErr := time.Parse(time.RFC1123, lastModified)
 
@@ -436,13 +554,14 @@ func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
 		copiedCfg.BaseURL = newBaseURL
 	}
 	copiedCfg.SpecAbsolutePath = remoteParsedURL.String()
+	copiedCfg.remoteDepth = depth
 
 	if len(remoteFile.data) > 0 {
 		i.logger.Debug("[rolodex remote loaded] successfully loaded file", "file", absolutePath)
 	}
 
 	processingWaiter.file = remoteFile
-	processingWaiter.done = true
+	processingWaiter.finish()
 
 	// remove from processing
 	i.ProcessingFiles.Delete(remoteParsedURL.Path)
@@ -464,3 +583,115 @@ func (i *RemoteFS) Open(remoteURL string) (fs.File, error) {
 	}
 	return remoteFile, errors.Join(i.remoteErrors...)
 }
+
+// fetchWithCache fetches a remote document via the RemoteCache. Fresh documents are served from disk, stale
+// documents are revalidated using a conditional request, and offline caches never touch the network.
+// The content and the Last-Modified value of the document are returned.
+func (i *RemoteFS) fetchWithCache(ctx context.Context, remoteURL string) ([]byte, string, error) {
+	entry, err := i.cache.Get(remoteURL)
+	if err != nil {
+		i.logger.Warn("[rolodex remote loader] unable to read remote cache, ignoring entry", "url", remoteURL,
+			"error", err.Error())
+		entry = nil
+	}
+	if entry != nil && (i.cache.IsOffline() || i.cache.IsFresh(entry)) {
+		i.logger.Debug("[rolodex remote loader] serving remote file from cache", "url", remoteURL)
+		return entry.Content, entry.LastModified, nil
+	}
+	if i.cache.IsOffline() {
+		return nil, "", fmt.Errorf("unable to open '%s' in offline mode (cache directory '%s'): %w",
+			remoteURL, i.cache.GetDirectory(), ErrRemoteCacheMiss)
+	}
+
+	header := make(http.Header)
+	if entry != nil {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	response, err := i.sendRequest(ctx, remoteURL, header)
+	if err != nil {
+		return nil, "", err
+	}
+	if response == nil {
+		return nil, "", fmt.Errorf("empty response from remote URL: %s", remoteURL)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		i.logger.Debug("[rolodex remote loader] remote file not modified, serving from cache", "url", remoteURL)
+		entry.FetchedAt = time.Time{}
+		if putErr := i.cache.Put(entry); putErr != nil {
+			i.logger.Warn("[rolodex remote loader] unable to update remote cache", "url", remoteURL,
+				"error", putErr.Error())
+		}
+		return entry.Content, entry.LastModified, nil
+	}
+
+	responseBytes, readError := i.readRemoteBody(remoteURL, response)
+	if readError != nil {
+		return nil, "", fmt.Errorf("error reading bytes from remote file '%s': [%w]",
+			remoteURL, readError)
+	}
+	if response.StatusCode >= 400 {
+		return nil, "", fmt.Errorf("unable to fetch remote document '%s' (error %d)", remoteURL,
+			response.StatusCode)
+	}
+
+	fetched := &RemoteCacheEntry{
+		URL:          remoteURL,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Content:      responseBytes,
+	}
+	if putErr := i.cache.Put(fetched); putErr != nil {
+		i.logger.Warn("[rolodex remote loader] unable to write remote cache", "url", remoteURL,
+			"error", putErr.Error())
+	}
+	return responseBytes, fetched.LastModified, nil
+}
+
+// sendRequest fetches a remote URL. The request handler is used (with the supplied headers and context) unless
+// the RemoteHandlerFunc has been replaced. Custom handlers have no way to accept a context, so the
+// result is abandoned (rather than the request) if the context is done first.
+func (i *RemoteFS) sendRequest(ctx context.Context, remoteURL string, header http.Header) (*http.Response, error) {
+	if handler := i.requestHandler(); handler != nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		response, err := handler(req)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return response, err
+	}
+
+	type result struct {
+		response *http.Response
+		err      error
+	}
+	fetched := make(chan result, 1)
+	go func() {
+		response, err := i.RemoteHandlerFunc(remoteURL)
+		fetched <- result{response, err}
+	}()
+	select {
+	case r := <-fetched:
+		return r.response, r.err
+	case <-ctx.Done():
+		// the handler can not be cancelled, so the response it returns is closed once it arrives.
+		go func() {
+			if r := <-fetched; r.response != nil && r.response.Body != nil {
+				_ = r.response.Body.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
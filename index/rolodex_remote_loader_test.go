@@ -5,9 +5,11 @@ package index
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -442,3 +444,145 @@ func TestNewRemoteFS_BadURL(t *testing.T) {
 	assert.Nil(t, x)
 	assert.Error(t, y)
 }
+
+func TestRemoteFS_OpenWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+			_, _ = rw.Write([]byte(`openapi: 3.1.0`))
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	rfs, _ := NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := rfs.OpenWithContext(ctx, server.URL+"/slow.yaml")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// already cancelled contexts never fetch.
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	_, err = rfs.OpenWithContext(cancelled, server.URL+"/slow.yaml")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	f, err := rfs.OpenWithContext(context.Background(), server.URL+"/slow.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, f)
+}
+
+func TestRemoteFS_OpenWithContext_WaitingForFetch(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		_, _ = rw.Write([]byte(`openapi: 3.1.0`))
+	}))
+	defer server.Close()
+
+	rfs, _ := NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+
+	first := make(chan error)
+	go func() {
+		_, err := rfs.OpenWithContext(context.Background(), server.URL+"/shared.yaml")
+		first <- err
+	}()
+	<-started
+
+	// a second open waits for the first fetch, but gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := rfs.OpenWithContext(ctx, server.URL+"/shared.yaml")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// and waiters are released as soon as the fetch completes.
+	waiter := make(chan fs.File)
+	go func() {
+		f, _ := rfs.OpenWithContext(context.Background(), server.URL+"/shared.yaml")
+		waiter <- f
+	}()
+	close(release)
+	assert.NoError(t, <-first)
+	assert.NotNil(t, <-waiter)
+}
+
+func TestRemoteFS_OpenWithContext_CustomHandler(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	rfs, _ := NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+	rfs.SetRemoteHandlerFunc(func(url string) (*http.Response, error) {
+		<-release
+		return nil, errors.New("too late")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := rfs.OpenWithContext(ctx, "https://pb33f.io/custom.yaml")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (c *closeRecorder) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestRemoteFS_OpenWithContext_CustomHandler_ClosesAbandonedResponse(t *testing.T) {
+	release := make(chan struct{})
+	body := &closeRecorder{Reader: strings.NewReader("type: string"), closed: make(chan struct{})}
+
+	rfs, _ := NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+	rfs.SetRemoteHandlerFunc(func(url string) (*http.Response, error) {
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := rfs.OpenWithContext(ctx, "https://pb33f.io/custom.yaml")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	select {
+	case <-body.closed:
+	case <-time.After(time.Second):
+		t.Fatal("the abandoned response body was not closed")
+	}
+}
+
+func TestRemoteFS_AssignedHandler(t *testing.T) {
+	server := test_buildServer()
+	defer server.Close()
+
+	// a handler assigned directly replaces the default http client.
+	calls := 0
+	rfs, _ := NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+	rfs.RemoteHandlerFunc = func(url string) (*http.Response, error) {
+		calls++
+		return http.Get(url)
+	}
+	_, err := rfs.Open(server.URL + "/file1.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// an assigned request handler is used instead of either.
+	requests := 0
+	rfs, _ = NewRemoteFSWithConfig(CreateOpenAPIIndexConfig())
+	rfs.RemoteRequestHandlerFunc = func(req *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultClient.Do(req)
+	}
+	_, err = rfs.Open(server.URL + "/file1.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel"
+)
+
+// lookupIPAddr resolves host names when checking a remote policy, it's a variable so tests can replace it.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// newPolicyHTTPClient creates an http client that enforces the redirect and network rules of a remote policy.
+// Every redirect is checked against the policy, and the dialer refuses to connect to private addresses (if blocked),
+// regardless of what the host name resolved to when it was checked (preventing DNS rebinding).
+func newPolicyHTTPClient(policy *datamodel.RemoteReferencePolicy) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return policy.CheckAddress(address, net.ParseIP(host))
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil // a proxy would bypass the address checks.
+
+	return &http.Client{
+		Timeout:   time.Second * 120,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if policy.MaxRedirects < 0 || (policy.MaxRedirects > 0 && len(via) > policy.MaxRedirects) {
+				return &datamodel.RemotePolicyError{Reason: datamodel.RemotePolicyTooManyRedirects,
+					URL: via[0].URL.String(),
+					Detail: fmt.Sprintf("redirect to '%s' exceeds the maximum of %d redirect(s)",
+						req.URL.String(), max(policy.MaxRedirects, 0))}
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return policy.CheckURL(req.URL)
+		},
+	}
+}
+
+// checkRemotePolicy checks a remote URL (about to be fetched at the supplied depth) against the remote policy,
+// host names are resolved if private networks are blocked.
+func (i *RemoteFS) checkRemotePolicy(ctx context.Context, remoteURL *url.URL, depth int) error {
+	policy := i.remotePolicy()
+	if policy == nil {
+		return nil
+	}
+	if i.requestHandler() == nil {
+		// the policy is enforced by the http client of the default handler, a custom handler would bypass it.
+		return datamodel.ErrRemotePolicyCustomHandler
+	}
+	if policy.MaxFetchDepth > 0 && depth > policy.MaxFetchDepth {
+		return &datamodel.RemotePolicyError{Reason: datamodel.RemotePolicyFetchDepthExceeded, URL: remoteURL.String(),
+			Detail: fmt.Sprintf("depth %d exceeds the maximum depth of %d", depth, policy.MaxFetchDepth)}
+	}
+	if err := policy.CheckURL(remoteURL); err != nil {
+		return err
+	}
+	if policy.BlockPrivateNetworks && net.ParseIP(remoteURL.Hostname()) == nil {
+		addresses, err := lookupIPAddr(ctx, remoteURL.Hostname())
+		if err != nil {
+			return err
+		}
+		for _, addr := range addresses {
+			if aErr := policy.CheckAddress(remoteURL.String(), addr.IP); aErr != nil {
+				return aErr
+			}
+		}
+	}
+	return nil
+}
+
+// readRemoteBody reads the body of a remote response, enforcing the size limits of the remote policy.
+func (i *RemoteFS) readRemoteBody(remoteURL string, response *http.Response) ([]byte, error) {
+	policy := i.remotePolicy()
+	if policy == nil {
+		return io.ReadAll(response.Body)
+	}
+	tooLarge := func(size string) error {
+		return &datamodel.RemotePolicyError{Reason: datamodel.RemotePolicyResponseTooLarge, URL: remoteURL,
+			Detail: fmt.Sprintf("response of %s bytes exceeds the maximum of %d bytes", size, policy.MaxResponseBytes)}
+	}
+	reader := io.Reader(response.Body)
+	if policy.MaxResponseBytes > 0 {
+		if response.ContentLength > policy.MaxResponseBytes {
+			return nil, tooLarge(strconv.FormatInt(response.ContentLength, 10))
+		}
+		reader = io.LimitReader(response.Body, policy.MaxResponseBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if policy.MaxResponseBytes > 0 && int64(len(data)) > policy.MaxResponseBytes {
+		return nil, tooLarge("more than " + strconv.FormatInt(policy.MaxResponseBytes, 10))
+	}
+	if policy.MaxTotalBytes > 0 {
+		if total := i.totalBytes.Add(int64(len(data))); total > policy.MaxTotalBytes {
+			return nil, &datamodel.RemotePolicyError{Reason: datamodel.RemotePolicyTotalBytesExceeded, URL: remoteURL,
+				Detail: fmt.Sprintf("%d bytes fetched exceeds the maximum of %d bytes", total, policy.MaxTotalBytes)}
+		}
+	}
+	return data, nil
+}
+
+func (i *RemoteFS) remotePolicy() *datamodel.RemoteReferencePolicy {
+	if i.indexConfig == nil {
+		return nil
+	}
+	return i.indexConfig.RemotePolicy
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func buildPolicyTestServer() *httptest.Server {
+	schema := func(ref string) string {
+		if ref == "" {
+			return "components:\n  schemas:\n    Thing:\n      type: string"
+		}
+		return fmt.Sprintf("components:\n  schemas:\n    Thing:\n      $ref: '%s#/components/schemas/Thing'", ref)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/a.yaml":
+			_, _ = rw.Write([]byte(schema("b.yaml")))
+		case "/b.yaml":
+			_, _ = rw.Write([]byte(schema("c.yaml")))
+		case "/c.yaml", "/small.yaml":
+			_, _ = rw.Write([]byte(schema("")))
+		case "/big.yaml":
+			_, _ = rw.Write([]byte(strings.Repeat("#", 2048)))
+		case "/streamed.yaml":
+			// no content length is sent when the body is flushed in chunks.
+			for n := 0; n < 4; n++ {
+				_, _ = rw.Write([]byte(strings.Repeat("#", 512)))
+				rw.(http.Flusher).Flush()
+			}
+		case "/redirect1.yaml":
+			http.Redirect(rw, req, "/redirect2.yaml", http.StatusFound)
+		case "/redirect2.yaml":
+			http.Redirect(rw, req, "/small.yaml", http.StatusFound)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func newPolicyRemoteFS(t *testing.T, policy *datamodel.RemoteReferencePolicy) *RemoteFS {
+	cfg := CreateOpenAPIIndexConfig()
+	cfg.RemotePolicy = policy
+	rfs, err := NewRemoteFSWithConfig(cfg)
+	require.NoError(t, err)
+	return rfs
+}
+
+func requirePolicyError(t *testing.T, err error, reason datamodel.RemotePolicyReason) {
+	var policyErr *datamodel.RemotePolicyError
+	require.True(t, errors.As(err, &policyErr), "expected a policy error, got: %v", err)
+	assert.Equal(t, reason, policyErr.Reason)
+}
+
+func TestRemoteFS_Policy_BlockPrivateNetworks(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	rfs := newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{BlockPrivateNetworks: true})
+	_, err := rfs.Open(server.URL + "/small.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyPrivateAddress)
+	assert.Len(t, rfs.GetErrors(), 1)
+
+	// host names are resolved before they are fetched.
+	original := lookupIPAddr
+	defer func() { lookupIPAddr = original }()
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.8")}}, nil
+	}
+	_, err = rfs.Open("https://internal.pb33f.io/spec.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyPrivateAddress)
+
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return nil, errors.New("no such host")
+	}
+	_, err = rfs.Open("https://nowhere.pb33f.io/spec.yaml")
+	assert.EqualError(t, err, "no such host")
+}
+
+func TestRemoteFS_Policy_DialerBlocksPrivateAddresses(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	// even if a host passed the checks, the dialer will refuse to connect.
+	client := newPolicyHTTPClient(&datamodel.RemoteReferencePolicy{BlockPrivateNetworks: true})
+	_, err := client.Get(server.URL + "/small.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyPrivateAddress)
+}
+
+func TestRemoteFS_Policy_CustomHandler(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	cfg := CreateOpenAPIIndexConfig()
+	cfg.RemotePolicy = &datamodel.RemoteReferencePolicy{AllowedHosts: []string{"127.0.0.1"}}
+	cfg.RemoteURLHandler = http.Get
+	_, err := NewRemoteFSWithConfig(cfg)
+	assert.ErrorIs(t, err, datamodel.ErrRemotePolicyCustomHandler)
+
+	// handlers set later can't enforce the policy either, so nothing is fetched.
+	rfs := newPolicyRemoteFS(t, cfg.RemotePolicy)
+	fetched := false
+	rfs.SetRemoteHandlerFunc(func(url string) (*http.Response, error) {
+		fetched = true
+		return http.Get(url)
+	})
+	_, err = rfs.Open(server.URL + "/small.yaml")
+	assert.ErrorIs(t, err, datamodel.ErrRemotePolicyCustomHandler)
+	assert.False(t, fetched)
+
+	// the same goes for handlers assigned directly.
+	rfs = newPolicyRemoteFS(t, cfg.RemotePolicy)
+	rfs.RemoteHandlerFunc = func(url string) (*http.Response, error) {
+		fetched = true
+		return http.Get(url)
+	}
+	_, err = rfs.Open(server.URL + "/small.yaml")
+	assert.ErrorIs(t, err, datamodel.ErrRemotePolicyCustomHandler)
+	assert.False(t, fetched)
+}
+
+func TestRemoteFS_Policy_HostAllowList(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	rfs := newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{AllowedHosts: []string{"pb33f.io"}})
+	_, err := rfs.Open(server.URL + "/small.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyHostDenied)
+
+	rfs = newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{AllowedHosts: []string{"127.0.0.1"}})
+	f, err := rfs.Open(server.URL + "/small.yaml")
+	assert.NoError(t, err)
+	assert.NotNil(t, f)
+}
+
+func TestRemoteFS_Policy_MaxResponseBytes(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	rfs := newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{MaxResponseBytes: 1024})
+	_, err := rfs.Open(server.URL + "/big.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyResponseTooLarge)
+	assert.Contains(t, err.Error(), "response of 2048 bytes exceeds the maximum of 1024 bytes")
+
+	_, err = rfs.Open(server.URL + "/streamed.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyResponseTooLarge)
+
+	_, err = rfs.Open(server.URL + "/small.yaml")
+	assert.NoError(t, err)
+}
+
+func TestRemoteFS_Policy_MaxTotalBytes(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	rfs := newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{MaxTotalBytes: 100})
+	_, err := rfs.Open(server.URL + "/small.yaml")
+	assert.NoError(t, err)
+	_, err = rfs.Open(server.URL + "/c.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyTotalBytesExceeded)
+}
+
+func TestRemoteFS_Policy_MaxRedirects(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	rfs := newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{MaxRedirects: 1})
+	_, err := rfs.Open(server.URL + "/redirect1.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyTooManyRedirects)
+
+	rfs = newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{MaxRedirects: -1})
+	_, err = rfs.Open(server.URL + "/redirect2.yaml")
+	requirePolicyError(t, err, datamodel.RemotePolicyTooManyRedirects)
+
+	rfs = newPolicyRemoteFS(t, &datamodel.RemoteReferencePolicy{MaxRedirects: 2})
+	_, err = rfs.Open(server.URL + "/redirect1.yaml")
+	assert.NoError(t, err)
+
+	// redirect targets are checked against the policy too.
+	client := newPolicyHTTPClient(&datamodel.RemoteReferencePolicy{DeniedHosts: []string{"127.0.0.1"}})
+	redirector := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Redirect(rw, req, server.URL+"/small.yaml", http.StatusFound)
+	}))
+	defer redirector.Close()
+	_, err = client.Get(strings.Replace(redirector.URL, "127.0.0.1", "localhost", 1))
+	requirePolicyError(t, err, datamodel.RemotePolicyHostDenied)
+}
+
+func TestRolodex_Policy_MaxFetchDepth(t *testing.T) {
+	server := buildPolicyTestServer()
+	defer server.Close()
+
+	spec := fmt.Sprintf(`openapi: 3.1.0
+components:
+  schemas:
+    Root:
+      $ref: '%s/a.yaml#/components/schemas/Thing'`, server.URL)
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+
+	cf := CreateOpenAPIIndexConfig()
+	cf.AvoidCircularReferenceCheck = true
+	cf.RemotePolicy = &datamodel.RemoteReferencePolicy{MaxFetchDepth: 2}
+
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	remoteFS, _ := NewRemoteFSWithConfig(cf)
+	rolo.AddRemoteFS("", remoteFS)
+
+	err := rolo.IndexTheRolodex()
+	requirePolicyError(t, err, datamodel.RemotePolicyFetchDepthExceeded)
+	assert.Contains(t, err.Error(), "/c.yaml")
+	assert.Contains(t, err.Error(), "depth 3 exceeds the maximum depth of 2")
+
+	files := remoteFS.GetFiles()
+	assert.Len(t, files, 2)
+}
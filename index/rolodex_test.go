@@ -4,6 +4,8 @@
 package index
 
 import (
+	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 	"io"
@@ -1845,3 +1847,57 @@ func TestHumanFileSize(t *testing.T) {
 	assert.Equal(t, "1 MB", HumanFileSize(1024*1024))
 
 }
+
+func TestRolodex_IndexTheRolodexWithContext_Cancelled(t *testing.T) {
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(`openapi: 3.1.0
+components:
+  schemas:
+    Thing:
+      type: string`), &rootNode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rolo := NewRolodex(CreateClosedAPIIndexConfig())
+	rolo.SetRootNode(&rootNode)
+	assert.ErrorIs(t, rolo.IndexTheRolodexWithContext(ctx), context.Canceled)
+	assert.Nil(t, rolo.GetRootIndex())
+
+	// nothing was marked as indexed, so it can be indexed again.
+	assert.NoError(t, rolo.IndexTheRolodexWithContext(context.Background()))
+	assert.NotNil(t, rolo.GetRootIndex())
+}
+
+func TestRolodex_IndexTheRolodexWithContext_SlowRemote(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(fmt.Sprintf(`openapi: 3.1.0
+components:
+  schemas:
+    Thing:
+      $ref: '%s/slow.yaml#/components/schemas/Thing'`, server.URL)), &rootNode)
+
+	cf := CreateOpenAPIIndexConfig()
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	remoteFS, _ := NewRemoteFSWithConfig(cf)
+	rolo.AddRemoteFS("", remoteFS)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := rolo.IndexTheRolodexWithContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
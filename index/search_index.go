@@ -128,7 +128,7 @@ func (index *SpecIndex) SearchIndexForReferenceByReferenceWithContext(ctx contex
 		if filepath.Base(roloLookup) == index.GetSpecFileName() {
 			return nil, index, ctx
 		}
-		rFile, err := index.rolodex.Open(roloLookup)
+		rFile, err := index.rolodex.openFromDepth(roloLookup, index.remoteDepth())
 		if err != nil {
 			return nil, index, ctx
 		}
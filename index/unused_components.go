@@ -0,0 +1,271 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// FindUnusedComponents returns every component declared in any file of the rolodex (in 'components' or the Swagger
+// 'definitions', 'parameters', 'responses' and 'securityDefinitions') that cannot be reached from a path, a webhook
+// or a security requirement of the root document. References are followed transitively, and the schemas named by
+// discriminator mappings count as being used.
+//
+// Results are sorted by their full definition. The rolodex must not have been resolved, because resolving replaces
+// references with the content they point to.
+func (r *Rolodex) FindUnusedComponents() []*Reference {
+	indexes := slices.Clone(r.GetIndexes())
+	if r.rootIndex != nil {
+		indexes = append([]*SpecIndex{r.rootIndex}, indexes...)
+	}
+	return findUnusedComponents(indexes)
+}
+
+// FindUnusedComponents returns every component in the index that cannot be reached from a path, a webhook or a
+// security requirement. If the index belongs to a rolodex, components in every file of the rolodex are checked.
+func (index *SpecIndex) FindUnusedComponents() []*Reference {
+	if index.rolodex != nil {
+		return index.rolodex.FindUnusedComponents()
+	}
+	return findUnusedComponents([]*SpecIndex{index})
+}
+
+type componentUsage struct {
+	root    *SpecIndex
+	indexes map[string]*SpecIndex
+	refs    map[*yaml.Node]*Reference
+	used    map[string]bool
+	walked  map[*yaml.Node]bool
+}
+
+func findUnusedComponents(indexes []*SpecIndex) []*Reference {
+	if len(indexes) == 0 || indexes[0] == nil {
+		return nil
+	}
+	u := &componentUsage{
+		root:    indexes[0],
+		indexes: make(map[string]*SpecIndex),
+		refs:    make(map[*yaml.Node]*Reference),
+		used:    make(map[string]bool),
+		walked:  make(map[*yaml.Node]bool),
+	}
+	for _, idx := range indexes {
+		if idx == nil {
+			continue
+		}
+		u.indexes[idx.specAbsolutePath] = idx
+		for _, ref := range idx.rawSequencedRefs {
+			if ref.KeyNode != nil {
+				u.refs[ref.KeyNode] = ref
+			}
+		}
+	}
+
+	// everything reachable from the paths, webhooks and security requirements of the root document is used.
+	if root := documentContent(u.root); utils.IsNodeMap(root) {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			switch root.Content[i].Value {
+			case "paths", "webhooks":
+				u.walk(u.root, root.Content[i+1])
+			case "security":
+				u.useSecurityRequirements(root.Content[i+1])
+			}
+		}
+	}
+
+	var unused []*Reference
+	for _, idx := range u.indexes {
+		for _, component := range declaredComponents(idx) {
+			if !u.used[component.FullDefinition] {
+				unused = append(unused, component)
+			}
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].FullDefinition < unused[j].FullDefinition
+	})
+	return unused
+}
+
+// walk looks through a node for references, security requirements and discriminator mappings, following each
+// of them to the nodes they point to.
+func (u *componentUsage) walk(idx *SpecIndex, node *yaml.Node) {
+	if node == nil || u.walked[node] {
+		return
+	}
+	u.walked[node] = true
+	if !utils.IsNodeMap(node) {
+		for _, n := range node.Content {
+			u.walk(idx, n)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch {
+		case key == "$ref" && utils.IsNodeStringValue(value):
+			if ref := u.refs[value]; ref != nil {
+				u.use(idx, ref.FullDefinition)
+			}
+			continue
+		case key == "security" && utils.IsNodeArray(value):
+			u.useSecurityRequirements(value)
+		case key == "discriminator" && utils.IsNodeMap(value):
+			_, mapping := utils.FindKeyNodeTop("mapping", value.Content)
+			if utils.IsNodeMap(mapping) {
+				for j := 1; j < len(mapping.Content); j += 2 {
+					u.use(idx, mappingDefinition(idx.specAbsolutePath, mapping.Content[j].Value))
+				}
+			}
+		}
+		u.walk(idx, value)
+	}
+}
+
+// use marks the component that owns a definition as used, and walks it. Definitions that are not inside a
+// component (a whole file, for example) are walked as they are.
+func (u *componentUsage) use(from *SpecIndex, fullDefinition string) {
+	file, fragment, _ := strings.Cut(fullDefinition, "#")
+	if file == "" {
+		file = from.specAbsolutePath
+	}
+	idx := u.indexes[file]
+	segments := splitPointer(fragment)
+	if definition, _, ok := componentDefinition(segments); ok {
+		u.used[file+definition] = true
+		segments = splitPointer(definition)
+	}
+	if idx != nil {
+		u.walk(idx, nodeAtPointer(documentContent(idx), segments))
+	}
+}
+
+// useSecurityRequirements marks the security schemes named by a list of security requirements as used.
+func (u *componentUsage) useSecurityRequirements(requirements *yaml.Node) {
+	if !utils.IsNodeArray(requirements) {
+		return
+	}
+	for _, requirement := range requirements.Content {
+		for i := 0; i < len(requirement.Content); i += 2 {
+			name := escapePointerSegment(requirement.Content[i].Value)
+			u.use(u.root, "#/components/securitySchemes/"+name)
+			u.use(u.root, "#/securityDefinitions/"+name)
+		}
+	}
+}
+
+// declaredComponents returns a reference for every component declared in the file of an index.
+func declaredComponents(idx *SpecIndex) []*Reference {
+	root := documentContent(idx)
+	if !utils.IsNodeMap(root) {
+		return nil
+	}
+	var components []*Reference
+	add := func(segments []string, key, node, parent *yaml.Node) {
+		definition, name, _ := componentDefinition(segments)
+		components = append(components, &Reference{
+			FullDefinition: idx.specAbsolutePath + definition,
+			Definition:     definition,
+			Name:           name,
+			Node:           node,
+			KeyNode:        key,
+			ParentNode:     parent,
+			Index:          idx,
+		})
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		container, value := root.Content[i].Value, root.Content[i+1]
+		if !utils.IsNodeMap(value) {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			name, child := escapePointerSegment(value.Content[j].Value), value.Content[j+1]
+			switch {
+			case container == "components" && utils.IsNodeMap(child):
+				for k := 0; k+1 < len(child.Content); k += 2 {
+					add([]string{container, name, escapePointerSegment(child.Content[k].Value)},
+						child.Content[k], child.Content[k+1], child)
+				}
+			case slices.Contains(dependencyComponentContainers, container):
+				add([]string{container, name}, value.Content[j], child, value)
+			}
+		}
+	}
+	return components
+}
+
+// mappingDefinition returns the full definition of a discriminator mapping value, which is either a schema name
+// or a reference.
+func mappingDefinition(file, value string) string {
+	if !strings.ContainsAny(value, "#/") && ExtractFileType(value) == UNSUPPORTED {
+		return fmt.Sprintf("%s#/components/schemas/%s", file, escapePointerSegment(value))
+	}
+	location, fragment, _ := strings.Cut(value, "#")
+	switch {
+	case location == "":
+		location = file
+	case strings.HasPrefix(location, "http"):
+	case strings.HasPrefix(file, "http"):
+		if base, err := url.Parse(file); err == nil {
+			if rel, rErr := url.Parse(location); rErr == nil {
+				location = base.ResolveReference(rel).String()
+			}
+		}
+	case !filepath.IsAbs(location):
+		location, _ = filepath.Abs(filepath.Join(filepath.Dir(file), location))
+	}
+	return location + "#" + fragment
+}
+
+func documentContent(idx *SpecIndex) *yaml.Node {
+	if idx == nil || idx.root == nil {
+		return nil
+	}
+	if idx.root.Kind == yaml.DocumentNode {
+		if len(idx.root.Content) == 0 {
+			return nil
+		}
+		return idx.root.Content[0]
+	}
+	return idx.root
+}
+
+// nodeAtPointer returns the node found at the supplied JSON pointer segments, or nil if there isn't one.
+func nodeAtPointer(node *yaml.Node, segments []string) *yaml.Node {
+	for _, segment := range segments {
+		if node == nil {
+			return nil
+		}
+		segment = unescapePointerSegment(segment)
+		switch {
+		case utils.IsNodeMap(node):
+			var found *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					found = node.Content[i+1]
+					break
+				}
+			}
+			node = found
+		case utils.IsNodeArray(node):
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func unusedDefinitions(refs []*Reference) []string {
+	var definitions []string
+	for _, ref := range refs {
+		definitions = append(definitions, ref.Definition)
+	}
+	return definitions
+}
+
+func TestSpecIndex_FindUnusedComponents(t *testing.T) {
+	spec := `openapi: 3.1.0
+security:
+  - apiKey: []
+paths:
+  /pets:
+    get:
+      security:
+        - oauth: [read]
+      parameters:
+        - $ref: '#/components/parameters/Limit'
+      responses:
+        "200":
+          $ref: '#/components/responses/Pets'
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        $ref: '#/components/requestBodies/NewPet'
+components:
+  parameters:
+    Limit:
+      name: limit
+      in: query
+    Offset:
+      name: offset
+      in: query
+  responses:
+    Pets:
+      description: pets
+      content:
+        application/json:
+          schema:
+            $ref: '#/components/schemas/Pet'
+    Orphan:
+      description: nobody uses me
+  requestBodies:
+    NewPet:
+      content:
+        application/json:
+          schema:
+            type: object
+  securitySchemes:
+    apiKey:
+      type: apiKey
+    oauth:
+      type: oauth2
+    basic:
+      type: http
+  schemas:
+    Pet:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+      discriminator:
+        propertyName: kind
+        mapping:
+          dog: Dog
+          bird: '#/components/schemas/Bird'
+    Cat:
+      type: object
+      properties:
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Dog:
+      type: object
+    Bird:
+      type: object
+    Owner:
+      type: object
+    Unused:
+      type: object
+      properties:
+        lonely:
+          $ref: '#/components/schemas/AlsoUnused'
+    AlsoUnused:
+      type: string
+    pet:
+      type: string`
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+
+	unused := idx.FindUnusedComponents()
+	assert.Equal(t, []string{
+		"#/components/parameters/Offset",
+		"#/components/responses/Orphan",
+		"#/components/schemas/AlsoUnused",
+		"#/components/schemas/Unused",
+		"#/components/schemas/pet",
+		"#/components/securitySchemes/basic",
+	}, unusedDefinitions(unused))
+	assert.Equal(t, "Orphan", unused[1].Name)
+	assert.Equal(t, "description", unused[1].Node.Content[0].Value)
+	assert.Same(t, idx, unused[1].Index)
+}
+
+func TestSpecIndex_FindUnusedComponents_Swagger(t *testing.T) {
+	spec := `swagger: 2.0
+security:
+  - key: []
+paths:
+  /pets:
+    get:
+      parameters:
+        - $ref: '#/parameters/limit'
+      responses:
+        "200":
+          schema:
+            $ref: '#/definitions/Pet'
+definitions:
+  Pet:
+    type: object
+  Orphan:
+    type: object
+parameters:
+  limit:
+    name: limit
+    in: query
+  offset:
+    name: offset
+    in: query
+responses:
+  NotFound:
+    description: not found
+securityDefinitions:
+  key:
+    type: apiKey
+  basic:
+    type: basic`
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(spec), &rootNode)
+	idx := NewSpecIndexWithConfig(&rootNode, CreateClosedAPIIndexConfig())
+	assert.Equal(t, []string{
+		"#/definitions/Orphan",
+		"#/parameters/offset",
+		"#/responses/NotFound",
+		"#/securityDefinitions/basic",
+	}, unusedDefinitions(idx.FindUnusedComponents()))
+}
+
+func TestRolodex_FindUnusedComponents(t *testing.T) {
+	dir := writeReindexFiles(t)
+	root := `openapi: 3.1.0
+paths:
+  /a:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: 'a.yaml#/components/schemas/A'
+components:
+  schemas:
+    B:
+      $ref: 'b.yaml#/components/schemas/B'`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.yaml"), []byte(root), 0o644))
+
+	var rootNode yaml.Node
+	_ = yaml.Unmarshal([]byte(root), &rootNode)
+	cf := CreateOpenAPIIndexConfig()
+	cf.BasePath = dir
+	cf.SpecFilePath = "root.yaml"
+	fileFS, err := NewLocalFSWithConfig(&LocalFSConfig{BaseDirectory: dir, IndexConfig: cf})
+	require.NoError(t, err)
+	rolo := NewRolodex(cf)
+	rolo.SetRootNode(&rootNode)
+	rolo.AddLocalFS(dir, fileFS)
+	require.NoError(t, rolo.IndexTheRolodex())
+
+	// A and C (referenced by A) are used, B is only referenced by an unused component.
+	var unused []string
+	for _, ref := range rolo.FindUnusedComponents() {
+		unused = append(unused, ref.FullDefinition)
+	}
+	assert.Equal(t, []string{
+		filepath.Join(dir, "b.yaml") + "#/components/schemas/B",
+		filepath.Join(dir, "root.yaml") + "#/components/schemas/B",
+	}, unused)
+	assert.Len(t, rolo.GetRootIndex().FindUnusedComponents(), 2)
+}
+
+func TestMappingDefinition(t *testing.T) {
+	assert.Equal(t, "/specs/root.yaml#/components/schemas/Dog", mappingDefinition("/specs/root.yaml", "Dog"))
+	assert.Equal(t, "/specs/root.yaml#/components/schemas/Dog",
+		mappingDefinition("/specs/root.yaml", "#/components/schemas/Dog"))
+	assert.Equal(t, "/specs/pets/dog.yaml#", mappingDefinition("/specs/root.yaml", "pets/dog.yaml"))
+	assert.Equal(t, "https://pb33f.io/pets/dog.yaml#/Dog",
+		mappingDefinition("https://pb33f.io/root.yaml", "pets/dog.yaml#/Dog"))
+	assert.Equal(t, "https://pb33f.io/dog.yaml#/Dog",
+		mappingDefinition("/specs/root.yaml", "https://pb33f.io/dog.yaml#/Dog"))
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+// Package inference provides tools for inferring an OpenAPI 3.1 document from recorded HTTP traffic.
+//
+// Undocumented services can still be observed. The Inferrer consumes request / response pairs (Exchanges), read
+// from HAR captures or from net/http requests and responses, generalises the paths into templates, and infers the
+// parameters and the schemas of request and response bodies from what was observed. The result is a high-level
+// document that can be rendered using Render().
+package inference
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Exchange is a recorded HTTP request, and the response that was received for it.
+type Exchange struct {
+	Method         string      // HTTP method of the request.
+	URL            *url.URL    // URL of the request, including the query.
+	RequestHeader  http.Header // headers of the request.
+	RequestBody    []byte      // body of the request, nil if there was none.
+	StatusCode     int         // status code of the response, 0 if there was no response.
+	ResponseHeader http.Header // headers of the response.
+	ResponseBody   []byte      // body of the response, nil if there was none.
+}
+
+// NewExchange creates an Exchange from a request and its response, which may be nil. The bodies are read, and
+// replaced, so the request and response can still be used.
+func NewExchange(request *http.Request, response *http.Response) (*Exchange, error) {
+	if request == nil || request.URL == nil {
+		return nil, fmt.Errorf("unable to create exchange, no request supplied")
+	}
+	exchange := &Exchange{
+		Method:        strings.ToUpper(request.Method),
+		URL:           request.URL,
+		RequestHeader: request.Header.Clone(),
+	}
+	if exchange.Method == "" {
+		exchange.Method = http.MethodGet
+	}
+	if request.Body != nil && request.Body != http.NoBody {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+		_ = request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+		exchange.RequestBody = body
+	}
+	if response != nil {
+		exchange.StatusCode = response.StatusCode
+		exchange.ResponseHeader = response.Header.Clone()
+		if response.Body != nil && response.Body != http.NoBody {
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read response body: %w", err)
+			}
+			_ = response.Body.Close()
+			response.Body = io.NopCloser(bytes.NewReader(body))
+			exchange.ResponseBody = body
+		}
+	}
+	return exchange, nil
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method   string         `json:"method"`
+				URL      string         `json:"url"`
+				Headers  []harNameValue `json:"headers"`
+				PostData *struct {
+					MimeType string         `json:"mimeType"`
+					Text     string         `json:"text"`
+					Params   []harNameValue `json:"params"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status  int            `json:"status"`
+				Headers []harNameValue `json:"headers"`
+				Content struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ExchangesFromHAR reads the exchanges recorded in a HAR (HTTP Archive) file. Base64 encoded response content is
+// decoded, and entries without a response (status 0) are kept without one.
+func ExchangesFromHAR(data []byte) ([]*Exchange, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("unable to read HAR file: %w", err)
+	}
+	exchanges := make([]*Exchange, 0, len(har.Log.Entries))
+	for i, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read HAR entry %d, invalid URL '%s': %w", i, entry.Request.URL, err)
+		}
+		exchange := &Exchange{
+			Method:         strings.ToUpper(entry.Request.Method),
+			URL:            u,
+			RequestHeader:  harHeader(entry.Request.Headers),
+			StatusCode:     entry.Response.Status,
+			ResponseHeader: harHeader(entry.Response.Headers),
+		}
+		if pd := entry.Request.PostData; pd != nil {
+			if pd.MimeType != "" && exchange.RequestHeader.Get("Content-Type") == "" {
+				exchange.RequestHeader.Set("Content-Type", pd.MimeType)
+			}
+			switch {
+			case pd.Text != "":
+				exchange.RequestBody = []byte(pd.Text)
+			case len(pd.Params) > 0:
+				values := url.Values{}
+				for _, p := range pd.Params {
+					values.Add(p.Name, p.Value)
+				}
+				exchange.RequestBody = []byte(values.Encode())
+			}
+		}
+		content := entry.Response.Content
+		if content.MimeType != "" && exchange.ResponseHeader.Get("Content-Type") == "" {
+			exchange.ResponseHeader.Set("Content-Type", content.MimeType)
+		}
+		if content.Text != "" {
+			exchange.ResponseBody = []byte(content.Text)
+			if content.Encoding == "base64" {
+				decoded, err := base64.StdEncoding.DecodeString(content.Text)
+				if err != nil {
+					return nil, fmt.Errorf("unable to read HAR entry %d, invalid base64 content: %w", i, err)
+				}
+				exchange.ResponseBody = decoded
+			}
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, nil
+}
+
+func harHeader(pairs []harNameValue) http.Header {
+	header := make(http.Header)
+	for _, p := range pairs {
+		// HTTP/2 pseudo headers, such as ':authority', are not headers of the request.
+		if strings.HasPrefix(p.Name, ":") {
+			continue
+		}
+		header.Add(p.Name, p.Value)
+	}
+	return header
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExchange(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/users?dryRun=true", strings.NewReader(`{"name":"pb33f"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := &http.Response{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":1}`)),
+	}
+
+	exchange, err := NewExchange(req, res)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, exchange.Method)
+	assert.Equal(t, "/users", exchange.URL.Path)
+	assert.Equal(t, `{"name":"pb33f"}`, string(exchange.RequestBody))
+	assert.Equal(t, http.StatusCreated, exchange.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(exchange.ResponseBody))
+
+	// the bodies are replaced, so they can still be read.
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"name":"pb33f"}`, string(body))
+	body, _ = io.ReadAll(res.Body)
+	assert.Equal(t, `{"id":1}`, string(body))
+}
+
+func TestNewExchange_NoResponse(t *testing.T) {
+	exchange, err := NewExchange(httptest.NewRequest(http.MethodGet, "/health", nil), nil)
+	require.NoError(t, err)
+	assert.Nil(t, exchange.RequestBody)
+	assert.Zero(t, exchange.StatusCode)
+}
+
+func TestNewExchange_NoRequest(t *testing.T) {
+	_, err := NewExchange(nil, nil)
+	assert.EqualError(t, err, "unable to create exchange, no request supplied")
+}
+
+func TestExchangesFromHAR(t *testing.T) {
+	har := `{"log": {"entries": [
+  {
+    "request": {
+      "method": "post",
+      "url": "https://api.example.com/users",
+      "headers": [{"name": ":authority", "value": "api.example.com"}, {"name": "X-Tenant", "value": "a"}],
+      "postData": {"mimeType": "application/json", "text": "{\"name\":\"pb33f\"}"}
+    },
+    "response": {
+      "status": 201,
+      "headers": [],
+      "content": {"mimeType": "application/json", "text": "eyJpZCI6MX0=", "encoding": "base64"}
+    }
+  },
+  {
+    "request": {
+      "method": "POST",
+      "url": "https://api.example.com/login",
+      "headers": [],
+      "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "pb33f"}]}
+    },
+    "response": {"status": 0, "headers": [], "content": {}}
+  }
+]}}`
+
+	exchanges, err := ExchangesFromHAR([]byte(har))
+	require.NoError(t, err)
+	require.Len(t, exchanges, 2)
+
+	assert.Equal(t, http.MethodPost, exchanges[0].Method)
+	assert.Equal(t, "api.example.com", exchanges[0].URL.Host)
+	assert.Equal(t, "application/json", exchanges[0].RequestHeader.Get("Content-Type"))
+	assert.Equal(t, "a", exchanges[0].RequestHeader.Get("X-Tenant"))
+	assert.Empty(t, exchanges[0].RequestHeader.Get(":authority"))
+	assert.Equal(t, `{"name":"pb33f"}`, string(exchanges[0].RequestBody))
+	assert.Equal(t, `{"id":1}`, string(exchanges[0].ResponseBody))
+	assert.Equal(t, "application/json", exchanges[0].ResponseHeader.Get("Content-Type"))
+
+	assert.Equal(t, "user=pb33f", string(exchanges[1].RequestBody))
+	assert.Zero(t, exchanges[1].StatusCode)
+	assert.Nil(t, exchanges[1].ResponseBody)
+}
+
+func TestExchangesFromHAR_Invalid(t *testing.T) {
+	_, err := ExchangesFromHAR([]byte(`not json`))
+	assert.ErrorContains(t, err, "unable to read HAR file")
+
+	_, err = ExchangesFromHAR([]byte(`{"log":{"entries":[{"request":{"method":"GET","url":"://nope"}}]}}`))
+	assert.ErrorContains(t, err, "unable to read HAR entry 0, invalid URL '://nope'")
+
+	_, err = ExchangesFromHAR([]byte(`{"log":{"entries":[{"request":{"method":"GET","url":"/"},
+		"response":{"status":200,"content":{"text":"!!","encoding":"base64"}}}]}}`))
+	assert.ErrorContains(t, err, "invalid base64 content")
+}
@@ -0,0 +1,426 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+const (
+	// DefaultTitle is the title of inferred documents, when no Info is set.
+	DefaultTitle = "Inferred API"
+	// DefaultVersion is the version of inferred documents, when no Info is set.
+	DefaultVersion = "1.0.0"
+
+	inferredOpenAPIVersion = "3.1.0"
+	octetStream            = "application/octet-stream"
+)
+
+// operationMethods are the methods of the operations of a path item, other methods are ignored.
+var operationMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// ignoredHeaders are request headers that are part of HTTP, or added by clients and proxies, rather than
+// parameters of an API.
+var ignoredHeaders = map[string]bool{
+	"Accept": true, "Accept-Charset": true, "Accept-Encoding": true, "Accept-Language": true, "Authorization": true,
+	"Cache-Control": true, "Connection": true, "Content-Encoding": true, "Content-Length": true, "Content-Type": true,
+	"Cookie": true, "Dnt": true, "Expect": true, "Forwarded": true, "Host": true, "If-Match": true,
+	"If-Modified-Since": true, "If-None-Match": true, "If-Range": true, "If-Unmodified-Since": true,
+	"Keep-Alive": true, "Origin": true, "Pragma": true, "Priority": true, "Proxy-Authorization": true,
+	"Proxy-Connection": true, "Range": true, "Referer": true, "Te": true, "Trailer": true,
+	"Transfer-Encoding": true, "Upgrade": true, "Upgrade-Insecure-Requests": true, "User-Agent": true, "Via": true,
+	"X-Forwarded-For": true, "X-Forwarded-Host": true, "X-Forwarded-Proto": true, "X-Real-Ip": true,
+	"X-Requested-With": true,
+}
+
+// Inferrer infers an OpenAPI 3.1 document from recorded HTTP exchanges.
+//
+// Paths are generalised into templates: segments that look like values, such as numbers, UUIDs, dates and long
+// tokens, become path parameters, as do the segments of a path that has more distinct literal segments than the
+// literal threshold. Query parameters and request headers become parameters, required if they were sent with every
+// request of the operation. JSON bodies are inferred as schemas, merging every observation into unions, optional
+// properties and formats. Responses are grouped by status code.
+//
+// Schemas are inline, and values observed are never used as examples, as recorded traffic can hold credentials and
+// personal data. Repeated schemas can be moved into components with the ExtractInlineSchemas method of the document.
+type Inferrer struct {
+	exchanges        []*Exchange
+	info             *base.Info
+	literalThreshold int
+}
+
+// operationObservation holds everything observed for an operation.
+type operationObservation struct {
+	method       string
+	path         string
+	count        int
+	pathValues   *orderedmap.Map[string, *shape]
+	query        *orderedmap.Map[string, *observedParameter]
+	headers      *orderedmap.Map[string, *observedParameter]
+	bodies       int
+	requestTypes *orderedmap.Map[string, *shape]
+	responses    map[int]*orderedmap.Map[string, *shape]
+}
+
+// observedParameter is a query parameter or header, and the values observed for it.
+type observedParameter struct {
+	count    int
+	repeated bool
+	values   *shape
+}
+
+// NewInferrer creates a new Inferrer, without any exchanges.
+func NewInferrer() *Inferrer {
+	return &Inferrer{literalThreshold: DefaultLiteralThreshold}
+}
+
+// SetInfo sets the Info of inferred documents. By default, the title is DefaultTitle and the version is
+// DefaultVersion.
+func (i *Inferrer) SetInfo(info *base.Info) {
+	i.info = info
+}
+
+// SetLiteralThreshold sets the number of distinct literal segments a path segment can have before they are
+// generalised into a parameter, such as '/files/{id}' for '/files/readme', '/files/license' and so on. The default
+// is DefaultLiteralThreshold.
+func (i *Inferrer) SetLiteralThreshold(threshold int) {
+	i.literalThreshold = threshold
+}
+
+// Add adds recorded exchanges to the Inferrer. Exchanges without a method or URL, and exchanges using a method that
+// an OpenAPI path item has no operation for, such as CONNECT, are ignored.
+func (i *Inferrer) Add(exchanges ...*Exchange) {
+	for _, e := range exchanges {
+		if e != nil && e.URL != nil && e.Method != "" {
+			i.exchanges = append(i.exchanges, e)
+		}
+	}
+}
+
+// Document infers a document from the exchanges added to the Inferrer. The document can be rendered using Render().
+func (i *Inferrer) Document() *v3.Document {
+	info := i.info
+	if info == nil {
+		info = &base.Info{Title: DefaultTitle, Version: DefaultVersion}
+	}
+	doc := &v3.Document{
+		Version: inferredOpenAPIVersion,
+		Info:    info,
+		Paths:   &v3.Paths{PathItems: orderedmap.New[string, *v3.PathItem]()},
+	}
+
+	root := newPathNode()
+	for _, e := range i.exchanges {
+		root.insert(splitPath(e.URL.Path))
+	}
+	root.generalise(i.literalThreshold)
+	root.nameParameters("", map[string]bool{})
+
+	servers := make(map[string]bool)
+	observations := make(map[string]*operationObservation)
+	var order []*operationObservation
+	for _, e := range i.exchanges {
+		if e.URL.Host != "" {
+			server := e.URL.Scheme + "://" + e.URL.Host
+			if e.URL.Scheme == "" {
+				server = "https://" + e.URL.Host
+			}
+			if !servers[server] {
+				servers[server] = true
+				doc.Servers = append(doc.Servers, &v3.Server{URL: server})
+			}
+		}
+		method := strings.ToLower(e.Method)
+		path, values, ok := root.template(splitPath(e.URL.Path))
+		if !ok || !operationMethods[method] {
+			continue
+		}
+		key := method + " " + path
+		obs, ok := observations[key]
+		if !ok {
+			obs = &operationObservation{
+				method:       method,
+				path:         path,
+				pathValues:   orderedmap.New[string, *shape](),
+				query:        orderedmap.New[string, *observedParameter](),
+				headers:      orderedmap.New[string, *observedParameter](),
+				requestTypes: orderedmap.New[string, *shape](),
+				responses:    make(map[int]*orderedmap.Map[string, *shape]),
+			}
+			observations[key] = obs
+			order = append(order, obs)
+		}
+		obs.observe(e, values)
+	}
+
+	// paths are sorted, so that related paths are next to each other.
+	slices.SortStableFunc(order, func(a, b *operationObservation) int {
+		return strings.Compare(a.path, b.path)
+	})
+	operationIds := make(map[string]bool)
+	for _, obs := range order {
+		pathItem := doc.Paths.PathItems.GetOrZero(obs.path)
+		if pathItem == nil {
+			pathItem = &v3.PathItem{}
+			doc.Paths.PathItems.Set(obs.path, pathItem)
+		}
+		operation := obs.operation()
+		operation.OperationId = uniqueName(operationId(obs.method, obs.path), operationIds)
+		setOperation(pathItem, obs.method, operation)
+	}
+	return doc
+}
+
+// observe adds an exchange to the observations of an operation.
+func (o *operationObservation) observe(e *Exchange, pathValues map[string]string) {
+	o.count++
+	for _, name := range templateParameters(o.path) {
+		values := o.pathValues.GetOrZero(name)
+		if values == nil {
+			values = newShape()
+			o.pathValues.Set(name, values)
+		}
+		values.observeText(pathValues[name])
+	}
+
+	query := e.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		observeParameter(o.query, k, query[k])
+	}
+
+	var headers []string
+	for k := range e.RequestHeader {
+		name := http.CanonicalHeaderKey(k)
+		if !ignoredHeaders[name] && !strings.HasPrefix(name, "Sec-") {
+			headers = append(headers, k)
+		}
+	}
+	slices.Sort(headers)
+	for _, k := range headers {
+		observeParameter(o.headers, http.CanonicalHeaderKey(k), e.RequestHeader[k])
+	}
+
+	if len(e.RequestBody) > 0 {
+		o.bodies++
+		observeBody(o.requestTypes, e.RequestHeader.Get("Content-Type"), e.RequestBody)
+	}
+
+	if e.StatusCode > 0 {
+		contents, ok := o.responses[e.StatusCode]
+		if !ok {
+			contents = orderedmap.New[string, *shape]()
+			o.responses[e.StatusCode] = contents
+		}
+		if len(e.ResponseBody) > 0 {
+			observeBody(contents, e.ResponseHeader.Get("Content-Type"), e.ResponseBody)
+		}
+	}
+}
+
+func observeParameter(parameters *orderedmap.Map[string, *observedParameter], name string, values []string) {
+	p := parameters.GetOrZero(name)
+	if p == nil {
+		p = &observedParameter{values: newShape()}
+		parameters.Set(name, p)
+	}
+	p.count++
+	if len(values) > 1 {
+		p.repeated = true
+	}
+	for _, v := range values {
+		p.values.observeText(v)
+	}
+}
+
+// observeBody adds a body to the shapes of the media types of a request or response. JSON is inferred as a schema,
+// form data as an object, text as a string and anything else as binary.
+func observeBody(contents *orderedmap.Map[string, *shape], contentType string, body []byte) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		mediaType = octetStream
+		if jsonBody(body) {
+			mediaType = "application/json"
+		}
+	}
+	s := contents.GetOrZero(mediaType)
+	if s == nil {
+		s = newShape()
+		contents.Set(mediaType, s)
+	}
+	switch {
+	case isJSON(mediaType) && s.observeJSON(body):
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			s.observe(string(body))
+			return
+		}
+		form := make(map[string]any, len(values))
+		for k, v := range values {
+			if len(v) == 1 {
+				form[k] = v[0]
+				continue
+			}
+			items := make([]any, len(v))
+			for i := range v {
+				items[i] = v[i]
+			}
+			form[k] = items
+		}
+		s.observe(form)
+	case strings.HasPrefix(mediaType, "text/") || isJSON(mediaType):
+		s.count++
+		s.strings++
+	default:
+		s.count++
+		s.strings++
+		s.format = "binary"
+	}
+}
+
+// jsonBody returns true if a body, without a content type, is a JSON object or array.
+func jsonBody(body []byte) bool {
+	trimmed := strings.TrimSpace(string(body))
+	return (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && newShape().observeJSON(body)
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// operation builds an operation from the observations.
+func (o *operationObservation) operation() *v3.Operation {
+	operation := &v3.Operation{}
+	for name, values := range o.pathValues.FromOldest() {
+		required := true
+		operation.Parameters = append(operation.Parameters, &v3.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: &required,
+			Schema:   base.CreateSchemaProxy(values.schema()),
+		})
+	}
+	for _, params := range []struct {
+		in     string
+		values *orderedmap.Map[string, *observedParameter]
+	}{{"query", o.query}, {"header", o.headers}} {
+		for name, p := range params.values.FromOldest() {
+			required := p.count == o.count
+			schema := p.values.schema()
+			if p.repeated {
+				schema = &base.Schema{
+					Type:  []string{"array"},
+					Items: &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(schema)},
+				}
+			}
+			operation.Parameters = append(operation.Parameters, &v3.Parameter{
+				Name:     name,
+				In:       params.in,
+				Required: &required,
+				Schema:   base.CreateSchemaProxy(schema),
+			})
+		}
+	}
+
+	if o.requestTypes.Len() > 0 {
+		required := o.bodies == o.count
+		operation.RequestBody = &v3.RequestBody{Content: mediaTypes(o.requestTypes), Required: &required}
+	}
+
+	operation.Responses = &v3.Responses{Codes: orderedmap.New[string, *v3.Response]()}
+	codes := make([]int, 0, len(o.responses))
+	for code := range o.responses {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		response := &v3.Response{Description: http.StatusText(code)}
+		if response.Description == "" {
+			response.Description = "Status " + strconv.Itoa(code)
+		}
+		if o.responses[code].Len() > 0 {
+			response.Content = mediaTypes(o.responses[code])
+		}
+		operation.Responses.Codes.Set(strconv.Itoa(code), response)
+	}
+	if len(codes) == 0 {
+		operation.Responses.Default = &v3.Response{Description: "No response was recorded"}
+	}
+	return operation
+}
+
+func mediaTypes(shapes *orderedmap.Map[string, *shape]) *orderedmap.Map[string, *v3.MediaType] {
+	content := orderedmap.New[string, *v3.MediaType]()
+	for name, s := range shapes.FromOldest() {
+		content.Set(name, &v3.MediaType{Schema: base.CreateSchemaProxy(s.schema())})
+	}
+	return content
+}
+
+// templateParameters returns the names of the parameters of a path template, in order.
+func templateParameters(path string) []string {
+	var names []string
+	for _, segment := range splitPath(path) {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+// operationId creates an operationId from a method and path template, such as 'getUsersById' for
+// 'GET /users/{id}'.
+func operationId(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(method)
+	for _, segment := range splitPath(path) {
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + segment[1:len(segment)-1]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return sb.String()
+}
+
+func setOperation(pathItem *v3.PathItem, method string, operation *v3.Operation) {
+	switch method {
+	case "get":
+		pathItem.Get = operation
+	case "put":
+		pathItem.Put = operation
+	case "post":
+		pathItem.Post = operation
+	case "delete":
+		pathItem.Delete = operation
+	case "options":
+		pathItem.Options = operation
+	case "head":
+		pathItem.Head = operation
+	case "patch":
+		pathItem.Patch = operation
+	case "trace":
+		pathItem.Trace = operation
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exchange(method, rawURL string, status int, request, response string) *Exchange {
+	u, _ := url.Parse(rawURL)
+	e := &Exchange{
+		Method:         method,
+		URL:            u,
+		RequestHeader:  http.Header{},
+		StatusCode:     status,
+		ResponseHeader: http.Header{},
+	}
+	if request != "" {
+		e.RequestHeader.Set("Content-Type", "application/json")
+		e.RequestBody = []byte(request)
+	}
+	if response != "" {
+		e.ResponseHeader.Set("Content-Type", "application/json; charset=utf-8")
+		e.ResponseBody = []byte(response)
+	}
+	return e
+}
+
+func TestInferrer_Document(t *testing.T) {
+	inferrer := NewInferrer()
+	inferrer.Add(
+		exchange("GET", "https://api.example.com/users?limit=10&tag=a&tag=b", 200,
+			"", `[{"id": 1, "name": "dave"}, {"id": 2, "name": "quobix", "email": "q@pb33f.io"}]`),
+		exchange("GET", "https://api.example.com/users", 200, "", `[]`),
+		exchange("GET", "https://api.example.com/users/1", 200, "", `{"id": 1, "name": "dave", "manager": null}`),
+		exchange("GET", "https://api.example.com/users/2", 200, "", `{"id": 2, "name": "quobix", "manager": 1}`),
+		exchange("GET", "https://api.example.com/users/99", 404, "", `{"error": "not found"}`),
+		exchange("POST", "https://api.example.com/users", 201, `{"name": "pb33f"}`, `{"id": 3, "name": "pb33f"}`),
+		exchange("DELETE", "https://api.example.com/users/3", 204, "", ""),
+		exchange("CONNECT", "https://api.example.com/users", 200, "", ""),
+	)
+	inferrer.Add(nil)
+
+	doc := inferrer.Document()
+	assert.Equal(t, "3.1.0", doc.Version)
+	assert.Equal(t, DefaultTitle, doc.Info.Title)
+	require.Len(t, doc.Servers, 1)
+	assert.Equal(t, "https://api.example.com", doc.Servers[0].URL)
+
+	require.Equal(t, 2, doc.Paths.PathItems.Len())
+	users := doc.Paths.PathItems.GetOrZero("/users")
+	require.NotNil(t, users)
+	user := doc.Paths.PathItems.GetOrZero("/users/{id}")
+	require.NotNil(t, user)
+
+	// query parameters are optional if they were not always sent, and repeated ones are arrays.
+	list := users.Get
+	assert.Equal(t, "getUsers", list.OperationId)
+	require.Len(t, list.Parameters, 2)
+	assert.Equal(t, "limit", list.Parameters[0].Name)
+	assert.Equal(t, "query", list.Parameters[0].In)
+	assert.False(t, *list.Parameters[0].Required)
+	assert.Equal(t, []string{"integer"}, list.Parameters[0].Schema.Schema().Type)
+	assert.Equal(t, []string{"array"}, list.Parameters[1].Schema.Schema().Type)
+
+	items := list.Responses.Codes.GetOrZero("200").Content.GetOrZero("application/json").Schema.Schema().Items.A.Schema()
+	assert.Equal(t, []string{"id", "name"}, items.Required)
+	assert.Equal(t, "email", items.Properties.GetOrZero("email").Schema().Format)
+
+	// path parameters are always required, and typed from their values.
+	get := user.Get
+	assert.Equal(t, "getUsersById", get.OperationId)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "id", get.Parameters[0].Name)
+	assert.Equal(t, "path", get.Parameters[0].In)
+	assert.True(t, *get.Parameters[0].Required)
+	assert.Equal(t, []string{"integer"}, get.Parameters[0].Schema.Schema().Type)
+
+	// responses are grouped by status code.
+	var codes []string
+	for code := range get.Responses.Codes.KeysFromOldest() {
+		codes = append(codes, code)
+	}
+	assert.Equal(t, []string{"200", "404"}, codes)
+	assert.Equal(t, "Not Found", get.Responses.Codes.GetOrZero("404").Description)
+	manager := get.Responses.Codes.GetOrZero("200").Content.GetOrZero("application/json").Schema.Schema().
+		Properties.GetOrZero("manager").Schema()
+	assert.Equal(t, []string{"integer", "null"}, manager.Type)
+
+	create := users.Post
+	assert.True(t, *create.RequestBody.Required)
+	assert.Equal(t, []string{"name"}, create.RequestBody.Content.GetOrZero("application/json").Schema.Schema().Required)
+
+	remove := user.Delete
+	assert.Nil(t, remove.Responses.Codes.GetOrZero("204").Content)
+	assert.Equal(t, "No Content", remove.Responses.Codes.GetOrZero("204").Description)
+}
+
+func TestInferrer_Render(t *testing.T) {
+	inferrer := NewInferrer()
+	inferrer.SetInfo(&base.Info{Title: "Legacy", Version: "0.1.0"})
+	inferrer.Add(
+		exchange("GET", "http://legacy.local/accounts/3fa85f64-5717-4562-b3fc-2c963f66afa6/invoices/17", 200,
+			"", `{"total": 10.5, "issued": "2024-01-31"}`),
+		exchange("PUT", "http://legacy.local/accounts/3fa85f64-5717-4562-b3fc-2c963f66afa6", 200,
+			`{"name": "pb33f", "active": true}`, ""),
+	)
+
+	rendered, err := inferrer.Document().Render()
+	require.NoError(t, err)
+	assert.Contains(t, string(rendered), "openapi: 3.1.0")
+	assert.Contains(t, string(rendered), "/accounts/{id}/invoices/{invoiceId}:")
+	assert.Contains(t, string(rendered), "operationId: putAccountsById")
+
+	// the rendered document can be read back, and is valid.
+	doc, err := libopenapi.NewDocument(rendered)
+	require.NoError(t, err)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	assert.Equal(t, "Legacy", model.Model.Info.Title)
+
+	invoice := model.Model.Paths.PathItems.GetOrZero("/accounts/{id}/invoices/{invoiceId}").Get
+	require.NotNil(t, invoice)
+	assert.Equal(t, "uuid", invoice.Parameters[0].Schema.Schema().Format)
+	schema := invoice.Responses.Codes.GetOrZero("200").Content.GetOrZero("application/json").Schema.Schema()
+	assert.Equal(t, []string{"number"}, schema.Properties.GetOrZero("total").Schema().Type)
+	assert.Equal(t, "date", schema.Properties.GetOrZero("issued").Schema().Format)
+}
+
+func TestInferrer_Bodies(t *testing.T) {
+	form := exchange("POST", "/login", 200, "", "")
+	form.RequestHeader.Set("Content-Type", "application/x-www-form-urlencoded")
+	form.RequestHeader.Set("X-Api-Version", "2")
+	form.RequestHeader.Set("User-Agent", "curl")
+	form.RequestBody = []byte("user=dave&remember=true")
+
+	text := exchange("POST", "/login", 400, "", "")
+	text.ResponseHeader.Set("Content-Type", "text/plain")
+	text.ResponseBody = []byte("bad request")
+
+	binary := exchange("GET", "/avatar", 200, "", "")
+	binary.ResponseHeader.Set("Content-Type", "image/png")
+	binary.ResponseBody = []byte{0x89, 0x50, 0x4e, 0x47}
+
+	untyped := exchange("GET", "/status", 200, "", "")
+	untyped.ResponseBody = []byte(`{"up": true}`)
+
+	unanswered := exchange("GET", "/timeout", 0, "", "")
+
+	inferrer := NewInferrer()
+	inferrer.Add(form, text, binary, untyped, unanswered)
+	doc := inferrer.Document()
+	assert.Empty(t, doc.Servers)
+
+	login := doc.Paths.PathItems.GetOrZero("/login").Post
+	require.Len(t, login.Parameters, 1)
+	assert.Equal(t, "X-Api-Version", login.Parameters[0].Name)
+	assert.Equal(t, "header", login.Parameters[0].In)
+	assert.False(t, *login.RequestBody.Required)
+	formSchema := login.RequestBody.Content.GetOrZero("application/x-www-form-urlencoded").Schema.Schema()
+	assert.Equal(t, []string{"remember", "user"}, formSchema.Required)
+	assert.Equal(t, []string{"string"}, formSchema.Properties.GetOrZero("remember").Schema().Type)
+	assert.Equal(t, []string{"string"},
+		login.Responses.Codes.GetOrZero("400").Content.GetOrZero("text/plain").Schema.Schema().Type)
+
+	avatar := doc.Paths.PathItems.GetOrZero("/avatar").Get
+	assert.Equal(t, "binary",
+		avatar.Responses.Codes.GetOrZero("200").Content.GetOrZero("image/png").Schema.Schema().Format)
+
+	status := doc.Paths.PathItems.GetOrZero("/status").Get
+	assert.NotNil(t, status.Responses.Codes.GetOrZero("200").Content.GetOrZero("application/json"))
+
+	timeout := doc.Paths.PathItems.GetOrZero("/timeout").Get
+	assert.Equal(t, 0, timeout.Responses.Codes.Len())
+	assert.Equal(t, "No response was recorded", timeout.Responses.Default.Description)
+}
+
+func TestInferrer_LiteralThreshold(t *testing.T) {
+	inferrer := NewInferrer()
+	inferrer.SetLiteralThreshold(1)
+	inferrer.Add(
+		exchange("GET", "/docs/readme", 200, "", ""),
+		exchange("GET", "/docs/license", 200, "", ""),
+	)
+	doc := inferrer.Document()
+	require.Equal(t, 1, doc.Paths.PathItems.Len())
+	get := doc.Paths.PathItems.GetOrZero("/docs/{id}").Get
+	require.NotNil(t, get)
+	assert.Equal(t, []string{"string"}, get.Parameters[0].Schema.Schema().Type)
+}
+
+func TestOperationId(t *testing.T) {
+	assert.Equal(t, "getUsersById", operationId("get", "/users/{id}"))
+	assert.Equal(t, "postOrdersByIdOrderItems", operationId("post", "/orders/{id}/order-items"))
+	assert.Equal(t, "get", operationId("get", "/"))
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultLiteralThreshold is the number of distinct literal segments a path segment can have before they are
+// generalised into a parameter.
+const DefaultLiteralThreshold = 10
+
+var (
+	numericSegment = regexp.MustCompile(`^-?\d+$`)
+	hexSegment     = regexp.MustCompile(`^[0-9a-fA-F]{8,}$`)
+	tokenSegment   = regexp.MustCompile(`^[0-9A-Za-z_\-.~]{16,}$`)
+	digitPattern   = regexp.MustCompile(`\d`)
+	letterPattern  = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// pathNode is a segment of the paths observed, in a trie of every path. A segment is either a literal, held by
+// its parent in literals, or a parameter, held by its parent as param.
+type pathNode struct {
+	literals map[string]*pathNode
+	order    []string
+	param    *pathNode
+	name     string // name of the parameter, if the node is one.
+}
+
+func newPathNode() *pathNode {
+	return &pathNode{literals: make(map[string]*pathNode)}
+}
+
+// splitPath splits a path into its segments, ignoring empty ones.
+func splitPath(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// insert adds the segments of a path to the trie. Segments that look like values, such as numbers, UUIDs, dates or
+// long tokens, are added as parameters.
+func (n *pathNode) insert(segments []string) {
+	node := n
+	for _, segment := range segments {
+		if isVariableSegment(segment) {
+			if node.param == nil {
+				node.param = newPathNode()
+			}
+			node = node.param
+			continue
+		}
+		child, ok := node.literals[segment]
+		if !ok {
+			child = newPathNode()
+			node.literals[segment] = child
+			node.order = append(node.order, segment)
+		}
+		node = child
+	}
+}
+
+// generalise merges the literal children of every node that has more than threshold of them into a parameter.
+func (n *pathNode) generalise(threshold int) {
+	if len(n.literals) > threshold {
+		if n.param == nil {
+			n.param = newPathNode()
+		}
+		for _, segment := range n.order {
+			n.param.merge(n.literals[segment])
+		}
+		n.literals = make(map[string]*pathNode)
+		n.order = nil
+	}
+	for _, segment := range n.order {
+		n.literals[segment].generalise(threshold)
+	}
+	if n.param != nil {
+		n.param.generalise(threshold)
+	}
+}
+
+// merge merges another trie into the node.
+func (n *pathNode) merge(other *pathNode) {
+	for _, segment := range other.order {
+		child, ok := n.literals[segment]
+		if !ok {
+			n.literals[segment] = other.literals[segment]
+			n.order = append(n.order, segment)
+			continue
+		}
+		child.merge(other.literals[segment])
+	}
+	if other.param != nil {
+		if n.param == nil {
+			n.param = other.param
+		} else {
+			n.param.merge(other.param)
+		}
+	}
+}
+
+// nameParameters names the parameters of the trie. The first parameter of a path is named 'id', and those after it
+// are named after the literal segment before them, such as 'postId' for '/users/{id}/posts/{postId}'.
+func (n *pathNode) nameParameters(previous string, used map[string]bool) {
+	for _, segment := range n.order {
+		n.literals[segment].nameParameters(segment, used)
+	}
+	if n.param == nil {
+		return
+	}
+	name := "id"
+	if len(used) > 0 && previous != "" {
+		name = parameterName(previous)
+	}
+	names := make(map[string]bool, len(used)+1)
+	for k := range used {
+		names[k] = true
+	}
+	n.param.name = uniqueName(name, names)
+	n.param.nameParameters("", names)
+}
+
+// template returns the template of a path, and the values of its parameters by name. It returns false if the path
+// was not inserted into the trie.
+func (n *pathNode) template(segments []string) (string, map[string]string, bool) {
+	var sb strings.Builder
+	values := make(map[string]string)
+	node := n
+	for _, segment := range segments {
+		if child, ok := node.literals[segment]; ok {
+			sb.WriteString("/" + segment)
+			node = child
+			continue
+		}
+		if node.param == nil {
+			return "", nil, false
+		}
+		node = node.param
+		sb.WriteString("/{" + node.name + "}")
+		values[node.name] = segment
+	}
+	if sb.Len() == 0 {
+		return "/", values, true
+	}
+	return sb.String(), values, true
+}
+
+// isVariableSegment returns true if a path segment looks like a value rather than a name.
+func isVariableSegment(segment string) bool {
+	switch {
+	case numericSegment.MatchString(segment),
+		uuidPattern.MatchString(segment),
+		datePattern.MatchString(segment):
+		return true
+	case hexSegment.MatchString(segment) && digitPattern.MatchString(segment):
+		return true
+	case tokenSegment.MatchString(segment):
+		return digitPattern.MatchString(segment) && letterPattern.MatchString(segment)
+	}
+	return false
+}
+
+// parameterName returns the name of a parameter that follows a literal segment, the singular of the segment
+// followed by 'Id', such as 'orderItemId' for 'order-items'.
+func parameterName(segment string) string {
+	words := strings.FieldsFunc(segment, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "id"
+	}
+	words[len(words)-1] = singular(words[len(words)-1])
+	var sb strings.Builder
+	for i, w := range words {
+		if i == 0 {
+			sb.WriteString(strings.ToLower(w[:1]) + w[1:])
+			continue
+		}
+		sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return sb.String() + "Id"
+}
+
+// singular returns the singular of an English plural, for the common cases.
+func singular(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "s") && len(word) > 1 && !strings.HasSuffix(lower, "ss") &&
+		!strings.HasSuffix(lower, "us") && !strings.HasSuffix(lower, "is"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// uniqueName returns name, or name followed by a number if it is already used, and marks it as used.
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	used[candidate] = true
+	return candidate
+}
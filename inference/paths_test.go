@@ -0,0 +1,97 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildPathTrie(threshold int, paths ...string) *pathNode {
+	root := newPathNode()
+	for _, p := range paths {
+		root.insert(splitPath(p))
+	}
+	root.generalise(threshold)
+	root.nameParameters("", map[string]bool{})
+	return root
+}
+
+func TestPathNode_Template(t *testing.T) {
+	root := buildPathTrie(DefaultLiteralThreshold,
+		"/users/123",
+		"/users/me",
+		"/users/42/posts/7",
+		"/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6/order-items/9",
+		"/reports/2024-01-31",
+		"/",
+	)
+
+	for path, expected := range map[string]string{
+		"/users/123":         "/users/{id}",
+		"/users/99":          "/users/{id}",
+		"/users/me":          "/users/me",
+		"/users/42/posts/7/": "/users/{id}/posts/{postId}",
+		"/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6/order-items/9": "/orders/{id}/order-items/{orderItemId}",
+		"/reports/2024-01-31": "/reports/{id}",
+		"/":                   "/",
+	} {
+		template, _, ok := root.template(splitPath(path))
+		assert.True(t, ok, path)
+		assert.Equal(t, expected, template, path)
+	}
+
+	_, values, _ := root.template(splitPath("/users/42/posts/7"))
+	assert.Equal(t, map[string]string{"id": "42", "postId": "7"}, values)
+
+	_, _, ok := root.template(splitPath("/unknown"))
+	assert.False(t, ok)
+}
+
+func TestPathNode_Generalise(t *testing.T) {
+	var paths []string
+	for i := 0; i < 4; i++ {
+		paths = append(paths, fmt.Sprintf("/files/file-%c/content", 'a'+i))
+	}
+	paths = append(paths, "/files/search")
+
+	// below the threshold, literals are kept.
+	root := buildPathTrie(5, paths...)
+	template, _, _ := root.template(splitPath("/files/file-a/content"))
+	assert.Equal(t, "/files/file-a/content", template)
+
+	// above it, they are merged into a parameter, along with everything below them.
+	root = buildPathTrie(4, paths...)
+	template, _, _ = root.template(splitPath("/files/file-a/content"))
+	assert.Equal(t, "/files/{id}/content", template)
+	template, _, _ = root.template(splitPath("/files/search"))
+	assert.Equal(t, "/files/{id}", template)
+}
+
+func TestPathNode_NestedParameters(t *testing.T) {
+	root := buildPathTrie(DefaultLiteralThreshold, "/1/2/items/3")
+	template, _, _ := root.template(splitPath("/1/2/items/3"))
+	assert.Equal(t, "/{id}/{id2}/items/{itemId}", template)
+}
+
+func TestIsVariableSegment(t *testing.T) {
+	for _, s := range []string{"123", "-1", "3fa85f64-5717-4562-b3fc-2c963f66afa6", "2024-01-31",
+		"5f2b8c1e9a", "a1B2c3D4e5F6g7H8i9"} {
+		assert.True(t, isVariableSegment(s), s)
+	}
+	for _, s := range []string{"users", "v1", "oauth2", "deadbeef", "order-items", "very-long-literal-name"} {
+		assert.False(t, isVariableSegment(s), s)
+	}
+}
+
+func TestParameterName(t *testing.T) {
+	assert.Equal(t, "userId", parameterName("users"))
+	assert.Equal(t, "categoryId", parameterName("categories"))
+	assert.Equal(t, "addressId", parameterName("addresses"))
+	assert.Equal(t, "orderItemId", parameterName("order-items"))
+	assert.Equal(t, "statusId", parameterName("status"))
+	assert.Equal(t, "id", parameterName("--"))
+}
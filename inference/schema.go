@@ -0,0 +1,238 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	datePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// shape is the merged shape of every value observed at one location, such as a property of a JSON body or a query
+// parameter. Each kind of value is counted, so that unions, optionality and nullability can be inferred.
+type shape struct {
+	count    int // values observed, including nulls.
+	nulls    int
+	booleans int
+	integers int
+	int64s   bool // an integer did not fit in 32 bits.
+	numbers  int
+	strings  int
+	format   string // format matched by every string, empty if there is none.
+	objects  int
+	props    *orderedmap.Map[string, *shape]
+	arrays   int
+	items    *shape
+}
+
+func newShape() *shape {
+	return &shape{props: orderedmap.New[string, *shape]()}
+}
+
+// observeJSON decodes a JSON document and merges it into the shape. It returns false if the data is not valid JSON.
+func (s *shape) observeJSON(data []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return false
+	}
+	s.observe(value)
+	return true
+}
+
+// observe merges a decoded JSON value into the shape.
+func (s *shape) observe(value any) {
+	s.count++
+	switch v := value.(type) {
+	case nil:
+		s.nulls++
+	case bool:
+		s.booleans++
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			s.observeInteger(i)
+		} else {
+			s.numbers++
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			s.observeInteger(int64(v))
+		} else {
+			s.numbers++
+		}
+	case string:
+		s.observeString(v)
+	case map[string]any:
+		s.objects++
+		// properties are ordered by name, as decoded objects do not keep the order of their keys.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			prop := s.props.GetOrZero(k)
+			if prop == nil {
+				prop = newShape()
+				s.props.Set(k, prop)
+			}
+			prop.observe(v[k])
+		}
+	case []any:
+		s.arrays++
+		if s.items == nil {
+			s.items = newShape()
+		}
+		for _, item := range v {
+			s.items.observe(item)
+		}
+	}
+}
+
+// observeText merges a value that was observed as text, such as a query parameter, into the shape. Integers,
+// numbers and booleans are recognised, anything else is a string.
+func (s *shape) observeText(text string) {
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		s.count++
+		s.observeInteger(i)
+		return
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		s.count++
+		s.numbers++
+		return
+	}
+	if text == "true" || text == "false" {
+		s.count++
+		s.booleans++
+		return
+	}
+	s.observe(text)
+}
+
+func (s *shape) observeInteger(i int64) {
+	s.integers++
+	if i > math.MaxInt32 || i < math.MinInt32 {
+		s.int64s = true
+	}
+}
+
+func (s *shape) observeString(v string) {
+	format := stringFormat(v)
+	switch {
+	case s.strings == 0:
+		s.format = format
+	case s.format != format:
+		s.format = ""
+	}
+	s.strings++
+}
+
+// stringFormat returns the format of a string, if it has a recognised one.
+func stringFormat(v string) string {
+	switch {
+	case uuidPattern.MatchString(v):
+		return "uuid"
+	case datePattern.MatchString(v):
+		if _, err := time.Parse(time.DateOnly, v); err == nil {
+			return "date"
+		}
+	case emailPattern.MatchString(v):
+		return "email"
+	}
+	if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return "date-time"
+	}
+	if ip := net.ParseIP(v); ip != nil {
+		if ip.To4() != nil && strings.Contains(v, ".") {
+			return "ipv4"
+		}
+		return "ipv6"
+	}
+	if u, err := url.Parse(v); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return "uri"
+	}
+	return ""
+}
+
+// schema builds a schema from the shape. A single kind of value becomes a typed schema, multiple kinds become a
+// oneOf. Null values are merged into the type of the schema, or into a variant of the oneOf. Properties are required
+// when they were present in every object observed.
+func (s *shape) schema() *base.Schema {
+	var variants []*base.Schema
+	if s.booleans > 0 {
+		variants = append(variants, &base.Schema{Type: []string{"boolean"}})
+	}
+	switch {
+	case s.numbers > 0:
+		variants = append(variants, &base.Schema{Type: []string{"number"}})
+	case s.integers > 0:
+		format := "int32"
+		if s.int64s {
+			format = "int64"
+		}
+		variants = append(variants, &base.Schema{Type: []string{"integer"}, Format: format})
+	}
+	if s.strings > 0 {
+		variants = append(variants, &base.Schema{Type: []string{"string"}, Format: s.format})
+	}
+	if s.objects > 0 {
+		object := &base.Schema{Type: []string{"object"}}
+		if s.props.Len() > 0 {
+			object.Properties = orderedmap.New[string, *base.SchemaProxy]()
+			for name, prop := range s.props.FromOldest() {
+				object.Properties.Set(name, base.CreateSchemaProxy(prop.schema()))
+				if prop.count == s.objects {
+					object.Required = append(object.Required, name)
+				}
+			}
+		}
+		variants = append(variants, object)
+	}
+	if s.arrays > 0 {
+		array := &base.Schema{Type: []string{"array"}}
+		if s.items != nil && s.items.count > 0 {
+			array.Items = &base.DynamicValue[*base.SchemaProxy, bool]{A: base.CreateSchemaProxy(s.items.schema())}
+		}
+		variants = append(variants, array)
+	}
+
+	switch len(variants) {
+	case 0:
+		if s.nulls > 0 {
+			return &base.Schema{Type: []string{"null"}}
+		}
+		return &base.Schema{}
+	case 1:
+		if s.nulls > 0 {
+			variants[0].Type = append(variants[0].Type, "null")
+		}
+		return variants[0]
+	}
+	if s.nulls > 0 {
+		variants = append(variants, &base.Schema{Type: []string{"null"}})
+	}
+	union := &base.Schema{}
+	for _, v := range variants {
+		union.OneOf = append(union.OneOf, base.CreateSchemaProxy(v))
+	}
+	return union
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package inference
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// renderShape observes JSON documents, and renders the schema inferred from them.
+func renderShape(t *testing.T, documents ...string) string {
+	s := newShape()
+	for _, d := range documents {
+		require.True(t, s.observeJSON([]byte(d)), d)
+	}
+	b, err := yaml.Marshal(s.schema())
+	require.NoError(t, err)
+	return strings.TrimSpace(string(b))
+}
+
+func TestShape_Object(t *testing.T) {
+	rendered := renderShape(t,
+		`{"id": 1, "email": "dave@pb33f.io", "created": "2024-01-31T10:00:00Z", "nickname": null, "tags": ["a"]}`,
+		`{"id": 5000000000, "email": "quobix@pb33f.io", "created": "2024-02-01T10:00:00Z", "nickname": "q"}`,
+	)
+	assert.Equal(t, `type: object
+properties:
+    created:
+        type: string
+        format: date-time
+    email:
+        type: string
+        format: email
+    id:
+        type: integer
+        format: int64
+    nickname:
+        type:
+            - string
+            - "null"
+    tags:
+        type: array
+        items:
+            type: string
+required:
+    - created
+    - email
+    - id
+    - nickname`, rendered)
+}
+
+func TestShape_Union(t *testing.T) {
+	rendered := renderShape(t, `{"value": 1}`, `{"value": "one"}`, `{"value": null}`, `{"value": {"count": 1}}`)
+	assert.Equal(t, `type: object
+properties:
+    value:
+        oneOf:
+            - type: integer
+              format: int32
+            - type: string
+            - type: object
+              properties:
+                count:
+                    type: integer
+                    format: int32
+              required:
+                - count
+            - type: "null"
+required:
+    - value`, rendered)
+}
+
+func TestShape_Numbers(t *testing.T) {
+	// integers and numbers are merged into numbers.
+	assert.Equal(t, "type: number", renderShape(t, `1`, `1.5`))
+	assert.Equal(t, "type: array\nitems:\n    type: boolean", renderShape(t, `[true, false]`))
+	assert.Equal(t, "type: array", renderShape(t, `[]`))
+	assert.Equal(t, `type: "null"`, renderShape(t, `null`))
+}
+
+func TestShape_Formats(t *testing.T) {
+	// formats are kept only when every string has them.
+	assert.Equal(t, "type: string\nformat: uuid", renderShape(t, `"3fa85f64-5717-4562-b3fc-2c963f66afa6"`))
+	assert.Equal(t, "type: string", renderShape(t, `"3fa85f64-5717-4562-b3fc-2c963f66afa6"`, `"nope"`))
+
+	for value, format := range map[string]string{
+		"2024-01-31":                  "date",
+		"2024-01-31T10:00:00.123Z":    "date-time",
+		"dave@pb33f.io":               "email",
+		"https://pb33f.io/libopenapi": "uri",
+		"192.168.0.1":                 "ipv4",
+		"::1":                         "ipv6",
+		"2024-13-45":                  "",
+		"hello":                       "",
+	} {
+		assert.Equal(t, format, stringFormat(value), value)
+	}
+}
+
+func TestShape_ObserveText(t *testing.T) {
+	s := newShape()
+	s.observeText("10")
+	s.observeText("20")
+	b, _ := yaml.Marshal(s.schema())
+	assert.Equal(t, "type: integer\nformat: int32\n", string(b))
+
+	s = newShape()
+	s.observeText("true")
+	s.observeText("2.5")
+	s.observeText("abc")
+	assert.Equal(t, 3, s.count)
+	assert.Len(t, s.schema().OneOf, 3)
+}
+
+func TestShape_InvalidJSON(t *testing.T) {
+	assert.False(t, newShape().observeJSON([]byte(`{`)))
+}
@@ -0,0 +1,643 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package libopenapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/datamodel/high"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/datamodel/low"
+	v3low "github.com/pb33f/libopenapi/datamodel/low/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// MergeCollisionStrategy decides what happens when two documents being merged declare the same component,
+// or the same operation.
+type MergeCollisionStrategy int
+
+const (
+	// MergeCollisionError fails the merge when there are any collisions, every collision is reported.
+	MergeCollisionError MergeCollisionStrategy = iota
+
+	// MergeCollisionPrefix renames colliding components, using the ComponentPrefix of the source, and rewrites the
+	// references to them. Operations can't be renamed, colliding operations keep the first one (and are reported
+	// as MergeCollisionKeepFirst).
+	MergeCollisionPrefix
+
+	// MergeCollisionKeepFirst keeps the component or operation that was merged first.
+	MergeCollisionKeepFirst
+
+	// MergeCollisionDedupe keeps the first of two identical components or operations (compared using Hash()),
+	// collisions that are not identical are handled by the DedupeFallback strategy. Hashes do not follow
+	// references, two schemas are identical if they reference components with the same names.
+	MergeCollisionDedupe
+)
+
+// MergeConfiguration configures how MergeDocuments combines documents.
+type MergeConfiguration struct {
+	// Strategy handles collisions, defaults to MergeCollisionError.
+	Strategy MergeCollisionStrategy
+
+	// DedupeFallback handles collisions that are not identical when the Strategy is MergeCollisionDedupe.
+	// It defaults to MergeCollisionError.
+	DedupeFallback MergeCollisionStrategy
+
+	// DocumentConfiguration is used to build the merged document. References to files are rewritten to be relative
+	// to its BasePath, or are made absolute if there is no BasePath. If it's nil, file and remote references are
+	// allowed if the first document allowed them.
+	DocumentConfiguration *datamodel.DocumentConfiguration
+}
+
+// MergeSource is a document to merge.
+type MergeSource struct {
+	Document Document
+
+	// PathPrefix is added to the start of every path of the document, for example '/pets'.
+	PathPrefix string
+
+	// ComponentPrefix is added to the names of colliding components when the MergeCollisionPrefix strategy is used.
+	// Defaults to 'Doc<n>' where n is the position of the source, starting at 1.
+	ComponentPrefix string
+}
+
+// MergeCollision describes a component, operation or tag that was declared by more than one document.
+type MergeCollision struct {
+	// Type is the component type (schemas, parameters etc), 'paths', 'webhooks' or 'tags'.
+	Type string
+
+	// Name is the name of the component or tag, or the method and path of an operation ('GET /pets').
+	Name string
+
+	// Source is the position of the source that collided with an earlier source.
+	Source int
+
+	// Identical is true if the colliding values have the same hash.
+	Identical bool
+
+	// Strategy is the strategy that handled the collision.
+	Strategy MergeCollisionStrategy
+
+	// RenamedTo is the new name of a renamed component.
+	RenamedTo string
+}
+
+// Error returns a description of the collision.
+func (m *MergeCollision) Error() string {
+	return fmt.Sprintf("document %d: %s '%s' already exists in another document", m.Source, m.Type, m.Name)
+}
+
+// MergeReport lists the collisions found while merging documents.
+type MergeReport struct {
+	Collisions []*MergeCollision
+}
+
+// MergeDocuments combines the paths, webhooks, components, tags, servers and security requirements of OpenAPI 3+
+// documents into a single new document. The openapi version, info, and external docs of the first document are used.
+//
+// Paths can be prefixed per source, colliding components and operations are handled using the configured strategy,
+// and references are rewritten to follow renamed components, and to keep pointing at the same files.
+//
+// The merged document is returned with a report of every collision. If the strategy is MergeCollisionError (or the
+// dedupe fallback is) and there are collisions, the report is returned with an error.
+func MergeDocuments(config *MergeConfiguration, sources ...*MergeSource) (*v3high.Document, *MergeReport, error) {
+	if config == nil {
+		config = &MergeConfiguration{}
+	}
+	if len(sources) == 0 {
+		return nil, nil, errors.New("there are no documents to merge")
+	}
+	m := &documentMerger{
+		config:   config,
+		report:   &MergeReport{},
+		merged:   make(map[string]*yaml.Node),
+		origins:  make(map[string]*v3high.Components),
+		mergedAs: make(map[string]string),
+		ops:      make(map[string]*v3high.Operation),
+	}
+
+	docConfig := config.DocumentConfiguration
+	for i, source := range sources {
+		if source == nil || source.Document == nil {
+			return nil, nil, fmt.Errorf("document %d is missing, unable to merge documents", i+1)
+		}
+		model, errs := source.Document.BuildV3Model()
+		if model == nil {
+			return nil, nil, fmt.Errorf("unable to build document %d: %w", i+1, errors.Join(errs...))
+		}
+		if docConfig == nil {
+			docConfig = datamodel.NewDocumentConfiguration()
+			if model.Index != nil && model.Index.GetConfig() != nil {
+				docConfig.AllowFileReferences = model.Index.GetConfig().AllowFileLookup
+				docConfig.AllowRemoteReferences = model.Index.GetConfig().AllowRemoteLookup
+			}
+		}
+		if err := m.merge(i+1, source, &model.Model, docConfig.BasePath); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var failed []error
+	for _, c := range m.report.Collisions {
+		if c.Strategy == MergeCollisionError {
+			failed = append(failed, c)
+		}
+	}
+	if len(failed) > 0 {
+		return nil, m.report, fmt.Errorf("unable to merge documents, %d collisions found: %w",
+			len(failed), errors.Join(failed...))
+	}
+
+	rendered, err := yaml.Marshal(m.render())
+	if err != nil {
+		return nil, m.report, err
+	}
+	info, err := datamodel.ExtractSpecInfoWithDocumentCheck(rendered, docConfig.BypassDocumentCheck)
+	if err != nil {
+		return nil, m.report, fmt.Errorf("unable to build merged document: %w", err)
+	}
+	lowDoc, err := v3low.CreateDocumentFromConfig(info, docConfig)
+	if lowDoc == nil {
+		return nil, m.report, err
+	}
+	return v3high.NewDocument(lowDoc), m.report, err
+}
+
+type documentMerger struct {
+	config *MergeConfiguration
+	report *MergeReport
+
+	// merged holds the merged values of the top level keys of the document.
+	merged map[string]*yaml.Node
+
+	// origins holds the components of the document each merged component came from, keyed by type and the
+	// merged name, and mergedAs the name it was originally declared with.
+	origins  map[string]*v3high.Components
+	mergedAs map[string]string
+
+	// ops holds every merged operation, keyed by webhook or path and method.
+	ops map[string]*v3high.Operation
+}
+
+func (m *documentMerger) merge(n int, source *MergeSource, doc *v3high.Document, basePath string) error {
+	rendered, err := doc.Render()
+	if err != nil {
+		return fmt.Errorf("unable to render document %d: %w", n, err)
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(rendered, &node); err != nil {
+		return fmt.Errorf("unable to read document %d: %w", n, err)
+	}
+	if len(node.Content) == 0 || !utils.IsNodeMap(node.Content[0]) {
+		return fmt.Errorf("document %d is empty, unable to merge documents", n)
+	}
+	root := node.Content[0]
+
+	// the first document decides the version, info and everything else that can't be merged.
+	if n == 1 {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			switch key := root.Content[i].Value; key {
+			case "openapi", "info", "jsonSchemaDialect", "externalDocs":
+				m.merged[key] = root.Content[i+1]
+			}
+		}
+	}
+
+	// work out which components will be renamed, then rewrite the references in the document to match.
+	renamed := m.mergeComponents(n, source, doc.Components, mapValue(root, "components"))
+	location := ""
+	if doc.Index != nil {
+		location = doc.Index.GetSpecAbsolutePath()
+	}
+	rewriteMergedReferences(root, renamed, location, basePath)
+	rewriteMergedSecurity(root, renamed)
+
+	m.mergeSequence("servers", mapValue(root, "servers"), "url")
+	m.mergeSequence("security", mapValue(root, "security"), "")
+	m.mergeTags(n, mapValue(root, "tags"))
+	var pathItems *orderedmap.Map[string, *v3high.PathItem]
+	if doc.Paths != nil {
+		pathItems = doc.Paths.PathItems
+	}
+	m.mergePathItems(n, "paths", source.PathPrefix, pathItems, mapValue(root, "paths"))
+	m.mergePathItems(n, "webhooks", "", doc.Webhooks, mapValue(root, "webhooks"))
+	return nil
+}
+
+// mergeComponents adds the components of a document to the merged components, returning the components that
+// have been renamed, keyed by their original definition.
+func (m *documentMerger) mergeComponents(n int, source *MergeSource, components *v3high.Components,
+	node *yaml.Node,
+) map[string]string {
+	renamed := make(map[string]string)
+	if !utils.IsNodeMap(node) {
+		return renamed
+	}
+	prefix := source.ComponentPrefix
+	if prefix == "" {
+		prefix = fmt.Sprintf("Doc%d", n)
+	}
+	merged := m.mergedMap("components")
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		componentType, values := node.Content[i].Value, node.Content[i+1]
+		if !utils.IsNodeMap(values) {
+			continue
+		}
+		if strings.HasPrefix(componentType, "x-") {
+			if mapValue(merged, componentType) == nil {
+				merged.Content = append(merged.Content, node.Content[i], values)
+			}
+			continue
+		}
+		target := mapValue(merged, componentType)
+		if target == nil {
+			target = utils.CreateEmptyMapNode()
+			merged.Content = append(merged.Content, utils.CreateStringNode(componentType), target)
+		}
+		for j := 0; j+1 < len(values.Content); j += 2 {
+			name := values.Content[j].Value
+			key := componentType + "/" + name
+			if mapValue(target, name) == nil {
+				target.Content = append(target.Content, values.Content[j], values.Content[j+1])
+				m.origins[key], m.mergedAs[key] = components, name
+				continue
+			}
+
+			collision := &MergeCollision{Type: componentType, Name: name, Source: n}
+			collision.Identical = componentHash(m.origins[key], componentType, m.mergedAs[key]) ==
+				componentHash(components, componentType, name)
+			collision.Strategy = m.resolve(collision.Identical)
+			m.report.Collisions = append(m.report.Collisions, collision)
+			if collision.Strategy != MergeCollisionPrefix {
+				continue
+			}
+
+			newName := prefix + name
+			for c := 2; mapValue(target, newName) != nil; c++ {
+				newName = fmt.Sprintf("%s%s%d", prefix, name, c)
+			}
+			collision.RenamedTo = newName
+			renamed["#/components/"+componentType+"/"+name] = "#/components/" + componentType + "/" + newName
+			target.Content = append(target.Content, utils.CreateStringNode(newName), values.Content[j+1])
+			m.origins[componentType+"/"+newName], m.mergedAs[componentType+"/"+newName] = components, name
+		}
+	}
+	return renamed
+}
+
+// resolve returns the strategy that handles a collision.
+func (m *documentMerger) resolve(identical bool) MergeCollisionStrategy {
+	if m.config.Strategy != MergeCollisionDedupe || identical {
+		return m.config.Strategy
+	}
+	if m.config.DedupeFallback == MergeCollisionDedupe {
+		return MergeCollisionError
+	}
+	return m.config.DedupeFallback
+}
+
+// mergePathItems adds the path items of a document (or webhooks) to the merged path items. Path items that already
+// exist have the operations they don't have added to them.
+func (m *documentMerger) mergePathItems(n int, key, prefix string,
+	pathItems *orderedmap.Map[string, *v3high.PathItem], node *yaml.Node,
+) {
+	if !utils.IsNodeMap(node) || pathItems == nil {
+		return
+	}
+	merged := m.mergedMap(key)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, item := node.Content[i].Value, node.Content[i+1]
+		pathItem := pathItems.GetOrZero(name)
+		mergedName := name
+		if prefix != "" {
+			mergedName = strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(name, "/")
+		}
+		existing := mapValue(merged, mergedName)
+		if existing == nil {
+			merged.Content = append(merged.Content, utils.CreateStringNode(mergedName), item)
+			if pathItem != nil {
+				for method, op := range pathItem.GetOperations().FromOldest() {
+					m.ops[key+" "+mergedName+" "+method] = op
+				}
+			}
+			continue
+		}
+		if pathItem == nil || !utils.IsNodeMap(existing) || !utils.IsNodeMap(item) {
+			continue
+		}
+
+		// merge the operations, and anything else the existing path item doesn't have.
+		for j := 0; j+1 < len(item.Content); j += 2 {
+			field := item.Content[j].Value
+			if mapValue(existing, field) == nil {
+				existing.Content = append(existing.Content, item.Content[j], item.Content[j+1])
+				if op := pathItem.GetOperations().GetOrZero(strings.ToLower(field)); op != nil {
+					m.ops[key+" "+mergedName+" "+strings.ToLower(field)] = op
+				}
+				continue
+			}
+			op := pathItem.GetOperations().GetOrZero(strings.ToLower(field))
+			if op == nil {
+				continue
+			}
+			collision := &MergeCollision{
+				Type: key, Name: fmt.Sprintf("%s %s", strings.ToUpper(field), mergedName), Source: n,
+			}
+			if first := m.ops[key+" "+mergedName+" "+strings.ToLower(field)]; first != nil {
+				collision.Identical = first.GoLow().Hash() == op.GoLow().Hash()
+			}
+			collision.Strategy = m.resolve(collision.Identical)
+			if collision.Strategy == MergeCollisionPrefix {
+				// operations can't be renamed, the first one is kept.
+				collision.Strategy = MergeCollisionKeepFirst
+			}
+			m.report.Collisions = append(m.report.Collisions, collision)
+		}
+	}
+}
+
+// mergeTags adds the tags of a document to the merged tags, the first tag with a name is kept.
+func (m *documentMerger) mergeTags(n int, node *yaml.Node) {
+	if !utils.IsNodeArray(node) {
+		return
+	}
+	merged := m.mergedSequence("tags")
+	for _, tag := range node.Content {
+		_, name := utils.FindKeyNodeTop("name", tag.Content)
+		if name == nil {
+			continue
+		}
+		var existing int
+		found := false
+		for e, t := range merged.Content {
+			if _, en := utils.FindKeyNodeTop("name", t.Content); en != nil && en.Value == name.Value {
+				existing, found = e, true
+				break
+			}
+		}
+		if !found {
+			merged.Content = append(merged.Content, tag)
+			continue
+		}
+		// tags that are the same are not collisions.
+		if equalNodes(merged.Content[existing], tag) {
+			continue
+		}
+		m.report.Collisions = append(m.report.Collisions, &MergeCollision{
+			Type: "tags", Name: name.Value, Source: n, Strategy: MergeCollisionKeepFirst,
+		})
+	}
+}
+
+// mergeSequence adds the items of a sequence to a merged sequence, skipping items that already exist. If a key is
+// supplied, items are compared using the value of the key, otherwise by their content.
+func (m *documentMerger) mergeSequence(name string, node *yaml.Node, key string) {
+	if !utils.IsNodeArray(node) {
+		return
+	}
+	merged := m.mergedSequence(name)
+	for _, item := range node.Content {
+		exists := false
+		for _, e := range merged.Content {
+			if key != "" {
+				_, a := utils.FindKeyNodeTop(key, e.Content)
+				_, b := utils.FindKeyNodeTop(key, item.Content)
+				exists = a != nil && b != nil && a.Value == b.Value
+			} else {
+				exists = equalNodes(e, item)
+			}
+			if exists {
+				break
+			}
+		}
+		if !exists {
+			merged.Content = append(merged.Content, item)
+		}
+	}
+}
+
+func (m *documentMerger) mergedMap(key string) *yaml.Node {
+	if m.merged[key] == nil {
+		m.merged[key] = utils.CreateEmptyMapNode()
+	}
+	return m.merged[key]
+}
+
+func (m *documentMerger) mergedSequence(key string) *yaml.Node {
+	if m.merged[key] == nil {
+		m.merged[key] = utils.CreateEmptySequenceNode()
+	}
+	return m.merged[key]
+}
+
+// render creates the merged document node.
+func (m *documentMerger) render() *yaml.Node {
+	root := utils.CreateEmptyMapNode()
+	for _, key := range []string{
+		"openapi", "info", "jsonSchemaDialect", "servers", "security", "tags", "paths", "webhooks", "components",
+		"externalDocs",
+	} {
+		if m.merged[key] != nil {
+			root.Content = append(root.Content, utils.CreateStringNode(key), m.merged[key])
+		}
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+}
+
+// rewriteMergedReferences rewrites references (and discriminator mappings) to renamed components, and makes
+// references to other files relative to the base path of the merged document.
+func rewriteMergedReferences(node *yaml.Node, renamed map[string]string, location, basePath string) {
+	if node == nil {
+		return
+	}
+	if utils.IsNodeMap(node) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if key == "$ref" && utils.IsNodeStringValue(value) {
+				value.Value = rewriteMergedReference(value.Value, renamed, location, basePath)
+				continue
+			}
+			if key == "discriminator" && utils.IsNodeMap(value) {
+				if _, mapping := utils.FindKeyNodeTop("mapping", value.Content); utils.IsNodeMap(mapping) {
+					for j := 1; j < len(mapping.Content); j += 2 {
+						v := mapping.Content[j].Value
+						if r, ok := renamed["#/components/schemas/"+v]; ok && !strings.Contains(v, "/") {
+							mapping.Content[j].Value = strings.TrimPrefix(r, "#/components/schemas/")
+						} else {
+							mapping.Content[j].Value = rewriteMergedReference(v, renamed, location, basePath)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, n := range node.Content {
+		rewriteMergedReferences(n, renamed, location, basePath)
+	}
+}
+
+// rewriteMergedSecurity renames the security schemes used by the security requirements of a document, and of its
+// operations, to follow renamed schemes. Security requirements use the names of schemes, not references.
+func rewriteMergedSecurity(root *yaml.Node, renamed map[string]string) {
+	schemes := make(map[string]string)
+	for from, to := range renamed {
+		if name, ok := strings.CutPrefix(from, "#/components/securitySchemes/"); ok {
+			schemes[name] = strings.TrimPrefix(to, "#/components/securitySchemes/")
+		}
+	}
+	if len(schemes) == 0 {
+		return
+	}
+	renameSecurityRequirements(mapValue(root, "security"), schemes)
+	renamePathItemSecurity(mapValue(root, "paths"), schemes)
+	renamePathItemSecurity(mapValue(root, "webhooks"), schemes)
+	if components := mapValue(root, "components"); utils.IsNodeMap(components) {
+		renamePathItemSecurity(mapValue(components, "pathItems"), schemes)
+		if callbacks := mapValue(components, "callbacks"); utils.IsNodeMap(callbacks) {
+			for i := 1; i < len(callbacks.Content); i += 2 {
+				renamePathItemSecurity(callbacks.Content[i], schemes)
+			}
+		}
+	}
+}
+
+// renamePathItemSecurity renames the schemes used by the operations (and their callbacks) of a map of path items.
+func renamePathItemSecurity(pathItems *yaml.Node, schemes map[string]string) {
+	if !utils.IsNodeMap(pathItems) {
+		return
+	}
+	for i := 1; i < len(pathItems.Content); i += 2 {
+		pathItem := pathItems.Content[i]
+		if !utils.IsNodeMap(pathItem) {
+			continue
+		}
+		for j := 1; j < len(pathItem.Content); j += 2 {
+			operation := pathItem.Content[j]
+			if !utils.IsNodeMap(operation) {
+				continue
+			}
+			renameSecurityRequirements(mapValue(operation, "security"), schemes)
+			if callbacks := mapValue(operation, "callbacks"); utils.IsNodeMap(callbacks) {
+				for k := 1; k < len(callbacks.Content); k += 2 {
+					renamePathItemSecurity(callbacks.Content[k], schemes)
+				}
+			}
+		}
+	}
+}
+
+// renameSecurityRequirements renames the schemes used by a sequence of security requirements.
+func renameSecurityRequirements(requirements *yaml.Node, schemes map[string]string) {
+	if requirements == nil || requirements.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, requirement := range requirements.Content {
+		if !utils.IsNodeMap(requirement) {
+			continue
+		}
+		for i := 0; i+1 < len(requirement.Content); i += 2 {
+			if to, ok := schemes[requirement.Content[i].Value]; ok {
+				requirement.Content[i].Value = to
+			}
+		}
+	}
+}
+
+func rewriteMergedReference(ref string, renamed map[string]string, location, basePath string) string {
+	file, fragment, hasFragment := strings.Cut(ref, "#")
+	if file == "" {
+		// a local reference, it may need to follow a renamed component.
+		for from, to := range renamed {
+			if ref == from || strings.HasPrefix(ref, from+"/") {
+				return to + strings.TrimPrefix(ref, from)
+			}
+		}
+		return ref
+	}
+	if strings.HasPrefix(file, "http") || location == "" || !strings.ContainsAny(file, "./") {
+		return ref
+	}
+	if strings.HasPrefix(location, "http") {
+		if base, err := url.Parse(location); err == nil {
+			if rel, rErr := url.Parse(file); rErr == nil {
+				file = base.ResolveReference(rel).String()
+			}
+		}
+	} else if !filepath.IsAbs(file) {
+		file, _ = filepath.Abs(filepath.Join(filepath.Dir(location), file))
+		if basePath != "" {
+			if abs, err := filepath.Abs(basePath); err == nil {
+				if rel, err := filepath.Rel(abs, file); err == nil {
+					file = filepath.ToSlash(rel)
+				}
+			}
+		}
+	}
+	if hasFragment {
+		return file + "#" + fragment
+	}
+	return file
+}
+
+// componentHash returns the hash of a component, or an empty hash if it can't be found.
+func componentHash(components *v3high.Components, componentType, name string) [32]byte {
+	if components == nil {
+		return [32]byte{}
+	}
+	var value any
+	switch componentType {
+	case v3low.SchemasLabel:
+		value = components.Schemas.GetOrZero(name)
+	case v3low.ResponsesLabel:
+		value = components.Responses.GetOrZero(name)
+	case v3low.ParametersLabel:
+		value = components.Parameters.GetOrZero(name)
+	case "examples":
+		value = components.Examples.GetOrZero(name)
+	case v3low.RequestBodiesLabel:
+		value = components.RequestBodies.GetOrZero(name)
+	case v3low.HeadersLabel:
+		value = components.Headers.GetOrZero(name)
+	case "securitySchemes":
+		value = components.SecuritySchemes.GetOrZero(name)
+	case "links":
+		value = components.Links.GetOrZero(name)
+	case v3low.CallbacksLabel:
+		value = components.Callbacks.GetOrZero(name)
+	case v3low.PathItemsLabel:
+		value = components.PathItems.GetOrZero(name)
+	}
+	if gl, ok := value.(high.GoesLowUntyped); ok && !reflect.ValueOf(value).IsNil() {
+		if h, ok := gl.GoLowUntyped().(low.Hashable); ok && !reflect.ValueOf(h).IsNil() {
+			return h.Hash()
+		}
+	}
+	return [32]byte{}
+}
+
+func equalNodes(a, b *yaml.Node) bool {
+	ab, _ := yaml.Marshal(a)
+	bb, _ := yaml.Marshal(b)
+	return string(ab) == string(bb)
+}
+
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if !utils.IsNodeMap(node) {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package libopenapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mergePets = `openapi: 3.1.0
+info:
+  title: pets
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+security:
+  - apiKey: []
+tags:
+  - name: pets
+    description: all about pets
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pets]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      name: key
+      in: header
+  schemas:
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string`
+
+const mergeOwners = `openapi: 3.1.0
+info:
+  title: owners
+  version: 2.0.0
+servers:
+  - url: https://api.example.com
+  - url: https://owners.example.com
+security:
+  - apiKey: []
+tags:
+  - name: pets
+    description: owners of pets
+  - name: owners
+paths:
+  /pets:
+    post:
+      operationId: createOwnedPet
+      responses:
+        "201":
+          description: created
+  /owners:
+    get:
+      operationId: listOwners
+      tags: [owners]
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        default:
+          description: error
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Error'
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      name: key
+      in: header
+  schemas:
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+    Pet:
+      type: object
+      discriminator:
+        propertyName: kind
+        mapping:
+          owned: Pet
+      properties:
+        owner:
+          type: string`
+
+func mergeSources(t *testing.T, specs ...string) []*MergeSource {
+	var sources []*MergeSource
+	for _, spec := range specs {
+		doc, err := NewDocument([]byte(spec))
+		require.NoError(t, err)
+		sources = append(sources, &MergeSource{Document: doc})
+	}
+	return sources
+}
+
+func TestMergeDocuments_Error(t *testing.T) {
+	merged, report, err := MergeDocuments(nil, mergeSources(t, mergePets, mergeOwners)...)
+	assert.Nil(t, merged)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "3 collisions found")
+	assert.ErrorContains(t, err, "document 2: schemas 'Pet' already exists in another document")
+
+	// identical components and tags that are the same are still collisions, the tag with another description is kept.
+	require.Len(t, report.Collisions, 4)
+	assert.Equal(t, "securitySchemes", report.Collisions[0].Type)
+	assert.True(t, report.Collisions[0].Identical)
+	assert.Equal(t, "Error", report.Collisions[1].Name)
+	assert.Equal(t, MergeCollisionError, report.Collisions[1].Strategy)
+	assert.Equal(t, "Pet", report.Collisions[2].Name)
+	assert.False(t, report.Collisions[2].Identical)
+	assert.Equal(t, MergeCollisionKeepFirst, report.Collisions[3].Strategy)
+	assert.Equal(t, "tags", report.Collisions[3].Type)
+}
+
+func TestMergeDocuments_Prefix(t *testing.T) {
+	sources := mergeSources(t, mergePets, mergeOwners)
+	sources[1].ComponentPrefix = "Owners"
+	merged, report, err := MergeDocuments(&MergeConfiguration{Strategy: MergeCollisionPrefix}, sources...)
+	require.NoError(t, err)
+	require.Len(t, report.Collisions, 4)
+	assert.Equal(t, "OwnersPet", report.Collisions[2].RenamedTo)
+
+	assert.Equal(t, "pets", merged.Info.Title)
+	assert.Len(t, merged.Servers, 2)
+	require.Len(t, merged.Tags, 2)
+	assert.Equal(t, "all about pets", merged.Tags[0].Description)
+
+	// the renamed security scheme is used by the security requirements of the document it came from.
+	require.Len(t, merged.Security, 2)
+	assert.Equal(t, []string{"apiKey"}, slices.Collect(merged.Security[0].Requirements.KeysFromOldest()))
+	assert.Equal(t, []string{"OwnersapiKey"}, slices.Collect(merged.Security[1].Requirements.KeysFromOldest()))
+
+	// the operations of the same path are merged, and references follow the renamed components.
+	pets := merged.Paths.PathItems.GetOrZero("/pets")
+	assert.Equal(t, "listPets", pets.Get.OperationId)
+	assert.Equal(t, "createOwnedPet", pets.Post.OperationId)
+	owners := merged.Paths.PathItems.GetOrZero("/owners").Get
+	assert.Equal(t, "#/components/schemas/OwnersPet",
+		owners.Responses.Codes.GetOrZero("200").Content.GetOrZero("application/json").Schema.GetReference())
+	assert.Equal(t, "#/components/schemas/OwnersError",
+		owners.Responses.Default.Content.GetOrZero("application/json").Schema.GetReference())
+
+	assert.Equal(t, 4, orderedmap.Len(merged.Components.Schemas))
+	assert.Equal(t, 2, orderedmap.Len(merged.Components.SecuritySchemes))
+	ownersPet := merged.Components.Schemas.GetOrZero("OwnersPet").Schema()
+	assert.Equal(t, "OwnersPet", ownersPet.Discriminator.Mapping.GetOrZero("owned"))
+	assert.NotNil(t, ownersPet.Properties.GetOrZero("owner"))
+
+	// operations can't be prefixed, so the first one is kept, and reported as kept.
+	changed := strings.Replace(mergePets, "operationId: listPets", "operationId: listAllPets", 1)
+	merged, report, err = MergeDocuments(&MergeConfiguration{Strategy: MergeCollisionPrefix},
+		mergeSources(t, mergePets, changed)...)
+	require.NoError(t, err)
+	var operation *MergeCollision
+	for _, c := range report.Collisions {
+		if c.Type == "paths" {
+			operation = c
+		}
+	}
+	require.NotNil(t, operation)
+	assert.Equal(t, "GET /pets", operation.Name)
+	assert.False(t, operation.Identical)
+	assert.Equal(t, MergeCollisionKeepFirst, operation.Strategy)
+	assert.Empty(t, operation.RenamedTo)
+	assert.Equal(t, "listPets", merged.Paths.PathItems.GetOrZero("/pets").Get.OperationId)
+}
+
+func TestMergeDocuments_PrefixSecurity(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: %s
+  version: 1.0.0
+security:
+  - auth: []
+paths:
+  /%s:
+    get:
+      security:
+        - auth: [read]
+          other: []
+      responses:
+        "200":
+          description: ok
+      callbacks:
+        done:
+          '{$request.body#/url}':
+            post:
+              security:
+                - auth: []
+              responses:
+                "200":
+                  description: ok
+components:
+  securitySchemes:
+    auth:
+      type: apiKey
+      name: %s
+      in: header
+    other:
+      type: http
+      scheme: bearer`
+	merged, report, err := MergeDocuments(&MergeConfiguration{Strategy: MergeCollisionPrefix},
+		mergeSources(t, fmt.Sprintf(spec, "pets", "pets", "key"), fmt.Sprintf(spec, "owners", "owners", "token"))...)
+	require.NoError(t, err)
+	require.Len(t, report.Collisions, 2)
+	assert.Equal(t, "Doc2auth", report.Collisions[0].RenamedTo)
+
+	// the root and operation security requirements of the second document use its renamed scheme.
+	require.Len(t, merged.Security, 2)
+	assert.Equal(t, []string{"auth"}, slices.Collect(merged.Security[0].Requirements.KeysFromOldest()))
+	assert.Equal(t, []string{"Doc2auth"}, slices.Collect(merged.Security[1].Requirements.KeysFromOldest()))
+
+	pets := merged.Paths.PathItems.GetOrZero("/pets").Get
+	assert.Equal(t, []string{"auth", "other"}, slices.Collect(pets.Security[0].Requirements.KeysFromOldest()))
+	owners := merged.Paths.PathItems.GetOrZero("/owners").Get
+	assert.Equal(t, []string{"Doc2auth", "Doc2other"}, slices.Collect(owners.Security[0].Requirements.KeysFromOldest()))
+	assert.Equal(t, []string{"read"}, owners.Security[0].Requirements.GetOrZero("Doc2auth"))
+	callback := owners.Callbacks.GetOrZero("done").Expression.GetOrZero("{$request.body#/url}").Post
+	assert.Equal(t, []string{"Doc2auth"}, slices.Collect(callback.Security[0].Requirements.KeysFromOldest()))
+	assert.Equal(t, "token", merged.Components.SecuritySchemes.GetOrZero("Doc2auth").Name)
+}
+
+func TestMergeDocuments_Dedupe(t *testing.T) {
+	sources := mergeSources(t, mergePets, mergeOwners)
+	sources[1].PathPrefix = "/v2"
+	config := &MergeConfiguration{Strategy: MergeCollisionDedupe, DedupeFallback: MergeCollisionPrefix}
+	merged, report, err := MergeDocuments(config, sources...)
+	require.NoError(t, err)
+	require.Len(t, report.Collisions, 4)
+
+	// identical components are kept once, the rest fall back to being prefixed.
+	assert.Equal(t, MergeCollisionDedupe, report.Collisions[0].Strategy)
+	assert.Equal(t, MergeCollisionDedupe, report.Collisions[1].Strategy)
+	assert.Equal(t, MergeCollisionPrefix, report.Collisions[2].Strategy)
+	assert.Equal(t, "Doc2Pet", report.Collisions[2].RenamedTo)
+	assert.Equal(t, 3, orderedmap.Len(merged.Components.Schemas))
+	assert.Equal(t, 1, orderedmap.Len(merged.Components.SecuritySchemes))
+
+	// the paths of the second document are prefixed, so they don't collide.
+	assert.Equal(t, []string{"/pets", "/v2/pets", "/v2/owners"}, mergePathNames(merged))
+	assert.Equal(t, "#/components/schemas/Error",
+		merged.Paths.PathItems.GetOrZero("/v2/owners").Get.Responses.Default.Content.GetOrZero("application/json").
+			Schema.GetReference())
+
+	// identical documents only have identical collisions, extensions of the components are kept from the first.
+	merged, report, err = MergeDocuments(config, mergeSources(t, mergePets, mergePets, mergePets+"\n  x-other: true")...)
+	require.NoError(t, err)
+	assert.Equal(t, 2, orderedmap.Len(merged.Components.Schemas))
+	for _, c := range report.Collisions {
+		assert.True(t, c.Identical, c.Name)
+	}
+}
+
+func TestMergeDocuments_KeepFirst(t *testing.T) {
+	changed := mergePets + "\n        age:\n          type: integer"
+	merged, report, err := MergeDocuments(&MergeConfiguration{Strategy: MergeCollisionKeepFirst},
+		mergeSources(t, mergePets, changed)...)
+	require.NoError(t, err)
+
+	// the operation, both schemas and the security scheme collide, the tag is the same in both.
+	require.Len(t, report.Collisions, 4)
+	assert.Equal(t, "paths", report.Collisions[3].Type)
+	assert.Equal(t, "GET /pets", report.Collisions[3].Name)
+	assert.True(t, report.Collisions[3].Identical)
+	assert.False(t, report.Collisions[2].Identical)
+	assert.Nil(t, merged.Components.Schemas.GetOrZero("Pet").Schema().Properties.GetOrZero("age"))
+}
+
+func TestMergeDocuments_FileReferences(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pets"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pets", "pet.yaml"), []byte("type: object"), 0o644))
+
+	spec := `openapi: 3.1.0
+info:
+  title: pets
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'pet.yaml'`
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = filepath.Join(dir, "pets")
+	config.SpecFilePath = "openapi.yaml"
+	doc, err := NewDocumentWithConfiguration([]byte(spec), config)
+	require.NoError(t, err)
+
+	// the reference is relative to the base path of the merged document.
+	mergedConfig := datamodel.NewDocumentConfiguration()
+	mergedConfig.BasePath = dir
+	mergedConfig.AllowFileReferences = true
+	merged, _, err := MergeDocuments(&MergeConfiguration{DocumentConfiguration: mergedConfig},
+		&MergeSource{Document: doc})
+	require.NoError(t, err)
+	pet := merged.Components.Schemas.GetOrZero("Pet")
+	assert.Equal(t, "pets/pet.yaml", pet.GetReference())
+	assert.Equal(t, "object", pet.Schema().Type[0])
+}
+
+func TestMergeDocuments_Invalid(t *testing.T) {
+	_, _, err := MergeDocuments(nil)
+	assert.EqualError(t, err, "there are no documents to merge")
+
+	_, _, err = MergeDocuments(nil, &MergeSource{})
+	assert.EqualError(t, err, "document 1 is missing, unable to merge documents")
+
+	swagger, _ := NewDocument([]byte("swagger: 2.0"))
+	_, _, err = MergeDocuments(nil, &MergeSource{Document: swagger})
+	assert.ErrorContains(t, err, "unable to build document 1")
+}
+
+func mergePathNames(doc *v3high.Document) []string {
+	var names []string
+	for name := range doc.Paths.PathItems.KeysFromOldest() {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,715 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	// PostmanCollectionSchema is the schema of the Postman collections created by a CollectionExporter.
+	PostmanCollectionSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+	// webhookURLVariable is the collection variable holding the URL webhook requests are sent to.
+	webhookURLVariable = "webhookUrl"
+	webhooksFolder     = "Webhooks"
+
+	// defaultCollectionSeed seeds the values of a CollectionExporter, so exports are the same every time.
+	defaultCollectionSeed = 1
+)
+
+var serverVariablePattern = regexp.MustCompile(`\{([^{}]+)}`)
+
+// CollectionExporter exports example requests for every operation of a document (and its webhooks) as a Postman
+// (v2.1) collection, or as a HAR (HTTP Archive 1.2) log.
+//
+// Requests are generated by a RequestGenerator, so parameters are serialized according to their style, and values
+// are taken from examples first. JSON request bodies are rendered using a MockGenerator, which also takes examples
+// first, and then renders the schema.
+//
+// In Postman collections, operations are grouped into a folder for their first tag, and webhooks into a 'Webhooks'
+// folder. Server variables become collection variables, so URLs are templated, as are the credentials of security
+// schemes, which use the name of the scheme. A request uses the first security scheme of its security requirement.
+// Webhook requests are sent to the '{{webhookUrl}}' variable.
+//
+// Values rendered from schemas are seeded, so exporting the same document gives the same values every time (see
+// SetSeed). The HAR startedDateTime and date values use the current time, unless a clock is set with SetClock.
+//
+// Use NewCollectionExporter to create a new CollectionExporter.
+type CollectionExporter struct {
+	document *v3.Document
+	requests *RequestGenerator
+	mock     *MockGenerator
+	now      func() time.Time
+}
+
+// NewCollectionExporter creates a new CollectionExporter for the supplied document.
+func NewCollectionExporter(document *v3.Document) *CollectionExporter {
+	mock := NewMockGeneratorWithBuiltInProviders(JSON)
+	mock.SetPretty()
+	c := &CollectionExporter{
+		document: document,
+		requests: NewRequestGenerator(document),
+		mock:     mock,
+		now:      time.Now,
+	}
+	c.SetSeed(defaultCollectionSeed)
+	return c
+}
+
+// SetSeed seeds the values rendered for parameters and bodies without examples, so the same seed exports the same
+// values every time. The MockGenerator is seeded too, so call SetSeed after SetMockGenerator.
+func (c *CollectionExporter) SetSeed(seed int64) {
+	if c.requests.renderer != nil {
+		c.requests.renderer.SetSeed(seed)
+	}
+	if c.mock != nil {
+		c.mock.SetSeed(seed)
+	}
+}
+
+// SetClock sets the function used to get the current time, which is used for the HAR startedDateTime and for date
+// values rendered from schemas. The default is time.Now.
+func (c *CollectionExporter) SetClock(now func() time.Time) {
+	c.now = now
+	if c.requests.renderer != nil {
+		c.requests.renderer.SetClock(now)
+	}
+	if c.mock != nil {
+		c.mock.SetClock(now)
+	}
+}
+
+// SetCredential sets the credential to use for a security scheme (by name). See RequestGenerator.SetCredential.
+func (c *CollectionExporter) SetCredential(securitySchemeName, credential string) {
+	c.requests.SetCredential(securitySchemeName, credential)
+}
+
+// SetPreferredMediaTypes sets the order in which request body media types are selected.
+func (c *CollectionExporter) SetPreferredMediaTypes(mediaTypes ...string) {
+	c.requests.SetPreferredMediaTypes(mediaTypes...)
+}
+
+// SetMockGenerator sets the MockGenerator used to render JSON request bodies.
+func (c *CollectionExporter) SetMockGenerator(mock *MockGenerator) {
+	c.mock = mock
+}
+
+// collectionOperation is an operation (or webhook) of the document, and where it is found.
+type collectionOperation struct {
+	folder    string
+	name      string
+	path      string
+	method    string
+	pathItem  *v3.PathItem
+	operation *v3.Operation
+	server    *v3.Server
+	webhook   bool
+}
+
+// operations walks the paths, then the webhooks of the document.
+func (c *CollectionExporter) operations() []*collectionOperation {
+	if c.document == nil {
+		return nil
+	}
+	var ops []*collectionOperation
+	add := func(path string, pathItem *v3.PathItem, webhook bool) {
+		if pathItem == nil {
+			return
+		}
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			op := &collectionOperation{
+				path:      path,
+				method:    strings.ToUpper(method),
+				pathItem:  pathItem,
+				operation: operation,
+				webhook:   webhook,
+			}
+			op.name = operation.Summary
+			if op.name == "" {
+				op.name = operation.OperationId
+			}
+			if op.name == "" {
+				op.name = op.method + " " + path
+			}
+			switch {
+			case webhook:
+				op.folder = webhooksFolder
+				op.server = &v3.Server{URL: defaultServerURL}
+			case len(operation.Tags) > 0:
+				op.folder = operation.Tags[0]
+			}
+			if !webhook {
+				switch {
+				case len(operation.Servers) > 0:
+					op.server = operation.Servers[0]
+				case len(pathItem.Servers) > 0:
+					op.server = pathItem.Servers[0]
+				case len(c.document.Servers) > 0:
+					op.server = c.document.Servers[0]
+				}
+			}
+			ops = append(ops, op)
+		}
+	}
+	if c.document.Paths != nil {
+		for path, pathItem := range c.document.Paths.PathItems.FromOldest() {
+			add(path, pathItem, false)
+		}
+	}
+	for name, pathItem := range c.document.Webhooks.FromOldest() {
+		add(name, pathItem, true)
+	}
+	return ops
+}
+
+// body renders the request body of an operation, using the preferred media type.
+func (c *CollectionExporter) body(operation *v3.Operation) (body []byte, mediaTypeName, contentType string,
+	mediaType *v3.MediaType, err error,
+) {
+	if operation.RequestBody == nil || operation.RequestBody.Content == nil {
+		return nil, "", "", nil, nil
+	}
+	mediaTypeName, mediaType = c.requests.preferredMediaType(operation.RequestBody.Content)
+	if mediaType == nil {
+		return nil, "", "", nil, nil
+	}
+	if strings.Contains(strings.ToLower(mediaTypeName), "json") && c.mock != nil {
+		body, err = c.mock.GenerateMock(mediaType, "")
+		contentType = mediaTypeName
+	} else {
+		body, contentType, err = c.requests.renderBody(mediaTypeName, mediaType)
+	}
+	if err != nil {
+		return nil, "", "", nil, fmt.Errorf("unable to render request body for media type '%s': %w", mediaTypeName, err)
+	}
+	return body, mediaTypeName, contentType, mediaType, nil
+}
+
+type postmanCollection struct {
+	Info     postmanInfo        `json:"info"`
+	Item     []*postmanItem     `json:"item"`
+	Variable []*postmanVariable `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// postmanItem is a folder, when it holds items, or a request.
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Item        []*postmanItem  `json:"item,omitempty"`
+	Request     *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []*postmanKeyValue `json:"header"`
+	URL         *postmanURL        `json:"url"`
+	Body        *postmanBody       `json:"body,omitempty"`
+	Auth        *postmanAuth       `json:"auth,omitempty"`
+	Description string             `json:"description,omitempty"`
+	variables   []*postmanVariable // collection variables used by the request.
+}
+
+type postmanURL struct {
+	Raw      string             `json:"raw"`
+	Protocol string             `json:"protocol,omitempty"`
+	Host     []string           `json:"host,omitempty"`
+	Port     string             `json:"port,omitempty"`
+	Path     []string           `json:"path,omitempty"`
+	Query    []*postmanKeyValue `json:"query,omitempty"`
+	Variable []*postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string              `json:"mode"`
+	Raw        string              `json:"raw,omitempty"`
+	URLEncoded []*postmanKeyValue  `json:"urlencoded,omitempty"`
+	FormData   []*postmanKeyValue  `json:"formdata,omitempty"`
+	Options    *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type postmanAuth struct {
+	Type   string             `json:"type"`
+	APIKey []*postmanKeyValue `json:"apikey,omitempty"`
+	Basic  []*postmanKeyValue `json:"basic,omitempty"`
+	Bearer []*postmanKeyValue `json:"bearer,omitempty"`
+	OAuth2 []*postmanKeyValue `json:"oauth2,omitempty"`
+}
+
+type postmanVariable struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// ExportPostman exports a Postman (v2.1) collection of the operations and webhooks of the document.
+func (c *CollectionExporter) ExportPostman() ([]byte, error) {
+	collection := &postmanCollection{Info: postmanInfo{Schema: PostmanCollectionSchema}}
+	if c.document != nil && c.document.Info != nil {
+		collection.Info.Name = c.document.Info.Title
+		collection.Info.Description = c.document.Info.Description
+		collection.Info.Version = c.document.Info.Version
+	}
+
+	// folders are ordered by the tags of the document first.
+	folders := make(map[string]*postmanItem)
+	var order []*postmanItem
+	folder := func(name string) *postmanItem {
+		if f, ok := folders[name]; ok {
+			return f
+		}
+		f := &postmanItem{Name: name}
+		folders[name] = f
+		order = append(order, f)
+		return f
+	}
+	if c.document != nil {
+		for _, tag := range c.document.Tags {
+			folder(tag.Name).Description = tag.Description
+		}
+	}
+
+	variables := make(map[string]bool)
+	var untagged []*postmanItem
+	for _, op := range c.operations() {
+		request, err := c.postmanRequest(op)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export '%s %s': %w", op.method, op.path, err)
+		}
+		for _, v := range request.variables {
+			if !variables[v.Key] {
+				variables[v.Key] = true
+				collection.Variable = append(collection.Variable, v)
+			}
+		}
+		item := &postmanItem{Name: op.name, Request: request}
+		if op.folder == "" {
+			untagged = append(untagged, item)
+			continue
+		}
+		f := folder(op.folder)
+		f.Item = append(f.Item, item)
+	}
+	for _, f := range order {
+		if len(f.Item) > 0 {
+			collection.Item = append(collection.Item, f)
+		}
+	}
+	collection.Item = append(collection.Item, untagged...)
+	if collection.Item == nil {
+		collection.Item = []*postmanItem{}
+	}
+	return marshalCollection(collection)
+}
+
+// postmanRequest creates the request of an operation, with a templated URL.
+func (c *CollectionExporter) postmanRequest(op *collectionOperation) (*postmanRequest, error) {
+	request := &postmanRequest{
+		Method:      op.method,
+		Header:      []*postmanKeyValue{},
+		Description: op.operation.Description,
+	}
+
+	// the server URL is templated using the server variables.
+	u := &postmanURL{}
+	base := "{{" + webhookURLVariable + "}}"
+	if op.webhook {
+		request.variables = append(request.variables, &postmanVariable{
+			Key: webhookURLVariable, Value: defaultServerURL, Description: "The URL webhooks are sent to.",
+		})
+		u.Host = []string{base}
+	} else {
+		base = postmanServerURL(op.server, u, &request.variables)
+	}
+
+	path := op.path
+	var query, cookies []string
+	for _, param := range mergeParameters(op.pathItem, op.operation) {
+		value := c.requests.parameterValue(param)
+		if value == nil {
+			continue
+		}
+		style, explode := parameterStyle(param)
+		switch param.In {
+		case pathParam:
+			path = strings.ReplaceAll(path, fmt.Sprintf("{%s}", param.Name), ":"+param.Name)
+			u.Variable = append(u.Variable, &postmanKeyValue{
+				Key:         param.Name,
+				Value:       serializePathParameter(param.Name, style, explode, value),
+				Description: param.Description,
+			})
+		case queryParam:
+			for i, pair := range serializeQueryParameter(param.Name, style, explode, param.AllowReserved, value) {
+				query = append(query, pair)
+				kv := postmanQueryPair(pair)
+				if i == 0 {
+					kv.Description = param.Description
+				}
+				u.Query = append(u.Query, kv)
+			}
+		case headerParam:
+			request.Header = append(request.Header, &postmanKeyValue{
+				Key:         param.Name,
+				Value:       serializeSimple(value, explode, func(s string) string { return s }),
+				Description: param.Description,
+			})
+		case cookieParam:
+			cookies = append(cookies, serializeCookieParameter(param.Name, explode, value)...)
+		}
+	}
+
+	// only the first security scheme can be used as the auth of a request.
+	if schemes := c.requests.securitySchemes(op.operation); len(schemes) > 0 {
+		auth, cookie := c.postmanAuth(schemes[0], &request.variables)
+		request.Auth = auth
+		if cookie != "" {
+			cookies = append(cookies, cookie)
+		}
+	}
+	if len(cookies) > 0 {
+		request.Header = append(request.Header, &postmanKeyValue{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+
+	body, mediaTypeName, contentType, mediaType, err := c.body(op.operation)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType != nil {
+		request.Body = c.postmanBody(body, mediaTypeName, mediaType)
+		if request.Body.Mode != "formdata" {
+			// Postman sets the content type (and boundary) of form data itself.
+			request.Header = append(request.Header, &postmanKeyValue{Key: "Content-Type", Value: contentType})
+		}
+	}
+
+	if !op.webhook {
+		for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+			if segment != "" {
+				u.Path = append(u.Path, segment)
+			}
+		}
+		base += "/" + strings.TrimPrefix(path, "/")
+	}
+	u.Raw = base
+	if len(query) > 0 {
+		u.Raw += "?" + strings.Join(query, "&")
+	}
+	request.URL = u
+	return request, nil
+}
+
+// postmanServerURL templates a server URL, filling in the protocol, host and path of the URL, and returns it.
+// Server variables are added to the supplied variables.
+func postmanServerURL(server *v3.Server, u *postmanURL, variables *[]*postmanVariable) string {
+	serverURL := defaultServerURL
+	if server != nil && server.URL != "" {
+		serverURL = serverVariablePattern.ReplaceAllString(strings.TrimSuffix(server.URL, "/"), "{{$1}}")
+		if server.Variables != nil {
+			for name, variable := range server.Variables.FromOldest() {
+				value := variable.Default
+				if value == "" && len(variable.Enum) > 0 {
+					value = variable.Enum[0]
+				}
+				*variables = append(*variables, &postmanVariable{
+					Key: name, Value: value, Description: variable.Description,
+				})
+			}
+		}
+		if !strings.Contains(serverURL, "://") {
+			serverURL = strings.TrimSuffix(defaultServerURL+"/"+strings.TrimPrefix(serverURL, "/"), "/")
+		}
+	}
+
+	protocol, rest, _ := strings.Cut(serverURL, "://")
+	host, path, _ := strings.Cut(rest, "/")
+	u.Protocol = protocol
+	if h, port, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(port, "/") {
+		host = h
+		u.Port = port
+	}
+	u.Host = strings.Split(host, ".")
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			u.Path = append(u.Path, segment)
+		}
+	}
+	return serverURL
+}
+
+// postmanQueryPair splits a serialized query parameter into an unescaped key and value.
+func postmanQueryPair(pair string) *postmanKeyValue {
+	key, value, _ := strings.Cut(pair, "=")
+	if k, err := url.QueryUnescape(key); err == nil {
+		key = k
+	}
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+	return &postmanKeyValue{Key: key, Value: value}
+}
+
+// postmanAuth creates the auth of a security scheme, using a collection variable for the credential. apiKey schemes
+// using a cookie are returned as a cookie instead, as Postman only supports headers and query parameters.
+func (c *CollectionExporter) postmanAuth(scheme namedSecurityScheme, variables *[]*postmanVariable,
+) (*postmanAuth, string) {
+	name, sc := scheme.name, scheme.scheme
+	variable := func(key, placeholder string) string {
+		*variables = append(*variables, &postmanVariable{
+			Key: key, Value: c.requests.credential(name, placeholder), Description: sc.Description,
+		})
+		return "{{" + key + "}}"
+	}
+	switch strings.ToLower(sc.Type) {
+	case "apikey":
+		value := variable(name, "YOUR_API_KEY")
+		if sc.In == cookieParam {
+			return nil, fmt.Sprintf("%s=%s", sc.Name, value)
+		}
+		in := headerParam
+		if sc.In == queryParam {
+			in = queryParam
+		}
+		return &postmanAuth{Type: "apikey", APIKey: []*postmanKeyValue{
+			{Key: "key", Value: sc.Name, Type: "string"},
+			{Key: "value", Value: value, Type: "string"},
+			{Key: "in", Value: in, Type: "string"},
+		}}, ""
+	case "http":
+		switch strings.ToLower(sc.Scheme) {
+		case "basic":
+			username, password, _ := strings.Cut(c.requests.credential(name, "username:password"), ":")
+			*variables = append(*variables,
+				&postmanVariable{Key: name + "Username", Value: username, Description: sc.Description},
+				&postmanVariable{Key: name + "Password", Value: password, Description: sc.Description})
+			return &postmanAuth{Type: "basic", Basic: []*postmanKeyValue{
+				{Key: "username", Value: "{{" + name + "Username}}", Type: "string"},
+				{Key: "password", Value: "{{" + name + "Password}}", Type: "string"},
+			}}, ""
+		case "bearer":
+			return &postmanAuth{Type: "bearer", Bearer: []*postmanKeyValue{
+				{Key: "token", Value: variable(name, "YOUR_TOKEN"), Type: "string"},
+			}}, ""
+		}
+	case "oauth2", "openidconnect":
+		return &postmanAuth{Type: "oauth2", OAuth2: []*postmanKeyValue{
+			{Key: "accessToken", Value: variable(name, "YOUR_ACCESS_TOKEN"), Type: "string"},
+			{Key: "addTokenTo", Value: "header", Type: "string"},
+		}}, ""
+	}
+	return nil, ""
+}
+
+// postmanBody creates the body of a request. Form bodies use key/value pairs, other bodies are raw.
+func (c *CollectionExporter) postmanBody(body []byte, mediaTypeName string, mediaType *v3.MediaType) *postmanBody {
+	lower := strings.ToLower(mediaTypeName)
+	if lower == "application/x-www-form-urlencoded" || strings.HasPrefix(lower, "multipart/") {
+		value := c.requests.exampleValue(mediaType.Example, mediaType.Examples, mediaType.Schema, rootType)
+		var pairs []*postmanKeyValue
+		for _, k := range sortedKeys(value) {
+			field := value.(map[string]any)[k]
+			kv := &postmanKeyValue{Key: k, Value: fmt.Sprint(field)}
+			if !isScalar(field) {
+				encoded, _ := json.Marshal(field)
+				kv.Value = string(encoded)
+			}
+			pairs = append(pairs, kv)
+		}
+		if lower == "application/x-www-form-urlencoded" {
+			return &postmanBody{Mode: "urlencoded", URLEncoded: pairs}
+		}
+		for _, kv := range pairs {
+			kv.Type = "text"
+		}
+		return &postmanBody{Mode: "formdata", FormData: pairs}
+	}
+
+	b := &postmanBody{Mode: "raw", Raw: string(body), Options: &postmanBodyOptions{}}
+	switch {
+	case strings.Contains(lower, "json"):
+		b.Options.Raw.Language = "json"
+	case strings.Contains(lower, "xml"):
+		b.Options.Raw.Language = "xml"
+	default:
+		b.Options.Raw.Language = "text"
+	}
+	return b
+}
+
+type harLog struct {
+	Log struct {
+		Version string      `json:"version"`
+		Creator harCreator  `json:"creator"`
+		Entries []*harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	Time            int            `json:"time"`
+	Request         *harRequest    `json:"request"`
+	Response        *harResponse   `json:"response"`
+	Cache           struct{}       `json:"cache"`
+	Timings         map[string]int `json:"timings"`
+	Comment         string         `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harNameValue `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	QueryString []*harNameValue `json:"queryString"`
+	PostData    *harPostData    `json:"postData,omitempty"`
+	HeadersSize int             `json:"headersSize"`
+	BodySize    int             `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int             `json:"status"`
+	StatusText  string          `json:"statusText"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*harNameValue `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	Content     struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+	} `json:"content"`
+	RedirectURL string `json:"redirectURL"`
+	HeadersSize int    `json:"headersSize"`
+	BodySize    int    `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// ExportHAR exports a HAR (1.2) log of the same requests as ExportPostman, with the values filled in. The requests
+// have not been sent, so the responses are empty.
+func (c *CollectionExporter) ExportHAR() ([]byte, error) {
+	har := &harLog{}
+	har.Log.Version = "1.2"
+	har.Log.Creator = harCreator{Name: "libopenapi", Version: "1.0"}
+	har.Log.Entries = []*harEntry{}
+	started := c.now().UTC().Format(time.RFC3339Nano)
+	for _, op := range c.operations() {
+		path := op.path
+		if op.webhook {
+			path = ""
+		}
+		req, err := c.requests.generateRequest(path, op.method, op.pathItem, op.operation, op.server)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export '%s %s': %w", op.method, op.path, err)
+		}
+
+		// bodies are rendered the same way as they are for Postman.
+		body, _, contentType, mediaType, err := c.body(op.operation)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export '%s %s': %w", op.method, op.path, err)
+		}
+
+		request := &harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*harNameValue{},
+			Headers:     []*harNameValue{},
+			QueryString: []*harNameValue{},
+			HeadersSize: -1,
+		}
+		keys := make([]string, 0, len(req.Header))
+		for k := range req.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range req.Header[k] {
+				request.Headers = append(request.Headers, &harNameValue{Name: k, Value: v})
+			}
+		}
+		for _, cookie := range strings.Split(req.Header.Get("Cookie"), "; ") {
+			if name, value, ok := strings.Cut(cookie, "="); ok {
+				request.Cookies = append(request.Cookies, &harNameValue{Name: name, Value: value})
+			}
+		}
+		if req.URL.RawQuery != "" {
+			for _, pair := range strings.Split(req.URL.RawQuery, "&") {
+				kv := postmanQueryPair(pair)
+				request.QueryString = append(request.QueryString, &harNameValue{Name: kv.Key, Value: kv.Value})
+			}
+		}
+		if mediaType != nil {
+			request.PostData = &harPostData{MimeType: contentType, Text: string(body)}
+			request.BodySize = len(body)
+		}
+
+		response := &harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []*harNameValue{},
+			Headers:     []*harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+		har.Log.Entries = append(har.Log.Entries, &harEntry{
+			StartedDateTime: started,
+			Request:         request,
+			Response:        response,
+			Timings:         map[string]int{"send": 0, "wait": 0, "receive": 0},
+			Comment:         op.name,
+		})
+	}
+	return marshalCollection(har)
+}
+
+// marshalCollection renders a collection as indented JSON, without escaping HTML characters in URLs and bodies.
+func marshalCollection(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,338 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var collectionSpec = `openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.0.0
+  description: Manages pets.
+servers:
+  - url: https://{region}.pets.example.com/v1
+    variables:
+      region:
+        default: eu
+        enum: [eu, us]
+security:
+  - apiKey: []
+tags:
+  - name: pets
+    description: Everything about pets.
+  - name: unused
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        description: The pet.
+        schema:
+          type: integer
+          example: 42
+    put:
+      tags: [pets]
+      summary: Update a pet
+      security:
+        - bearer: []
+      parameters:
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+          example: [cute, fluffy]
+        - name: session
+          in: cookie
+          example: s3cr3t
+        - name: X-Trace
+          in: header
+          example: abc
+      requestBody:
+        content:
+          application/json:
+            example:
+              name: Rex
+      responses:
+        "200":
+          description: updated
+  /login:
+    post:
+      operationId: login
+      security:
+        - basic: []
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            example:
+              user: rex
+              pass: woof
+      responses:
+        "204":
+          description: logged in
+  /status:
+    get:
+      responses:
+        "200":
+          description: status
+webhooks:
+  newPet:
+    post:
+      summary: A new pet
+      security: []
+      requestBody:
+        content:
+          text/plain:
+            example: Rex
+      responses:
+        "200":
+          description: received
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: cookie
+      name: key
+    bearer:
+      type: http
+      scheme: bearer
+      description: A token.
+    basic:
+      type: http
+      scheme: basic
+`
+
+func TestCollectionExporter_ExportPostman(t *testing.T) {
+	doc := buildModel(t, collectionSpec)
+	exporter := NewCollectionExporter(doc)
+	exporter.SetCredential("bearer", "t0k3n")
+
+	b, err := exporter.ExportPostman()
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+  "info": {
+    "name": "Pets",
+    "description": "Manages pets.",
+    "version": "1.0.0",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "item": [
+    {
+      "name": "pets",
+      "description": "Everything about pets.",
+      "item": [
+        {
+          "name": "Update a pet",
+          "request": {
+            "method": "PUT",
+            "header": [
+              {"key": "X-Trace", "value": "abc"},
+              {"key": "Cookie", "value": "session=s3cr3t"},
+              {"key": "Content-Type", "value": "application/json"}
+            ],
+            "url": {
+              "raw": "https://{{region}}.pets.example.com/v1/pets/:petId?tags=cute&tags=fluffy",
+              "protocol": "https",
+              "host": ["{{region}}", "pets", "example", "com"],
+              "path": ["v1", "pets", ":petId"],
+              "query": [{"key": "tags", "value": "cute"}, {"key": "tags", "value": "fluffy"}],
+              "variable": [{"key": "petId", "value": "42", "description": "The pet."}]
+            },
+            "body": {
+              "mode": "raw",
+              "raw": "{\n  \"name\": \"Rex\"\n}",
+              "options": {"raw": {"language": "json"}}
+            },
+            "auth": {
+              "type": "bearer",
+              "bearer": [{"key": "token", "value": "{{bearer}}", "type": "string"}]
+            }
+          }
+        }
+      ]
+    },
+    {
+      "name": "Webhooks",
+      "item": [
+        {
+          "name": "A new pet",
+          "request": {
+            "method": "POST",
+            "header": [{"key": "Content-Type", "value": "text/plain"}],
+            "url": {"raw": "{{webhookUrl}}", "host": ["{{webhookUrl}}"]},
+            "body": {"mode": "raw", "raw": "Rex", "options": {"raw": {"language": "text"}}}
+          }
+        }
+      ]
+    },
+    {
+      "name": "login",
+      "request": {
+        "method": "POST",
+        "header": [{"key": "Content-Type", "value": "application/x-www-form-urlencoded"}],
+        "url": {
+          "raw": "https://{{region}}.pets.example.com/v1/login",
+          "protocol": "https",
+          "host": ["{{region}}", "pets", "example", "com"],
+          "path": ["v1", "login"]
+        },
+        "body": {
+          "mode": "urlencoded",
+          "urlencoded": [{"key": "pass", "value": "woof"}, {"key": "user", "value": "rex"}]
+        },
+        "auth": {
+          "type": "basic",
+          "basic": [
+            {"key": "username", "value": "{{basicUsername}}", "type": "string"},
+            {"key": "password", "value": "{{basicPassword}}", "type": "string"}
+          ]
+        }
+      }
+    },
+    {
+      "name": "GET /status",
+      "request": {
+        "method": "GET",
+        "header": [{"key": "Cookie", "value": "key={{apiKey}}"}],
+        "url": {
+          "raw": "https://{{region}}.pets.example.com/v1/status",
+          "protocol": "https",
+          "host": ["{{region}}", "pets", "example", "com"],
+          "path": ["v1", "status"]
+        }
+      }
+    }
+  ],
+  "variable": [
+    {"key": "region", "value": "eu"},
+    {"key": "bearer", "value": "t0k3n", "description": "A token."},
+    {"key": "basicUsername", "value": "username"},
+    {"key": "basicPassword", "value": "password"},
+    {"key": "apiKey", "value": "YOUR_API_KEY"},
+    {"key": "webhookUrl", "value": "http://localhost", "description": "The URL webhooks are sent to."}
+  ]
+}`, string(b))
+}
+
+func TestCollectionExporter_ExportHAR(t *testing.T) {
+	doc := buildModel(t, collectionSpec)
+	exporter := NewCollectionExporter(doc)
+	exporter.SetClock(func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) })
+
+	b, err := exporter.ExportHAR()
+	require.NoError(t, err)
+
+	var har harLog
+	require.NoError(t, json.Unmarshal(b, &har))
+	assert.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 4)
+
+	entry := har.Log.Entries[0]
+	assert.Equal(t, "2023-01-02T03:04:05Z", entry.StartedDateTime)
+	assert.Equal(t, "Update a pet", entry.Comment)
+	assert.Equal(t, "PUT", entry.Request.Method)
+	assert.Equal(t, "https://eu.pets.example.com/v1/pets/42?tags=cute&tags=fluffy", entry.Request.URL)
+	assert.Equal(t, []*harNameValue{{Name: "tags", Value: "cute"}, {Name: "tags", Value: "fluffy"}},
+		entry.Request.QueryString)
+	assert.Equal(t, []*harNameValue{{Name: "session", Value: "s3cr3t"}}, entry.Request.Cookies)
+	assert.Contains(t, entry.Request.Headers, &harNameValue{Name: "Authorization", Value: "Bearer YOUR_TOKEN"})
+	assert.Equal(t, &harPostData{MimeType: "application/json", Text: "{\n  \"name\": \"Rex\"\n}"},
+		entry.Request.PostData)
+	assert.Equal(t, len(entry.Request.PostData.Text), entry.Request.BodySize)
+
+	login := har.Log.Entries[1]
+	assert.Equal(t, "pass=woof&user=rex", login.Request.PostData.Text)
+	assert.Contains(t, login.Request.Headers, &harNameValue{Name: "Authorization", Value: "Basic dXNlcm5hbWU6cGFzc3dvcmQ="})
+
+	status := har.Log.Entries[2]
+	assert.Nil(t, status.Request.PostData)
+	assert.Equal(t, []*harNameValue{{Name: "key", Value: "YOUR_API_KEY"}}, status.Request.Cookies)
+
+	webhook := har.Log.Entries[3]
+	assert.Equal(t, "http://localhost", webhook.Request.URL)
+	assert.Equal(t, &harPostData{MimeType: "text/plain", Text: "Rex"}, webhook.Request.PostData)
+}
+
+func TestCollectionExporter_Deterministic(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: Orders
+  version: 1.0.0
+paths:
+  /orders/{orderId}:
+    post:
+      parameters:
+        - name: orderId
+          in: path
+          required: true
+          schema:
+            type: string
+            format: uuid
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: X-Request-Name
+          in: header
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [firstName, placed]
+              properties:
+                firstName:
+                  type: string
+                placed:
+                  type: string
+                  format: date-time`
+	doc := buildModel(t, spec)
+	clock := func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+	export := func(seed int64) (postman, har []byte) {
+		exporter := NewCollectionExporter(doc)
+		if seed != 0 {
+			exporter.SetSeed(seed)
+		}
+		exporter.SetClock(clock)
+		postman, err := exporter.ExportPostman()
+		require.NoError(t, err)
+		har, err = exporter.ExportHAR()
+		require.NoError(t, err)
+		return postman, har
+	}
+
+	// every export of the same document is the same, unless it is seeded differently.
+	postman, har := export(0)
+	again, againHAR := export(0)
+	assert.Equal(t, string(postman), string(again))
+	assert.Equal(t, string(har), string(againHAR))
+	assert.Contains(t, string(har), `"startedDateTime": "2023-01-02T03:04:05Z"`)
+	assert.Contains(t, string(har), `2023-01-02T03:04:05Z\"`)
+
+	reseeded, _ := export(7)
+	assert.NotEqual(t, string(postman), string(reseeded))
+}
+
+func TestCollectionExporter_Empty(t *testing.T) {
+	exporter := NewCollectionExporter(nil)
+	b, err := exporter.ExportPostman()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"info": {"name": "", "schema": "`+PostmanCollectionSchema+`"}, "item": []}`, string(b))
+
+	b, err = exporter.ExportHAR()
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"entries": []`)
+}
@@ -0,0 +1,630 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"go/format"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// goInitialisms are words that are written in upper case in Go identifiers.
+var goInitialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true, "eof": true, "guid": true,
+	"html": true, "http": true, "https": true, "id": true, "ip": true, "json": true, "jwt": true, "sql": true,
+	"ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true, "ui": true, "uid": true, "uri": true,
+	"url": true, "utc": true, "uuid": true, "xml": true,
+}
+
+// goKind is the kind of Go type a schema becomes, which decides when pointers are needed.
+type goKind int
+
+const (
+	goScalar goKind = iota
+	goStruct
+	goSlice
+	goMap
+	goAny
+	goOneOf
+)
+
+// GoTypeGenerator generates Go types from the schemas of a document. A type is generated for every schema in the
+// components, and for every inline request body and response schema (named after the operation). Inline object
+// schemas in properties are generated as their own types, named after the type and property that hold them.
+//
+//   - Properties that are not required, or are nullable, are pointers (slices, maps and interfaces are not).
+//   - Enums are typed constants.
+//   - allOf schemas embed the referenced types, and hold the properties of inline schemas.
+//   - oneOf and anyOf schemas are sealed interfaces, each variant implements the interface. A '<Name>Value' struct
+//     holds the interface and decodes it from JSON using the discriminator, or by trying each variant in turn.
+//   - Properties that refer to a schema that is part of a circular reference (found by the resolver) are pointers,
+//     so the types don't hold themselves.
+//
+// Use NewGoTypeGenerator to create a new GoTypeGenerator.
+type GoTypeGenerator struct {
+	document    *v3.Document
+	packageName string
+	mediaTypes  []string
+}
+
+// NewGoTypeGenerator creates a new GoTypeGenerator for the supplied document. Types are generated in the 'models'
+// package by default.
+func NewGoTypeGenerator(document *v3.Document) *GoTypeGenerator {
+	return &GoTypeGenerator{
+		document:    document,
+		packageName: "models",
+		mediaTypes:  DefaultPreferredMediaTypes,
+	}
+}
+
+// SetPackageName sets the name of the package the types are generated in.
+func (g *GoTypeGenerator) SetPackageName(packageName string) {
+	g.packageName = packageName
+}
+
+// SetPreferredMediaTypes sets the order in which media types are picked, when generating types for request bodies
+// and responses that support more than one media type.
+func (g *GoTypeGenerator) SetPreferredMediaTypes(mediaTypes ...string) {
+	g.mediaTypes = mediaTypes
+}
+
+// Generate generates the Go source of every type, formatted using gofmt.
+func (g *GoTypeGenerator) Generate() ([]byte, error) {
+	gen := &goGeneration{
+		used:     make(map[string]bool),
+		refs:     make(map[string]string),
+		kinds:    make(map[string]goKind),
+		declared: make(map[string]bool),
+		imports:  make(map[string]bool),
+		circular: make(map[string]bool),
+	}
+	for name := range circularSchemaNames(g.document) {
+		gen.circular[goIdentifier(name)] = true
+	}
+
+	// reserve the names of the components first, so references to them can be used before they are generated.
+	components := componentSchemas(g.document)
+	for _, c := range components {
+		c.name = gen.reserve(c.name)
+	}
+	for _, c := range components {
+		gen.declare(c.name, c.schema, fmt.Sprintf("the '%s' schema", referenceName(c.source)))
+	}
+	for _, o := range operationSchemas(g.document, g.mediaTypes) {
+		gen.declare(uniqueName(goIdentifier(o.name), gen.used), o.schema, o.source)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by libopenapi. DO NOT EDIT.\n\n")
+	sb.WriteString("package " + g.packageName + "\n\n")
+	if len(gen.imports) > 0 {
+		var imports []string
+		for i := range gen.imports {
+			imports = append(imports, strconv.Quote(i))
+		}
+		sort.Strings(imports)
+		sb.WriteString("import (\n" + strings.Join(imports, "\n") + "\n)\n\n")
+	}
+	for _, decl := range gen.decls {
+		sb.WriteString(decl)
+		sb.WriteString("\n")
+	}
+	if gen.strictDecoder {
+		sb.WriteString(goDecodeStrict)
+	}
+	source := []byte(sb.String())
+	formatted, err := format.Source(source)
+	if err != nil {
+		return source, fmt.Errorf("unable to format generated types: %w", err)
+	}
+	return formatted, nil
+}
+
+// goDecodeStrict is added to the generated source when a oneOf without a discriminator needs to be decoded.
+const goDecodeStrict = `// decodeStrict decodes JSON into a value, failing if the JSON has fields the value doesn't have.
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+`
+
+type goGeneration struct {
+	decls         []string
+	used          map[string]bool   // type names that have been used.
+	refs          map[string]string // schema references (by name) and the types generated for them.
+	kinds         map[string]goKind
+	declared      map[string]bool
+	imports       map[string]bool
+	circular      map[string]bool
+	strictDecoder bool
+}
+
+// reserve records the type name of a referenced schema, before the type is generated.
+func (g *goGeneration) reserve(schemaName string) string {
+	if name, ok := g.refs[schemaName]; ok {
+		return name
+	}
+	name := uniqueName(goIdentifier(schemaName), g.used)
+	g.refs[schemaName] = name
+	return name
+}
+
+// declare generates a named type for a schema, and returns the name of the type.
+func (g *goGeneration) declare(name string, sp *highbase.SchemaProxy, source string) string {
+	if g.declared[name] {
+		return name
+	}
+	g.declared[name] = true
+	g.used[name] = true
+
+	// hold the place of the declaration, so types appear before the types nested inside them.
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	schema := sp.Schema()
+	if schema == nil {
+		g.kinds[name] = goAny
+		g.decls[slot] = g.typeComment(name, nil, source) + fmt.Sprintf("type %s any\n", name)
+		return name
+	}
+	g.kinds[name] = schemaKind(schema)
+	var decl string
+	switch g.kinds[name] {
+	case goOneOf:
+		decl = g.oneOf(name, schema, source)
+	case goStruct:
+		decl = g.typeComment(name, schema, source) + g.structType(name, schema)
+	default:
+		if len(schema.Enum) > 0 {
+			decl = g.enum(name, schema, source)
+		} else {
+			underlying, _ := g.underlying(schema, name, source)
+			decl = g.typeComment(name, schema, source) + fmt.Sprintf("type %s %s\n", name, underlying)
+		}
+	}
+	g.decls[slot] = decl
+	return name
+}
+
+// schemaKind decides which kind of Go type a schema becomes.
+func schemaKind(schema *highbase.Schema) goKind {
+	if schema == nil {
+		return goAny
+	}
+	types := schemaTypes(schema)
+	switch {
+	case len(schema.OneOf) > 0 || len(schema.AnyOf) > 0:
+		return goOneOf
+	case len(schema.AllOf) > 0 || orderedmap.Len(schema.Properties) > 0:
+		return goStruct
+	case len(types) != 1:
+		return goAny
+	case types[0] == "array" || (types[0] == "string" && (schema.Format == "binary" || schema.Format == "byte")):
+		return goSlice
+	case types[0] == "object":
+		return goMap
+	}
+	return goScalar
+}
+
+// typeOf returns the Go type of a schema used by a property, array or map, declaring named types when needed.
+func (g *goGeneration) typeOf(sp *highbase.SchemaProxy, hint, source string) (string, goKind) {
+	if sp == nil {
+		return "any", goAny
+	}
+	if sp.IsReference() {
+		schemaName := referenceName(sp.GetReference())
+		name, reserved := g.refs[schemaName]
+		if !reserved {
+			name = g.reserve(schemaName)
+			g.declare(name, sp, fmt.Sprintf("the '%s' schema", schemaName))
+		}
+		kind, ok := g.kinds[name]
+		if !ok {
+			kind = schemaKind(sp.Schema())
+		}
+		if kind == goOneOf {
+			return name + "Value", goOneOf
+		}
+		return name, kind
+	}
+	schema := sp.Schema()
+	if schema == nil {
+		return "any", goAny
+	}
+	kind := schemaKind(schema)
+	switch {
+	case kind == goOneOf:
+		return g.declare(uniqueName(hint, g.used), sp, source) + "Value", kind
+	case kind == goStruct || len(schema.Enum) > 0:
+		return g.declare(uniqueName(hint, g.used), sp, source), kind
+	}
+	return g.underlying(schema, hint, source)
+}
+
+// underlying returns the Go type of a schema that isn't a struct, enum or oneOf.
+func (g *goGeneration) underlying(schema *highbase.Schema, hint, source string) (string, goKind) {
+	types := schemaTypes(schema)
+	if len(types) != 1 {
+		return "any", goAny
+	}
+	switch types[0] {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time", goScalar
+		case "binary", "byte":
+			return "[]byte", goSlice
+		}
+		return "string", goScalar
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32", goScalar
+		}
+		return "int64", goScalar
+	case "number":
+		if schema.Format == "float" {
+			return "float32", goScalar
+		}
+		return "float64", goScalar
+	case "boolean":
+		return "bool", goScalar
+	case "array":
+		if schema.Items != nil && schema.Items.IsA() {
+			item, _ := g.typeOf(schema.Items.A, hint+"Item", "the items of "+source)
+			return "[]" + item, goSlice
+		}
+		return "[]any", goSlice
+	case "object":
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+			value, _ := g.typeOf(schema.AdditionalProperties.A, hint+"Value", "the values of "+source)
+			return "map[string]" + value, goMap
+		}
+		return "map[string]any", goMap
+	}
+	return "any", goAny
+}
+
+// goField is a field of a generated struct.
+type goField struct {
+	name     string
+	jsonName string
+	typ      string
+	embedded bool
+	optional bool
+	comment  string
+}
+
+// structType generates a struct for an object schema, embedding the types referenced by allOf.
+func (g *goGeneration) structType(name string, schema *highbase.Schema) string {
+	var fields []*goField
+	names := make(map[string]bool)
+	g.structFields(name, schema, &fields, names, make(map[*highbase.Schema]bool))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("type %s struct {\n", name))
+	for _, f := range fields {
+		if f.comment != "" {
+			sb.WriteString(commentLines(f.comment, "\t", "// "))
+		}
+		if f.embedded {
+			sb.WriteString(fmt.Sprintf("\t%s\n", f.typ))
+			continue
+		}
+		tag := f.jsonName
+		if f.optional {
+			tag += ",omitempty"
+		}
+		sb.WriteString(fmt.Sprintf("\t%s %s `json:%s`\n", f.name, f.typ, strconv.Quote(tag)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// structFields adds the fields of an object schema, and the schemas it is composed of, to a struct.
+func (g *goGeneration) structFields(name string, schema *highbase.Schema, fields *[]*goField, names map[string]bool,
+	seen map[*highbase.Schema]bool,
+) {
+	if seen[schema] {
+		return
+	}
+	seen[schema] = true
+	for _, member := range schema.AllOf {
+		if member == nil {
+			continue
+		}
+		if member.IsReference() {
+			typ, kind := g.typeOf(member, name, "")
+			if kind == goStruct {
+				if g.circular[name] && g.circular[typ] {
+					typ = "*" + typ
+				}
+				if !names[strings.TrimPrefix(typ, "*")] {
+					names[strings.TrimPrefix(typ, "*")] = true
+					*fields = append(*fields, &goField{typ: typ, embedded: true})
+				}
+				continue
+			}
+		}
+		if s := member.Schema(); s != nil {
+			g.structFields(name, s, fields, names, seen)
+		}
+	}
+	for propName, prop := range schema.Properties.FromOldest() {
+		fieldName := uniqueName(goIdentifier(propName), names)
+		typ, kind := g.typeOf(prop, name+fieldName, fmt.Sprintf("the '%s' property of %s", propName, name))
+		optional := !slices.Contains(schema.Required, propName)
+		var ps *highbase.Schema
+		if !prop.IsReference() {
+			ps = prop.Schema()
+		}
+		nullable := isNullable(ps)
+		circular := kind == goStruct && g.circular[name] && g.circular[typ]
+		switch kind {
+		case goScalar, goStruct, goOneOf:
+			if optional || nullable || circular {
+				typ = "*" + typ
+			}
+		}
+		f := &goField{name: fieldName, jsonName: propName, typ: typ, optional: optional}
+		if ps != nil {
+			f.comment = ps.Description
+			if ps.Deprecated != nil && *ps.Deprecated {
+				if f.comment != "" {
+					f.comment += "\n\n"
+				}
+				f.comment += "Deprecated: this property is deprecated."
+			}
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// enum generates a named type with a constant for every value of an enum. When the schema has no single type, the
+// type is worked out from the values. Constants are only generated for strings, numbers and booleans, other enums
+// are a plain type.
+func (g *goGeneration) enum(name string, schema *highbase.Schema, source string) string {
+	underlying, _ := g.underlying(schema, name, source)
+	if underlying == "any" {
+		underlying = enumUnderlying(schema.Enum)
+	}
+	var constants []string
+	used := make(map[string]bool)
+	for _, value := range schema.Enum {
+		if value == nil || value.Tag == "!!null" {
+			continue
+		}
+		literal, ok := enumLiteral(underlying, value)
+		if !ok {
+			continue
+		}
+		suffix := goIdentifier(value.Value)
+		if suffix == "" {
+			suffix = "Empty"
+		}
+		constants = append(constants, fmt.Sprintf("\t%s %s = %s\n", uniqueName(name+suffix, used), name, literal))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(g.typeComment(name, schema, source))
+	sb.WriteString(fmt.Sprintf("type %s %s\n", name, underlying))
+	if len(constants) > 0 {
+		sb.WriteString(fmt.Sprintf("\n// The values of %s.\nconst (\n", name))
+		sb.WriteString(strings.Join(constants, ""))
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
+
+// enumUnderlying works out the type of enum values that all have the same kind, any is returned otherwise.
+func enumUnderlying(values []*yaml.Node) string {
+	underlying := ""
+	for _, value := range values {
+		if value == nil || value.Tag == "!!null" {
+			continue
+		}
+		var t string
+		switch value.Tag {
+		case "!!str":
+			t = "string"
+		case "!!int":
+			t = "int64"
+		case "!!float":
+			t = "float64"
+		case "!!bool":
+			t = "bool"
+		default:
+			return "any"
+		}
+		switch {
+		case underlying == "" || underlying == t:
+			underlying = t
+		case (underlying == "int64" && t == "float64") || (underlying == "float64" && t == "int64"):
+			underlying = "float64"
+		default:
+			return "any"
+		}
+	}
+	if underlying == "" {
+		return "any"
+	}
+	return underlying
+}
+
+// enumLiteral returns the Go literal of an enum value for the underlying type of the enum, if it has one.
+func enumLiteral(underlying string, value *yaml.Node) (string, bool) {
+	switch underlying {
+	case "string":
+		return strconv.Quote(value.Value), true
+	case "int32", "int64":
+		if i, err := strconv.ParseInt(value.Value, 0, 64); err == nil {
+			return strconv.FormatInt(i, 10), true
+		}
+	case "float32", "float64":
+		if f, err := strconv.ParseFloat(value.Value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return strconv.FormatFloat(f, 'g', -1, 64), true
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(value.Value); err == nil {
+			return strconv.FormatBool(b), true
+		}
+	}
+	return "", false
+}
+
+// oneOf generates a sealed interface for a oneOf (or anyOf) schema, implemented by each variant, and a value type
+// that decodes the interface from JSON.
+func (g *goGeneration) oneOf(name string, schema *highbase.Schema, source string) string {
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+	proxies := schema.OneOf
+	if len(proxies) == 0 {
+		proxies = schema.AnyOf
+	}
+
+	type variant struct {
+		typ    string
+		values []string
+	}
+	var variants []*variant
+	for i, sp := range proxies {
+		typ, _ := g.typeOf(sp, fmt.Sprintf("%sOption%d", name, i+1), fmt.Sprintf("option %d of %s", i+1, name))
+		if !sp.IsReference() && !g.declared[typ] {
+			// variants need a named type to implement the interface.
+			typ = g.declare(uniqueName(fmt.Sprintf("%sOption%d", name, i+1), g.used), sp,
+				fmt.Sprintf("option %d of %s", i+1, name))
+		}
+		v := &variant{typ: strings.TrimPrefix(typ, "*")}
+		if sp.IsReference() {
+			v.values = []string{referenceName(sp.GetReference())}
+		}
+		variants = append(variants, v)
+	}
+
+	var property string
+	if schema.Discriminator != nil && schema.Discriminator.PropertyName != "" {
+		property = schema.Discriminator.PropertyName
+		for value, target := range schema.Discriminator.Mapping.FromOldest() {
+			for _, v := range variants {
+				if len(v.values) > 0 && (v.values[0] == referenceName(target) || v.values[0] == target) {
+					v.values = append(v.values, value)
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+	names := make([]string, 0, len(variants))
+	for _, v := range variants {
+		names = append(names, v.typ)
+	}
+	sb.WriteString(fmt.Sprintf("// %s is one of %s, it is generated from %s.\n", name, joinWords(names), source))
+	if schema.Description != "" {
+		sb.WriteString("//\n")
+		sb.WriteString(commentLines(schema.Description, "", "// "))
+	}
+	sb.WriteString(fmt.Sprintf("type %s interface {\n\tis%s()\n}\n\n", name, name))
+	for _, v := range variants {
+		sb.WriteString(fmt.Sprintf("func (%s) is%s() {}\n", v.typ, name))
+	}
+
+	value := name + "Value"
+	g.used[value] = true
+	sb.WriteString(fmt.Sprintf("\n// %s holds a %s, so it can be encoded and decoded as JSON.\n", value, name))
+	sb.WriteString(fmt.Sprintf("type %s struct {\n\t%s\n}\n\n", value, name))
+	sb.WriteString(fmt.Sprintf("// MarshalJSON encodes the %s held by the value.\n", name))
+	sb.WriteString(fmt.Sprintf("func (v %s) MarshalJSON() ([]byte, error) {\n\treturn json.Marshal(v.%s)\n}\n\n",
+		value, name))
+
+	if property != "" {
+		sb.WriteString(fmt.Sprintf("// UnmarshalJSON decodes a %s, using the '%s' property to choose its type.\n",
+			name, property))
+		sb.WriteString(fmt.Sprintf("func (v *%s) UnmarshalJSON(data []byte) error {\n", value))
+		sb.WriteString(fmt.Sprintf("\tvar discriminator struct {\n\t\tValue string `json:%s`\n\t}\n",
+			strconv.Quote(property)))
+		sb.WriteString("\tif err := json.Unmarshal(data, &discriminator); err != nil {\n\t\treturn err\n\t}\n")
+		sb.WriteString("\tswitch discriminator.Value {\n")
+		for _, v := range variants {
+			if len(v.values) == 0 {
+				continue
+			}
+			quoted := make([]string, 0, len(v.values))
+			for _, val := range v.values {
+				quoted = append(quoted, strconv.Quote(val))
+			}
+			sb.WriteString(fmt.Sprintf("\tcase %s:\n\t\tvar value %s\n", strings.Join(quoted, ", "), v.typ))
+			sb.WriteString("\t\tif err := json.Unmarshal(data, &value); err != nil {\n\t\t\treturn err\n\t\t}\n")
+			sb.WriteString(fmt.Sprintf("\t\tv.%s = value\n", name))
+		}
+		sb.WriteString(fmt.Sprintf("\tdefault:\n\t\treturn fmt.Errorf(\"unknown %s type '%%s'\", discriminator.Value)\n",
+			name))
+		sb.WriteString("\t}\n\treturn nil\n}\n")
+		return sb.String()
+	}
+
+	g.strictDecoder = true
+	g.imports["bytes"] = true
+	sb.WriteString(fmt.Sprintf("// UnmarshalJSON decodes a %s, using the first type the JSON can be decoded into.\n", name))
+	sb.WriteString(fmt.Sprintf("func (v *%s) UnmarshalJSON(data []byte) error {\n", value))
+	for i, v := range variants {
+		sb.WriteString(fmt.Sprintf("\tvar value%d %s\n", i+1, v.typ))
+		sb.WriteString(fmt.Sprintf("\tif decodeStrict(data, &value%d) == nil {\n\t\tv.%s = value%d\n\t\treturn nil\n\t}\n",
+			i+1, name, i+1))
+	}
+	sb.WriteString(fmt.Sprintf("\treturn fmt.Errorf(\"unable to decode %s, the JSON does not match any type\")\n}\n",
+		name))
+	return sb.String()
+}
+
+// typeComment returns the doc comment of a generated type.
+func (g *goGeneration) typeComment(name string, schema *highbase.Schema, source string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("// %s is generated from %s.\n", name, source))
+	if schema != nil && schema.Description != "" {
+		sb.WriteString("//\n")
+		sb.WriteString(commentLines(schema.Description, "", "// "))
+	}
+	if schema != nil && schema.Deprecated != nil && *schema.Deprecated {
+		sb.WriteString("//\n// Deprecated: this schema is deprecated.\n")
+	}
+	return sb.String()
+}
+
+// goIdentifier turns a name into an exported Go identifier, writing initialisms in upper case.
+func goIdentifier(name string) string {
+	var sb strings.Builder
+	for _, word := range identifierWords(name) {
+		if goInitialisms[strings.ToLower(word)] {
+			sb.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	id := sb.String()
+	if id != "" && unicode.IsDigit([]rune(id)[0]) {
+		id = "N" + id
+	}
+	return id
+}
+
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
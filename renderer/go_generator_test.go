@@ -0,0 +1,267 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var typeGeneratorSpec = `openapi: 3.1.0
+info:
+  title: gen
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                pet_id:
+                  type: string
+                  format: uuid
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    Pet:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: petType
+        mapping:
+          cat: '#/components/schemas/Cat'
+    Base:
+      type: object
+      description: The base of every pet.
+      required: [petType, status]
+      properties:
+        petType:
+          type: string
+        status:
+          type: string
+          enum: [available, sold, ""]
+        born:
+          type: string
+          format: date-time
+        nickname:
+          type: [string, "null"]
+        tags:
+          type: array
+          items:
+            type: string
+        attributes:
+          type: object
+          additionalProperties:
+            type: integer
+        owner:
+          type: object
+          properties:
+            id:
+              type: integer
+              format: int32
+    Cat:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            lives:
+              type: integer
+              deprecated: true
+              description: how many lives are left
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+      properties:
+        bark:
+          type: boolean
+        parent:
+          $ref: '#/components/schemas/Dog'
+    Shape:
+      anyOf:
+        - type: string
+        - type: object
+          properties:
+            sides:
+              type: integer`
+
+// typeCheckGo parses and type checks generated Go source.
+func typeCheckGo(t *testing.T, source []byte) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", source, parser.ParseComments)
+	require.NoError(t, err)
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("models", fset, []*ast.File{file}, nil)
+	require.NoError(t, err)
+}
+
+func TestGoTypeGenerator_Generate(t *testing.T) {
+	gen := NewGoTypeGenerator(buildModel(t, typeGeneratorSpec))
+	gen.SetPackageName("pets")
+	out, err := gen.Generate()
+	require.NoError(t, err)
+	typeCheckGo(t, out)
+	source := string(out)
+
+	assert.Contains(t, source, "// Code generated by libopenapi. DO NOT EDIT.\n\npackage pets\n")
+
+	// required properties are values, optional and nullable ones are pointers.
+	assert.Contains(t, source, "PetType    string           `json:\"petType\"`")
+	assert.Contains(t, source, "Born       *time.Time       `json:\"born,omitempty\"`")
+	assert.Contains(t, source, "Nickname   *string          `json:\"nickname,omitempty\"`")
+	assert.Contains(t, source, "Tags       []string         `json:\"tags,omitempty\"`")
+	assert.Contains(t, source, "Attributes map[string]int64 `json:\"attributes,omitempty\"`")
+	assert.Contains(t, source, "// Base is generated from the 'Base' schema.\n//\n// The base of every pet.\n")
+
+	// nested objects and enums are named after the property that holds them.
+	assert.Contains(t, source, "Owner      *BaseOwner       `json:\"owner,omitempty\"`")
+	assert.Contains(t, source, "type BaseOwner struct {\n\tID *int32 `json:\"id,omitempty\"`\n}")
+	assert.Contains(t, source, "type BaseStatus string")
+	assert.Contains(t, source, "BaseStatusAvailable BaseStatus = \"available\"")
+	assert.Contains(t, source, "BaseStatusEmpty     BaseStatus = \"\"")
+
+	// allOf embeds the referenced types, and holds the properties of inline schemas.
+	assert.Contains(t, source, "type Cat struct {\n\tBase\n\t// how many lives are left\n\t//\n"+
+		"\t// Deprecated: this property is deprecated.\n\tLives *int64 `json:\"lives,omitempty\"`\n}")
+	assert.Contains(t, source, "type Dog struct {\n\tBase\n\tBark   *bool `json:\"bark,omitempty\"`\n"+
+		"\tParent *Dog  `json:\"parent,omitempty\"`\n}")
+
+	// oneOf is a sealed interface, decoded using the discriminator.
+	assert.Contains(t, source, "type Pet interface {\n\tisPet()\n}")
+	assert.Contains(t, source, "func (Cat) isPet() {}")
+	assert.Contains(t, source, "case \"Cat\", \"cat\":")
+	assert.Contains(t, source, "case \"Dog\":")
+
+	// anyOf without a discriminator tries each type.
+	assert.Contains(t, source, "type ShapeOption1 string")
+	assert.Contains(t, source, "if decodeStrict(data, &value2) == nil {")
+	assert.Contains(t, source, "func decodeStrict(data []byte, v any) error {")
+
+	// inline request and response schemas are named after the operation.
+	assert.Contains(t, source, "type ListPets200Response []PetValue")
+	assert.Contains(t, source, "PetID *string `json:\"pet_id,omitempty\"`")
+}
+
+func TestGoTypeGenerator_Circular(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: circular
+  version: 1.0.0
+components:
+  schemas:
+    Node:
+      type: object
+      required: [child]
+      properties:
+        child:
+          $ref: '#/components/schemas/Child'
+        siblings:
+          type: array
+          items:
+            $ref: '#/components/schemas/Node'
+    Child:
+      type: object
+      required: [node]
+      properties:
+        node:
+          $ref: '#/components/schemas/Node'
+        label:
+          type: string
+          format: binary`
+	doc, err := libopenapi.NewDocument([]byte(spec))
+	require.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	require.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "infinite circular reference detected: Child")
+	assert.ErrorContains(t, errs[1], "infinite circular reference detected: Node")
+
+	out, err := NewGoTypeGenerator(&m.Model).Generate()
+	require.NoError(t, err)
+	typeCheckGo(t, out)
+
+	// required properties that are part of a circular reference are pointers, so the types don't hold themselves.
+	assert.Contains(t, string(out), "Child    *Child `json:\"child\"`")
+	assert.Contains(t, string(out), "Siblings []Node `json:\"siblings,omitempty\"`")
+	assert.Contains(t, string(out), "Node  *Node  `json:\"node\"`")
+	assert.Contains(t, string(out), "Label []byte `json:\"label,omitempty\"`")
+	assert.NotContains(t, string(out), "import")
+}
+
+func TestGoTypeGenerator_Enums(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: enums
+  version: 1.0.0
+components:
+  schemas:
+    Release:
+      type: string
+      format: date-time
+      enum: [2024-01-01T00:00:00Z, 2025-01-01T00:00:00Z]
+    Level:
+      enum: [1, 2.5, 3]
+    Mixed:
+      enum: [one, 2, true]
+    Untyped:
+      enum: [red, green]`
+
+	out, err := NewGoTypeGenerator(buildModel(t, spec)).Generate()
+	require.NoError(t, err)
+	typeCheckGo(t, out)
+	source := string(out)
+
+	// types without basic constants are declared without any.
+	assert.Contains(t, source, "type Release time.Time\n")
+	assert.NotContains(t, source, "ReleaseT")
+	assert.Contains(t, source, "type Mixed any\n")
+	assert.NotContains(t, source, "MixedOne")
+
+	// the type of an untyped enum is worked out from its values.
+	assert.Contains(t, source, "type Level float64\n")
+	assert.Contains(t, source, "LevelN25 Level = 2.5")
+	assert.Contains(t, source, "type Untyped string\n")
+	assert.Contains(t, source, "UntypedRed   Untyped = \"red\"")
+}
+
+func TestGoTypeGenerator_Empty(t *testing.T) {
+	out, err := NewGoTypeGenerator(nil).Generate()
+	require.NoError(t, err)
+	assert.Equal(t, "// Code generated by libopenapi. DO NOT EDIT.\n\npackage models\n", string(out))
+}
+
+func TestGoIdentifier(t *testing.T) {
+	assert.Equal(t, "PetID", goIdentifier("pet_id"))
+	assert.Equal(t, "PetID", goIdentifier("petId"))
+	assert.Equal(t, "HTTPServerURL", goIdentifier("HTTPServerUrl"))
+	assert.Equal(t, "N200Response", goIdentifier("200 response"))
+	assert.Equal(t, "", goIdentifier("--"))
+}
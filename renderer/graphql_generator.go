@@ -0,0 +1,540 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// gqlBuiltIns are type names that generated types cannot use.
+var gqlBuiltIns = []string{"Query", "Mutation", "Subscription", "String", "Int", "Float", "Boolean", "ID", "JSON",
+	"DateTime"}
+
+// gqlMode decides if a schema is generated as an output type, or an input type.
+type gqlMode int
+
+const (
+	gqlOutput gqlMode = iota
+	gqlInput
+)
+
+// gqlKind is the kind of GraphQL type a schema becomes.
+type gqlKind int
+
+const (
+	gqlScalar gqlKind = iota
+	gqlObject
+	gqlUnion
+	gqlEnum
+)
+
+// GraphQLGenerator generates a GraphQL schema (SDL) from a document.
+//
+//   - Object schemas in the components become types, and input types ('<Name>Input') when they are used by request
+//     bodies or parameters. Read only properties are left out of input types, write only properties are left out of
+//     types. allOf schemas hold the fields of every schema they are composed of.
+//   - oneOf (and anyOf) schemas of objects become unions, and '@oneOf' input types.
+//   - String enums become enums.
+//   - GET operations become fields of Query, POST, PUT, PATCH and DELETE operations become fields of Mutation. The
+//     parameters are arguments, and the request body is the 'input' argument. A field returns the schema of the first
+//     successful response, or Boolean when it has no content.
+//   - Schemas that cannot be typed (maps, schemas without a type, or with many types) use a JSON scalar, and
+//     date-time strings use a DateTime scalar.
+//
+// Names are made valid GraphQL names. Names that are already taken have a number added, in the order they are
+// generated, so '<Name>Input' becomes '<Name>Input2' when the document has a '<Name>Input' schema, and a type
+// named after a built-in type, such as 'Query', becomes 'Query2'.
+//
+// Use NewGraphQLGenerator to create a new GraphQLGenerator.
+type GraphQLGenerator struct {
+	document   *v3.Document
+	mediaTypes []string
+}
+
+// NewGraphQLGenerator creates a new GraphQLGenerator for the supplied document.
+func NewGraphQLGenerator(document *v3.Document) *GraphQLGenerator {
+	return &GraphQLGenerator{
+		document:   document,
+		mediaTypes: DefaultPreferredMediaTypes,
+	}
+}
+
+// SetPreferredMediaTypes sets the order in which media types are picked, when generating types for parameters,
+// request bodies and responses that support more than one media type.
+func (q *GraphQLGenerator) SetPreferredMediaTypes(mediaTypes ...string) {
+	q.mediaTypes = mediaTypes
+}
+
+// Generate generates the GraphQL schema.
+func (q *GraphQLGenerator) Generate() []byte {
+	gen := &gqlGeneration{
+		used:     make(map[string]bool),
+		names:    make(map[string]string),
+		declared: make(map[string]bool),
+		inlined:  make(map[gqlInlineKey]string),
+		scalars:  make(map[string]bool),
+		lists:    make(map[string]bool),
+	}
+	for _, name := range gqlBuiltIns {
+		gen.used[name] = true
+	}
+
+	// reserve the names of the components first, so they keep their names.
+	components := componentSchemas(q.document)
+	for _, c := range components {
+		if s := c.schema.Schema(); gqlKindOf(s) != gqlScalar {
+			gen.name(c.name, gqlOutput, s)
+		}
+	}
+	for _, c := range components {
+		gen.typeOf(highbase.CreateSchemaProxyRef(c.source), c.schema, gqlOutput, "")
+	}
+	query, mutation := q.operations(gen)
+
+	var sb strings.Builder
+	sb.WriteString("# Code generated by libopenapi. DO NOT EDIT.\n")
+	for _, scalar := range []string{"DateTime", "JSON"} {
+		if gen.scalars[scalar] {
+			sb.WriteString("\nscalar " + scalar + "\n")
+		}
+	}
+	for _, decl := range append(append(gen.decls, query), mutation) {
+		if decl != "" {
+			sb.WriteString("\n")
+			sb.WriteString(decl)
+		}
+	}
+	return []byte(sb.String())
+}
+
+// operations generates the fields of Query and Mutation.
+func (q *GraphQLGenerator) operations(gen *gqlGeneration) (string, string) {
+	if q.document == nil || q.document.Paths == nil {
+		return "", ""
+	}
+	roots := map[string]*strings.Builder{"Query": {}, "Mutation": {}}
+	fields := map[string]map[string]bool{"Query": {}, "Mutation": {}}
+	for pathName, pathItem := range q.document.Paths.PathItems.FromOldest() {
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			root := "Mutation"
+			switch method {
+			case "get":
+				root = "Query"
+			case "head", "options", "trace":
+				continue
+			}
+			opName := operation.OperationId
+			if opName == "" {
+				opName = method + " " + pathName
+			}
+			base := pascalCase(opName)
+
+			var args []string
+			argNames := make(map[string]bool)
+			for _, p := range mergeParameters(pathItem, operation) {
+				sp := p.Schema
+				if sp == nil {
+					sp = preferredSchema(p.Content, q.mediaTypes)
+				}
+				argType := gen.fieldType(sp, gqlInput, base+pascalCase(p.Name),
+					p.In == "path" || (p.Required != nil && *p.Required))
+				args = append(args, gqlDescription(p.Description, "    ")+fmt.Sprintf("    %s: %s\n",
+					uniqueName(gqlFieldName(p.Name), argNames), argType))
+			}
+			if operation.RequestBody != nil {
+				if sp := preferredSchema(operation.RequestBody.Content, q.mediaTypes); sp != nil {
+					required := operation.RequestBody.Required != nil && *operation.RequestBody.Required
+					args = append(args, gqlDescription(operation.RequestBody.Description, "    ")+
+						fmt.Sprintf("    %s: %s\n", uniqueName("input", argNames),
+							gen.fieldType(sp, gqlInput, base, required)))
+				}
+			}
+
+			returns := "Boolean"
+			if operation.Responses != nil {
+				for code, response := range operation.Responses.Codes.FromOldest() {
+					if sp := preferredSchema(response.Content, q.mediaTypes); sp != nil && strings.HasPrefix(code, "2") {
+						returns = gen.fieldType(sp, gqlOutput, base+"Response", true)
+						break
+					}
+				}
+			}
+
+			sb := roots[root]
+			description := operation.Summary
+			if operation.Description != "" {
+				description = strings.TrimSpace(description + "\n\n" + operation.Description)
+			}
+			sb.WriteString(gqlDescription(description, "  "))
+			sb.WriteString("  " + uniqueName(gqlFieldName(opName), fields[root]))
+			if len(args) > 0 {
+				sb.WriteString("(\n" + strings.Join(args, "") + "  )")
+			}
+			sb.WriteString(": " + returns)
+			if operation.Deprecated != nil && *operation.Deprecated {
+				sb.WriteString(" @deprecated")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if roots["Query"].Len() == 0 && roots["Mutation"].Len() == 0 {
+		return "", ""
+	}
+	if roots["Query"].Len() == 0 {
+		// a schema must have a query type, even if it has no fields to query.
+		roots["Query"].WriteString("  _empty: Boolean\n")
+	}
+	query := "type Query {\n" + roots["Query"].String() + "}\n"
+	if roots["Mutation"].Len() == 0 {
+		return query, ""
+	}
+	return query, "type Mutation {\n" + roots["Mutation"].String() + "}\n"
+}
+
+type gqlGeneration struct {
+	decls    []string
+	used     map[string]bool         // type names.
+	names    map[string]string       // referenced schemas (by name and mode) and the types generated for them.
+	declared map[string]bool         // the types that have been declared, not only named.
+	inlined  map[gqlInlineKey]string // inline schemas and the types generated for them.
+	scalars  map[string]bool         // the custom scalars that are used.
+	lists    map[string]bool         // the referenced list schemas being typed, to stop lists of themselves.
+}
+
+// gqlInlineKey identifies the type generated for an inline schema. Enums are the same type in both modes.
+type gqlInlineKey struct {
+	node *yaml.Node
+	mode gqlMode
+}
+
+// gqlKindOf decides which kind of GraphQL type a schema becomes. oneOf and anyOf schemas can only become unions if
+// they only hold objects.
+func gqlKindOf(schema *highbase.Schema) gqlKind {
+	if schema == nil {
+		return gqlScalar
+	}
+	types := schemaTypes(schema)
+	switch {
+	case len(schema.OneOf) > 0 || len(schema.AnyOf) > 0:
+		variants := schema.OneOf
+		if len(variants) == 0 {
+			variants = schema.AnyOf
+		}
+		for _, sp := range variants {
+			if sp == nil || gqlKindOf(sp.Schema()) != gqlObject {
+				return gqlScalar
+			}
+		}
+		return gqlUnion
+	case len(schema.AllOf) > 0 || orderedmap.Len(schema.Properties) > 0:
+		return gqlObject
+	case len(schema.Enum) > 0 && len(types) == 1 && types[0] == "string":
+		return gqlEnum
+	}
+	return gqlScalar
+}
+
+// name returns the name of the type generated for a referenced schema, reserving it if it hasn't been generated.
+// Enums are the same type in both modes, input types are named '<Name>Input'.
+func (g *gqlGeneration) name(schemaName string, mode gqlMode, schema *highbase.Schema) string {
+	key := schemaName
+	suffix := ""
+	if mode == gqlInput && gqlKindOf(schema) != gqlEnum {
+		key += "\x00input"
+		suffix = "Input"
+	}
+	if name, ok := g.names[key]; ok {
+		return name
+	}
+	name := uniqueName(gqlTypeName(schemaName)+suffix, g.used)
+	g.names[key] = name
+	return name
+}
+
+// fieldType returns the type of a field or argument, which is not null when it is required, and not nullable.
+func (g *gqlGeneration) fieldType(sp *highbase.SchemaProxy, mode gqlMode, hint string, required bool) string {
+	var schema *highbase.Schema
+	if sp != nil {
+		schema = sp.Schema()
+	}
+	t := g.typeOf(sp, sp, mode, hint)
+	if required && !isNullable(schema) {
+		t += "!"
+	}
+	return t
+}
+
+// typeOf returns the GraphQL type of a schema, declaring the types it needs.
+func (g *gqlGeneration) typeOf(ref, sp *highbase.SchemaProxy, mode gqlMode, hint string) string {
+	if sp == nil {
+		g.scalars["JSON"] = true
+		return "JSON"
+	}
+	schema := sp.Schema()
+	kind := gqlKindOf(schema)
+	if kind == gqlScalar {
+		if ref == nil || !ref.IsReference() {
+			return g.scalar(schema, mode, hint)
+		}
+		// a list that holds itself, directly or through other lists, cannot be typed.
+		reference := ref.GetReference()
+		if g.lists[reference] {
+			g.scalars["JSON"] = true
+			return "JSON"
+		}
+		g.lists[reference] = true
+		defer delete(g.lists, reference)
+		return g.scalar(schema, mode, hint)
+	}
+
+	var name, base string
+	if ref != nil && ref.IsReference() {
+		schemaName := referenceName(ref.GetReference())
+		base = gqlTypeName(schemaName)
+		name = g.name(schemaName, mode, schema)
+		if g.declared[name] {
+			return name
+		}
+	} else {
+		// inline schemas reached more than once, such as through allOf, are only declared once.
+		key := gqlInlineKey{node: schemaIdentity(sp), mode: mode}
+		if kind == gqlEnum {
+			key.mode = gqlOutput
+		}
+		if name, ok := g.inlined[key]; ok && key.node != nil {
+			return name
+		}
+		base = gqlTypeName(hint)
+		name = base
+		if mode == gqlInput && kind != gqlEnum {
+			name += "Input"
+		}
+		name = uniqueName(name, g.used)
+		if key.node != nil {
+			g.inlined[key] = name
+		}
+	}
+	g.declared[name] = true
+
+	// hold the place of the declaration, so types appear before the types nested inside them.
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+	switch kind {
+	case gqlEnum:
+		g.decls[slot] = g.enum(name, schema)
+	case gqlUnion:
+		g.decls[slot] = g.union(name, base, schema, mode)
+	default:
+		g.decls[slot] = g.object(name, base, schema, mode)
+	}
+	return name
+}
+
+// scalar returns the scalar type of a schema, or a list of the type of its items.
+func (g *gqlGeneration) scalar(schema *highbase.Schema, mode gqlMode, hint string) string {
+	types := schemaTypes(schema)
+	if schema != nil && len(types) == 1 {
+		switch types[0] {
+		case "string":
+			if schema.Format == "date-time" {
+				g.scalars["DateTime"] = true
+				return "DateTime"
+			}
+			return "String"
+		case "integer":
+			return "Int"
+		case "number":
+			return "Float"
+		case "boolean":
+			return "Boolean"
+		case "array":
+			if len(schema.PrefixItems) == 0 && schema.Items != nil && schema.Items.IsA() {
+				item := schema.Items.A
+				return "[" + g.fieldType(item, mode, hint+"Item", true) + "]"
+			}
+		}
+	}
+	g.scalars["JSON"] = true
+	return "JSON"
+}
+
+// gqlField is a field of a type or input type.
+type gqlField struct {
+	owner    string // the name of the schema that holds the property, which names its inline types.
+	name     string
+	schema   *highbase.SchemaProxy
+	required bool
+}
+
+// object declares a type, or an input type, with a field for every property of a schema, and the schemas in its
+// allOf.
+func (g *gqlGeneration) object(name, base string, schema *highbase.Schema, mode gqlMode) string {
+	var fields []*gqlField
+	gqlFields(schema, base, &fields, make(map[string]bool), make(map[*highbase.Schema]bool))
+
+	var sb strings.Builder
+	keyword := "type"
+	if mode == gqlInput {
+		keyword = "input"
+	}
+	sb.WriteString(gqlDescription(schema.Description, ""))
+	sb.WriteString(fmt.Sprintf("%s %s {\n", keyword, name))
+	fieldNames := make(map[string]bool)
+	for _, f := range fields {
+		var ps *highbase.Schema
+		if f.schema != nil {
+			ps = f.schema.Schema()
+		}
+		if ps != nil && ((mode == gqlInput && ps.ReadOnly != nil && *ps.ReadOnly) ||
+			(mode == gqlOutput && ps.WriteOnly != nil && *ps.WriteOnly)) {
+			continue
+		}
+		if f.schema != nil && !f.schema.IsReference() && ps != nil {
+			sb.WriteString(gqlDescription(ps.Description, "  "))
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s", uniqueName(gqlFieldName(f.name), fieldNames),
+			g.fieldType(f.schema, mode, f.owner+pascalCase(f.name), f.required)))
+		if mode == gqlOutput && f.schema != nil && !f.schema.IsReference() && ps != nil && ps.Deprecated != nil &&
+			*ps.Deprecated {
+			sb.WriteString(" @deprecated")
+		}
+		sb.WriteString("\n")
+	}
+	if len(fieldNames) == 0 {
+		// types must have at least one field.
+		sb.WriteString("  _empty: Boolean\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// gqlFields collects the properties of a schema, and of the schemas in its allOf.
+func gqlFields(schema *highbase.Schema, owner string, fields *[]*gqlField, names map[string]bool,
+	seen map[*highbase.Schema]bool,
+) {
+	if schema == nil || seen[schema] {
+		return
+	}
+	seen[schema] = true
+	for _, member := range schema.AllOf {
+		if member == nil {
+			continue
+		}
+		memberOwner := owner
+		if member.IsReference() {
+			memberOwner = gqlTypeName(referenceName(member.GetReference()))
+		}
+		gqlFields(member.Schema(), memberOwner, fields, names, seen)
+	}
+	for propName, sp := range schema.Properties.FromOldest() {
+		if names[propName] {
+			continue
+		}
+		names[propName] = true
+		f := &gqlField{owner: owner, name: propName, schema: sp}
+		for _, r := range schema.Required {
+			f.required = f.required || r == propName
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// union declares a union of the object types of a oneOf (or anyOf) schema, or an '@oneOf' input type, which holds
+// exactly one of them.
+func (g *gqlGeneration) union(name, base string, schema *highbase.Schema, mode gqlMode) string {
+	variants := schema.OneOf
+	if len(variants) == 0 {
+		variants = schema.AnyOf
+	}
+	var members, memberNames []string
+	for i, sp := range variants {
+		memberName := fmt.Sprintf("option%d", i+1)
+		if sp.IsReference() {
+			memberName = referenceName(sp.GetReference())
+		}
+		members = append(members, g.typeOf(sp, sp, mode, base+pascalCase(memberName)))
+		memberNames = append(memberNames, memberName)
+	}
+	description := gqlDescription(schema.Description, "")
+	if mode == gqlOutput {
+		return description + fmt.Sprintf("union %s = %s\n", name, strings.Join(members, " | "))
+	}
+	var sb strings.Builder
+	sb.WriteString(description)
+	sb.WriteString(fmt.Sprintf("input %s @oneOf {\n", name))
+	fieldNames := make(map[string]bool)
+	for i, member := range members {
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", uniqueName(gqlFieldName(memberNames[i]), fieldNames), member))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// enum declares an enum for a string enum schema.
+func (g *gqlGeneration) enum(name string, schema *highbase.Schema) string {
+	var sb strings.Builder
+	sb.WriteString(gqlDescription(schema.Description, ""))
+	sb.WriteString(fmt.Sprintf("enum %s {\n", name))
+	values := make(map[string]bool)
+	for _, value := range schema.Enum {
+		if value == nil || value.Tag == "!!null" {
+			continue
+		}
+		v := protoConstantName(value.Value)
+		if v == "" || unicode.IsDigit([]rune(v)[0]) {
+			v = "_" + v
+		}
+		sb.WriteString("  " + uniqueName(v, values) + "\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// gqlTypeName turns a name into a GraphQL type name.
+func gqlTypeName(name string) string {
+	id := pascalCase(name)
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "_" + id
+	}
+	return id
+}
+
+// gqlFieldName turns a name into a GraphQL field name, in lower camel case.
+func gqlFieldName(name string) string {
+	var sb strings.Builder
+	for i, word := range identifierWords(name) {
+		if i == 0 {
+			sb.WriteString(strings.ToLower(word))
+			continue
+		}
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	id := sb.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "_" + id
+	}
+	return id
+}
+
+// gqlDescription returns a description, as a block string when it has more than one line.
+func gqlDescription(description, indent string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return ""
+	}
+	if !strings.Contains(description, "\n") {
+		return indent + tsString(description) + "\n"
+	}
+	description = strings.ReplaceAll(description, `"""`, `\"""`)
+	return indent + `"""` + "\n" + commentLines(description, indent, "") + indent + `"""` + "\n"
+}
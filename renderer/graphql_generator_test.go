@@ -0,0 +1,355 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var graphqlSpec = `openapi: 3.1.0
+info:
+  title: pet store
+  version: 1.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List all pets.
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+        - name: X-Request-ID
+          in: header
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+    delete:
+      operationId: deletePet
+      deprecated: true
+      responses:
+        "204":
+          description: deleted
+  /pets/{petId}/tags:
+    put:
+      operationId: tagPet
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema:
+            type: string
+        - name: input
+          in: query
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [tags]
+              properties:
+                tags:
+                  type: array
+                  items:
+                    type: string
+      responses:
+        "200":
+          description: tagged
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  count:
+                    type: integer
+components:
+  schemas:
+    Pet:
+      description: A pet.
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: kind
+    Base:
+      type: object
+      required: [id, kind]
+      properties:
+        id:
+          type: string
+          readOnly: true
+        kind:
+          type: string
+        born:
+          type: string
+          format: date-time
+        status:
+          type: string
+          enum: [available, sold-out]
+    Cat:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            lives:
+              type: integer
+            friend:
+              $ref: '#/components/schemas/Cat'
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            good-boy:
+              type: boolean
+              deprecated: true
+            extra:
+              type: object
+              additionalProperties: true
+    CatInput:
+      type: object
+      properties:
+        name:
+          type: string
+    Query:
+      type: object
+      properties:
+        text:
+          type: string
+`
+
+func TestGraphQLGenerator_Generate(t *testing.T) {
+	doc := buildModel(t, graphqlSpec)
+	sdl := string(NewGraphQLGenerator(doc).Generate())
+
+	assert.Equal(t, `# Code generated by libopenapi. DO NOT EDIT.
+
+scalar DateTime
+
+scalar JSON
+
+"A pet."
+union Pet = Cat | Dog
+
+type Cat {
+  id: String!
+  kind: String!
+  born: DateTime
+  status: BaseStatus
+  lives: Int
+  friend: Cat
+}
+
+enum BaseStatus {
+  AVAILABLE
+  SOLD_OUT
+}
+
+type Dog {
+  id: String!
+  kind: String!
+  born: DateTime
+  status: BaseStatus
+  goodBoy: Boolean @deprecated
+  extra: JSON
+}
+
+type Base {
+  id: String!
+  kind: String!
+  born: DateTime
+  status: BaseStatus
+}
+
+type CatInput {
+  name: String
+}
+
+type Query2 {
+  text: String
+}
+
+"A pet."
+input PetInput @oneOf {
+  cat: CatInput2
+  dog: DogInput
+}
+
+input CatInput2 {
+  kind: String!
+  born: DateTime
+  status: BaseStatus
+  lives: Int
+  friend: CatInput2
+}
+
+input DogInput {
+  kind: String!
+  born: DateTime
+  status: BaseStatus
+  goodBoy: Boolean
+  extra: JSON
+}
+
+input TagPetInput {
+  tags: [String!]!
+}
+
+type TagPetResponse {
+  count: Int
+}
+
+type Query {
+  "List all pets."
+  listPets(
+    limit: Int
+    xRequestID: String!
+  ): [Pet!]!
+  getPet(
+    petId: String!
+  ): Pet!
+}
+
+type Mutation {
+  createPet(
+    input: PetInput!
+  ): Pet!
+  deletePet(
+    petId: String!
+  ): Boolean @deprecated
+  tagPet(
+    petId: String!
+    input: String
+    input2: TagPetInput
+  ): TagPetResponse!
+}
+`, sdl)
+}
+
+func TestGraphQLGenerator_Generate_Empty(t *testing.T) {
+	doc := buildModel(t, `openapi: 3.1.0
+info:
+  title: empty
+  version: 1.0.0`)
+	assert.Equal(t, "# Code generated by libopenapi. DO NOT EDIT.\n", string(NewGraphQLGenerator(doc).Generate()))
+}
+
+func TestGraphQLGenerator_Generate_MutationsOnly(t *testing.T) {
+	doc := buildModel(t, `openapi: 3.1.0
+info:
+  title: mutations
+  version: 1.0.0
+paths:
+  /things:
+    post:
+      description: |
+        Creates a thing.
+        Returns nothing.
+      requestBody:
+        content:
+          text/plain:
+            schema:
+              type: string
+      responses:
+        "204":
+          description: created`)
+	assert.Equal(t, `# Code generated by libopenapi. DO NOT EDIT.
+
+type Query {
+  _empty: Boolean
+}
+
+type Mutation {
+  """
+  Creates a thing.
+  Returns nothing.
+  """
+  postThings(
+    input: String
+  ): Boolean
+}
+`, string(NewGraphQLGenerator(doc).Generate()))
+}
+
+func TestGraphQLGenerator_Generate_ListOfItself(t *testing.T) {
+	doc := buildModel(t, `openapi: 3.1.0
+info:
+  title: lists
+  version: 1.0.0
+paths:
+  /lists:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/List'
+components:
+  schemas:
+    List:
+      type: array
+      items:
+        $ref: '#/components/schemas/List'`)
+	assert.Equal(t, `# Code generated by libopenapi. DO NOT EDIT.
+
+scalar JSON
+
+type Query {
+  getLists: [JSON!]!
+}
+`, string(NewGraphQLGenerator(doc).Generate()))
+}
+
+func TestGraphQLGenerator_Names(t *testing.T) {
+	assert.Equal(t, "xRequestID", gqlFieldName("X-Request-ID"))
+	assert.Equal(t, "_2fa", gqlFieldName("2fa"))
+	assert.Equal(t, "_1Thing", gqlTypeName("1 thing"))
+	assert.Equal(t, "", gqlDescription("  ", ""))
+}
@@ -0,0 +1,539 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// DefaultReferenceSchemaDepth is how deep schema trees are expanded in API references, before references are
+// only linked to.
+const DefaultReferenceSchemaDepth = 8
+
+// htmlReferenceStyle is the stylesheet of the HTML API reference.
+const htmlReferenceStyle = `*{box-sizing:border-box}
+body{margin:0;font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;color:#1f2328;line-height:1.5}
+nav{position:fixed;top:0;bottom:0;left:0;width:280px;overflow-y:auto;padding:1rem;background:#f6f8fa;border-right:1px solid #d0d7de;font-size:.875rem}
+nav ul{list-style:none;margin:0 0 1rem;padding:0}
+nav a{display:block;padding:.15rem 0;color:inherit;text-decoration:none}
+nav h2{font-size:.75rem;text-transform:uppercase;margin:1rem 0 .25rem;color:#656d76}
+main{margin-left:280px;padding:1rem 2rem;max-width:1100px}
+code,pre{font-family:ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;font-size:.85em}
+pre{background:#f6f8fa;padding:.75rem;overflow-x:auto;border-radius:6px}
+table{border-collapse:collapse;width:100%;margin-bottom:1rem}
+th,td{border:1px solid #d0d7de;padding:.4rem .6rem;text-align:left;vertical-align:top}
+th{background:#f6f8fa}
+.operation{border-top:1px solid #d0d7de;padding-top:1rem;margin-top:2rem}
+.method{display:inline-block;min-width:4.5em;padding:0 .4em;border-radius:4px;color:#fff;font-size:.75rem;font-weight:600;text-align:center;background:#6e7781}
+.method.get{background:#1f883d}.method.post{background:#0969da}.method.put{background:#9a6700}.method.patch{background:#8250df}.method.delete{background:#cf222e}
+.deprecated{text-decoration:line-through}
+.badge{display:inline-block;padding:0 .4em;border-radius:4px;font-size:.75rem;background:#eaeef2}
+.badge.warning{background:#fff8c5}
+.schema{list-style:none;padding-left:1rem;border-left:1px solid #d0d7de}
+.schema>li{margin:.25rem 0}
+.property{font-family:ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;font-weight:600}
+.type{color:#656d76;font-family:ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;font-size:.85em}
+.required{color:#cf222e;font-size:.75rem}
+details>summary{cursor:pointer}
+`
+
+// HTMLReferenceGenerator generates a static HTML API reference for a document, as a single page without any
+// scripts or external resources.
+//
+// The page lists the operations grouped by their first tag (untagged operations, then webhooks follow), with their
+// parameters, request bodies and responses, and the schemas of the components. Descriptions are rendered from
+// Markdown. Schemas are rendered as trees, where references can be expanded and collapsed. References to circular
+// schemas (found by the index), or references found inside themselves, link to the schema instead, so trees are
+// always finite.
+//
+// Examples are taken from the document. If a MockGenerator is set, mocks are generated for request and response
+// bodies that have no examples. Mocks use random values, so the output is only deterministic without a
+// MockGenerator (or with value providers that are deterministic).
+//
+// Use NewHTMLReferenceGenerator to create a new HTMLReferenceGenerator.
+type HTMLReferenceGenerator struct {
+	document *v3.Document
+	mock     *MockGenerator
+	depth    int
+}
+
+// NewHTMLReferenceGenerator creates a new HTMLReferenceGenerator for the supplied document.
+func NewHTMLReferenceGenerator(document *v3.Document) *HTMLReferenceGenerator {
+	return &HTMLReferenceGenerator{document: document, depth: DefaultReferenceSchemaDepth}
+}
+
+// SetMockGenerator sets the MockGenerator used to generate examples for bodies without examples.
+func (h *HTMLReferenceGenerator) SetMockGenerator(mock *MockGenerator) {
+	h.mock = mock
+}
+
+// SetSchemaDepth sets how deep schema trees are expanded, before references are only linked to.
+func (h *HTMLReferenceGenerator) SetSchemaDepth(depth int) {
+	h.depth = depth
+}
+
+// htmlReference is the state of a single generation.
+type htmlReference struct {
+	*HTMLReferenceGenerator
+	sb       *strings.Builder
+	circular map[string]bool
+	schemas  map[string]string // component schema names and their anchors.
+}
+
+// Generate generates the HTML API reference.
+func (h *HTMLReferenceGenerator) Generate() []byte {
+	r := &htmlReference{
+		HTMLReferenceGenerator: h,
+		sb:                     &strings.Builder{},
+		circular:               circularSchemaNames(h.document),
+		schemas:                make(map[string]string),
+	}
+	anchors := make(map[string]bool)
+	groups := referenceGroups(h.document, anchors)
+	components := componentSchemas(h.document)
+	for _, c := range components {
+		if _, ok := r.schemas[c.name]; !ok {
+			r.schemas[c.name] = uniqueName("schema-"+referenceSlug(c.name), anchors)
+		}
+	}
+
+	title := "API Reference"
+	var info *highbase.Info
+	if h.document != nil && h.document.Info != nil {
+		info = h.document.Info
+		if info.Title != "" {
+			title = info.Title
+		}
+	}
+
+	r.write("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	r.write("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	r.write("<meta name=\"generator\" content=\"libopenapi\">\n")
+	r.writef("<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", html.EscapeString(title), htmlReferenceStyle)
+
+	// navigation
+	r.writef("<nav>\n<a href=\"#top\"><strong>%s</strong></a>\n", html.EscapeString(title))
+	for _, g := range groups {
+		r.writef("<h2><a href=\"#%s\">%s</a></h2>\n<ul>\n", g.anchor, html.EscapeString(g.name))
+		for _, op := range g.operations {
+			r.writef("<li><a href=\"#%s\">%s %s</a></li>\n", op.anchor, htmlMethod(op.method),
+				html.EscapeString(htmlOperationLabel(op)))
+		}
+		r.write("</ul>\n")
+	}
+	if len(components) > 0 {
+		r.write("<h2><a href=\"#schemas\">Schemas</a></h2>\n<ul>\n")
+		for _, c := range components {
+			r.writef("<li><a href=\"#%s\">%s</a></li>\n", r.schemas[c.name], html.EscapeString(c.name))
+		}
+		r.write("</ul>\n")
+	}
+	r.write("</nav>\n<main>\n")
+
+	// header
+	r.writef("<header id=\"top\">\n<h1>%s", html.EscapeString(title))
+	if info != nil && info.Version != "" {
+		r.writef(" <span class=\"badge\">%s</span>", html.EscapeString(info.Version))
+	}
+	r.write("</h1>\n")
+	if info != nil {
+		r.markdown(info.Description)
+	}
+	if h.document != nil && len(h.document.Servers) > 0 {
+		r.write("<h2>Servers</h2>\n<table>\n<thead><tr><th>URL</th><th>Description</th></tr></thead>\n<tbody>\n")
+		for _, server := range h.document.Servers {
+			r.writef("<tr><td><code>%s</code></td><td>%s</td></tr>\n", html.EscapeString(server.URL),
+				markdownInline(server.Description))
+		}
+		r.write("</tbody>\n</table>\n")
+	}
+	r.write("</header>\n")
+
+	for _, g := range groups {
+		r.writef("<section id=\"%s\">\n<h2>%s</h2>\n", g.anchor, html.EscapeString(g.name))
+		r.markdown(g.description)
+		for _, op := range g.operations {
+			r.operation(op)
+		}
+		r.write("</section>\n")
+	}
+
+	if len(components) > 0 {
+		r.write("<section id=\"schemas\">\n<h2>Schemas</h2>\n")
+		for _, c := range components {
+			schema := c.schema.Schema()
+			r.writef("<article id=\"%s\" class=\"schema-definition\">\n<h3>%s <span class=\"type\">%s</span>",
+				r.schemas[c.name], html.EscapeString(c.name), html.EscapeString(referenceSchemaType(c.schema)))
+			if r.circular[c.name] {
+				r.write(" <span class=\"badge\">circular</span>")
+			}
+			r.write("</h3>\n")
+			if schema != nil {
+				r.markdown(schema.Description)
+			}
+			r.schemaTree(c.schema, []string{c.name}, 0)
+			r.write("</article>\n")
+		}
+		r.write("</section>\n")
+	}
+	r.write("</main>\n</body>\n</html>\n")
+	return []byte(r.sb.String())
+}
+
+func (r *htmlReference) write(s string) {
+	r.sb.WriteString(s)
+}
+
+func (r *htmlReference) writef(format string, args ...any) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+func (r *htmlReference) markdown(text string) {
+	if strings.TrimSpace(text) != "" {
+		r.write(markdownToHTML(text))
+	}
+}
+
+// markdownCell renders a description for a table cell, without a paragraph when there is only one.
+func markdownCell(text string) string {
+	out := strings.TrimSpace(markdownToHTML(text))
+	inner := strings.TrimSuffix(strings.TrimPrefix(out, "<p>"), "</p>")
+	if len(inner) == len(out)-len("<p></p>") && !strings.Contains(inner, "<p>") {
+		return inner
+	}
+	return out
+}
+
+// htmlMethod renders an HTTP method as a badge.
+func htmlMethod(method string) string {
+	return fmt.Sprintf("<span class=\"method %s\">%s</span>", strings.ToLower(method), method)
+}
+
+// htmlOperationLabel is the label of an operation in the navigation, its summary or its path.
+func htmlOperationLabel(op *referenceOperation) string {
+	if op.operation.Summary != "" {
+		return op.operation.Summary
+	}
+	return op.path
+}
+
+// operation renders an operation, with its parameters, request body and responses.
+func (r *htmlReference) operation(op *referenceOperation) {
+	o := op.operation
+	deprecated := o.Deprecated != nil && *o.Deprecated
+	class := "operation"
+	if deprecated {
+		class += " deprecated-operation"
+	}
+	r.writef("<article id=\"%s\" class=\"%s\">\n", op.anchor, class)
+	pathClass := ""
+	if deprecated {
+		pathClass = " class=\"deprecated\""
+	}
+	r.writef("<h3>%s <code%s>%s</code>", htmlMethod(op.method), pathClass, html.EscapeString(op.path))
+	if deprecated {
+		r.write(" <span class=\"badge warning\">deprecated</span>")
+	}
+	r.write("</h3>\n")
+	if o.Summary != "" {
+		r.writef("<p><strong>%s</strong></p>\n", markdownInline(o.Summary))
+	}
+	r.markdown(o.Description)
+	if o.OperationId != "" {
+		r.writef("<p>Operation ID: <code>%s</code></p>\n", html.EscapeString(o.OperationId))
+	}
+
+	if len(op.parameters) > 0 {
+		r.write("<h4>Parameters</h4>\n<table>\n<thead><tr><th>Name</th><th>In</th><th>Type</th><th>Required</th>" +
+			"<th>Description</th></tr></thead>\n<tbody>\n")
+		for _, p := range op.parameters {
+			required := ""
+			if referenceRequired(p) {
+				required = "yes"
+			}
+			name := "<code>" + html.EscapeString(p.Name) + "</code>"
+			if p.Deprecated {
+				name = "<span class=\"deprecated\">" + name + "</span>"
+			}
+			r.writef("<tr><td>%s</td><td>%s</td><td><span class=\"type\">%s</span></td><td>%s</td><td>%s</td></tr>\n",
+				name, html.EscapeString(p.In), html.EscapeString(referenceSchemaType(referenceParameterSchema(p))),
+				required, markdownCell(p.Description))
+		}
+		r.write("</tbody>\n</table>\n")
+	}
+
+	if o.RequestBody != nil {
+		r.write("<h4>Request body")
+		if o.RequestBody.Required != nil && *o.RequestBody.Required {
+			r.write(" <span class=\"required\">required</span>")
+		}
+		r.write("</h4>\n")
+		r.markdown(o.RequestBody.Description)
+		r.content(o.RequestBody.Content)
+	}
+
+	if o.Responses != nil {
+		r.write("<h4>Responses</h4>\n")
+		codes := o.Responses.Codes
+		if o.Responses.Default != nil {
+			codes = orderedmap.New[string, *v3.Response]()
+			for code, response := range o.Responses.Codes.FromOldest() {
+				codes.Set(code, response)
+			}
+			codes.Set("default", o.Responses.Default)
+		}
+		for code, response := range codes.FromOldest() {
+			r.writef("<h5><code>%s</code></h5>\n", html.EscapeString(code))
+			if response == nil {
+				continue
+			}
+			r.markdown(response.Description)
+			if orderedmap.Len(response.Headers) > 0 {
+				r.write("<table>\n<thead><tr><th>Header</th><th>Type</th><th>Description</th></tr></thead>\n<tbody>\n")
+				for name, header := range response.Headers.FromOldest() {
+					if header == nil {
+						continue
+					}
+					r.writef("<tr><td><code>%s</code></td><td><span class=\"type\">%s</span></td><td>%s</td></tr>\n",
+						html.EscapeString(name), html.EscapeString(referenceSchemaType(header.Schema)),
+						markdownCell(header.Description))
+				}
+				r.write("</tbody>\n</table>\n")
+			}
+			r.content(response.Content)
+		}
+	}
+	r.write("</article>\n")
+}
+
+// content renders the schema and examples of each media type.
+func (r *htmlReference) content(content *orderedmap.Map[string, *v3.MediaType]) {
+	for name, mediaType := range content.FromOldest() {
+		if mediaType == nil {
+			continue
+		}
+		r.writef("<div class=\"media-type\">\n<p><span class=\"badge\">%s</span></p>\n", html.EscapeString(name))
+		if mediaType.Schema != nil {
+			r.schemaTree(mediaType.Schema, nil, 0)
+		}
+		for _, example := range referenceExamples(name, mediaType, r.mock) {
+			label := "Example"
+			if example.name != "" {
+				label += ": " + html.EscapeString(example.name)
+			}
+			if example.summary != "" {
+				label += " (" + html.EscapeString(example.summary) + ")"
+			}
+			r.writef("<details open>\n<summary>%s</summary>\n<pre><code>%s</code></pre>\n</details>\n", label,
+				html.EscapeString(example.value))
+		}
+		r.write("</div>\n")
+	}
+}
+
+// schemaTree renders a schema as a tree. The stack holds the names of the schemas the tree is found inside.
+func (r *htmlReference) schemaTree(sp *highbase.SchemaProxy, stack []string, depth int) {
+	if sp == nil {
+		return
+	}
+	if sp.IsReference() {
+		r.write("<ul class=\"schema\">\n<li>")
+		r.reference(sp, stack, depth)
+		r.write("</li>\n</ul>\n")
+		return
+	}
+	schema := sp.Schema()
+	if schema == nil {
+		return
+	}
+	if items := r.capture(func() { r.schemaItems(schema, stack, depth) }); items != "" {
+		r.write("<ul class=\"schema\">\n" + items + "</ul>\n")
+	}
+}
+
+// capture returns what a function writes, instead of writing it.
+func (r *htmlReference) capture(f func()) string {
+	save := r.sb
+	r.sb = &strings.Builder{}
+	f()
+	out := r.sb.String()
+	r.sb = save
+	return out
+}
+
+// schemaItems renders the list items of a schema: its properties, items and compositions.
+func (r *htmlReference) schemaItems(schema *highbase.Schema, stack []string, depth int) {
+	r.constraints(schema)
+	for name, prop := range schema.Properties.FromOldest() {
+		required := false
+		for _, req := range schema.Required {
+			required = required || req == name
+		}
+		r.property(name, prop, required, stack, depth)
+	}
+	if schema.Items != nil && schema.Items.IsA() {
+		r.property("[items]", schema.Items.A, false, stack, depth)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		r.property("[additional properties]", schema.AdditionalProperties.A, false, stack, depth)
+	}
+	for _, composition := range []struct {
+		name    string
+		schemas []*highbase.SchemaProxy
+	}{{"all of", schema.AllOf}, {"one of", schema.OneOf}, {"any of", schema.AnyOf}} {
+		if len(composition.schemas) == 0 {
+			continue
+		}
+		r.writef("<li><span class=\"badge\">%s</span>\n", composition.name)
+		if composition.name == "one of" && schema.Discriminator != nil {
+			r.writef(" <span class=\"type\">discriminator: %s</span>\n",
+				html.EscapeString(schema.Discriminator.PropertyName))
+		}
+		r.write("<ul class=\"schema\">\n")
+		for i, variant := range composition.schemas {
+			if variant == nil {
+				continue
+			}
+			if variant.IsReference() {
+				r.write("<li>")
+				r.reference(variant, stack, depth)
+				r.write("</li>\n")
+				continue
+			}
+			r.property(fmt.Sprintf("[%d]", i+1), variant, false, stack, depth)
+		}
+		r.write("</ul>\n</li>\n")
+	}
+}
+
+// constraints renders the enum, default and other constraints of a schema, as a list item.
+func (r *htmlReference) constraints(schema *highbase.Schema) {
+	var parts []string
+	if len(schema.Enum) > 0 {
+		var values []string
+		for _, v := range schema.Enum {
+			if v != nil {
+				values = append(values, "<code>"+html.EscapeString(v.Value)+"</code>")
+			}
+		}
+		parts = append(parts, "enum: "+strings.Join(values, ", "))
+	}
+	if schema.Const != nil {
+		parts = append(parts, "const: <code>"+html.EscapeString(schema.Const.Value)+"</code>")
+	}
+	if schema.Default != nil && schema.Default.Value != "" {
+		parts = append(parts, "default: <code>"+html.EscapeString(schema.Default.Value)+"</code>")
+	}
+	if schema.Pattern != "" {
+		parts = append(parts, "pattern: <code>"+html.EscapeString(schema.Pattern)+"</code>")
+	}
+	for _, bound := range []struct {
+		name  string
+		value *float64
+	}{{"minimum", schema.Minimum}, {"maximum", schema.Maximum}} {
+		if bound.value != nil {
+			parts = append(parts, fmt.Sprintf("%s: <code>%v</code>", bound.name, *bound.value))
+		}
+	}
+	for _, bound := range []struct {
+		name  string
+		value *int64
+	}{
+		{"min length", schema.MinLength}, {"max length", schema.MaxLength},
+		{"min items", schema.MinItems}, {"max items", schema.MaxItems},
+	} {
+		if bound.value != nil {
+			parts = append(parts, fmt.Sprintf("%s: <code>%d</code>", bound.name, *bound.value))
+		}
+	}
+	if len(parts) > 0 {
+		r.writef("<li class=\"constraints\">%s</li>\n", strings.Join(parts, "; "))
+	}
+}
+
+// property renders a property of a schema, with its type, description and its own tree.
+func (r *htmlReference) property(name string, sp *highbase.SchemaProxy, required bool, stack []string, depth int) {
+	r.writef("<li><span class=\"property\">%s</span> ", html.EscapeString(name))
+	if sp.IsReference() {
+		if required {
+			r.write("<span class=\"required\">required</span> ")
+		}
+		r.reference(sp, stack, depth)
+		r.write("</li>\n")
+		return
+	}
+	schema := sp.Schema()
+	r.writef("<span class=\"type\">%s</span>", html.EscapeString(referenceSchemaType(sp)))
+	if required {
+		r.write(" <span class=\"required\">required</span>")
+	}
+	if schema != nil {
+		if schema.ReadOnly != nil && *schema.ReadOnly {
+			r.write(" <span class=\"badge\">read only</span>")
+		}
+		if schema.WriteOnly != nil && *schema.WriteOnly {
+			r.write(" <span class=\"badge\">write only</span>")
+		}
+		if schema.Deprecated != nil && *schema.Deprecated {
+			r.write(" <span class=\"badge warning\">deprecated</span>")
+		}
+		r.write("\n")
+		r.markdown(schema.Description)
+
+		// array items that are references are already described by the type.
+		nested := orderedmap.Len(schema.Properties) > 0 || len(schema.AllOf)+len(schema.OneOf)+len(schema.AnyOf) > 0 ||
+			(schema.Items != nil && schema.Items.IsA() && schema.Items.A != nil) ||
+			(schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA())
+		if nested && depth < r.depth {
+			r.write("<ul class=\"schema\">\n")
+			r.schemaItems(schema, stack, depth+1)
+			r.write("</ul>\n")
+		} else {
+			r.constraintsOnly(schema)
+		}
+	}
+	r.write("</li>\n")
+}
+
+// constraintsOnly renders the constraints of a schema that has no nested tree.
+func (r *htmlReference) constraintsOnly(schema *highbase.Schema) {
+	if out := r.capture(func() { r.constraints(schema) }); out != "" {
+		r.write("<ul class=\"schema\">\n" + out + "</ul>\n")
+	}
+}
+
+// reference renders a reference to a component schema, which can be expanded unless the schema is already being
+// rendered, or the tree is too deep. Circular schemas (found by the index) are marked as circular.
+func (r *htmlReference) reference(sp *highbase.SchemaProxy, stack []string, depth int) {
+	name := referenceName(sp.GetReference())
+	link := html.EscapeString(name)
+	if anchor, ok := r.schemas[name]; ok {
+		link = fmt.Sprintf("<a href=\"#%s\">%s</a>", anchor, link)
+	}
+	inside := false
+	for _, s := range stack {
+		inside = inside || s == name
+	}
+	if r.circular[name] {
+		link += " <span class=\"badge\">circular</span>"
+	}
+	schema := sp.Schema()
+	if inside || depth >= r.depth || schema == nil {
+		r.writef("<span class=\"type\">%s</span>", link)
+		return
+	}
+	items := r.capture(func() { r.schemaItems(schema, append(stack, name), depth+1) })
+	if items == "" {
+		r.writef("<span class=\"type\">%s</span>", link)
+		return
+	}
+	r.writef("<details>\n<summary><span class=\"type\">%s</span></summary>\n<ul class=\"schema\">\n%s</ul>\n</details>",
+		link, items)
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var referenceSpec = `openapi: 3.1.0
+info:
+  title: Pets <API>
+  version: 1.0.0
+  description: |
+    Manages **pets**.
+
+    - adopt
+    - return
+servers:
+  - url: https://pets.example.com
+    description: Production.
+tags:
+  - name: pets
+    description: Everything about pets.
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        description: The id of the pet.
+        schema:
+          type: integer
+          format: int64
+    get:
+      tags: [pets]
+      operationId: getPet
+      summary: Get a pet
+      description: Returns a single pet, see [the guide](https://example.com/guide).
+      responses:
+        "200":
+          description: The pet.
+          headers:
+            X-Rate-Limit:
+              description: Calls left.
+              schema:
+                type: integer
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+              examples:
+                rex:
+                  summary: A dog.
+                  value:
+                    id: 1
+                    name: Rex
+        default:
+          description: An error.
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Error'
+  /status:
+    post:
+      deprecated: true
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                verbose:
+                  type: boolean
+                  default: false
+      responses:
+        "204":
+          description: OK
+webhooks:
+  newPet:
+    post:
+      operationId: newPet
+      requestBody:
+        content:
+          text/plain:
+            schema:
+              type: string
+              example: Rex
+      responses:
+        "200":
+          description: OK
+components:
+  schemas:
+    Pet:
+      type: object
+      description: A <pet>.
+      required: [id]
+      properties:
+        id:
+          type: integer
+          format: int64
+          readOnly: true
+        name:
+          type: string
+          pattern: ^[a-z]+$
+        status:
+          type: string
+          enum: [available, sold]
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      type: object
+      properties:
+        name:
+          type: string
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+`
+
+func TestHTMLReferenceGenerator_Generate(t *testing.T) {
+	doc := buildModel(t, referenceSpec)
+	out := string(NewHTMLReferenceGenerator(doc).Generate())
+
+	// the output is deterministic.
+	assert.Equal(t, out, string(NewHTMLReferenceGenerator(doc).Generate()))
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>\n"))
+	assert.NotContains(t, out, "<script")
+	assert.Contains(t, out, "<title>Pets &lt;API&gt;</title>")
+	assert.Contains(t, out, "<p>Manages <strong>pets</strong>.</p>\n<ul>\n<li>adopt</li>\n<li>return</li>\n</ul>")
+
+	// navigation, grouped by tag, then untagged operations and webhooks.
+	assert.Contains(t, out, `<li><a href="#operation-getpet"><span class="method get">GET</span> Get a pet</a></li>`)
+	assert.Less(t, strings.Index(out, `<section id="tag-pets">`), strings.Index(out, `<section id="tag-other">`))
+	assert.Less(t, strings.Index(out, `<section id="tag-other">`), strings.Index(out, `<section id="tag-webhooks">`))
+	assert.Contains(t, out, `<article id="webhook-newpet" class="operation">`)
+
+	// operations.
+	assert.Contains(t, out, `<p>Returns a single pet, see <a href="https://example.com/guide">the guide</a>.</p>`)
+	assert.Contains(t, out, `<tr><td><code>petId</code></td><td>path</td><td><span class="type">integer&lt;int64&gt;</span>`+
+		`</td><td>yes</td><td>The id of the pet.</td></tr>`)
+	assert.Contains(t, out, `<tr><td><code>X-Rate-Limit</code></td><td><span class="type">integer</span></td>`+
+		`<td>Calls left.</td></tr>`)
+	assert.Contains(t, out, `<code class="deprecated">/status</code> <span class="badge warning">deprecated</span>`)
+	assert.Contains(t, out, "<h4>Request body <span class=\"required\">required</span></h4>")
+	assert.Contains(t, out, "<h5><code>default</code></h5>")
+	assert.Contains(t, out, "<li class=\"constraints\">default: <code>false</code></li>")
+
+	// examples, from the media type and from the schema.
+	assert.Contains(t, out, "<summary>Example: rex (A dog.)</summary>\n<pre><code>{\n  &#34;id&#34;: 1,")
+	assert.Contains(t, out, "<summary>Example</summary>\n<pre><code>Rex</code></pre>")
+
+	// circular schemas expand once, then link to the schema.
+	assert.Contains(t, out, "<li><details>\n<summary><span class=\"type\"><a href=\"#schema-pet\">Pet</a> "+
+		"<span class=\"badge\">circular</span></span></summary>")
+	assert.Contains(t, out, "<li><span class=\"property\">[items]</span> <span class=\"type\">"+
+		"<a href=\"#schema-pet\">Pet</a> <span class=\"badge\">circular</span></span></li>")
+	assert.Contains(t, out, "<h3>Pet <span class=\"type\">object</span> <span class=\"badge\">circular</span></h3>\n"+
+		"<p>A &lt;pet&gt;.</p>")
+	assert.Contains(t, out, "<span class=\"required\">required</span> <span class=\"badge\">read only</span>")
+	assert.Contains(t, out, "<li class=\"constraints\">enum: <code>available</code>, <code>sold</code></li>")
+}
+
+func TestHTMLReferenceGenerator_SchemaDepth(t *testing.T) {
+	doc := buildModel(t, referenceSpec)
+	generator := NewHTMLReferenceGenerator(doc)
+	generator.SetSchemaDepth(0)
+	out := string(generator.Generate())
+	assert.NotContains(t, out, "<details>\n")
+	assert.Contains(t, out, "<li><span class=\"type\"><a href=\"#schema-error\">Error</a></span></li>")
+}
+
+func TestHTMLReferenceGenerator_MockGenerator(t *testing.T) {
+	doc := buildModel(t, referenceSpec)
+	generator := NewHTMLReferenceGenerator(doc)
+	without := strings.Count(string(generator.Generate()), "<summary>Example")
+
+	generator.SetMockGenerator(NewMockGeneratorWithBuiltInProviders(JSON))
+	out := string(generator.Generate())
+
+	// the error response and the status request body have no examples.
+	assert.Equal(t, without+2, strings.Count(out, "<summary>Example"))
+	assert.Contains(t, out, "&#34;message&#34;")
+}
+
+func TestHTMLReferenceGenerator_Empty(t *testing.T) {
+	out := string(NewHTMLReferenceGenerator(nil).Generate())
+	assert.Contains(t, out, "<title>API Reference</title>")
+	assert.NotContains(t, out, "<section")
+}
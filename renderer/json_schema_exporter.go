@@ -0,0 +1,427 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/datamodel/low"
+	"github.com/pb33f/libopenapi/json"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// JSONSchemaDialect is the version of JSON Schema that schemas are exported as, it is used as the '$schema' of every
+// exported document.
+type JSONSchemaDialect string
+
+const (
+	JSONSchemaDraft202012 JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	JSONSchemaDraft07     JSONSchemaDialect = "http://json-schema.org/draft-07/schema#"
+)
+
+// JSONSchemaExporter exports OpenAPI schemas as standalone JSON Schema documents, that can be used by tools that
+// understand JSON Schema, but not OpenAPI.
+//
+// Keywords that only exist in OpenAPI are translated:
+//
+//   - 'nullable' adds 'null' to the type (and enum) of a schema, or allows null when the schema has no type.
+//   - boolean 'exclusiveMinimum' and 'exclusiveMaximum' (OpenAPI 3.0) become numbers.
+//   - 'discriminator' is removed, each variant of the oneOf (or anyOf) requires the values of the discriminator
+//     property that select it.
+//   - 'xml' and 'externalDocs' are removed, and 'example' becomes 'examples'.
+//
+// Referenced schemas are bundled in '$defs' ('definitions' for draft-07) by default. When inlined, schemas that are
+// part of a circular reference are still bundled, so the cycle can be kept.
+//
+// Use NewJSONSchemaExporter to create a new JSONSchemaExporter.
+type JSONSchemaExporter struct {
+	dialect JSONSchemaDialect
+	inline  bool
+}
+
+// NewJSONSchemaExporter creates a new JSONSchemaExporter that exports JSON Schema 2020-12 documents, bundling
+// referenced schemas.
+func NewJSONSchemaExporter() *JSONSchemaExporter {
+	return &JSONSchemaExporter{dialect: JSONSchemaDraft202012}
+}
+
+// SetDialect sets the version of JSON Schema that schemas are exported as.
+func (e *JSONSchemaExporter) SetDialect(dialect JSONSchemaDialect) {
+	e.dialect = dialect
+}
+
+// SetInline decides if referenced schemas are copied into the schemas that reference them, instead of being
+// bundled.
+func (e *JSONSchemaExporter) SetInline(inline bool) {
+	e.inline = inline
+}
+
+// ExportComponents exports every schema in the components of a document, in the order they are declared.
+func (e *JSONSchemaExporter) ExportComponents(document *v3.Document) (*orderedmap.Map[string, []byte], error) {
+	exported := orderedmap.New[string, []byte]()
+	for _, c := range componentSchemas(document) {
+		b, err := e.Export(c.schema)
+		if err != nil {
+			return nil, fmt.Errorf("unable to export schema '%s': %w", c.name, err)
+		}
+		exported.Set(c.name, b)
+	}
+	return exported, nil
+}
+
+// Export exports a schema as a JSON Schema document.
+func (e *JSONSchemaExporter) Export(schema *highbase.SchemaProxy) ([]byte, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("there is no schema to export")
+	}
+	s, err := schema.BuildSchema()
+	if err != nil || s == nil {
+		return nil, fmt.Errorf("unable to build schema: %w", err)
+	}
+	return e.export(s, schemaIdentity(schema))
+}
+
+// ExportSchema exports a schema as a JSON Schema document.
+func (e *JSONSchemaExporter) ExportSchema(schema *highbase.Schema) ([]byte, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("there is no schema to export")
+	}
+	var root *yaml.Node
+	if schema.ParentProxy != nil {
+		root = schemaIdentity(schema.ParentProxy)
+	}
+	return e.export(schema, root)
+}
+
+func (e *JSONSchemaExporter) export(schema *highbase.Schema, root *yaml.Node) ([]byte, error) {
+	x := &jsonSchemaExport{
+		exporter: e,
+		root:     root,
+		defs:     utils.CreateEmptyMapNode(),
+		defNames: make(map[*yaml.Node]string),
+		used:     make(map[string]bool),
+		inlined:  make(map[*yaml.Node]bool),
+	}
+	node, err := x.schema(schema)
+	if err != nil {
+		return nil, err
+	}
+	document := utils.CreateEmptyMapNode()
+	document.Content = append(document.Content, utils.CreateStringNode("$schema"),
+		utils.CreateStringNode(string(e.dialect)))
+	if utils.IsNodeMap(node) {
+		document.Content = append(document.Content, node.Content...)
+	}
+	if len(x.defs.Content) > 0 {
+		document.Content = append(document.Content, utils.CreateStringNode(x.defsKeyword()), x.defs)
+	}
+	return json.YAMLNodeToJSON(document, "  ")
+}
+
+type jsonSchemaExport struct {
+	exporter *JSONSchemaExporter
+	root     *yaml.Node            // the node of the exported schema, references to it point to '#'.
+	defs     *yaml.Node            // the bundled schemas.
+	defNames map[*yaml.Node]string // the names of bundled schemas, by the node they are defined by.
+	used     map[string]bool       // bundled schema names.
+	inlined  map[*yaml.Node]bool   // the schemas being inlined, a reference to one of them is a cycle.
+}
+
+func (x *jsonSchemaExport) defsKeyword() string {
+	if x.exporter.dialect == JSONSchemaDraft07 {
+		return "definitions"
+	}
+	return "$defs"
+}
+
+// proxy exports a schema, bundling or inlining it if it's a reference.
+func (x *jsonSchemaExport) proxy(sp *highbase.SchemaProxy) (*yaml.Node, error) {
+	if !sp.IsReference() {
+		s, err := sp.BuildSchema()
+		if err != nil || s == nil {
+			return nil, fmt.Errorf("unable to build schema: %w", err)
+		}
+		return x.schema(s)
+	}
+	id := schemaIdentity(sp)
+	if id == nil {
+		return nil, fmt.Errorf("unable to resolve reference '%s'", sp.GetReference())
+	}
+	if id == x.root {
+		return utils.CreateRefNode("#"), nil
+	}
+	if name, ok := x.defNames[id]; ok {
+		return x.defRef(name), nil
+	}
+	s, err := sp.BuildSchema()
+	if err != nil || s == nil {
+		return nil, fmt.Errorf("unable to build schema '%s': %w", sp.GetReference(), err)
+	}
+	if x.exporter.inline && !x.inlined[id] {
+		x.inlined[id] = true
+		defer delete(x.inlined, id)
+		return x.schema(s)
+	}
+
+	// bundle the schema, holding its place so it appears before the schemas it references.
+	name := uniqueName(referenceName(sp.GetReference()), x.used)
+	x.defNames[id] = name
+	placeholder := utils.CreateEmptyMapNode()
+	x.defs.Content = append(x.defs.Content, utils.CreateStringNode(name), placeholder)
+	node, err := x.schema(s)
+	if err != nil {
+		return nil, err
+	}
+	*placeholder = *node
+	return x.defRef(name), nil
+}
+
+func (x *jsonSchemaExport) defRef(name string) *yaml.Node {
+	name = strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+	return utils.CreateRefNode("#/" + x.defsKeyword() + "/" + name)
+}
+
+// schema exports a schema, replacing the schemas inside it with exported ones, then translating its keywords.
+func (x *jsonSchemaExport) schema(s *highbase.Schema) (*yaml.Node, error) {
+	rendered, err := s.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+	node, _ := rendered.(*yaml.Node)
+	if node == nil || !utils.IsNodeMap(node) {
+		return utils.CreateEmptyMapNode(), nil
+	}
+
+	single := map[string]*highbase.SchemaProxy{
+		"not": s.Not, "contains": s.Contains, "if": s.If, "then": s.Then, "else": s.Else,
+		"propertyNames": s.PropertyNames, "unevaluatedItems": s.UnevaluatedItems,
+	}
+	if s.Items != nil && s.Items.IsA() {
+		single["items"] = s.Items.A
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.IsA() {
+		single["additionalProperties"] = s.AdditionalProperties.A
+	}
+	if s.UnevaluatedProperties != nil && s.UnevaluatedProperties.IsA() {
+		single["unevaluatedProperties"] = s.UnevaluatedProperties.A
+	}
+	lists := map[string][]*highbase.SchemaProxy{
+		"allOf": s.AllOf, "oneOf": s.OneOf, "anyOf": s.AnyOf, "prefixItems": s.PrefixItems,
+	}
+	maps := map[string]*orderedmap.Map[string, *highbase.SchemaProxy]{
+		"properties": s.Properties, "patternProperties": s.PatternProperties, "dependentSchemas": s.DependentSchemas,
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if sp, ok := single[key]; ok && sp != nil {
+			if node.Content[i+1], err = x.proxy(sp); err != nil {
+				return nil, err
+			}
+		}
+		if proxies, ok := lists[key]; ok {
+			seq := utils.CreateEmptySequenceNode()
+			for _, sp := range proxies {
+				exported, pErr := x.proxy(sp)
+				if pErr != nil {
+					return nil, pErr
+				}
+				if key == "oneOf" || key == "anyOf" {
+					exported = x.narrow(exported, sp, s.Discriminator)
+				}
+				seq.Content = append(seq.Content, exported)
+			}
+			node.Content[i+1] = seq
+		}
+		if m, ok := maps[key]; ok {
+			mapping := utils.CreateEmptyMapNode()
+			for name, sp := range m.FromOldest() {
+				exported, pErr := x.proxy(sp)
+				if pErr != nil {
+					return nil, pErr
+				}
+				mapping.Content = append(mapping.Content, utils.CreateStringNode(name), exported)
+			}
+			node.Content[i+1] = mapping
+		}
+	}
+	return x.translate(node, s), nil
+}
+
+// narrow makes a variant of a oneOf (or anyOf) with a discriminator require the values of the discriminator property
+// that select it, which are the name of the referenced schema and the keys of the mapping that point to it.
+func (x *jsonSchemaExport) narrow(variant *yaml.Node, sp *highbase.SchemaProxy, discriminator *highbase.Discriminator,
+) *yaml.Node {
+	if discriminator == nil || discriminator.PropertyName == "" || sp == nil || !sp.IsReference() {
+		return variant
+	}
+	refName := referenceName(sp.GetReference())
+	values := []string{refName}
+	for value, target := range discriminator.Mapping.FromOldest() {
+		if (referenceName(target) == refName || target == refName) && value != refName {
+			values = append(values, value)
+		}
+	}
+	property := utils.CreateEmptyMapNode()
+	if len(values) == 1 {
+		property.Content = append(property.Content, utils.CreateStringNode("const"), utils.CreateStringNode(values[0]))
+	} else {
+		enum := utils.CreateEmptySequenceNode()
+		for _, v := range values {
+			enum.Content = append(enum.Content, utils.CreateStringNode(v))
+		}
+		property.Content = append(property.Content, utils.CreateStringNode("enum"), enum)
+	}
+	properties := utils.CreateEmptyMapNode()
+	properties.Content = append(properties.Content, utils.CreateStringNode(discriminator.PropertyName), property)
+	required := utils.CreateEmptySequenceNode()
+	required.Content = append(required.Content, utils.CreateStringNode(discriminator.PropertyName))
+
+	// keywords next to a $ref are ignored before 2019-09, so the reference is wrapped in an allOf.
+	narrowed := variant
+	if x.exporter.dialect == JSONSchemaDraft07 {
+		narrowed = utils.CreateEmptyMapNode()
+		allOf := utils.CreateEmptySequenceNode()
+		allOf.Content = append(allOf.Content, variant)
+		narrowed.Content = append(narrowed.Content, utils.CreateStringNode("allOf"), allOf)
+	}
+	narrowed.Content = append(narrowed.Content, utils.CreateStringNode("properties"), properties,
+		utils.CreateStringNode("required"), required)
+	return narrowed
+}
+
+// translate replaces the OpenAPI keywords of a schema with JSON Schema ones, and the keywords the dialect doesn't
+// support.
+func (x *jsonSchemaExport) translate(node *yaml.Node, s *highbase.Schema) *yaml.Node {
+	for _, key := range []string{"$schema", "discriminator", "xml", "externalDocs"} {
+		removeKey(node, key)
+	}
+	if example := removeKey(node, "example"); example != nil && keyValue(node, "examples") == nil {
+		examples := utils.CreateEmptySequenceNode()
+		examples.Content = append(examples.Content, example)
+		node.Content = append(node.Content, utils.CreateStringNode("examples"), examples)
+	}
+
+	// boolean exclusive bounds turn the minimum or maximum into an exclusive one.
+	for _, bound := range []struct {
+		exclusive *highbase.DynamicValue[bool, float64]
+		keyword   string
+		limit     string
+	}{{s.ExclusiveMinimum, "exclusiveMinimum", "minimum"}, {s.ExclusiveMaximum, "exclusiveMaximum", "maximum"}} {
+		if bound.exclusive == nil || !bound.exclusive.IsA() {
+			continue
+		}
+		removeKey(node, bound.keyword)
+		if bound.exclusive.A {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == bound.limit {
+					node.Content[i] = utils.CreateStringNode(bound.keyword)
+				}
+			}
+		}
+	}
+
+	if x.exporter.dialect == JSONSchemaDraft07 {
+		for _, key := range []string{"$anchor", "minContains", "maxContains", "unevaluatedItems", "unevaluatedProperties"} {
+			removeKey(node, key)
+		}
+		if prefixItems := removeKey(node, "prefixItems"); prefixItems != nil {
+			if items := removeKey(node, "items"); items != nil {
+				node.Content = append(node.Content, utils.CreateStringNode("additionalItems"), items)
+			}
+			node.Content = append(node.Content, utils.CreateStringNode("items"), prefixItems)
+		}
+		if dependentSchemas := removeKey(node, "dependentSchemas"); dependentSchemas != nil {
+			node.Content = append(node.Content, utils.CreateStringNode("dependencies"), dependentSchemas)
+		}
+	}
+
+	nullable := removeKey(node, "nullable")
+	if nullable == nil || nullable.Value != "true" {
+		return node
+	}
+	typeNode := keyValue(node, "type")
+	if typeNode == nil {
+		// without a type, null is allowed next to the schema.
+		wrapped := utils.CreateEmptyMapNode()
+		anyOf := utils.CreateEmptySequenceNode()
+		null := utils.CreateEmptyMapNode()
+		null.Content = append(null.Content, utils.CreateStringNode("type"), utils.CreateStringNode("null"))
+		anyOf.Content = append(anyOf.Content, node, null)
+		wrapped.Content = append(wrapped.Content, utils.CreateStringNode("anyOf"), anyOf)
+		return wrapped
+	}
+	if utils.IsNodeStringValue(typeNode) {
+		types := utils.CreateEmptySequenceNode()
+		types.Content = append(types.Content, utils.CreateStringNode(typeNode.Value))
+		*typeNode = *types
+	}
+	if !slices.Contains(nodeValues(typeNode), "null") {
+		typeNode.Content = append(typeNode.Content, utils.CreateStringNode("null"))
+	}
+	if enum := keyValue(node, "enum"); enum != nil {
+		for _, v := range enum.Content {
+			if v.Tag == "!!null" {
+				return node
+			}
+		}
+		enum.Content = append(enum.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"})
+	}
+	return node
+}
+
+// schemaIdentity returns the node a schema is defined by, so the same schema can be found through different
+// references. References are usually resolved when the model is built, those that aren't are looked up.
+func schemaIdentity(sp *highbase.SchemaProxy) *yaml.Node {
+	lp := sp.GoLow()
+	if lp == nil {
+		return nil
+	}
+	node := lp.GetValueNode()
+	if isRef, _, _ := utils.IsNodeRefValue(node); !isRef {
+		return utils.NodeAlias(node)
+	}
+	s := lp.Schema()
+	if s == nil {
+		return nil
+	}
+	resolved, _, _, _ := low.LocateRefNodeWithContext(lp.GetContext(), node, s.Index)
+	return resolved
+}
+
+// keyValue returns the value of a key in a mapping node.
+func keyValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeKey removes a key from a mapping node, and returns its value.
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// nodeValues returns the values of a sequence node.
+func nodeValues(node *yaml.Node) []string {
+	values := make([]string, 0, len(node.Content))
+	for _, n := range node.Content {
+		values = append(values, n.Value)
+	}
+	return values
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonSchemaSpec = `openapi: 3.0.3
+info:
+  title: pets
+  version: 1.0.0
+paths: {}
+components:
+  schemas:
+    Pet:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: petType
+        mapping:
+          cat: '#/components/schemas/Cat'
+    Cat:
+      type: object
+      required: [petType]
+      xml:
+        name: cat
+      example:
+        petType: cat
+      properties:
+        petType:
+          type: string
+          enum: [cat, Cat]
+          nullable: true
+        lives:
+          type: integer
+          minimum: 0
+          exclusiveMinimum: true
+          maximum: 9
+          exclusiveMaximum: false
+        friend:
+          $ref: '#/components/schemas/Pet'
+        owner:
+          allOf:
+            - $ref: '#/components/schemas/Owner'
+          nullable: true
+    Dog:
+      type: object
+      properties:
+        pack:
+          type: array
+          items:
+            $ref: '#/components/schemas/Dog'
+    Owner:
+      type: object
+      properties:
+        name:
+          type: string`
+
+func TestJSONSchemaExporter_ExportComponents(t *testing.T) {
+	exported, err := NewJSONSchemaExporter().ExportComponents(buildModel(t, jsonSchemaSpec))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pet", "Cat", "Dog", "Owner"}, exportedNames(exported))
+
+	// the discriminator narrows each variant, and the cycle back to the pet points to the root of the document.
+	assert.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "oneOf": [
+    {"$ref": "#/$defs/Cat", "properties": {"petType": {"enum": ["Cat", "cat"]}}, "required": ["petType"]},
+    {"$ref": "#/$defs/Dog", "properties": {"petType": {"const": "Dog"}}, "required": ["petType"]}
+  ],
+  "$defs": {
+    "Cat": {
+      "type": "object",
+      "required": ["petType"],
+      "properties": {
+        "petType": {"type": ["string", "null"], "enum": ["cat", "Cat", null]},
+        "lives": {"type": "integer", "exclusiveMinimum": 0, "maximum": 9},
+        "friend": {"$ref": "#"},
+        "owner": {"anyOf": [{"allOf": [{"$ref": "#/$defs/Owner"}]}, {"type": "null"}]}
+      },
+      "examples": [{"petType": "cat"}]
+    },
+    "Owner": {"type": "object", "properties": {"name": {"type": "string"}}},
+    "Dog": {"type": "object", "properties": {"pack": {"type": "array", "items": {"$ref": "#/$defs/Dog"}}}}
+  }
+}`, string(exported.GetOrZero("Pet")))
+
+	assert.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {"pack": {"type": "array", "items": {"$ref": "#"}}}
+}`, string(exported.GetOrZero("Dog")))
+}
+
+func TestJSONSchemaExporter_InlineDraft07(t *testing.T) {
+	m := buildModel(t, jsonSchemaSpec)
+	exporter := NewJSONSchemaExporter()
+	exporter.SetDialect(JSONSchemaDraft07)
+	exporter.SetInline(true)
+
+	// the variants are wrapped, the owner is inlined, and only the circular dog is bundled.
+	b, err := exporter.Export(m.Components.Schemas.GetOrZero("Pet"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"$schema": "http://json-schema.org/draft-07/schema#"`)
+	assert.Contains(t, string(b), `"owner": {
+              "anyOf": [
+                {
+                  "allOf": [
+                    {
+                      "type": "object",`)
+	assert.Contains(t, string(b), `      ],
+      "properties": {
+        "petType": {
+          "const": "Dog"
+        }
+      },`)
+	assert.Contains(t, string(b), `"$ref": "#/definitions/Dog"`)
+	assert.Contains(t, string(b), `"definitions": {
+    "Dog": {`)
+	assert.NotContains(t, string(b), "Owner")
+	assert.NotContains(t, string(b), "$defs")
+
+	// keywords introduced after draft-07 are translated.
+	m = buildModel(t, `openapi: 3.1.0
+info:
+  title: tuples
+  version: 1.0.0
+components:
+  schemas:
+    Point:
+      type: object
+      dependentSchemas:
+        z:
+          required: [x]
+      unevaluatedProperties: false
+      properties:
+        position:
+          type: array
+          prefixItems:
+            - type: number
+            - type: number
+          items:
+            type: string
+          example: [1, 2]
+          examples:
+            - [3, 4]`)
+	b, err = exporter.Export(m.Components.Schemas.GetOrZero("Point"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "position": {
+      "type": "array",
+      "examples": [[3, 4]],
+      "additionalItems": {"type": "string"},
+      "items": [{"type": "number"}, {"type": "number"}]
+    }
+  },
+  "dependencies": {"z": {"required": ["x"]}}
+}`, string(b))
+}
+
+func TestJSONSchemaExporter_ExportSchema(t *testing.T) {
+	exporter := NewJSONSchemaExporter()
+	nullable := true
+	b, err := exporter.ExportSchema(&highbase.Schema{
+		Type:     []string{"string"},
+		Nullable: &nullable,
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": ["string", "null"]}`,
+		string(b))
+
+	// references created in code can't be resolved.
+	_, err = exporter.ExportSchema(&highbase.Schema{
+		AllOf: []*highbase.SchemaProxy{highbase.CreateSchemaProxyRef("#/components/schemas/Missing")},
+	})
+	assert.EqualError(t, err, "unable to resolve reference '#/components/schemas/Missing'")
+
+	_, err = exporter.Export(nil)
+	assert.Error(t, err)
+	_, err = exporter.ExportSchema(nil)
+	assert.Error(t, err)
+}
+
+func exportedNames(exported *orderedmap.Map[string, []byte]) []string {
+	var names []string
+	for name := range exported.KeysFromOldest() {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// mdLink matches links in text that has already been escaped, the URL can hold balanced parentheses (one level deep).
+var (
+	mdHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdListItem   = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdRule       = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdBlockQuote = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(((?:[^()\s]|\([^()\s]*\))+)(?:\s+&#34;.*?&#34;)?\)`)
+	mdAutoLink   = regexp.MustCompile(`&lt;((?:https?://|mailto:)[^\s&]+)&gt;`)
+	mdStrong     = regexp.MustCompile(`\*\*([^*]+?)\*\*|__([^_]+?)__`)
+	mdEmphasis   = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_\s][^_]*?)_\b`)
+	mdSafeURL    = regexp.MustCompile(`^(?i:https?://|mailto:|#|/|\.\.?/|[^:/?#]+(?:[/?#]|$))`)
+)
+
+// markdownToHTML renders CommonMark flavored descriptions as HTML. It supports the common subset of Markdown used
+// in descriptions: paragraphs, headings, lists, block quotes, fenced code blocks, rules, code spans, emphasis and
+// links. HTML in the text is always escaped, and links that do not use a safe URL scheme are removed.
+func markdownToHTML(text string) string {
+	var sb strings.Builder
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	list := ""
+	var item []string
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			sb.WriteString("<p>" + markdownInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	flushItem := func() {
+		if item != nil {
+			sb.WriteString("<li>" + markdownInline(strings.Join(item, "\n")) + "</li>\n")
+			item = nil
+		}
+	}
+	closeList := func() {
+		flushItem()
+		if list != "" {
+			sb.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	closeBlocks := func() {
+		flushParagraph()
+		closeList()
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			closeBlocks()
+			fence := trimmed[:3]
+			language := strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1]))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if language != "" {
+				class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(strings.Fields(language)[0]))
+			}
+			sb.WriteString(fmt.Sprintf("<pre><code%s>%s</code></pre>\n", class,
+				html.EscapeString(strings.Join(code, "\n"))))
+		case trimmed == "":
+			flushParagraph()
+			flushItem()
+		case mdHeading.MatchString(trimmed):
+			closeBlocks()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			sb.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", len(m[1]), markdownInline(m[2]), len(m[1])))
+		case mdRule.MatchString(trimmed):
+			closeBlocks()
+			sb.WriteString("<hr>\n")
+		case mdBlockQuote.MatchString(line):
+			closeBlocks()
+			var quote []string
+			for ; i < len(lines) && mdBlockQuote.MatchString(lines[i]); i++ {
+				quote = append(quote, mdBlockQuote.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			sb.WriteString("<blockquote>\n" + markdownToHTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+		case mdListItem.MatchString(line):
+			flushParagraph()
+			m := mdListItem.FindStringSubmatch(line)
+			kind := "ul"
+			if m[1][0] >= '0' && m[1][0] <= '9' {
+				kind = "ol"
+			}
+			if kind != list {
+				closeList()
+				sb.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			flushItem()
+			item = []string{m[2]}
+		case item != nil:
+			// lines following a list item continue it.
+			item = append(item, trimmed)
+		default:
+			if list != "" && len(paragraph) == 0 {
+				closeList()
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	closeBlocks()
+	return sb.String()
+}
+
+// markdownInline renders the inline elements of Markdown text as HTML.
+func markdownInline(text string) string {
+	// code spans and links are replaced with placeholders, so emphasis is not applied inside them.
+	var held []string
+	hold := func(s string) string {
+		held = append(held, s)
+		return fmt.Sprintf("\x00%d\x00", len(held)-1)
+	}
+
+	var sb strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			sb.WriteString(hold("<code>" + html.EscapeString(part) + "</code>"))
+			continue
+		}
+		if i%2 == 1 {
+			sb.WriteString("`")
+		}
+		sb.WriteString(html.EscapeString(part))
+	}
+	out := sb.String()
+
+	out = mdLink.ReplaceAllStringFunc(out, func(s string) string {
+		m := mdLink.FindStringSubmatch(s)
+		href := html.UnescapeString(m[2])
+		if !mdSafeURL.MatchString(href) {
+			return hold(m[1])
+		}
+		return hold(fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), m[1]))
+	})
+	out = mdAutoLink.ReplaceAllStringFunc(out, func(s string) string {
+		href := mdAutoLink.FindStringSubmatch(s)[1]
+		return hold(fmt.Sprintf(`<a href="%s">%s</a>`, href, href))
+	})
+	out = mdStrong.ReplaceAllString(out, "<strong>$1$2</strong>")
+	out = mdEmphasis.ReplaceAllString(out, "<em>$1$2</em>")
+
+	for i := len(held) - 1; i >= 0; i-- {
+		out = strings.ReplaceAll(out, fmt.Sprintf("\x00%d\x00", i), held[i])
+	}
+	return out
+}
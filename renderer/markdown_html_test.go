@@ -0,0 +1,52 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	md := "# Pets\n\nThe *pet* store, with **many** pets\nand `<code>`.\n\n" +
+		"1. first\n2. second\n   continued\n\n- one\n- two\n\n> quoted\n> text\n\n---\n\n" +
+		"```json\n{\"a\": \"<b>\"}\n```\n\nend"
+	assert.Equal(t, `<h1>Pets</h1>
+<p>The <em>pet</em> store, with <strong>many</strong> pets
+and <code>&lt;code&gt;</code>.</p>
+<ol>
+<li>first</li>
+<li>second
+continued</li>
+</ol>
+<ul>
+<li>one</li>
+<li>two</li>
+</ul>
+<blockquote>
+<p>quoted
+text</p>
+</blockquote>
+<hr>
+<pre><code class="language-json">{&#34;a&#34;: &#34;&lt;b&gt;&#34;}</code></pre>
+<p>end</p>
+`, markdownToHTML(md))
+}
+
+func TestMarkdownInline(t *testing.T) {
+	assert.Equal(t, `&lt;script&gt;alert(1)&lt;/script&gt;`, markdownInline("<script>alert(1)</script>"))
+	assert.Equal(t, `see <a href="https://example.com/a_b_c">the <code>docs</code></a>`,
+		markdownInline("see [the `docs`](https://example.com/a_b_c)"))
+	assert.Equal(t, `<a href="#pets">pets</a> and <a href="pets.html">more</a>`,
+		markdownInline("[pets](#pets) and [more](pets.html)"))
+	assert.Equal(t, `click`, markdownInline("[click](javascript:void)"))
+	assert.Equal(t, `x`, markdownInline("[x](javascript:alert(4))"))
+	assert.Equal(t, `<a href="https://en.wikipedia.org/wiki/Pet_(animal)">pet</a>.`,
+		markdownInline(`[pet](https://en.wikipedia.org/wiki/Pet_(animal) "Pets").`))
+	assert.Equal(t, `<a href="https://example.com">https://example.com</a>`, markdownInline("<https://example.com>"))
+	assert.Equal(t, `snake_case_name and <em>em</em>`, markdownInline("snake_case_name and _em_"))
+	assert.Equal(t, "a `b", markdownInline("a `b"))
+	assert.Equal(t, "", markdownToHTML("  "))
+}
@@ -0,0 +1,656 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"strings"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+const (
+	// MarkdownReferenceIndexFile is the file holding the overview and contents of a Markdown API reference, when it
+	// is generated as one file per tag.
+	MarkdownReferenceIndexFile = "index.md"
+	// MarkdownReferenceSchemasFile is the file holding the schemas of a Markdown API reference, when it is generated
+	// as one file per tag.
+	MarkdownReferenceSchemasFile = "schemas.md"
+)
+
+// MarkdownReferenceGenerator generates a Markdown API reference for a document, as a single file, or as one file
+// per tag.
+//
+// Operations are grouped by their first tag (untagged operations, then webhooks follow). Each operation has a
+// heading, a table of its parameters, its request body, and a table of its responses. Schemas of the components
+// get a table of their properties, with the type, format, constraints and description of each property. Inline
+// objects are flattened into the table of the schema that holds them ('owner.name', 'tags[].name'), references
+// link to the schema.
+//
+// Headings are preceded by HTML anchors ('operation-<id>', 'schema-<name>'), so links do not depend on how the
+// Markdown is rendered, and stay the same between versions of a document. Schemas that are part of a circular
+// reference found by the index are marked, with the loop that was found.
+//
+// Examples are taken from the document. If a MockGenerator is set, mocks are generated for request and response
+// bodies that have no examples. Mocks use random values, so the output is only deterministic without a
+// MockGenerator (or with value providers that are deterministic).
+//
+// Use NewMarkdownReferenceGenerator to create a new MarkdownReferenceGenerator.
+type MarkdownReferenceGenerator struct {
+	document *v3.Document
+	mock     *MockGenerator
+	depth    int
+}
+
+// NewMarkdownReferenceGenerator creates a new MarkdownReferenceGenerator for the supplied document.
+func NewMarkdownReferenceGenerator(document *v3.Document) *MarkdownReferenceGenerator {
+	return &MarkdownReferenceGenerator{document: document, depth: DefaultReferenceSchemaDepth}
+}
+
+// SetMockGenerator sets the MockGenerator used to generate examples for bodies without examples.
+func (m *MarkdownReferenceGenerator) SetMockGenerator(mock *MockGenerator) {
+	m.mock = mock
+}
+
+// SetSchemaDepth sets how deep inline objects are flattened into the property tables.
+func (m *MarkdownReferenceGenerator) SetSchemaDepth(depth int) {
+	m.depth = depth
+}
+
+// markdownReference is the state of a single generation.
+type markdownReference struct {
+	*MarkdownReferenceGenerator
+	sb         *strings.Builder
+	file       string            // the file being written, empty when generating a single file.
+	files      map[string]string // anchors and the files they are in.
+	groups     []*referenceGroup
+	components []*namedSchema
+	schemas    map[string]string // component schema names and their anchors.
+	loops      map[string]string // circular schema names and the loop they are part of.
+}
+
+func (m *MarkdownReferenceGenerator) prepare() *markdownReference {
+	r := &markdownReference{
+		MarkdownReferenceGenerator: m,
+		sb:                         &strings.Builder{},
+		files:                      make(map[string]string),
+		schemas:                    make(map[string]string),
+		loops:                      make(map[string]string),
+	}
+	anchors := map[string]bool{"contents": true, "schemas": true}
+	r.groups = referenceGroups(m.document, anchors)
+	r.components = componentSchemas(m.document)
+	for _, c := range r.components {
+		if _, ok := r.schemas[c.name]; !ok {
+			r.schemas[c.name] = uniqueName("schema-"+referenceSlug(c.name), anchors)
+		}
+	}
+	for _, result := range circularReferences(m.document) {
+		var journey []string
+		for _, ref := range result.Journey {
+			if ref != nil {
+				journey = append(journey, referenceName(ref.FullDefinition))
+			}
+		}
+		for _, name := range journey {
+			if _, ok := r.loops[name]; !ok {
+				r.loops[name] = strings.Join(journey, " → ")
+			}
+		}
+	}
+	return r
+}
+
+// Generate generates the API reference as a single Markdown file.
+func (m *MarkdownReferenceGenerator) Generate() []byte {
+	r := m.prepare()
+	r.overview()
+	for _, g := range r.groups {
+		r.group(g, 2)
+	}
+	r.schemaSection(2)
+	return []byte(strings.TrimSpace(r.sb.String()) + "\n")
+}
+
+// GenerateFiles generates the API reference as one Markdown file per tag, named after the tag, with an index file
+// (MarkdownReferenceIndexFile) holding the overview and the contents, and a file holding the schemas
+// (MarkdownReferenceSchemasFile).
+func (m *MarkdownReferenceGenerator) GenerateFiles() *orderedmap.Map[string, []byte] {
+	r := m.prepare()
+	names := map[string]bool{
+		strings.TrimSuffix(MarkdownReferenceIndexFile, ".md"):   true,
+		strings.TrimSuffix(MarkdownReferenceSchemasFile, ".md"): true,
+	}
+	groupFiles := make([]string, len(r.groups))
+	for i, g := range r.groups {
+		file := uniqueName(referenceSlug(g.name), names) + ".md"
+		groupFiles[i] = file
+		r.files[g.anchor] = file
+		for _, op := range g.operations {
+			r.files[op.anchor] = file
+		}
+	}
+	r.files["schemas"] = MarkdownReferenceSchemasFile
+	for _, anchor := range r.schemas {
+		r.files[anchor] = MarkdownReferenceSchemasFile
+	}
+
+	files := orderedmap.New[string, []byte]()
+	write := func(file string, f func()) {
+		r.file = file
+		r.sb = &strings.Builder{}
+		f()
+		files.Set(file, []byte(strings.TrimSpace(r.sb.String())+"\n"))
+	}
+	write(MarkdownReferenceIndexFile, r.overview)
+	for i, g := range r.groups {
+		write(groupFiles[i], func() { r.group(g, 1) })
+	}
+	if len(r.components) > 0 {
+		write(MarkdownReferenceSchemasFile, func() { r.schemaSection(1) })
+	}
+	return files
+}
+
+func (r *markdownReference) write(s string) {
+	r.sb.WriteString(s)
+}
+
+func (r *markdownReference) writef(format string, args ...any) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+// paragraph writes a block of text, followed by a blank line.
+func (r *markdownReference) paragraph(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		r.write(text + "\n\n")
+	}
+}
+
+// heading writes a heading, preceded by its anchor.
+func (r *markdownReference) heading(level int, anchor, text string) {
+	if anchor != "" {
+		r.writef("<a id=\"%s\"></a>\n\n", anchor)
+	}
+	r.writef("%s %s\n\n", strings.Repeat("#", level), text)
+}
+
+// link returns a link to an anchor, which points to another file if the anchor is not in the file being written.
+func (r *markdownReference) link(text, anchor string) string {
+	return fmt.Sprintf("[%s](%s#%s)", mdEscapeText(text), r.fileOf(anchor), anchor)
+}
+
+// overview writes the title, description, servers and contents of the reference.
+func (r *markdownReference) overview() {
+	title := "API Reference"
+	var info *highbase.Info
+	if r.document != nil && r.document.Info != nil {
+		info = r.document.Info
+		if info.Title != "" {
+			title = info.Title
+		}
+	}
+	r.heading(1, "", mdEscapeText(title))
+	if info != nil {
+		if info.Version != "" {
+			r.paragraph("Version: `" + info.Version + "`")
+		}
+		r.paragraph(info.Description)
+	}
+	if r.document != nil && len(r.document.Servers) > 0 {
+		r.heading(2, "", "Servers")
+		r.write("| URL | Description |\n| --- | --- |\n")
+		for _, server := range r.document.Servers {
+			r.writef("| %s | %s |\n", mdCode(server.URL), mdCell(server.Description))
+		}
+		r.write("\n")
+	}
+	if len(r.groups) == 0 && len(r.components) == 0 {
+		return
+	}
+	r.heading(2, "contents", "Contents")
+	for _, g := range r.groups {
+		r.writef("- %s\n", r.link(g.name, g.anchor))
+		for _, op := range g.operations {
+			label := mdCode(op.method + " " + op.path)
+			if op.operation.Summary != "" {
+				label += " " + mdEscapeText(op.operation.Summary)
+			}
+			r.writef("  - [%s](%s#%s)\n", label, r.fileOf(op.anchor), op.anchor)
+		}
+	}
+	if len(r.components) > 0 {
+		r.writef("- %s\n", r.link("Schemas", "schemas"))
+	}
+	r.write("\n")
+}
+
+// fileOf returns the file to link to for an anchor, which is empty when the anchor is in the file being written.
+func (r *markdownReference) fileOf(anchor string) string {
+	if file := r.files[anchor]; r.file != "" && file != r.file {
+		return file
+	}
+	return ""
+}
+
+// group writes the operations of a group.
+func (r *markdownReference) group(g *referenceGroup, level int) {
+	r.heading(level, g.anchor, mdEscapeText(g.name))
+	r.paragraph(g.description)
+	for _, op := range g.operations {
+		r.operation(op, level+1)
+	}
+}
+
+// operation writes an operation, with its parameters, request body and responses.
+func (r *markdownReference) operation(op *referenceOperation, level int) {
+	o := op.operation
+	title := o.Summary
+	if title == "" {
+		title = op.method + " " + op.path
+	}
+	r.heading(level, op.anchor, mdEscapeText(title))
+	r.paragraph(mdCode(op.method + " " + op.path))
+	if o.Deprecated != nil && *o.Deprecated {
+		r.paragraph("> **Deprecated**")
+	}
+	r.paragraph(o.Description)
+	if o.OperationId != "" {
+		r.paragraph("Operation ID: " + mdCode(o.OperationId))
+	}
+
+	if len(op.parameters) > 0 {
+		r.heading(level+1, "", "Parameters")
+		r.write("| Name | In | Type | Format | Required | Description |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, p := range op.parameters {
+			sp := referenceParameterSchema(p)
+			description := mdCell(p.Description)
+			if p.Deprecated {
+				description = strings.TrimSpace("**Deprecated.** " + description)
+			}
+			r.writef("| %s | %s | %s | %s | %s | %s |\n", mdCode(p.Name), p.In, r.schemaType(sp), mdFormat(sp),
+				mdYes(referenceRequired(p)), description)
+		}
+		r.write("\n")
+	}
+
+	if o.RequestBody != nil {
+		r.heading(level+1, "", "Request body")
+		if o.RequestBody.Required != nil && *o.RequestBody.Required {
+			r.paragraph("Required.")
+		}
+		r.paragraph(o.RequestBody.Description)
+		r.content(o.RequestBody.Content, level+2, "")
+	}
+
+	if o.Responses != nil {
+		codes := orderedmap.New[string, *v3.Response]()
+		for code, response := range o.Responses.Codes.FromOldest() {
+			codes.Set(code, response)
+		}
+		if o.Responses.Default != nil {
+			codes.Set("default", o.Responses.Default)
+		}
+		r.heading(level+1, "", "Responses")
+		r.write("| Status | Description | Content |\n| --- | --- | --- |\n")
+		for code, response := range codes.FromOldest() {
+			if response == nil {
+				continue
+			}
+			var content []string
+			for name, mt := range response.Content.FromOldest() {
+				c := mdCode(name)
+				if mt != nil && mt.Schema != nil {
+					c += ": " + r.schemaType(mt.Schema)
+				}
+				content = append(content, c)
+			}
+			r.writef("| %s | %s | %s |\n", mdCode(code), mdCell(response.Description), strings.Join(content, "<br>"))
+		}
+		r.write("\n")
+		for code, response := range codes.FromOldest() {
+			if response == nil {
+				continue
+			}
+			if orderedmap.Len(response.Headers) > 0 {
+				r.heading(level+2, "", mdCode(code)+" headers")
+				r.write("| Header | Type | Format | Required | Description |\n| --- | --- | --- | --- | --- |\n")
+				for name, header := range response.Headers.FromOldest() {
+					if header != nil {
+						r.writef("| %s | %s | %s | %s | %s |\n", mdCode(name), r.schemaType(header.Schema),
+							mdFormat(header.Schema), mdYes(header.Required), mdCell(header.Description))
+					}
+				}
+				r.write("\n")
+			}
+			r.content(response.Content, level+2, mdCode(code)+" ")
+		}
+	}
+}
+
+// content writes the inline schemas and the examples of each media type. Referenced schemas are described by the
+// schemas section instead.
+func (r *markdownReference) content(content *orderedmap.Map[string, *v3.MediaType], level int, prefix string) {
+	for name, mediaType := range content.FromOldest() {
+		if mediaType == nil {
+			continue
+		}
+		examples := referenceExamples(name, mediaType, r.mock)
+		inline := mediaType.Schema != nil && !mediaType.Schema.IsReference()
+		if !inline && len(examples) == 0 && prefix != "" {
+			continue
+		}
+		r.heading(level, "", prefix+mdCode(name))
+		if mediaType.Schema != nil {
+			if inline {
+				r.schemaTable(mediaType.Schema.Schema())
+			} else {
+				r.paragraph("Schema: " + r.schemaType(mediaType.Schema))
+			}
+		}
+		for _, example := range examples {
+			label := "Example"
+			if example.name != "" {
+				label += " " + mdCode(example.name)
+			}
+			if example.summary != "" {
+				label += " (" + mdEscapeText(example.summary) + ")"
+			}
+			r.paragraph(label + ":")
+			r.paragraph(mdCodeBlock(example.value, mdLanguage(name)))
+		}
+	}
+}
+
+// schemaSection writes the schemas of the components.
+func (r *markdownReference) schemaSection(level int) {
+	if len(r.components) == 0 {
+		return
+	}
+	r.heading(level, "schemas", "Schemas")
+	for _, c := range r.components {
+		schema := c.schema.Schema()
+		r.heading(level+1, r.schemas[c.name], mdEscapeText(c.name))
+		if schema == nil {
+			continue
+		}
+		if loop, ok := r.loops[c.name]; ok {
+			r.paragraph("> **Circular:** " + mdCode(loop))
+		}
+		if schema.Deprecated != nil && *schema.Deprecated {
+			r.paragraph("> **Deprecated**")
+		}
+		r.paragraph(schema.Description)
+		r.schemaTable(schema)
+	}
+}
+
+// mdRow is a row of a property table.
+type mdRow struct {
+	name, schemaType, format, required, constraints, description string
+}
+
+// schemaTable writes the type, compositions and property table of a schema.
+func (r *markdownReference) schemaTable(schema *highbase.Schema) {
+	if schema == nil {
+		return
+	}
+	r.paragraph("Type: " + r.schemaType(highbase.CreateSchemaProxy(schema)))
+	for _, composition := range []struct {
+		name    string
+		schemas []*highbase.SchemaProxy
+	}{{"All of", schema.AllOf}, {"One of", schema.OneOf}, {"Any of", schema.AnyOf}} {
+		var variants []string
+		for _, sp := range composition.schemas {
+			if sp != nil && (sp.IsReference() || orderedmap.Len(sp.Schema().Properties) == 0) {
+				variants = append(variants, r.schemaType(sp))
+			}
+		}
+		if len(variants) > 0 {
+			r.paragraph(fmt.Sprintf("%s: %s", composition.name, strings.Join(variants, ", ")))
+		}
+	}
+	if schema.Discriminator != nil {
+		line := "Discriminator: " + mdCode(schema.Discriminator.PropertyName)
+		var mapping []string
+		for value, ref := range schema.Discriminator.Mapping.FromOldest() {
+			name := referenceName(ref)
+			if anchor, ok := r.schemas[name]; ok {
+				name = r.link(name, anchor)
+			}
+			mapping = append(mapping, mdCode(value)+" → "+name)
+		}
+		if len(mapping) > 0 {
+			line += " (" + strings.Join(mapping, ", ") + ")"
+		}
+		r.paragraph(line)
+	}
+	if constraints := mdConstraints(schema); constraints != "" {
+		r.paragraph("Constraints: " + constraints)
+	}
+
+	var rows []*mdRow
+	r.rows(schema, "", 0, &rows)
+	if len(rows) == 0 {
+		return
+	}
+	r.write("| Property | Type | Format | Required | Constraints | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n")
+	for _, row := range rows {
+		r.writef("| %s | %s | %s | %s | %s | %s |\n", row.name, row.schemaType, row.format, row.required,
+			row.constraints, row.description)
+	}
+	r.write("\n")
+}
+
+// rows collects the rows of the properties of a schema, and of the inline objects in its allOf. Inline objects and
+// arrays of inline objects are flattened into the rows, using the prefix.
+func (r *markdownReference) rows(schema *highbase.Schema, prefix string, depth int, rows *[]*mdRow) {
+	if schema == nil || depth > r.depth {
+		return
+	}
+	for _, member := range schema.AllOf {
+		if member != nil && !member.IsReference() {
+			r.rows(member.Schema(), prefix, depth, rows)
+		}
+	}
+	for name, sp := range schema.Properties.FromOldest() {
+		if sp == nil {
+			continue
+		}
+		required := false
+		for _, req := range schema.Required {
+			required = required || req == name
+		}
+		row := &mdRow{
+			name:       mdCode(prefix + name),
+			schemaType: r.schemaType(sp),
+			format:     mdFormat(sp),
+			required:   mdYes(required),
+		}
+		*rows = append(*rows, row)
+		if sp.IsReference() {
+			continue
+		}
+		ps := sp.Schema()
+		if ps == nil {
+			continue
+		}
+		row.constraints = mdConstraints(ps)
+		row.description = mdCell(ps.Description)
+		if orderedmap.Len(ps.Properties) > 0 || len(ps.AllOf) > 0 {
+			r.rows(ps, prefix+name+".", depth+1, rows)
+		}
+		if ps.Items != nil && ps.Items.IsA() && ps.Items.A != nil && !ps.Items.A.IsReference() {
+			r.rows(ps.Items.A.Schema(), prefix+name+"[].", depth+1, rows)
+		}
+	}
+}
+
+// schemaType describes the type of a schema, linking to the schemas that are referenced.
+func (r *markdownReference) schemaType(sp *highbase.SchemaProxy) string {
+	if sp == nil {
+		return "any"
+	}
+	if sp.IsReference() {
+		name := referenceName(sp.GetReference())
+		if anchor, ok := r.schemas[name]; ok {
+			return r.link(name, anchor)
+		}
+		return mdEscapeText(name)
+	}
+	schema := sp.Schema()
+	if schema == nil {
+		return "any"
+	}
+	var types []string
+	for _, t := range schema.Type {
+		if t == "array" && schema.Items != nil && schema.Items.IsA() && schema.Items.A != nil {
+			types = append(types, "array of "+r.schemaType(schema.Items.A))
+			continue
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		switch {
+		case len(schema.AllOf) > 0:
+			return "all of"
+		case len(schema.OneOf) > 0:
+			return "one of"
+		case len(schema.AnyOf) > 0:
+			return "any of"
+		case orderedmap.Len(schema.Properties) > 0:
+			return "object"
+		}
+		return "any"
+	}
+	if schema.Nullable != nil && *schema.Nullable {
+		types = append(types, "null")
+	}
+	return strings.Join(types, " or ")
+}
+
+// mdConstraints describes the constraints of a schema.
+func mdConstraints(schema *highbase.Schema) string {
+	var parts []string
+	if len(schema.Enum) > 0 {
+		var values []string
+		for _, v := range schema.Enum {
+			if v != nil {
+				values = append(values, mdCode(v.Value))
+			}
+		}
+		parts = append(parts, "enum: "+strings.Join(values, ", "))
+	}
+	if schema.Const != nil {
+		parts = append(parts, "const: "+mdCode(schema.Const.Value))
+	}
+	if schema.Default != nil && schema.Default.Value != "" {
+		parts = append(parts, "default: "+mdCode(schema.Default.Value))
+	}
+	if schema.Pattern != "" {
+		parts = append(parts, "pattern: "+mdCode(schema.Pattern))
+	}
+	if schema.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("minimum: %v", *schema.Minimum))
+	}
+	if schema.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("maximum: %v", *schema.Maximum))
+	}
+	for _, bound := range []struct {
+		name  string
+		value *int64
+	}{
+		{"min length", schema.MinLength}, {"max length", schema.MaxLength},
+		{"min items", schema.MinItems}, {"max items", schema.MaxItems},
+	} {
+		if bound.value != nil {
+			parts = append(parts, fmt.Sprintf("%s: %d", bound.name, *bound.value))
+		}
+	}
+	if schema.ReadOnly != nil && *schema.ReadOnly {
+		parts = append(parts, "read only")
+	}
+	if schema.WriteOnly != nil && *schema.WriteOnly {
+		parts = append(parts, "write only")
+	}
+	if schema.Deprecated != nil && *schema.Deprecated {
+		parts = append(parts, "deprecated")
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// mdFormat returns the format of a schema, or of the items of an array schema.
+func mdFormat(sp *highbase.SchemaProxy) string {
+	if sp == nil || sp.IsReference() {
+		return ""
+	}
+	schema := sp.Schema()
+	if schema == nil {
+		return ""
+	}
+	if schema.Format == "" && schema.Items != nil && schema.Items.IsA() {
+		return mdFormat(schema.Items.A)
+	}
+	return mdCell(schema.Format)
+}
+
+func mdYes(b bool) string {
+	if b {
+		return "yes"
+	}
+	return ""
+}
+
+// mdCode renders text as a code span, using a longer delimiter if the text holds backticks.
+func mdCode(text string) string {
+	delimiter := "`"
+	for strings.Contains(text, delimiter) {
+		delimiter += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return delimiter + strings.ReplaceAll(text, "|", "\\|") + delimiter
+}
+
+// mdCodeBlock renders text as a fenced code block, using a longer fence if the text holds one.
+func mdCodeBlock(text, language string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
+
+// mdLanguage returns the language of a code block for a media type.
+func mdLanguage(mediaType string) string {
+	lower := strings.ToLower(mediaType)
+	switch {
+	case strings.Contains(lower, "json"):
+		return "json"
+	case strings.Contains(lower, "yaml"):
+		return "yaml"
+	case strings.Contains(lower, "xml"):
+		return "xml"
+	}
+	return ""
+}
+
+// mdCell turns Markdown text into a table cell, which must be a single line without unescaped pipes.
+func mdCell(text string) string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\n\n", "<br><br>")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// mdEscapeText escapes the characters that would turn text into Markdown formatting.
+func mdEscapeText(text string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "<", "&lt;",
+		">", "&gt;", "|", "\\|", "`", "\\`")
+	return replacer.Replace(text)
+}
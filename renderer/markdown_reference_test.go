@@ -0,0 +1,140 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownReferenceGenerator_Generate(t *testing.T) {
+	doc := buildModel(t, referenceSpec)
+	out := string(NewMarkdownReferenceGenerator(doc).Generate())
+	assert.Equal(t, out, string(NewMarkdownReferenceGenerator(doc).Generate()))
+
+	assert.Contains(t, out, "# Pets &lt;API&gt;\n\nVersion: `1.0.0`\n\nManages **pets**.")
+	assert.Contains(t, out, "| `https://pets.example.com` | Production. |")
+	assert.Contains(t, out, "- [pets](#tag-pets)\n  - [`GET /pets/{petId}` Get a pet](#operation-getpet)\n")
+	assert.Contains(t, out, "- [Webhooks](#tag-webhooks)\n  - [`POST newPet`](#webhook-newpet)\n- [Schemas](#schemas)\n")
+
+	assert.Contains(t, out, "<a id=\"operation-getpet\"></a>\n\n### Get a pet\n\n`GET /pets/{petId}`\n\n")
+	assert.Contains(t, out, "| `petId` | path | integer | int64 | yes | The id of the pet. |\n")
+	assert.Contains(t, out, "| `200` | The pet. | `application/json`: [Pet](#schema-pet) |\n"+
+		"| `default` | An error. | `application/json`: [Error](#schema-error) |\n")
+	assert.Contains(t, out, "| `X-Rate-Limit` | integer |  |  | Calls left. |\n")
+	assert.Contains(t, out, "Example `rex` (A dog.):\n\n```json\n{\n  \"id\": 1,\n  \"name\": \"Rex\"\n}\n```")
+	assert.Contains(t, out, "### POST /status\n\n`POST /status`\n\n> **Deprecated**\n\n#### Request body\n\nRequired.")
+	assert.Contains(t, out, "| `verbose` | boolean |  |  | default: `false` |  |\n")
+	assert.Contains(t, out, "Example:\n\n```\nRex\n```")
+
+	// schemas, with the loops found by the index.
+	assert.Contains(t, out, "<a id=\"schema-pet\"></a>\n\n### Pet\n\n> **Circular:** `Pet → Owner → Pet`\n\n")
+	assert.Contains(t, out, "| `id` | integer | int64 | yes | read only |  |\n")
+	assert.Contains(t, out, "| `status` | string |  |  | enum: `available`, `sold` |  |\n")
+	assert.Contains(t, out, "| `pets` | array of [Pet](#schema-pet) |  |  |  |  |\n")
+	assert.NotContains(t, out, "### Error\n\n> **Circular:**")
+}
+
+func TestMarkdownReferenceGenerator_GenerateFiles(t *testing.T) {
+	doc := buildModel(t, referenceSpec)
+	files := NewMarkdownReferenceGenerator(doc).GenerateFiles()
+
+	var names []string
+	for name := range files.KeysFromOldest() {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"index.md", "pets.md", "other.md", "webhooks.md", "schemas.md"}, names)
+
+	index := string(files.GetOrZero(MarkdownReferenceIndexFile))
+	assert.Contains(t, index, "- [pets](pets.md#tag-pets)\n  - [`GET /pets/{petId}` Get a pet](pets.md#operation-getpet)\n")
+	assert.Contains(t, index, "- [Schemas](schemas.md#schemas)\n")
+	assert.NotContains(t, index, "## Get a pet")
+
+	pets := string(files.GetOrZero("pets.md"))
+	assert.Contains(t, pets, "<a id=\"tag-pets\"></a>\n\n# pets\n")
+	assert.Contains(t, pets, "| `200` | The pet. | `application/json`: [Pet](schemas.md#schema-pet) |")
+
+	schemas := string(files.GetOrZero(MarkdownReferenceSchemasFile))
+	assert.Contains(t, schemas, "# Schemas\n")
+	assert.Contains(t, schemas, "| `pets` | array of [Pet](#schema-pet) |")
+}
+
+func TestMarkdownReferenceGenerator_SchemaTables(t *testing.T) {
+	doc := buildModel(t, `openapi: 3.1.0
+info:
+  title: shapes
+  version: 1.0.0
+components:
+  schemas:
+    Shape:
+      oneOf:
+        - $ref: '#/components/schemas/Circle'
+        - $ref: '#/components/schemas/Square'
+      discriminator:
+        propertyName: kind
+        mapping:
+          circle: '#/components/schemas/Circle'
+    Circle:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          required: [radius]
+          properties:
+            radius:
+              type: number
+              minimum: 0
+              description: |
+                The radius | in cm.
+
+                Never negative.
+    Square:
+      type: object
+      properties:
+        corner:
+          type: object
+          properties:
+            x:
+              type: integer
+        labels:
+          type: array
+          items:
+            type: object
+            properties:
+              text:
+                type: string
+                maxLength: 10
+    Base:
+      type: object
+      properties:
+        kind:
+          type: string
+          deprecated: true
+`)
+	files := NewMarkdownReferenceGenerator(doc).GenerateFiles()
+	require.Equal(t, 2, files.Len())
+	out := string(files.GetOrZero(MarkdownReferenceSchemasFile))
+
+	assert.Contains(t, out, "Type: one of\n\nOne of: [Circle](#schema-circle), [Square](#schema-square)\n\n"+
+		"Discriminator: `kind` (`circle` → [Circle](#schema-circle))\n")
+	assert.Contains(t, out, "All of: [Base](#schema-base)\n\n| Property |")
+	assert.Contains(t, out, "| `radius` | number |  | yes | minimum: 0 | The radius \\| in cm.<br><br>Never negative. |\n")
+	assert.Contains(t, out, "| `corner` | object |  |  |  |  |\n| `corner.x` | integer |  |  |  |  |\n")
+	assert.Contains(t, out, "| `labels` | array of object |  |  |  |  |\n| `labels[].text` | string |  |  | max length: 10 |  |\n")
+	assert.Contains(t, out, "| `kind` | string |  |  | deprecated |  |\n")
+}
+
+func TestMarkdownReferenceGenerator_Empty(t *testing.T) {
+	assert.Equal(t, "# API Reference\n", string(NewMarkdownReferenceGenerator(nil).Generate()))
+	assert.Equal(t, 1, NewMarkdownReferenceGenerator(nil).GenerateFiles().Len())
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	assert.Equal(t, "``a `b` c``", mdCode("a `b` c"))
+	assert.Equal(t, "`` `a ``", mdCode("`a"))
+	assert.Equal(t, "`a\\|b`", mdCode("a|b"))
+	assert.Equal(t, "````\n```\ncode\n```\n````", mdCodeBlock("```\ncode\n```", ""))
+	assert.Equal(t, "\\*a\\_b\\* &lt;c&gt;", mdEscapeText("*a_b* <c>"))
+}
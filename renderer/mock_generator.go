@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
 	"github.com/pb33f/libopenapi/orderedmap"
@@ -55,6 +56,29 @@ func NewMockGenerator(mockType MockType) *MockGenerator {
 	return &MockGenerator{renderer: renderer, mockType: mockType}
 }
 
+// NewMockGeneratorWithBuiltInProviders creates a new mock generator that uses the embedded dictionary and the
+// built-in value providers to generate realistic values. It does not depend on any dictionary file on the system.
+func NewMockGeneratorWithBuiltInProviders(mockType MockType) *MockGenerator {
+	renderer := CreateRendererUsingBuiltInProviders()
+	return &MockGenerator{renderer: renderer, mockType: mockType}
+}
+
+// SetValueProviders sets the ValueProviderRegistry used by the renderer when generating mocks from schemas.
+func (mg *MockGenerator) SetValueProviders(registry *ValueProviderRegistry) {
+	mg.renderer.SetValueProviders(registry)
+}
+
+// SetSeed makes the mock generator render the same mocks every time it is seeded with the same value.
+// See SchemaRenderer.SetSeed.
+func (mg *MockGenerator) SetSeed(seed int64) {
+	mg.renderer.SetSeed(seed)
+}
+
+// SetClock sets the function used to get the current time, which is rendered for date, date-time and time formats.
+func (mg *MockGenerator) SetClock(now func() time.Time) {
+	mg.renderer.SetClock(now)
+}
+
 // SetPretty sets the pretty flag on the mock generator. If true, the mock will be rendered with indentation and newlines.
 // If false, the mock will be rendered as a single line which is good for API responses. False is the default.
 // This option only effects JSON mocks, there is no concept of pretty printing YAML.
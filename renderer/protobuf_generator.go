@@ -0,0 +1,730 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// ProtobufFieldExtension is the extension that sets the number of the field a property becomes. It can be set on
+// the schema of the property, or next to its $ref.
+const ProtobufFieldExtension = "x-proto-field"
+
+// ProtobufIssue is a part of a document that cannot be mapped to Protocol Buffers, or can only be mapped partly.
+type ProtobufIssue struct {
+	// Location is a JSON pointer to the part of the document.
+	Location string
+
+	// Message explains the problem, and what was generated instead.
+	Message string
+}
+
+// String returns the location and message of the issue.
+func (i *ProtobufIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Location, i.Message)
+}
+
+// protoType is the type of a field.
+type protoType struct {
+	name     string
+	repeated bool
+	mapType  bool
+	scalar   bool // scalars and enums can be optional, messages always track presence.
+}
+
+// ProtobufGenerator generates a proto3 file from a document. Every object schema in the components becomes a
+// message, and every string enum becomes an enum. Every operation becomes an RPC of a service, with a request message
+// that holds the parameters and the body, and returns the schema of the first successful response (errors are
+// expected to be gRPC statuses).
+//
+//   - Fields are numbered using the 'x-proto-field' extension of a property, or the order of the properties.
+//   - Properties that are not required (or can be null), and are scalars or enums, are optional fields.
+//   - oneOf (and anyOf) schemas are messages that hold a oneof.
+//   - allOf schemas are messages with the fields of every schema they are composed of.
+//   - Inline object schemas and enums are nested in the messages that hold them.
+//   - Arrays and maps that cannot be nested (arrays of arrays, maps of maps) are wrapped in a message.
+//   - Schemas without a type, or with many types, are 'google.protobuf.Value' fields.
+//
+// Constructs that cannot be mapped are returned as issues, next to the generated file.
+//
+// Use NewProtobufGenerator to create a new ProtobufGenerator.
+type ProtobufGenerator struct {
+	document    *v3.Document
+	packageName string
+	serviceName string
+	mediaTypes  []string
+}
+
+// NewProtobufGenerator creates a new ProtobufGenerator for the supplied document. The package is 'api' by default,
+// and the service is named after the title of the document.
+func NewProtobufGenerator(document *v3.Document) *ProtobufGenerator {
+	return &ProtobufGenerator{
+		document:    document,
+		packageName: "api",
+		mediaTypes:  DefaultPreferredMediaTypes,
+	}
+}
+
+// SetPackageName sets the name of the proto package, for example 'pets.v1'.
+func (p *ProtobufGenerator) SetPackageName(packageName string) {
+	p.packageName = packageName
+}
+
+// SetServiceName sets the name of the service the operations are generated in.
+func (p *ProtobufGenerator) SetServiceName(serviceName string) {
+	p.serviceName = serviceName
+}
+
+// SetPreferredMediaTypes sets the order in which media types are picked, when generating messages for request
+// bodies and responses that support more than one media type.
+func (p *ProtobufGenerator) SetPreferredMediaTypes(mediaTypes ...string) {
+	p.mediaTypes = mediaTypes
+}
+
+// Generate generates the proto file, and returns the issues found with constructs that cannot be mapped.
+func (p *ProtobufGenerator) Generate() ([]byte, []*ProtobufIssue) {
+	gen := &protoGeneration{
+		used:     make(map[string]bool),
+		refs:     make(map[string]string),
+		types:    make(map[string]*protoType),
+		declared: make(map[string]bool),
+		imports:  make(map[string]bool),
+	}
+	components := componentSchemas(p.document)
+	for _, c := range components {
+		gen.reserve(c.name)
+	}
+	for _, c := range components {
+		gen.declare(c.name, c.schema, c.source)
+	}
+	service := p.service(gen)
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by libopenapi. DO NOT EDIT.\n\n")
+	sb.WriteString("syntax = \"proto3\";\n\n")
+	sb.WriteString("package " + p.packageName + ";\n\n")
+	if len(gen.imports) > 0 {
+		var imports []string
+		for i := range gen.imports {
+			imports = append(imports, i)
+		}
+		sort.Strings(imports)
+		for _, i := range imports {
+			sb.WriteString(fmt.Sprintf("import %q;\n", i))
+		}
+		sb.WriteString("\n")
+	}
+	for _, decl := range gen.decls {
+		if decl != "" {
+			sb.WriteString(decl)
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString(service)
+	return []byte(strings.TrimRight(sb.String(), "\n") + "\n"), gen.issues
+}
+
+// service generates an RPC for every operation, and their request and response messages.
+func (p *ProtobufGenerator) service(gen *protoGeneration) string {
+	if p.document == nil || p.document.Paths == nil || orderedmap.Len(p.document.Paths.PathItems) == 0 {
+		return ""
+	}
+	name := p.serviceName
+	if name == "" {
+		if p.document.Info != nil {
+			name = tsIdentifier(p.document.Info.Title)
+		}
+		if name == "" || name == "Schema" {
+			name = "API"
+		}
+		name += "Service"
+	}
+
+	var sb strings.Builder
+	if p.document.Info != nil && p.document.Info.Description != "" {
+		sb.WriteString(commentLines(p.document.Info.Description, "", "// "))
+	}
+	sb.WriteString(fmt.Sprintf("service %s {\n", name))
+	rpcs := make(map[string]bool)
+	first := true
+	for pathName, pathItem := range p.document.Paths.PathItems.FromOldest() {
+		pathPointer := "#/paths/" + strings.ReplaceAll(strings.ReplaceAll(pathName, "~", "~0"), "/", "~1")
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			rpcName := operation.OperationId
+			if rpcName == "" {
+				rpcName = method + " " + pathName
+			}
+			rpcName = uniqueName(tsIdentifier(rpcName), rpcs)
+			location := pathPointer + "/" + method
+
+			// operation parameters override path item parameters with the same name and location.
+			var fields []*protoProperty
+			for i, param := range operation.Parameters {
+				fields = append(fields, parameterProperty(param, fmt.Sprintf("%s/parameters/%d", location, i)))
+			}
+			for i, param := range pathItem.Parameters {
+				overridden := false
+				for _, op := range operation.Parameters {
+					overridden = overridden || (op.Name == param.Name && op.In == param.In)
+				}
+				if !overridden {
+					fields = append(fields, parameterProperty(param, fmt.Sprintf("%s/parameters/%d", pathPointer, i)))
+				}
+			}
+			if operation.RequestBody != nil {
+				if sp := preferredSchema(operation.RequestBody.Content, p.mediaTypes); sp != nil {
+					required := operation.RequestBody.Required != nil && *operation.RequestBody.Required
+					fields = append(fields, &protoProperty{
+						name: "body", schema: sp, required: required, location: location + "/requestBody",
+						description: operation.RequestBody.Description,
+					})
+				}
+			}
+			source := fmt.Sprintf("the %s %s operation", strings.ToUpper(method), pathName)
+			request := "google.protobuf.Empty"
+			if len(fields) > 0 {
+				request = uniqueName(rpcName+"Request", gen.used)
+				gen.decls = append(gen.decls, fmt.Sprintf("// %s holds the parameters and body of %s.\n", request,
+					source)+gen.message(request, fields, nil, location, ""))
+			} else {
+				gen.imports["google/protobuf/empty.proto"] = true
+			}
+			response := gen.response(rpcName, operation, location, source, p.mediaTypes)
+
+			if !first {
+				sb.WriteString("\n")
+			}
+			first = false
+			comment := operation.Summary
+			if operation.Description != "" {
+				comment = strings.TrimSpace(comment + "\n\n" + operation.Description)
+			}
+			if comment != "" {
+				sb.WriteString(commentLines(comment, "  ", "// "))
+			}
+			sb.WriteString(fmt.Sprintf("  rpc %s(%s) returns (%s)", rpcName, request, response))
+			if operation.Deprecated != nil && *operation.Deprecated {
+				sb.WriteString(" {\n    option deprecated = true;\n  }\n")
+			} else {
+				sb.WriteString(";\n")
+			}
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// parameterProperty returns the field of a request message that holds a parameter.
+func parameterProperty(param *v3.Parameter, location string) *protoProperty {
+	sp := param.Schema
+	if sp == nil {
+		sp = preferredSchema(param.Content, nil)
+	}
+	return &protoProperty{
+		name:        param.Name,
+		schema:      sp,
+		required:    param.In == "path" || (param.Required != nil && *param.Required),
+		location:    location,
+		description: param.Description,
+		deprecated:  param.Deprecated,
+	}
+}
+
+type protoGeneration struct {
+	decls    []string
+	used     map[string]bool       // top level names.
+	refs     map[string]string     // schema references (by name) and the messages or enums generated for them.
+	types    map[string]*protoType // the types of referenced schemas that are not messages or enums.
+	declared map[string]bool
+	imports  map[string]bool
+	issues   []*ProtobufIssue
+}
+
+// issue reports a construct that cannot be mapped. Schemas that are part of more than one allOf are mapped more than
+// once, but their issues are only reported once.
+func (g *protoGeneration) issue(location, message string, args ...any) {
+	issue := &ProtobufIssue{Location: location, Message: fmt.Sprintf(message, args...)}
+	for _, i := range g.issues {
+		if *i == *issue {
+			return
+		}
+	}
+	g.issues = append(g.issues, issue)
+}
+
+// reserve records the name of a referenced schema, before it is generated.
+func (g *protoGeneration) reserve(schemaName string) string {
+	if name, ok := g.refs[schemaName]; ok {
+		return name
+	}
+	name := uniqueName(tsIdentifier(schemaName), g.used)
+	g.refs[schemaName] = name
+	return name
+}
+
+// declare generates a top level message or enum for a referenced schema. Referenced schemas that are neither are
+// not declared, fields that reference them use their type.
+func (g *protoGeneration) declare(schemaName string, sp *highbase.SchemaProxy, location string) {
+	if g.declared[schemaName] {
+		return
+	}
+	g.declared[schemaName] = true
+	name := g.reserve(schemaName)
+
+	// hold the place of the declaration, so it appears before the declarations it references.
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	schema := sp.Schema()
+	switch {
+	case schema == nil:
+		t := g.value(location, "the schema cannot be built")
+		g.types[schemaName] = &t
+	case isProtoMessage(schema):
+		g.decls[slot] = protoComment(schema, "") + g.schemaMessage(name, schema, location, "")
+	case isProtoEnum(schema):
+		g.decls[slot] = protoComment(schema, "") + g.enum(name, schema, "")
+	default:
+		// the type is held while it is worked out, so a schema that holds itself is found.
+		g.types[schemaName] = &protoType{name: "google.protobuf.Value"}
+		var nested []string
+		t := g.scalarType(schema, name, location, &nested, "")
+		g.types[schemaName] = &t
+		g.decls[slot] = strings.Join(nested, "\n")
+	}
+}
+
+// isProtoMessage returns true if a schema becomes a message.
+func isProtoMessage(schema *highbase.Schema) bool {
+	return len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 || len(schema.AllOf) > 0 ||
+		orderedmap.Len(schema.Properties) > 0
+}
+
+// isProtoEnum returns true if a schema becomes an enum, only string enums do.
+func isProtoEnum(schema *highbase.Schema) bool {
+	types := schemaTypes(schema)
+	return len(schema.Enum) > 0 && len(types) == 1 && types[0] == "string"
+}
+
+// fieldType returns the type of a field that holds a schema, declaring nested messages and enums when needed.
+func (g *protoGeneration) fieldType(sp *highbase.SchemaProxy, hint, location string, nested *[]string,
+	indent string,
+) protoType {
+	if sp == nil {
+		return g.value(location, "the schema is missing")
+	}
+	if sp.IsReference() {
+		schemaName := referenceName(sp.GetReference())
+		if !g.declared[schemaName] {
+			g.declare(schemaName, sp, sp.GetReference())
+		}
+		if t, ok := g.types[schemaName]; ok {
+			return *t
+		}
+		return protoType{name: g.refs[schemaName], scalar: isProtoEnum(sp.Schema())}
+	}
+	schema := sp.Schema()
+	switch {
+	case schema == nil:
+		return g.value(location, "the schema cannot be built")
+	case isProtoMessage(schema):
+		*nested = append(*nested, protoComment(schema, indent)+g.schemaMessage(hint, schema, location, indent))
+		return protoType{name: hint}
+	case isProtoEnum(schema):
+		*nested = append(*nested, protoComment(schema, indent)+g.enum(hint, schema, indent))
+		return protoType{name: hint, scalar: true}
+	}
+	return g.scalarType(schema, hint, location, nested, indent)
+}
+
+// scalarType returns the type of a schema that isn't a message or enum.
+func (g *protoGeneration) scalarType(schema *highbase.Schema, hint, location string, nested *[]string,
+	indent string,
+) protoType {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		g.imports["google/protobuf/struct.proto"] = true
+		return protoType{name: "google.protobuf.Value"}
+	}
+	if len(types) > 1 {
+		return g.value(location, "a schema with more than one type (%s)", strings.Join(types, ", "))
+	}
+	if len(schema.Enum) > 0 && types[0] != "string" {
+		g.issue(location, "only string enums can be mapped to enums, the %s type is used instead", types[0])
+	}
+	switch types[0] {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["google/protobuf/timestamp.proto"] = true
+			return protoType{name: "google.protobuf.Timestamp"}
+		case "binary", "byte":
+			return protoType{name: "bytes", scalar: true}
+		}
+		return protoType{name: "string", scalar: true}
+	case "integer":
+		if schema.Format == "int32" {
+			return protoType{name: "int32", scalar: true}
+		}
+		return protoType{name: "int64", scalar: true}
+	case "number":
+		if schema.Format == "float" {
+			return protoType{name: "float", scalar: true}
+		}
+		return protoType{name: "double", scalar: true}
+	case "boolean":
+		return protoType{name: "bool", scalar: true}
+	case "array":
+		if len(schema.PrefixItems) > 0 {
+			g.issue(location, "tuples (prefixItems) cannot be mapped, the items are google.protobuf.Value")
+			g.imports["google/protobuf/struct.proto"] = true
+			return protoType{name: "google.protobuf.Value", repeated: true}
+		}
+		if schema.Items == nil || !schema.Items.IsA() {
+			g.imports["google/protobuf/struct.proto"] = true
+			return protoType{name: "google.protobuf.Value", repeated: true}
+		}
+		item := g.fieldType(schema.Items.A, hint+"Item", location+"/items", nested, indent)
+		if item.repeated || item.mapType {
+			item = g.wrap(hint+"Item", item, nested, indent)
+		}
+		return protoType{name: item.name, repeated: true}
+	case "object":
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+			value := g.fieldType(schema.AdditionalProperties.A, hint+"Value", location+"/additionalProperties",
+				nested, indent)
+			if value.repeated || value.mapType {
+				value = g.wrap(hint+"Value", value, nested, indent)
+			}
+			return protoType{name: fmt.Sprintf("map<string, %s>", value.name), mapType: true}
+		}
+		g.imports["google/protobuf/struct.proto"] = true
+		return protoType{name: "google.protobuf.Struct"}
+	}
+	return g.value(location, "the '%s' type cannot be mapped", types[0])
+}
+
+// value reports an issue, and returns google.protobuf.Value, which can hold any JSON value.
+func (g *protoGeneration) value(location, message string, args ...any) protoType {
+	g.issue(location, message+", it is mapped to google.protobuf.Value", args...)
+	g.imports["google/protobuf/struct.proto"] = true
+	return protoType{name: "google.protobuf.Value"}
+}
+
+// wrap declares a message that holds a repeated or map field, for places that can't hold them directly.
+func (g *protoGeneration) wrap(name string, t protoType, nested *[]string, indent string) protoType {
+	label := ""
+	if t.repeated {
+		label = "repeated "
+	}
+	*nested = append(*nested, fmt.Sprintf("%smessage %s {\n%s  %s%s values = 1;\n%s}\n", indent, name, indent,
+		label, t.name, indent))
+	return protoType{name: name}
+}
+
+// protoProperty is a field of a message, from a property or a parameter.
+type protoProperty struct {
+	name        string
+	schema      *highbase.SchemaProxy
+	required    bool
+	location    string
+	description string
+	deprecated  bool
+}
+
+// schemaMessage generates a message for an object, allOf or oneOf schema.
+func (g *protoGeneration) schemaMessage(name string, schema *highbase.Schema, location, indent string) string {
+	var properties []*protoProperty
+	g.properties(schema, location, &properties, make(map[string]bool), make(map[*highbase.Schema]bool))
+	return g.message(name, properties, schema, location, indent)
+}
+
+// properties collects the properties of a schema, and of the schemas in its allOf.
+func (g *protoGeneration) properties(schema *highbase.Schema, location string, properties *[]*protoProperty,
+	names map[string]bool, seen map[*highbase.Schema]bool,
+) {
+	if schema == nil || seen[schema] {
+		return
+	}
+	seen[schema] = true
+	for i, member := range schema.AllOf {
+		memberLocation := fmt.Sprintf("%s/allOf/%d", location, i)
+		if member != nil && member.IsReference() {
+			memberLocation = member.GetReference()
+		}
+		ms := member.Schema()
+		if ms != nil && (len(ms.OneOf) > 0 || len(ms.AnyOf) > 0) {
+			g.issue(memberLocation, "a oneOf or anyOf inside an allOf cannot be mapped, it is left out")
+		}
+		g.properties(ms, memberLocation, properties, names, seen)
+	}
+	for propName, sp := range schema.Properties.FromOldest() {
+		if names[propName] {
+			continue
+		}
+		names[propName] = true
+		p := &protoProperty{
+			name:     propName,
+			schema:   sp,
+			location: location + "/properties/" + strings.ReplaceAll(strings.ReplaceAll(propName, "~", "~0"), "/", "~1"),
+		}
+		for _, r := range schema.Required {
+			p.required = p.required || r == propName
+		}
+		if !sp.IsReference() {
+			if ps := sp.Schema(); ps != nil {
+				p.description = ps.Description
+				p.deprecated = ps.Deprecated != nil && *ps.Deprecated
+			}
+		}
+		*properties = append(*properties, p)
+	}
+	if orderedmap.Len(schema.Properties) > 0 && schema.AdditionalProperties != nil &&
+		schema.AdditionalProperties.IsA() {
+		g.issue(location, "additionalProperties next to properties cannot be mapped, they are left out")
+	}
+	if orderedmap.Len(schema.PatternProperties) > 0 {
+		g.issue(location, "patternProperties cannot be mapped, they are left out")
+	}
+}
+
+// message generates a message with a field for every property, and a oneof for the oneOf (or anyOf) of the schema.
+func (g *protoGeneration) message(name string, properties []*protoProperty, schema *highbase.Schema, location,
+	indent string,
+) string {
+	inner := indent + "  "
+	var nested []string
+	var fields strings.Builder
+
+	// explicit field numbers are taken first, the other fields are numbered in order, skipping them.
+	numbers := make(map[*protoProperty]int)
+	taken := make(map[int]bool)
+	for _, p := range properties {
+		if n, ok := g.fieldNumber(p); ok {
+			if taken[n] {
+				g.issue(p.location, "field number %d is used by another field, the next free number is used", n)
+				continue
+			}
+			numbers[p] = n
+			taken[n] = true
+		}
+	}
+	next := func() int {
+		n := 1
+		for taken[n] || (n >= 19000 && n <= 19999) {
+			n++
+		}
+		taken[n] = true
+		return n
+	}
+
+	names := make(map[string]bool)
+	for _, p := range properties {
+		n, ok := numbers[p]
+		if !ok {
+			n = next()
+		}
+		fieldName := uniqueName(protoFieldName(p.name), names)
+		t := g.fieldType(p.schema, tsIdentifier(p.name), p.location, &nested, inner)
+		label := ""
+		switch {
+		case t.repeated:
+			label = "repeated "
+		case t.scalar && !p.required:
+			label = "optional "
+		case t.scalar && p.schema != nil && isNullable(p.schema.Schema()):
+			// a required scalar that can be null is optional, so null is an unset field rather than a zero value.
+			label = "optional "
+			g.issue(p.location, "a required property that can be null is mapped to an optional field, "+
+				"null and missing values cannot be told apart")
+		}
+		var options []string
+		if jsonName := protoJSONName(fieldName); jsonName != p.name {
+			options = append(options, fmt.Sprintf("json_name = %q", p.name))
+		}
+		if p.deprecated {
+			options = append(options, "deprecated = true")
+		}
+		if p.description != "" {
+			fields.WriteString(commentLines(p.description, inner, "// "))
+		}
+		fields.WriteString(fmt.Sprintf("%s%s%s %s = %d", inner, label, t.name, fieldName, n))
+		if len(options) > 0 {
+			fields.WriteString(" [" + strings.Join(options, ", ") + "]")
+		}
+		fields.WriteString(";\n")
+	}
+
+	if schema != nil && (len(schema.OneOf) > 0 || len(schema.AnyOf) > 0) {
+		proxies, keyword := schema.OneOf, "oneOf"
+		if len(proxies) == 0 {
+			proxies, keyword = schema.AnyOf, "anyOf"
+			g.issue(location, "anyOf is mapped to a oneof, which holds only one of the schemas")
+		}
+		fields.WriteString(fmt.Sprintf("%soneof %s {\n", inner, uniqueName(protoFieldName(name), names)))
+		for i, sp := range proxies {
+			hint := fmt.Sprintf("Option%d", i+1)
+			if sp != nil && sp.IsReference() {
+				hint = tsIdentifier(referenceName(sp.GetReference()))
+			}
+			t := g.fieldType(sp, hint, fmt.Sprintf("%s/%s/%d", location, keyword, i), &nested, inner)
+			if t.repeated || t.mapType {
+				t = g.wrap(hint, t, &nested, inner)
+			}
+			fields.WriteString(fmt.Sprintf("%s  %s %s = %d;\n", inner, t.name, uniqueName(protoFieldName(hint), names),
+				next()))
+		}
+		fields.WriteString(inner + "}\n")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%smessage %s {\n", indent, name))
+	for _, n := range nested {
+		sb.WriteString(n)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fields.String())
+	sb.WriteString(indent + "}\n")
+	return sb.String()
+}
+
+// fieldNumber returns the number of a field set by the 'x-proto-field' extension.
+func (g *protoGeneration) fieldNumber(p *protoProperty) (int, bool) {
+	if p.schema == nil {
+		return 0, false
+	}
+	var node *yaml.Node
+	if p.schema.IsReference() {
+		if ref := p.schema.GetReferenceNode(); ref != nil {
+			node = keyValue(ref, ProtobufFieldExtension)
+		}
+	} else if s := p.schema.Schema(); s != nil {
+		node = s.Extensions.GetOrZero(ProtobufFieldExtension)
+	}
+	if node == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(node.Value)
+	if err != nil || n < 1 || n > 536870911 || (n >= 19000 && n <= 19999) {
+		g.issue(p.location, "'%s' is not a valid field number, the next free number is used", node.Value)
+		return 0, false
+	}
+	return n, true
+}
+
+// enum generates an enum for a string enum schema. The first value is the zero value, which means no value was set.
+func (g *protoGeneration) enum(name string, schema *highbase.Schema, indent string) string {
+	prefix := protoConstantName(name)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%senum %s {\n", indent, name))
+	used := map[string]bool{prefix + "_UNSPECIFIED": true}
+	sb.WriteString(fmt.Sprintf("%s  %s_UNSPECIFIED = 0;\n", indent, prefix))
+	n := 1
+	for _, value := range schema.Enum {
+		if value == nil || value.Tag == "!!null" {
+			continue
+		}
+		suffix := protoConstantName(value.Value)
+		if suffix == "" {
+			suffix = fmt.Sprintf("VALUE_%d", n)
+		}
+		sb.WriteString(fmt.Sprintf("%s  %s = %d;\n", indent, uniqueName(prefix+"_"+suffix, used), n))
+		n++
+	}
+	sb.WriteString(indent + "}\n")
+	return sb.String()
+}
+
+// response returns the message an RPC returns, from the schema of the first successful response with content.
+func (g *protoGeneration) response(rpcName string, operation *v3.Operation, location, source string,
+	mediaTypes []string,
+) string {
+	if operation.Responses != nil {
+		for code, response := range operation.Responses.Codes.FromOldest() {
+			if !strings.HasPrefix(code, "2") {
+				continue
+			}
+			sp := preferredSchema(response.Content, mediaTypes)
+			if sp == nil {
+				continue
+			}
+			responseLocation := location + "/responses/" + code
+			if sp.IsReference() {
+				t := g.fieldType(sp, "", responseLocation, nil, "")
+				if !t.scalar && !t.repeated && !t.mapType && !strings.HasPrefix(t.name, "google.protobuf.") {
+					return t.name
+				}
+			}
+			name := uniqueName(rpcName+"Response", g.used)
+			comment := fmt.Sprintf("// %s is the %s response of %s.\n", name, code, source)
+			if s := sp.Schema(); s != nil && !sp.IsReference() && isProtoMessage(s) {
+				g.decls = append(g.decls, comment+g.schemaMessage(name, s, responseLocation, ""))
+			} else {
+				g.decls = append(g.decls, comment+g.message(name, []*protoProperty{{
+					name: "value", schema: sp, required: true, location: responseLocation,
+				}}, nil, responseLocation, ""))
+			}
+			return name
+		}
+	}
+	g.imports["google/protobuf/empty.proto"] = true
+	return "google.protobuf.Empty"
+}
+
+// protoComment returns the comment of a message or enum, from the description of its schema.
+func protoComment(schema *highbase.Schema, indent string) string {
+	if schema == nil || schema.Description == "" {
+		return ""
+	}
+	return commentLines(schema.Description, indent, "// ")
+}
+
+// protoFieldName turns a name into a lower snake case field name.
+func protoFieldName(name string) string {
+	words := identifierWords(name)
+	for i := range words {
+		words[i] = strings.ToLower(words[i])
+	}
+	fieldName := strings.Join(words, "_")
+	if fieldName == "" || fieldName[0] < 'a' || fieldName[0] > 'z' {
+		fieldName = "f_" + fieldName
+	}
+	return fieldName
+}
+
+// protoConstantName turns a name into an upper snake case enum value name.
+func protoConstantName(name string) string {
+	words := identifierWords(name)
+	for i := range words {
+		words[i] = strings.ToUpper(words[i])
+	}
+	return strings.Join(words, "_")
+}
+
+// protoJSONName returns the JSON name protoc gives a field, which is the field name in lower camel case.
+func protoJSONName(fieldName string) string {
+	var sb strings.Builder
+	upper := false
+	for _, r := range fieldName {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			sb.WriteString(strings.ToUpper(string(r)))
+			upper = false
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,404 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var protobufSpec = `openapi: 3.1.0
+info:
+  title: pet store
+  version: 1.0.0
+  description: Manages pets.
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List all pets.
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            format: int32
+        - name: X-Request-ID
+          in: header
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: pets
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Pet'
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+    delete:
+      deprecated: true
+      responses:
+        "204":
+          description: deleted
+components:
+  schemas:
+    Pet:
+      description: A pet is a cat or a dog.
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+    Base:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          x-proto-field: 3
+        petType:
+          type: string
+        born:
+          type: string
+          format: date-time
+        status:
+          type: string
+          enum: [available, sold, "on hold"]
+        tags:
+          type: array
+          items:
+            type: string
+        matrix:
+          type: array
+          items:
+            type: array
+            items:
+              type: number
+        attributes:
+          type: object
+          additionalProperties:
+            type: integer
+        owner:
+          $ref: '#/components/schemas/Owner'
+          x-proto-field: 10
+        anything: {}
+        mixed:
+          type: [string, integer]
+        point:
+          type: array
+          prefixItems:
+            - type: number
+    Owner:
+      type: object
+      description: The owner of a pet.
+      properties:
+        id:
+          type: integer
+          deprecated: true
+        address:
+          type: object
+          properties:
+            street:
+              type: string
+        size:
+          type: integer
+          enum: [1, 2]
+    Cat:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            lives:
+              type: integer
+              x-proto-field: 3
+            friend:
+              $ref: '#/components/schemas/Cat'
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+      properties:
+        bark:
+          type: boolean
+    Id:
+      type: string`
+
+func TestProtobufGenerator_Generate(t *testing.T) {
+	gen := NewProtobufGenerator(buildModel(t, protobufSpec))
+	gen.SetPackageName("pets.v1")
+	out, issues := gen.Generate()
+	proto := string(out)
+
+	assert.Contains(t, proto, `syntax = "proto3";
+
+package pets.v1;
+
+import "google/protobuf/empty.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+`)
+
+	// oneOf is a message holding a oneof.
+	assert.Contains(t, proto, `// A pet is a cat or a dog.
+message Pet {
+  oneof pet {
+    Cat cat = 1;
+    Dog dog = 2;
+  }
+}`)
+
+	// allOf schemas hold the fields of every schema, numbered by the extension, then in order.
+	assert.Contains(t, proto, `message Cat {
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    STATUS_AVAILABLE = 1;
+    STATUS_SOLD = 2;
+    STATUS_ON_HOLD = 3;
+  }
+
+  message MatrixItem {
+    repeated double values = 1;
+  }
+
+  string name = 3;
+  optional string pet_type = 1;
+  google.protobuf.Timestamp born = 2;
+  optional Status status = 4;
+  repeated string tags = 5;
+  repeated MatrixItem matrix = 6;
+  map<string, int64> attributes = 7;
+  Owner owner = 10;
+  google.protobuf.Value anything = 8;
+  google.protobuf.Value mixed = 9;
+  repeated google.protobuf.Value point = 11;
+  optional int64 lives = 12;
+  Cat friend = 13;
+}`)
+	assert.Contains(t, proto, `// The owner of a pet.
+message Owner {
+  message Address {
+    optional string street = 1;
+  }
+
+  optional int64 id = 1 [deprecated = true];
+  Address address = 2;
+  optional int64 size = 3;
+}`)
+	assert.NotContains(t, proto, "message Id")
+
+	// operations are RPCs, with request messages and the schema of the successful response.
+	assert.Contains(t, proto, `message ListPetsRequest {
+  optional int32 limit = 1;
+  string x_request_id = 2 [json_name = "X-Request-ID"];
+}`)
+	assert.Contains(t, proto, "message ListPetsResponse {\n  repeated Pet value = 1;\n}")
+	assert.Contains(t, proto, "message CreatePetRequest {\n  Pet body = 1;\n}")
+	assert.Contains(t, proto, "message DeletePetsPetIdRequest {\n  string pet_id = 1;\n}")
+	assert.Contains(t, proto, `// Manages pets.
+service PetStoreService {
+  // List all pets.
+  rpc ListPets(ListPetsRequest) returns (ListPetsResponse);
+
+  rpc CreatePet(CreatePetRequest) returns (Pet);
+
+  rpc DeletePetsPetId(DeletePetsPetIdRequest) returns (google.protobuf.Empty) {
+    option deprecated = true;
+  }
+}
+`)
+
+	// constructs that cannot be mapped are reported once, even when they are part of more than one allOf.
+	var reported []string
+	for _, issue := range issues {
+		reported = append(reported, issue.String())
+	}
+	assert.Equal(t, []string{
+		"#/components/schemas/Cat/allOf/1/properties/lives: field number 3 is used by another field, " +
+			"the next free number is used",
+		"#/components/schemas/Owner/properties/size: only string enums can be mapped to enums, " +
+			"the integer type is used instead",
+		"#/components/schemas/Base/properties/mixed: a schema with more than one type (string, integer), " +
+			"it is mapped to google.protobuf.Value",
+		"#/components/schemas/Base/properties/point: tuples (prefixItems) cannot be mapped, " +
+			"the items are google.protobuf.Value",
+	}, reported)
+}
+
+func TestProtobufGenerator_Service(t *testing.T) {
+	gen := NewProtobufGenerator(buildModel(t, `openapi: 3.1.0
+info:
+  title: ""
+  version: 1.0.0
+paths:
+  /shapes:
+    get:
+      operationId: getShape
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                anyOf:
+                  - type: array
+                    items:
+                      type: string
+                  - type: object
+                    properties:
+                      radius:
+                        type: number
+                        format: float
+                        x-proto-field: 19001
+        default:
+          description: error
+    put:
+      operationId: putShape
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              additionalProperties:
+                type: array
+                items:
+                  type: integer
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Name'
+components:
+  schemas:
+    Name:
+      type: string`))
+	out, issues := gen.Generate()
+	proto := string(out)
+
+	assert.Contains(t, proto, "service APIService {")
+	assert.Contains(t, proto, "  rpc GetShape(google.protobuf.Empty) returns (GetShapeResponse);")
+	assert.Contains(t, proto, `message GetShapeResponse {
+  message Option1 {
+    repeated string values = 1;
+  }
+
+  message Option2 {
+    optional float radius = 1;
+  }
+
+  oneof get_shape_response {
+    Option1 option1 = 1;
+    Option2 option2 = 2;
+  }
+}`)
+
+	// maps can't hold arrays, so the array is wrapped, and scalar responses are wrapped in a message.
+	assert.Contains(t, proto, `message PutShapeRequest {
+  message BodyValue {
+    repeated int64 values = 1;
+  }
+
+  map<string, BodyValue> body = 1;
+}`)
+	assert.Contains(t, proto, "message PutShapeResponse {\n  string value = 1;\n}")
+
+	require.Len(t, issues, 2)
+	assert.Equal(t, "#/paths/~1shapes/get/responses/200", issues[0].Location)
+	assert.Equal(t, "anyOf is mapped to a oneof, which holds only one of the schemas", issues[0].Message)
+	assert.Equal(t, "'19001' is not a valid field number, the next free number is used", issues[1].Message)
+
+	gen.SetServiceName("Shapes")
+	out, _ = gen.Generate()
+	assert.Contains(t, string(out), "service Shapes {")
+}
+
+func TestProtobufGenerator_Nullable(t *testing.T) {
+	gen := NewProtobufGenerator(buildModel(t, `openapi: 3.1.0
+info:
+  title: nullable
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name, nickname, age, owner]
+      properties:
+        name:
+          type: string
+        nickname:
+          type: [string, "null"]
+        age:
+          type: integer
+          nullable: true
+        owner:
+          type: [object, "null"]
+          properties:
+            name:
+              type: string
+        color:
+          type: [string, "null"]`))
+	out, issues := gen.Generate()
+
+	// required scalars that can be null are optional, messages always track presence.
+	assert.Contains(t, string(out), `message Pet {
+  message Owner {
+    optional string name = 1;
+  }
+
+  string name = 1;
+  optional string nickname = 2;
+  optional int64 age = 3;
+  Owner owner = 4;
+  optional string color = 5;
+}`)
+
+	var reported []string
+	for _, issue := range issues {
+		reported = append(reported, issue.String())
+	}
+	assert.Equal(t, []string{
+		"#/components/schemas/Pet/properties/nickname: a required property that can be null is mapped to an " +
+			"optional field, null and missing values cannot be told apart",
+		"#/components/schemas/Pet/properties/age: a required property that can be null is mapped to an " +
+			"optional field, null and missing values cannot be told apart",
+	}, reported)
+}
+
+func TestProtobufGenerator_Empty(t *testing.T) {
+	out, issues := NewProtobufGenerator(nil).Generate()
+	assert.Equal(t, "// Code generated by libopenapi. DO NOT EDIT.\n\nsyntax = \"proto3\";\n\npackage api;\n", string(out))
+	assert.Empty(t, issues)
+}
+
+func TestProtoNames(t *testing.T) {
+	assert.Equal(t, "x_request_id", protoFieldName("X-Request-ID"))
+	assert.Equal(t, "pet_type", protoFieldName("petType"))
+	assert.Equal(t, "f_200", protoFieldName("200"))
+	assert.Equal(t, "ON_HOLD", protoConstantName("on hold"))
+	assert.Equal(t, "xRequestId", protoJSONName("x_request_id"))
+}
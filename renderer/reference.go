@@ -0,0 +1,283 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// referenceUntagged is the group holding the operations without tags, in API references.
+	referenceUntagged = "Other"
+	// referenceWebhooks is the group holding webhooks, in API references.
+	referenceWebhooks = "Webhooks"
+)
+
+// referenceGroup is a group of operations in an API reference, for the first tag of the operations.
+type referenceGroup struct {
+	name        string
+	description string
+	anchor      string
+	operations  []*referenceOperation
+}
+
+// referenceOperation is an operation (or webhook) in an API reference.
+type referenceOperation struct {
+	anchor     string
+	method     string
+	path       string
+	operation  *v3.Operation
+	parameters []*v3.Parameter
+	webhook    bool
+}
+
+// referenceExample is an example of a request or response body.
+type referenceExample struct {
+	name    string
+	summary string
+	value   string
+}
+
+// referenceGroups groups the operations of a document by their first tag. Groups are ordered by the tags of the
+// document, then by the tags that are not declared, in the order they are used. Operations without tags follow, and
+// webhooks are last. Anchors are unique across the groups and the operations.
+func referenceGroups(document *v3.Document, anchors map[string]bool) []*referenceGroup {
+	if document == nil {
+		return nil
+	}
+	groups := make(map[string]*referenceGroup)
+	var order []*referenceGroup
+	group := func(name string) *referenceGroup {
+		if g, ok := groups[name]; ok {
+			return g
+		}
+		g := &referenceGroup{name: name}
+		groups[name] = g
+		order = append(order, g)
+		return g
+	}
+	for _, tag := range document.Tags {
+		group(tag.Name).description = tag.Description
+	}
+
+	var untagged, webhooks []*referenceOperation
+	add := func(path string, pathItem *v3.PathItem, webhook bool) {
+		if pathItem == nil {
+			return
+		}
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			op := &referenceOperation{
+				method:     strings.ToUpper(method),
+				path:       path,
+				operation:  operation,
+				parameters: mergeParameters(pathItem, operation),
+				webhook:    webhook,
+			}
+			switch {
+			case webhook:
+				webhooks = append(webhooks, op)
+			case len(operation.Tags) > 0:
+				g := group(operation.Tags[0])
+				g.operations = append(g.operations, op)
+			default:
+				untagged = append(untagged, op)
+			}
+		}
+	}
+	if document.Paths != nil {
+		for path, pathItem := range document.Paths.PathItems.FromOldest() {
+			add(path, pathItem, false)
+		}
+	}
+	for name, pathItem := range document.Webhooks.FromOldest() {
+		add(name, pathItem, true)
+	}
+	if len(untagged) > 0 {
+		if _, ok := groups[referenceUntagged]; ok {
+			g := groups[referenceUntagged]
+			g.operations = append(g.operations, untagged...)
+		} else {
+			group(referenceUntagged).operations = untagged
+		}
+	}
+	if len(webhooks) > 0 {
+		order = append(order, &referenceGroup{name: referenceWebhooks, operations: webhooks})
+	}
+
+	var result []*referenceGroup
+	for _, g := range order {
+		if len(g.operations) == 0 {
+			continue
+		}
+		g.anchor = uniqueName("tag-"+referenceSlug(g.name), anchors)
+		for _, op := range g.operations {
+			name := op.operation.OperationId
+			if name == "" {
+				name = op.method + " " + op.path
+			}
+			prefix := "operation-"
+			if op.webhook {
+				prefix = "webhook-"
+			}
+			op.anchor = uniqueName(prefix+referenceSlug(name), anchors)
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+// referenceSlug turns a name into a lower case anchor, using dashes between words.
+func referenceSlug(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteRune('-')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	if sb.Len() == 0 {
+		return "item"
+	}
+	return sb.String()
+}
+
+// referenceExamples returns the examples of a media type, falling back to the example of its schema. If there are
+// none, and a MockGenerator is supplied, a mock is generated instead.
+func referenceExamples(mediaTypeName string, mediaType *v3.MediaType, mock *MockGenerator) []*referenceExample {
+	if mediaType == nil {
+		return nil
+	}
+	if mediaType.Example != nil {
+		return []*referenceExample{{value: referenceExampleValue(mediaTypeName, mediaType.Example)}}
+	}
+	var examples []*referenceExample
+	for name, example := range mediaType.Examples.FromOldest() {
+		if example == nil {
+			continue
+		}
+		ex := &referenceExample{name: name, summary: example.Summary}
+		switch {
+		case example.Value != nil:
+			ex.value = referenceExampleValue(mediaTypeName, example.Value)
+		case example.ExternalValue != "":
+			ex.value = example.ExternalValue
+		}
+		examples = append(examples, ex)
+	}
+	if len(examples) > 0 {
+		return examples
+	}
+	if mediaType.Schema != nil {
+		if schema := mediaType.Schema.Schema(); schema != nil {
+			switch {
+			case len(schema.Examples) > 0:
+				return []*referenceExample{{value: referenceExampleValue(mediaTypeName, schema.Examples[0])}}
+			case schema.Example != nil:
+				return []*referenceExample{{value: referenceExampleValue(mediaTypeName, schema.Example)}}
+			}
+		}
+	}
+	if mock != nil {
+		if b, err := mock.GenerateMock(mediaType, ""); err == nil && len(b) > 0 {
+			return []*referenceExample{{value: string(b)}}
+		}
+	}
+	return nil
+}
+
+// referenceExampleValue renders an example as JSON, or as YAML for YAML media types. Strings are rendered as they
+// are.
+func referenceExampleValue(mediaTypeName string, node *yaml.Node) string {
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return node.Value
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	if strings.Contains(strings.ToLower(mediaTypeName), "yaml") {
+		b, _ := yaml.Marshal(value)
+		return strings.TrimSpace(string(b))
+	}
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// referenceSchemaType describes the type of a schema, such as 'integer<int64>', 'array<string>' or 'string | null'.
+// References are described by the name of the schema they point to.
+func referenceSchemaType(sp *highbase.SchemaProxy) string {
+	if sp == nil {
+		return "any"
+	}
+	if sp.IsReference() {
+		return referenceName(sp.GetReference())
+	}
+	schema := sp.Schema()
+	if schema == nil {
+		return "any"
+	}
+	var types []string
+	for _, t := range schema.Type {
+		switch {
+		case t == "array" && schema.Items != nil && schema.Items.IsA():
+			types = append(types, "array<"+referenceSchemaType(schema.Items.A)+">")
+		case schema.Format != "" && t != "null":
+			types = append(types, t+"<"+schema.Format+">")
+		default:
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		switch {
+		case len(schema.AllOf) > 0:
+			return "allOf"
+		case len(schema.OneOf) > 0:
+			return "oneOf"
+		case len(schema.AnyOf) > 0:
+			return "anyOf"
+		case orderedmap.Len(schema.Properties) > 0:
+			return "object"
+		}
+		return "any"
+	}
+	if schema.Nullable != nil && *schema.Nullable {
+		types = append(types, "null")
+	}
+	return strings.Join(types, " | ")
+}
+
+// referenceParameterSchema returns the schema of a parameter, or the schema of its first media type.
+func referenceParameterSchema(param *v3.Parameter) *highbase.SchemaProxy {
+	if param.Schema != nil {
+		return param.Schema
+	}
+	for _, mt := range param.Content.FromOldest() {
+		if mt != nil {
+			return mt.Schema
+		}
+	}
+	return nil
+}
+
+// referenceRequired returns true if a parameter is required, which path parameters always are.
+func referenceRequired(param *v3.Parameter) bool {
+	return param.In == pathParam || (param.Required != nil && *param.Required)
+}
@@ -0,0 +1,751 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	pathParam   = "path"
+	queryParam  = "query"
+	headerParam = "header"
+	cookieParam = "cookie"
+
+	simpleStyle         = "simple"
+	labelStyle          = "label"
+	matrixStyle         = "matrix"
+	formStyle           = "form"
+	spaceDelimitedStyle = "spaceDelimited"
+	pipeDelimitedStyle  = "pipeDelimited"
+	deepObjectStyle     = "deepObject"
+
+	defaultServerURL  = "http://localhost"
+	multipartBoundary = "libopenapi-example-boundary"
+)
+
+// DefaultPreferredMediaTypes is the order in which request body media types are selected when generating a request.
+// If none of these media types are defined by the operation, the first media type defined is used.
+var DefaultPreferredMediaTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"application/xml",
+	"text/plain",
+}
+
+// ExampleRequest is a complete example HTTP request generated for an operation by a RequestGenerator.
+type ExampleRequest struct {
+	Method    string      // HTTP method, upper case.
+	URL       *url.URL    // fully resolved URL, including path and query parameters.
+	Header    http.Header // all headers, including cookies, auth and the content type.
+	Body      []byte      // rendered body, nil if the operation has no request body.
+	MediaType string      // the media type selected from the request body, empty if there is no body.
+}
+
+// HTTPRequest converts the ExampleRequest into an *http.Request, using the supplied context.
+func (e *ExampleRequest) HTTPRequest(ctx context.Context) (*http.Request, error) {
+	var body io.Reader
+	if e.Body != nil {
+		body = bytes.NewReader(e.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, e.Method, e.URL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = e.Header.Clone()
+	return req, nil
+}
+
+// Curl renders the ExampleRequest as a curl command. Headers are sorted, so the output is stable.
+func (e *ExampleRequest) Curl() string {
+	var sb strings.Builder
+	sb.WriteString("curl -X ")
+	sb.WriteString(e.Method)
+	sb.WriteString(" ")
+	sb.WriteString(shellQuote(e.URL.String()))
+
+	keys := make([]string, 0, len(e.Header))
+	for k := range e.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range e.Header[k] {
+			sb.WriteString(" \\\n  -H ")
+			sb.WriteString(shellQuote(fmt.Sprintf("%s: %s", k, v)))
+		}
+	}
+	if e.Body != nil {
+		sb.WriteString(" \\\n  --data-raw ")
+		sb.WriteString(shellQuote(string(e.Body)))
+	}
+	return sb.String()
+}
+
+// RequestGenerator generates complete example HTTP requests for operations. Path parameters are filled into the
+// path template, query, header and cookie parameters are serialized according to their `style` and `explode`
+// values, a body is rendered for the preferred media type, and auth headers (or query parameters / cookies) are set
+// using the security requirements of the operation (or the document).
+//
+// Values are taken from examples first, and are otherwise rendered from schemas using a SchemaRenderer.
+// Use NewRequestGenerator to create a new RequestGenerator.
+type RequestGenerator struct {
+	document    *v3.Document
+	renderer    *SchemaRenderer
+	credentials map[string]string
+	mediaTypes  []string
+}
+
+// NewRequestGenerator creates a new RequestGenerator for the supplied document. The document is used to look up
+// security schemes, document level security requirements, servers and path level parameters. The generator uses a
+// SchemaRenderer with the built-in value providers by default.
+func NewRequestGenerator(document *v3.Document) *RequestGenerator {
+	return &RequestGenerator{
+		document:    document,
+		renderer:    CreateRendererUsingBuiltInProviders(),
+		credentials: make(map[string]string),
+		mediaTypes:  DefaultPreferredMediaTypes,
+	}
+}
+
+// SetRenderer sets the SchemaRenderer used to render values from schemas when there are no examples.
+func (rg *RequestGenerator) SetRenderer(renderer *SchemaRenderer) {
+	rg.renderer = renderer
+}
+
+// SetCredential sets the credential to use for a security scheme (by name). For apiKey schemes, this is the key,
+// for http bearer, oauth2 and openIdConnect schemes this is the token and for http basic schemes, this is the
+// 'username:password' pair (it will be base64 encoded). If no credential is set, a placeholder is used.
+func (rg *RequestGenerator) SetCredential(securitySchemeName, credential string) {
+	rg.credentials[securitySchemeName] = credential
+}
+
+// SetPreferredMediaTypes sets the order in which request body media types are selected.
+func (rg *RequestGenerator) SetPreferredMediaTypes(mediaTypes ...string) {
+	rg.mediaTypes = mediaTypes
+}
+
+// GenerateRequest generates an ExampleRequest for an operation, found at the supplied path, using the supplied
+// HTTP method. If the server is nil, the first server defined by the operation is used, then the first server
+// defined by the document. If there are no servers, or the server URL is relative, http://localhost is used as the host.
+func (rg *RequestGenerator) GenerateRequest(path, method string, operation *v3.Operation, server *v3.Server) (*ExampleRequest, error) {
+	if operation == nil {
+		return nil, errors.New("unable to generate request, no operation supplied")
+	}
+	var pathItem *v3.PathItem
+	if rg.document != nil && rg.document.Paths != nil && rg.document.Paths.PathItems != nil {
+		pathItem = rg.document.Paths.PathItems.GetOrZero(path)
+	}
+	return rg.generateRequest(path, method, pathItem, operation, server)
+}
+
+// generateRequest generates an ExampleRequest for an operation of a path item, which may be nil.
+func (rg *RequestGenerator) generateRequest(path, method string, pathItem *v3.PathItem, operation *v3.Operation,
+	server *v3.Server,
+) (*ExampleRequest, error) {
+	if server == nil {
+		if len(operation.Servers) > 0 {
+			server = operation.Servers[0]
+		} else if rg.document != nil && len(rg.document.Servers) > 0 {
+			server = rg.document.Servers[0]
+		}
+	}
+
+	req := &ExampleRequest{
+		Method: strings.ToUpper(method),
+		Header: make(http.Header),
+	}
+
+	renderedPath := path
+	var query []string
+	var cookies []string
+
+	for _, param := range mergeParameters(pathItem, operation) {
+		value := rg.parameterValue(param)
+		if value == nil {
+			continue
+		}
+		style, explode := parameterStyle(param)
+		switch param.In {
+		case pathParam:
+			renderedPath = strings.ReplaceAll(renderedPath, fmt.Sprintf("{%s}", param.Name),
+				serializePathParameter(param.Name, style, explode, value))
+		case queryParam:
+			query = append(query, serializeQueryParameter(param.Name, style, explode, param.AllowReserved, value)...)
+		case headerParam:
+			req.Header.Add(param.Name, serializeSimple(value, explode, func(s string) string { return s }))
+		case cookieParam:
+			cookies = append(cookies, serializeCookieParameter(param.Name, explode, value)...)
+		}
+	}
+
+	// authentication
+	for _, scheme := range rg.securitySchemes(operation) {
+		name, sc := scheme.name, scheme.scheme
+		switch strings.ToLower(sc.Type) {
+		case "apikey":
+			credential := rg.credential(name, "YOUR_API_KEY")
+			switch sc.In {
+			case queryParam:
+				query = append(query, fmt.Sprintf("%s=%s", url.QueryEscape(sc.Name), url.QueryEscape(credential)))
+			case cookieParam:
+				cookies = append(cookies, fmt.Sprintf("%s=%s", sc.Name, credential))
+			default:
+				req.Header.Set(sc.Name, credential)
+			}
+		case "http":
+			switch strings.ToLower(sc.Scheme) {
+			case "basic":
+				credential := rg.credential(name, "username:password")
+				req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credential)))
+			case "bearer":
+				req.Header.Set("Authorization", "Bearer "+rg.credential(name, "YOUR_TOKEN"))
+			default:
+				req.Header.Set("Authorization", fmt.Sprintf("%s %s", sc.Scheme, rg.credential(name, "YOUR_CREDENTIALS")))
+			}
+		case "oauth2", "openidconnect":
+			req.Header.Set("Authorization", "Bearer "+rg.credential(name, "YOUR_ACCESS_TOKEN"))
+		}
+	}
+
+	if len(cookies) > 0 {
+		req.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	// body
+	if operation.RequestBody != nil && operation.RequestBody.Content != nil {
+		mediaTypeName, mediaType := rg.preferredMediaType(operation.RequestBody.Content)
+		if mediaType != nil {
+			body, contentType, err := rg.renderBody(mediaTypeName, mediaType)
+			if err != nil {
+				return nil, fmt.Errorf("unable to render request body for media type '%s': %w", mediaTypeName, err)
+			}
+			req.Body = body
+			req.MediaType = mediaTypeName
+			req.Header.Set("Content-Type", contentType)
+		}
+	}
+
+	u, err := url.Parse(resolveServerURL(server) + renderedPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate request URL: %w", err)
+	}
+	if len(query) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += strings.Join(query, "&")
+	}
+	req.URL = u
+	return req, nil
+}
+
+// mergeParameters merges the path item level parameters with the operation level ones. Operation parameters
+// override path item parameters with the same name and location.
+func mergeParameters(pathItem *v3.PathItem, operation *v3.Operation) []*v3.Parameter {
+	var params []*v3.Parameter
+	if pathItem != nil {
+		for _, p := range pathItem.Parameters {
+			overridden := slices.ContainsFunc(operation.Parameters, func(op *v3.Parameter) bool {
+				return op.Name == p.Name && op.In == p.In
+			})
+			if !overridden {
+				params = append(params, p)
+			}
+		}
+	}
+	return append(params, operation.Parameters...)
+}
+
+type namedSecurityScheme struct {
+	name   string
+	scheme *v3.SecurityScheme
+}
+
+// securitySchemes returns the schemes of the first security requirement that applies to the operation. Security
+// requirements are alternatives, so only one needs to be satisfied.
+func (rg *RequestGenerator) securitySchemes(operation *v3.Operation) []namedSecurityScheme {
+	requirements := operation.Security
+	if requirements == nil && rg.document != nil {
+		requirements = rg.document.Security
+	}
+	if len(requirements) == 0 || rg.document == nil || rg.document.Components == nil ||
+		rg.document.Components.SecuritySchemes == nil {
+		return nil
+	}
+	var schemes []namedSecurityScheme
+	for _, requirement := range requirements {
+		if requirement == nil || requirement.Requirements == nil {
+			continue
+		}
+		for name := range requirement.Requirements.KeysFromOldest() {
+			if sc := rg.document.Components.SecuritySchemes.GetOrZero(name); sc != nil {
+				schemes = append(schemes, namedSecurityScheme{name: name, scheme: sc})
+			}
+		}
+		if len(schemes) > 0 {
+			break
+		}
+	}
+	return schemes
+}
+
+func (rg *RequestGenerator) credential(schemeName, placeholder string) string {
+	if c, ok := rg.credentials[schemeName]; ok {
+		return c
+	}
+	return placeholder
+}
+
+func (rg *RequestGenerator) preferredMediaType(content *orderedmap.Map[string, *v3.MediaType]) (string, *v3.MediaType) {
+	for _, preferred := range rg.mediaTypes {
+		for name, mt := range content.FromOldest() {
+			if strings.EqualFold(name, preferred) {
+				return name, mt
+			}
+		}
+	}
+	for name, mt := range content.FromOldest() {
+		return name, mt
+	}
+	return "", nil
+}
+
+// parameterValue will extract an example value for a parameter, or render one from the parameter schema.
+func (rg *RequestGenerator) parameterValue(param *v3.Parameter) any {
+	if param.Example != nil || orderedmap.Len(param.Examples) > 0 {
+		return rg.exampleValue(param.Example, param.Examples, nil, param.Name)
+	}
+	if param.Schema != nil {
+		return rg.exampleValue(nil, nil, param.Schema, param.Name)
+	}
+
+	// parameters using content are serialized using the media type, which is almost always JSON.
+	if param.Content != nil {
+		for _, mt := range param.Content.FromOldest() {
+			value := rg.exampleValue(mt.Example, mt.Examples, mt.Schema, param.Name)
+			encoded, _ := json.Marshal(value)
+			return string(encoded)
+		}
+	}
+	return nil
+}
+
+// exampleValue extracts a value from an example, the first of a map of examples, or by rendering a schema.
+func (rg *RequestGenerator) exampleValue(example *yaml.Node,
+	examples *orderedmap.Map[string, *highbase.Example], schema *highbase.SchemaProxy, name string,
+) any {
+	var value any
+	if example != nil {
+		_ = example.Decode(&value)
+		return value
+	}
+	if examples != nil {
+		for ex := range examples.ValuesFromOldest() {
+			if ex != nil && ex.Value != nil {
+				_ = ex.Value.Decode(&value)
+				return value
+			}
+		}
+	}
+	if schema != nil && rg.renderer != nil {
+		if s := schema.Schema(); s != nil {
+			structure := make(map[string]any)
+			rg.renderer.DiveIntoSchema(s, name, structure, 0)
+			return structure[name]
+		}
+	}
+	return nil
+}
+
+func (rg *RequestGenerator) renderBody(mediaTypeName string, mediaType *v3.MediaType) ([]byte, string, error) {
+	value := rg.exampleValue(mediaType.Example, mediaType.Examples, mediaType.Schema, rootType)
+	lower := strings.ToLower(mediaTypeName)
+	switch {
+	case strings.Contains(lower, "json"):
+		b, err := json.Marshal(value)
+		return b, mediaTypeName, err
+	case strings.Contains(lower, "yaml"):
+		b, err := yaml.Marshal(value)
+		return b, mediaTypeName, err
+	case strings.Contains(lower, "xml"):
+		b, err := renderXML(value, mediaType.Schema)
+		return b, mediaTypeName, err
+	case lower == "application/x-www-form-urlencoded":
+		var pairs []string
+		for _, k := range sortedKeys(value) {
+			pairs = append(pairs, serializeQueryParameter(k, formStyle, true, false, value.(map[string]any)[k])...)
+		}
+		return []byte(strings.Join(pairs, "&")), mediaTypeName, nil
+	case strings.HasPrefix(lower, "multipart/"):
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		_ = w.SetBoundary(multipartBoundary)
+		for _, k := range sortedKeys(value) {
+			field := value.(map[string]any)[k]
+			if isScalar(field) {
+				_ = w.WriteField(k, fmt.Sprint(field))
+			} else {
+				encoded, _ := json.Marshal(field)
+				_ = w.WriteField(k, string(encoded))
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), w.FormDataContentType(), nil
+	default:
+		if value == nil {
+			return []byte{}, mediaTypeName, nil
+		}
+		if isScalar(value) {
+			return []byte(fmt.Sprint(value)), mediaTypeName, nil
+		}
+		b, err := json.Marshal(value)
+		return b, mediaTypeName, err
+	}
+}
+
+// renderXML renders a value as an XML document. Element names, attributes and wrapped arrays are taken from the
+// `xml` objects of the schema, its properties and its items. The root element is named after the schema, or the
+// component it references.
+func renderXML(value any, proxy *highbase.SchemaProxy) ([]byte, error) {
+	name := "root"
+	var schema *highbase.Schema
+	if proxy != nil {
+		schema = proxy.Schema()
+		if ref := proxy.GetReference(); ref != "" {
+			name = ref[strings.LastIndex(ref, "/")+1:]
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := writeXMLElement(enc, name, value, schema); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXMLElement writes a value as an element (or as repeated elements for an array that is not wrapped).
+func writeXMLElement(enc *xml.Encoder, name string, value any, schema *highbase.Schema) error {
+	if schema != nil && schema.XML != nil && schema.XML.Name != "" {
+		name = schema.XML.Name
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	if items, ok := value.([]any); ok {
+		var itemSchema *highbase.Schema
+		if schema != nil && schema.Items != nil && schema.Items.IsA() {
+			itemSchema = schema.Items.A.Schema()
+		}
+		wrapped := schema != nil && schema.XML != nil && schema.XML.Wrapped
+		if wrapped {
+			if err := enc.EncodeToken(start); err != nil {
+				return err
+			}
+		}
+		for _, item := range items {
+			if err := writeXMLElement(enc, name, item, itemSchema); err != nil {
+				return err
+			}
+		}
+		if wrapped {
+			return enc.EncodeToken(start.End())
+		}
+		return nil
+	}
+
+	properties, ok := value.(map[string]any)
+	if !ok {
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if value != nil {
+			if err := enc.EncodeToken(xml.CharData(fmt.Sprint(value))); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	// properties are written in the order of the schema, then any others in alphabetical order.
+	var keys []string
+	if schema != nil {
+		for key := range schema.Properties.KeysFromOldest() {
+			if _, ok := properties[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, key := range sortedKeys(properties) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	var children []string
+	for _, key := range keys {
+		propSchema := propertySchema(schema, key)
+		if propSchema != nil && propSchema.XML != nil && propSchema.XML.Attribute &&
+			properties[key] != nil && isScalar(properties[key]) {
+			attrName := key
+			if propSchema.XML.Name != "" {
+				attrName = propSchema.XML.Name
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrName}, Value: fmt.Sprint(properties[key])})
+			continue
+		}
+		children = append(children, key)
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, key := range children {
+		if err := writeXMLElement(enc, key, properties[key], propertySchema(schema, key)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// propertySchema returns the schema of a property, if the schema defines it.
+func propertySchema(schema *highbase.Schema, name string) *highbase.Schema {
+	if schema == nil || schema.Properties == nil {
+		return nil
+	}
+	if proxy := schema.Properties.GetOrZero(name); proxy != nil {
+		return proxy.Schema()
+	}
+	return nil
+}
+
+// parameterStyle returns the style and explode values for a parameter, applying the defaults from the specification.
+func parameterStyle(param *v3.Parameter) (string, bool) {
+	style := param.Style
+	if style == "" {
+		switch param.In {
+		case queryParam, cookieParam:
+			style = formStyle
+		default:
+			style = simpleStyle
+		}
+	}
+	explode := style == formStyle
+	if param.Explode != nil {
+		explode = *param.Explode
+	}
+	return style, explode
+}
+
+// resolveServerURL renders the server URL with the default values of any server variables.
+func resolveServerURL(server *v3.Server) string {
+	if server == nil || server.URL == "" {
+		return defaultServerURL
+	}
+	serverURL := server.URL
+	if server.Variables != nil {
+		for name, variable := range server.Variables.FromOldest() {
+			value := variable.Default
+			if value == "" && len(variable.Enum) > 0 {
+				value = variable.Enum[0]
+			}
+			serverURL = strings.ReplaceAll(serverURL, fmt.Sprintf("{%s}", name), value)
+		}
+	}
+	serverURL = strings.TrimSuffix(serverURL, "/")
+	if !strings.Contains(serverURL, "://") {
+		serverURL = defaultServerURL + "/" + strings.TrimPrefix(serverURL, "/")
+		serverURL = strings.TrimSuffix(serverURL, "/")
+	}
+	return serverURL
+}
+
+func serializePathParameter(name, style string, explode bool, value any) string {
+	switch style {
+	case labelStyle:
+		if explode {
+			return "." + serializeSimpleWith(value, ".", "=", url.PathEscape, true)
+		}
+		return "." + serializeSimple(value, false, url.PathEscape)
+	case matrixStyle:
+		switch {
+		case isScalar(value):
+			return fmt.Sprintf(";%s=%s", name, url.PathEscape(fmt.Sprint(value)))
+		case explode && isMap(value):
+			return ";" + serializeSimpleWith(value, ";", "=", url.PathEscape, true)
+		case explode:
+			var parts []string
+			for _, item := range toSlice(value) {
+				parts = append(parts, fmt.Sprintf("%s=%s", name, url.PathEscape(fmt.Sprint(item))))
+			}
+			return ";" + strings.Join(parts, ";")
+		default:
+			return fmt.Sprintf(";%s=%s", name, serializeSimple(value, false, url.PathEscape))
+		}
+	default:
+		return serializeSimple(value, explode, url.PathEscape)
+	}
+}
+
+func serializeQueryParameter(name, style string, explode, allowReserved bool, value any) []string {
+	escape := url.QueryEscape
+	if allowReserved {
+		escape = func(s string) string { return s }
+	}
+	key := escape(name)
+	switch {
+	case isScalar(value):
+		return []string{fmt.Sprintf("%s=%s", key, escape(fmt.Sprint(value)))}
+	case style == deepObjectStyle && isMap(value):
+		var pairs []string
+		for _, k := range sortedKeys(value) {
+			pairs = append(pairs, fmt.Sprintf("%s%s=%s", key, escape("["+k+"]"),
+				escape(fmt.Sprint(value.(map[string]any)[k]))))
+		}
+		return pairs
+	case explode && isMap(value):
+		var pairs []string
+		for _, k := range sortedKeys(value) {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", escape(k), escape(fmt.Sprint(value.(map[string]any)[k]))))
+		}
+		return pairs
+	case explode:
+		var pairs []string
+		for _, item := range toSlice(value) {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", key, escape(fmt.Sprint(item))))
+		}
+		return pairs
+	default:
+		delimiter := ","
+		switch style {
+		case spaceDelimitedStyle:
+			delimiter = "%20"
+		case pipeDelimitedStyle:
+			delimiter = "|"
+		}
+		return []string{fmt.Sprintf("%s=%s", key, serializeSimpleWith(value, delimiter, ",", escape, false))}
+	}
+}
+
+func serializeCookieParameter(name string, explode bool, value any) []string {
+	switch {
+	case isScalar(value):
+		return []string{fmt.Sprintf("%s=%s", name, fmt.Sprint(value))}
+	case explode && !isMap(value):
+		var pairs []string
+		for _, item := range toSlice(value) {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", name, fmt.Sprint(item)))
+		}
+		return pairs
+	default:
+		return []string{fmt.Sprintf("%s=%s", name, serializeSimple(value, false, func(s string) string { return s }))}
+	}
+}
+
+// serializeSimple serializes a value using the 'simple' style, which is comma separated.
+func serializeSimple(value any, explode bool, escape func(string) string) string {
+	if explode {
+		return serializeSimpleWith(value, ",", "=", escape, true)
+	}
+	return serializeSimpleWith(value, ",", ",", escape, false)
+}
+
+// serializeSimpleWith serializes arrays and objects using the supplied delimiters. Exploded objects render
+// key/value pairs using the key delimiter, otherwise keys and values are both separated by the value delimiter.
+func serializeSimpleWith(value any, delimiter, keyDelimiter string, escape func(string) string, explode bool) string {
+	switch {
+	case isScalar(value):
+		return escape(fmt.Sprint(value))
+	case isMap(value):
+		m := value.(map[string]any)
+		var parts []string
+		for _, k := range sortedKeys(value) {
+			if explode {
+				parts = append(parts, escape(k)+keyDelimiter+escape(fmt.Sprint(m[k])))
+			} else {
+				parts = append(parts, escape(k), escape(fmt.Sprint(m[k])))
+			}
+		}
+		return strings.Join(parts, delimiter)
+	default:
+		var parts []string
+		for _, item := range toSlice(value) {
+			parts = append(parts, escape(fmt.Sprint(item)))
+		}
+		return strings.Join(parts, delimiter)
+	}
+}
+
+func isScalar(value any) bool {
+	if value == nil {
+		return true
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+func isMap(value any) bool {
+	_, ok := value.(map[string]any)
+	return ok
+}
+
+func toSlice(value any) []any {
+	if s, ok := value.([]any); ok {
+		return s
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{value}
+	}
+	items := make([]any, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+func sortedKeys(value any) []string {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellQuote wraps a value in single quotes, escaping any single quotes inside it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,416 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pb33f/libopenapi"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var requestGeneratorSpec = `openapi: 3.1.0
+info:
+  title: Pets
+  version: 1.0.0
+servers:
+  - url: https://{region}.pets.example.com/v1
+    variables:
+      region:
+        default: eu
+        enum: [eu, us]
+security:
+  - apiKey: []
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          example: 42
+      - name: X-Trace
+        in: header
+        schema:
+          type: string
+          example: abc
+    put:
+      operationId: updatePet
+      security:
+        - bearer: []
+      parameters:
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+          example: [cute, fluffy]
+        - name: filter
+          in: query
+          style: deepObject
+          schema:
+            type: object
+          example:
+            color: brown
+            size: small
+        - name: session
+          in: cookie
+          example: s3cr3t
+        - name: X-Trace
+          in: header
+          example: overridden
+      requestBody:
+        content:
+          application/xml:
+            schema:
+              type: object
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                  example: Tip
+      responses:
+        '200':
+          description: ok
+  /pets:
+    post:
+      operationId: createPet
+      parameters:
+        - name: ids
+          in: query
+          style: pipeDelimited
+          explode: false
+          example: [1, 2, 3]
+      requestBody:
+        content:
+          application/x-www-form-urlencoded:
+            example:
+              name: Tip
+              age: 3
+      responses:
+        '200':
+          description: ok
+    get:
+      operationId: listPets
+      security:
+        - basic: []
+          query: []
+      responses:
+        '200':
+          description: ok
+  /pets/{petId}/matrix/{coords}/label/{label}:
+    get:
+      parameters:
+        - name: petId
+          in: path
+          style: matrix
+          explode: true
+          example: [1, 2]
+        - name: coords
+          in: path
+          style: matrix
+          example:
+            x: 1
+            y: 2
+        - name: label
+          in: path
+          style: label
+          example: [a, b]
+      security: []
+      responses:
+        '200':
+          description: ok
+  /upload:
+    post:
+      requestBody:
+        content:
+          multipart/form-data:
+            example:
+              name: Tip
+              tags: [a]
+      responses:
+        '200':
+          description: ok
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-API-Key
+    bearer:
+      type: http
+      scheme: bearer
+    basic:
+      type: http
+      scheme: basic
+    query:
+      type: apiKey
+      in: query
+      name: key`
+
+// buildModel builds the high-level model of an OpenAPI 3+ specification, failing the test if it has any errors.
+func buildModel(t *testing.T, spec string) *v3.Document {
+	doc, err := libopenapi.NewDocument([]byte(spec))
+	require.NoError(t, err)
+	m, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	return &m.Model
+}
+
+func TestRequestGenerator_GenerateRequest(t *testing.T) {
+	model := buildModel(t, requestGeneratorSpec)
+	rg := NewRequestGenerator(model)
+	rg.SetCredential("bearer", "t0k3n")
+
+	op := model.Paths.PathItems.GetOrZero("/pets/{petId}").Put
+	req, err := rg.GenerateRequest("/pets/{petId}", "put", op, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "PUT", req.Method)
+	assert.Equal(t, "https://eu.pets.example.com/v1/pets/42?tags=cute&tags=fluffy&filter%5Bcolor%5D=brown&filter%5Bsize%5D=small",
+		req.URL.String())
+	assert.Equal(t, "overridden", req.Header.Get("X-Trace"))
+	assert.Equal(t, "session=s3cr3t", req.Header.Get("Cookie"))
+	assert.Equal(t, "Bearer t0k3n", req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-API-Key"))
+	assert.Equal(t, "application/json", req.MediaType)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"name":"Tip"}`, string(req.Body))
+
+	httpReq, err := req.HTTPRequest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, httpReq.Method)
+	body, _ := io.ReadAll(httpReq.Body)
+	assert.JSONEq(t, `{"name":"Tip"}`, string(body))
+
+	assert.Equal(t, `curl -X PUT 'https://eu.pets.example.com/v1/pets/42?tags=cute&tags=fluffy&filter%5Bcolor%5D=brown&filter%5Bsize%5D=small' \
+  -H 'Authorization: Bearer t0k3n' \
+  -H 'Content-Type: application/json' \
+  -H 'Cookie: session=s3cr3t' \
+  -H 'X-Trace: overridden' \
+  --data-raw '{"name":"Tip"}'`, req.Curl())
+}
+
+func TestRequestGenerator_GenerateRequest_FormAndDocumentSecurity(t *testing.T) {
+	model := buildModel(t, requestGeneratorSpec)
+	rg := NewRequestGenerator(model)
+
+	op := model.Paths.PathItems.GetOrZero("/pets").Post
+	req, err := rg.GenerateRequest("/pets", "post", op, &v3.Server{URL: "/api/"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/api/pets?ids=1|2|3", req.URL.String())
+	assert.Equal(t, "YOUR_API_KEY", req.Header.Get("X-API-Key"))
+	assert.Equal(t, "age=3&name=Tip", string(req.Body))
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+}
+
+func TestRequestGenerator_GenerateRequest_BasicAndQueryKey(t *testing.T) {
+	model := buildModel(t, requestGeneratorSpec)
+	rg := NewRequestGenerator(model)
+	rg.SetCredential("query", "k&y")
+
+	op := model.Paths.PathItems.GetOrZero("/pets").Get
+	req, err := rg.GenerateRequest("/pets", "get", op, &v3.Server{URL: "https://api.example.com/"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/pets?key=k%26y", req.URL.String())
+	assert.Equal(t, "Basic dXNlcm5hbWU6cGFzc3dvcmQ=", req.Header.Get("Authorization"))
+	assert.Nil(t, req.Body)
+	assert.NotContains(t, req.Curl(), "--data-raw")
+
+	httpReq, err := req.HTTPRequest(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, httpReq.Body)
+}
+
+func TestRequestGenerator_GenerateRequest_PathStyles(t *testing.T) {
+	model := buildModel(t, requestGeneratorSpec)
+	rg := NewRequestGenerator(model)
+
+	path := "/pets/{petId}/matrix/{coords}/label/{label}"
+	op := model.Paths.PathItems.GetOrZero(path).Get
+	req, err := rg.GenerateRequest(path, "get", op, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu.pets.example.com/v1/pets/;petId=1;petId=2/matrix/;coords=x,1,y,2/label/.a,b",
+		req.URL.String())
+
+	// security has been explicitly disabled.
+	assert.Empty(t, req.Header.Get("X-API-Key"))
+}
+
+func TestRequestGenerator_GenerateRequest_Multipart(t *testing.T) {
+	model := buildModel(t, requestGeneratorSpec)
+	rg := NewRequestGenerator(model)
+
+	op := model.Paths.PathItems.GetOrZero("/upload").Post
+	req, err := rg.GenerateRequest("/upload", "post", op, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/form-data; boundary="+multipartBoundary, req.Header.Get("Content-Type"))
+	assert.Contains(t, string(req.Body), `name="tags"`)
+	assert.Contains(t, string(req.Body), `["a"]`)
+
+	httpReq, err := req.HTTPRequest(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, httpReq.ParseMultipartForm(1024))
+	assert.Equal(t, "Tip", httpReq.FormValue("name"))
+}
+
+func TestRequestGenerator_GenerateRequest_RenderedFromSchema(t *testing.T) {
+	spec := `openapi: 3.0.0
+info:
+  title: Render
+  version: 1.0.0
+paths:
+  /things/{id}:
+    post:
+      parameters:
+        - name: id
+          in: path
+          schema:
+            type: string
+            format: uuid
+        - name: X-Json
+          in: header
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  city:
+                    type: string
+      requestBody:
+        content:
+          text/plain:
+            schema:
+              type: string
+              enum: [hello]
+      responses:
+        '200':
+          description: ok`
+
+	model := buildModel(t, spec)
+	rg := NewRequestGenerator(model)
+	op := model.Paths.PathItems.GetOrZero("/things/{id}").Post
+	req, err := rg.GenerateRequest("/things/{id}", "post", op, nil)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^http://localhost/things/[0-9a-f-]{36}$`, req.URL.String())
+	assert.Equal(t, "hello", string(req.Body))
+	assert.Equal(t, "text/plain", req.MediaType)
+
+	var header map[string]string
+	require.NoError(t, json.Unmarshal([]byte(req.Header.Get("X-Json")), &header))
+	assert.Contains(t, cities, header["city"])
+
+	rg.SetPreferredMediaTypes("application/xml")
+	req, err = rg.GenerateRequest("/things/{id}", "post", op, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", req.MediaType)
+}
+
+func TestRequestGenerator_GenerateRequest_XML(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: xml
+  version: 1.0.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/xml:
+            schema:
+              $ref: '#/components/schemas/Pet'
+            example:
+              id: 7
+              name: Rex & Co
+              tags: [good, boy]
+              photos: [a.png]
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id:
+          type: integer
+          xml:
+            attribute: true
+        name:
+          type: string
+          xml:
+            name: petName
+        tags:
+          type: array
+          xml:
+            wrapped: true
+          items:
+            type: string
+            xml:
+              name: tag
+        photos:
+          type: array
+          items:
+            type: string
+            xml:
+              name: photo`
+
+	model := buildModel(t, spec)
+	rg := NewRequestGenerator(model)
+	req, err := rg.GenerateRequest("/pets", "post", model.Paths.PathItems.GetOrZero("/pets").Post, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "application/xml", req.Header.Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<Pet id="7">
+  <petName>Rex &amp; Co</petName>
+  <tags>
+    <tag>good</tag>
+    <tag>boy</tag>
+  </tags>
+  <photo>a.png</photo>
+</Pet>`, string(req.Body))
+}
+
+func TestRequestGenerator_GenerateRequest_NoOperation(t *testing.T) {
+	rg := NewRequestGenerator(nil)
+	_, err := rg.GenerateRequest("/", "get", nil, nil)
+	assert.Error(t, err)
+
+	req, err := rg.GenerateRequest("/", "get", &v3.Operation{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost/", req.URL.String())
+	assert.True(t, strings.HasPrefix(req.Curl(), "curl -X GET 'http://localhost/'"))
+}
+
+func TestSerializeParameters(t *testing.T) {
+	obj := map[string]any{"role": "admin", "firstName": "Alex"}
+	arr := []any{3, 4, 5}
+
+	assert.Equal(t, "3,4,5", serializePathParameter("id", simpleStyle, false, arr))
+	assert.Equal(t, "firstName,Alex,role,admin", serializePathParameter("id", simpleStyle, false, obj))
+	assert.Equal(t, "firstName=Alex,role=admin", serializePathParameter("id", simpleStyle, true, obj))
+	assert.Equal(t, ".3.4.5", serializePathParameter("id", labelStyle, true, arr))
+	assert.Equal(t, ".firstName=Alex.role=admin", serializePathParameter("id", labelStyle, true, obj))
+	assert.Equal(t, ";id=5", serializePathParameter("id", matrixStyle, false, 5))
+	assert.Equal(t, ";id=3,4,5", serializePathParameter("id", matrixStyle, false, arr))
+	assert.Equal(t, ";firstName=Alex;role=admin", serializePathParameter("id", matrixStyle, true, obj))
+
+	assert.Equal(t, []string{"id=3,4,5"}, serializeQueryParameter("id", formStyle, false, false, arr))
+	assert.Equal(t, []string{"firstName=Alex", "role=admin"}, serializeQueryParameter("id", formStyle, true, false, obj))
+	assert.Equal(t, []string{"id=3%204%205"}, serializeQueryParameter("id", spaceDelimitedStyle, false, false, arr))
+	assert.Equal(t, []string{"id=a/b"}, serializeQueryParameter("id", formStyle, true, true, "a/b"))
+
+	assert.Equal(t, []string{"id=3", "id=4", "id=5"}, serializeCookieParameter("id", true, arr))
+	assert.Equal(t, []string{"id=firstName,Alex,role,admin"}, serializeCookieParameter("id", true, obj))
+}
@@ -4,14 +4,15 @@
 package renderer
 
 import (
-	cryptoRand "crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lucasjones/reggen"
@@ -54,9 +55,36 @@ const (
 // used to generate random words if there is no dictionary applied.
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
-func init() {
-	// create a new random seed
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+// sharedRandom is used by renderers and value providers that have not been seeded.
+var sharedRandom = newRandom(time.Now().UnixNano())
+
+// lockedSource is a rand.Source that can be used by more than one goroutine.
+type lockedSource struct {
+	source rand.Source64
+	lock   sync.Mutex
+}
+
+func (l *lockedSource) Int63() int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.source.Int63()
+}
+
+func (l *lockedSource) Uint64() uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.source.Uint64()
+}
+
+func (l *lockedSource) Seed(seed int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.source.Seed(seed)
+}
+
+// newRandom creates a random number generator for the seed, that can be used by more than one goroutine.
+func newRandom(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{source: rand.NewSource(seed).(rand.Source64)})
 }
 
 // SchemaRenderer is a renderer that will generate random words, numbers and values based on a dictionary file.
@@ -64,6 +92,9 @@ func init() {
 type SchemaRenderer struct {
 	words           []string
 	disableRequired bool
+	providers       *ValueProviderRegistry
+	rand            *rand.Rand
+	now             func() time.Time
 }
 
 // CreateRendererUsingDictionary will create a new SchemaRenderer using a custom dictionary file.
@@ -83,6 +114,58 @@ func CreateRendererUsingDefaultDictionary() *SchemaRenderer {
 	return wr
 }
 
+// CreateRendererUsingBuiltInProviders will create a new SchemaRenderer that uses the embedded dictionary and the
+// built-in value providers (see NewBuiltInValueProviderRegistry). It does not depend on any file on the system,
+// so it works fully offline, on any platform.
+func CreateRendererUsingBuiltInProviders() *SchemaRenderer {
+	return &SchemaRenderer{
+		words:     BuiltInDictionary(),
+		providers: NewBuiltInValueProviderRegistry(),
+	}
+}
+
+// SetValueProviders will set the ValueProviderRegistry the renderer will consult before generating values.
+// Set to nil to disable value providers.
+func (wr *SchemaRenderer) SetValueProviders(registry *ValueProviderRegistry) {
+	wr.providers = registry
+}
+
+// SetSeed makes the renderer generate the same values, in the same order, every time it is seeded with the same value.
+// The value providers of the renderer are seeded too.
+func (wr *SchemaRenderer) SetSeed(seed int64) {
+	wr.rand = newRandom(seed)
+	if wr.providers != nil {
+		wr.providers.SetSeed(seed)
+	}
+}
+
+// SetClock sets the function used to get the current time, which is rendered for date, date-time and time formats.
+// The default is time.Now.
+func (wr *SchemaRenderer) SetClock(now func() time.Time) {
+	wr.now = now
+}
+
+// random returns the random number generator of the renderer.
+func (wr *SchemaRenderer) random() *rand.Rand {
+	if wr.rand == nil {
+		return sharedRandom
+	}
+	return wr.rand
+}
+
+// clock returns the current time, using the clock of the renderer.
+func (wr *SchemaRenderer) clock() time.Time {
+	if wr.now == nil {
+		return time.Now()
+	}
+	return wr.now()
+}
+
+// GetValueProviders returns the ValueProviderRegistry used by the renderer, or nil if there isn't one.
+func (wr *SchemaRenderer) GetValueProviders() *ValueProviderRegistry {
+	return wr.providers
+}
+
 // RenderSchema takes a schema and renders it into an interface, ready to be converted to JSON or YAML.
 func (wr *SchemaRenderer) RenderSchema(schema *base.Schema) any {
 	// dive into the schema and render it
@@ -116,11 +199,19 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 		return
 	}
 
+	// check if a value provider can supply a value, enums and examples always take priority.
+	if wr.providers != nil && len(schema.Enum) == 0 && len(schema.Examples) == 0 {
+		if value, ok := wr.providers.Provide(schema, key); ok {
+			structure[key] = value
+			return
+		}
+	}
+
 	// render out a string.
 	if slices.Contains(schema.Type, stringType) {
 		// check for an enum, if there is one, then pick a random value from it.
 		if schema.Enum != nil && len(schema.Enum) > 0 {
-			enum := schema.Enum[rand.Int()%len(schema.Enum)]
+			enum := schema.Enum[wr.random().Intn(len(schema.Enum))]
 
 			var example any
 			_ = enum.Decode(&example)
@@ -170,11 +261,11 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 
 			switch schema.Format {
 			case dateTimeType:
-				structure[key] = time.Now().Format(time.RFC3339)
+				structure[key] = wr.clock().Format(time.RFC3339)
 			case dateType:
-				structure[key] = time.Now().Format("2006-01-02")
+				structure[key] = wr.clock().Format("2006-01-02")
 			case timeType:
-				structure[key] = time.Now().Format("15:04:05")
+				structure[key] = wr.clock().Format("15:04:05")
 			case emailType:
 				structure[key] = fmt.Sprintf("%s@%s.com",
 					wr.RandomWord(minLength, maxLength, 0),
@@ -183,11 +274,11 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 				structure[key] = fmt.Sprintf("%s.com", wr.RandomWord(minLength, maxLength, 0))
 			case ipv4Type:
 				structure[key] = fmt.Sprintf("%d.%d.%d.%d",
-					rand.Int()%255, rand.Int()%255, rand.Int()%255, rand.Int()%255)
+					wr.random().Intn(255), wr.random().Intn(255), wr.random().Intn(255), wr.random().Intn(255))
 			case ipv6Type:
 				structure[key] = fmt.Sprintf("%04x:%04x:%04x:%04x:%04x:%04x:%04x:%04x",
-					rand.Intn(65535), rand.Intn(65535), rand.Intn(65535), rand.Intn(65535),
-					rand.Intn(65535), rand.Intn(65535), rand.Intn(65535), rand.Intn(65535),
+					wr.random().Intn(65535), wr.random().Intn(65535), wr.random().Intn(65535), wr.random().Intn(65535),
+					wr.random().Intn(65535), wr.random().Intn(65535), wr.random().Intn(65535), wr.random().Intn(65535),
 				)
 			case uriType:
 				structure[key] = fmt.Sprintf("https://%s-%s-%s.com/%s",
@@ -214,9 +305,10 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 			default:
 				// if there is a pattern supplied, then try and generate a string from it.
 				if schema.Pattern != "" {
-					str, err := reggen.Generate(schema.Pattern, int(maxLength))
+					generator, err := reggen.NewGenerator(schema.Pattern)
 					if err == nil {
-						structure[key] = str
+						generator.SetSeed(wr.random().Int63())
+						structure[key] = generator.Generate(int(maxLength))
 					}
 				} else {
 					// last resort, generate a random value
@@ -234,7 +326,7 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 		slices.Contains(schema.Type, decimalType) {
 
 		if schema.Enum != nil && len(schema.Enum) > 0 {
-			enum := schema.Enum[rand.Int()%len(schema.Enum)]
+			enum := schema.Enum[wr.random().Intn(len(schema.Enum))]
 
 			var example any
 			_ = enum.Decode(&example)
@@ -268,9 +360,9 @@ func (wr *SchemaRenderer) DiveIntoSchema(schema *base.Schema, key string, struct
 
 			switch schema.Format {
 			case floatType:
-				structure[key] = rand.Float32()
+				structure[key] = wr.random().Float32()
 			case doubleType:
-				structure[key] = rand.Float64()
+				structure[key] = wr.random().Float64()
 			case int32Type:
 				structure[key] = int(wr.RandomInt(minimum, maximum))
 			case bigIntType:
@@ -447,12 +539,12 @@ func (wr *SchemaRenderer) RandomWord(min, max int64, depth int) string {
 		}
 		b := make([]byte, min)
 		for i := range b {
-			b[i] = letterBytes[rand.Intn(len(letterBytes))]
+			b[i] = letterBytes[wr.random().Intn(len(letterBytes))]
 		}
 		return string(b)
 	}
 
-	word := wr.words[rand.Int()%len(wr.words)]
+	word := wr.words[wr.random().Intn(len(wr.words))]
 	if min == 0 && max == 0 {
 		return word
 	}
@@ -464,17 +556,18 @@ func (wr *SchemaRenderer) RandomWord(min, max int64, depth int) string {
 
 // RandomInt will return a random int between the min and max values.
 func (wr *SchemaRenderer) RandomInt(min, max int64) int64 {
-	return rand.Int63n(max-min) + min
+	return wr.random().Int63n(max-min) + min
 }
 
 // RandomFloat64 will return a random float64 between 0 and 1.
 func (wr *SchemaRenderer) RandomFloat64() float64 {
-	return rand.Float64()
+	return wr.random().Float64()
 }
 
 // PseudoUUID will return a random UUID, it's not a real UUID, but it's good enough for mock /example data.
 func (wr *SchemaRenderer) PseudoUUID() string {
 	b := make([]byte, 16)
-	_, _ = cryptoRand.Read(b)
+	binary.BigEndian.PutUint64(b[:8], wr.random().Uint64())
+	binary.BigEndian.PutUint64(b[8:], wr.random().Uint64())
 	return strings.ToLower(fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
 }
@@ -1350,3 +1350,42 @@ properties:
 	loopMe(root, 0)
 	return root
 }
+
+func TestSchemaRenderer_SetSeed(t *testing.T) {
+	compiled := getSchema([]byte(`type: object
+properties:
+  id:
+    type: string
+    format: uuid
+  firstName:
+    type: string
+  code:
+    type: string
+    pattern: '^[A-Z]{3}-[0-9]{4}$'
+  address:
+    type: string
+    format: ipv4
+  count:
+    type: integer
+    format: int64
+  ratio:
+    type: number
+  created:
+    type: string
+    format: date-time
+required: [id, firstName, code, address, count, ratio, created]`))
+
+	clock := func() time.Time { return time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) }
+	render := func(seed int64) any {
+		wr := CreateRendererUsingBuiltInProviders()
+		wr.SetSeed(seed)
+		wr.SetClock(clock)
+		return wr.RenderSchema(compiled)
+	}
+
+	// the same seed renders the same values, a different seed does not.
+	first := render(42)
+	assert.Equal(t, first, render(42))
+	assert.NotEqual(t, first, render(43))
+	assert.Equal(t, "2023-01-02T03:04:05Z", first.(map[string]any)["created"])
+}
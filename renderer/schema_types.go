@@ -0,0 +1,220 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/index"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// namedSchema is a schema that code or another schema format is generated for, with the name of its type.
+type namedSchema struct {
+	name   string
+	schema *highbase.SchemaProxy
+	source string
+}
+
+// componentSchemas returns the schemas declared in the components of a document, in the order they are declared.
+func componentSchemas(document *v3.Document) []*namedSchema {
+	if document == nil || document.Components == nil {
+		return nil
+	}
+	var schemas []*namedSchema
+	for name, schema := range document.Components.Schemas.FromOldest() {
+		schemas = append(schemas, &namedSchema{name: name, schema: schema, source: "#/components/schemas/" + name})
+	}
+	return schemas
+}
+
+// operationSchemas returns the inline (not referenced) schemas of the request bodies and responses of every
+// operation in a document. They are named after the operation, 'ListPetsRequest' or 'ListPets200Response' for
+// example. Operations without an operationId are named using their method and path.
+func operationSchemas(document *v3.Document, mediaTypes []string) []*namedSchema {
+	if document == nil || document.Paths == nil {
+		return nil
+	}
+	var schemas []*namedSchema
+	for pathName, pathItem := range document.Paths.PathItems.FromOldest() {
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			name := operation.OperationId
+			if name == "" {
+				name = method + " " + pathName
+			}
+			source := fmt.Sprintf("the %s %s operation", strings.ToUpper(method), pathName)
+			if operation.RequestBody != nil {
+				if s := preferredSchema(operation.RequestBody.Content, mediaTypes); s != nil && !s.IsReference() {
+					schemas = append(schemas, &namedSchema{
+						name: name + " request", schema: s, source: "the request body of " + source,
+					})
+				}
+			}
+			if operation.Responses == nil {
+				continue
+			}
+			for code, response := range operation.Responses.Codes.FromOldest() {
+				if s := preferredSchema(response.Content, mediaTypes); s != nil && !s.IsReference() {
+					schemas = append(schemas, &namedSchema{
+						name: name + " " + code + " response", schema: s,
+						source: fmt.Sprintf("the %s response of %s", code, source),
+					})
+				}
+			}
+			if operation.Responses.Default != nil {
+				if s := preferredSchema(operation.Responses.Default.Content, mediaTypes); s != nil && !s.IsReference() {
+					schemas = append(schemas, &namedSchema{
+						name: name + " default response", schema: s, source: "the default response of " + source,
+					})
+				}
+			}
+		}
+	}
+	return schemas
+}
+
+// preferredSchema returns the schema of the first preferred media type, or the first media type with a schema.
+func preferredSchema(content *orderedmap.Map[string, *v3.MediaType], mediaTypes []string) *highbase.SchemaProxy {
+	for _, preferred := range mediaTypes {
+		for name, mt := range content.FromOldest() {
+			if strings.EqualFold(name, preferred) && mt.Schema != nil {
+				return mt.Schema
+			}
+		}
+	}
+	for _, mt := range content.FromOldest() {
+		if mt.Schema != nil {
+			return mt.Schema
+		}
+	}
+	return nil
+}
+
+// circularSchemaNames returns the names of the schemas that are part of a circular reference, found by the resolver
+// of the document index (or any index in its rolodex).
+func circularSchemaNames(document *v3.Document) map[string]bool {
+	names := make(map[string]bool)
+	for _, result := range circularReferences(document) {
+		for _, ref := range result.Journey {
+			if ref != nil {
+				names[referenceName(ref.FullDefinition)] = true
+			}
+		}
+	}
+	return names
+}
+
+// circularReferences returns the circular references found by the index of a document, and the indexes of the
+// rolodex, including the ones that are ignored.
+func circularReferences(document *v3.Document) []*index.CircularReferenceResult {
+	if document == nil || document.Index == nil {
+		return nil
+	}
+	indexes := []*index.SpecIndex{document.Index}
+	var results []*index.CircularReferenceResult
+	if rolodex := document.Index.GetRolodex(); rolodex != nil {
+		indexes = append(indexes, rolodex.GetIndexes()...)
+		results = append(results, rolodex.GetIgnoredCircularReferences()...)
+	}
+	for _, idx := range indexes {
+		results = append(results, idx.GetCircularReferences()...)
+		results = append(results, idx.GetIgnoredPolymorphicCircularReferences()...)
+		results = append(results, idx.GetIgnoredArrayCircularReferences()...)
+	}
+	return results
+}
+
+// referenceName returns the name of the schema a reference points to, which is the last segment of its JSON
+// pointer, or the name of the file if it points to a whole file.
+func referenceName(ref string) string {
+	file, fragment, _ := strings.Cut(ref, "#")
+	if fragment == "" || fragment == "/" {
+		base := path.Base(file)
+		return strings.TrimSuffix(base, path.Ext(base))
+	}
+	segments := strings.Split(fragment, "/")
+	return strings.ReplaceAll(strings.ReplaceAll(segments[len(segments)-1], "~1", "/"), "~0", "~")
+}
+
+// schemaTypes returns the types of a schema, without 'null'.
+func schemaTypes(schema *highbase.Schema) []string {
+	var types []string
+	for _, t := range schema.Type {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// isNullable returns true if a schema allows null values, using 'nullable' (OpenAPI 3.0) or a 'null' type (3.1+).
+func isNullable(schema *highbase.Schema) bool {
+	return schema != nil && ((schema.Nullable != nil && *schema.Nullable) || slices.Contains(schema.Type, "null"))
+}
+
+// identifierWords splits a name into words, on anything that isn't a letter or digit, and on changes of case.
+func identifierWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// pascalCase joins the words of a name, with the first letter of every word in upper case.
+func pascalCase(name string) string {
+	var sb strings.Builder
+	for _, word := range identifierWords(name) {
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	return sb.String()
+}
+
+// uniqueName adds a number to a name until it hasn't been used, then records it as used.
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// commentLines turns text into comment lines, with a prefix such as '// '.
+func commentLines(text, indent, prefix string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		sb.WriteString(strings.TrimRight(indent+prefix+strings.TrimSpace(line), " "))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
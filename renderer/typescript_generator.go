@@ -0,0 +1,656 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// tsPropertyName matches property names that don't need to be quoted in TypeScript.
+var tsPropertyName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsMode decides which properties of a schema are part of a type. Read only properties are not sent in requests, and
+// write only properties are not sent in responses.
+type tsMode int
+
+const (
+	tsFull tsMode = iota
+	tsRequest
+	tsResponse
+)
+
+// TypeScriptGenerator generates TypeScript declarations (a .d.ts file) from the schemas and operations of a document.
+// A type is generated for every schema in the components, and for the parameters, request body and responses of every
+// operation (named after the operationId, or the method and path when there isn't one).
+//
+//   - oneOf and anyOf schemas are unions, allOf schemas are intersections.
+//   - enum and const schemas are unions of literal types.
+//   - prefixItems are tuples.
+//   - oneOf schemas with a discriminator are discriminated unions, each variant is narrowed to the values of the
+//     discriminator property that select it.
+//   - Schemas that hold readOnly or writeOnly properties (directly, or through references) get two more types,
+//     '<Name>Request' without the read only properties and '<Name>Response' without the write only properties.
+//     Request bodies and parameters use the request types, and responses use the response types.
+//
+// Use NewTypeScriptGenerator to create a new TypeScriptGenerator.
+type TypeScriptGenerator struct {
+	document   *v3.Document
+	mediaTypes []string
+}
+
+// NewTypeScriptGenerator creates a new TypeScriptGenerator for the supplied document.
+func NewTypeScriptGenerator(document *v3.Document) *TypeScriptGenerator {
+	return &TypeScriptGenerator{
+		document:   document,
+		mediaTypes: DefaultPreferredMediaTypes,
+	}
+}
+
+// SetPreferredMediaTypes sets the order in which media types are picked, when generating types for parameters,
+// request bodies and responses that support more than one media type.
+func (t *TypeScriptGenerator) SetPreferredMediaTypes(mediaTypes ...string) {
+	t.mediaTypes = mediaTypes
+}
+
+// Generate generates the TypeScript declarations of every type.
+func (t *TypeScriptGenerator) Generate() []byte {
+	gen := &tsGeneration{
+		used:       map[string]bool{"Record": true},
+		types:      make(map[string]*tsType),
+		visibility: make(map[string]bool),
+	}
+	for _, c := range componentSchemas(t.document) {
+		gen.reserve(referenceName(c.source), c.schema)
+	}
+	gen.declarePending()
+	t.operations(gen)
+	gen.declarePending()
+
+	var sb strings.Builder
+	sb.WriteString("// Code generated by libopenapi. DO NOT EDIT.\n")
+	for _, decl := range gen.decls {
+		sb.WriteString("\n")
+		sb.WriteString(decl)
+	}
+	return []byte(sb.String())
+}
+
+// operations declares the parameters, request body and responses of every operation.
+func (t *TypeScriptGenerator) operations(gen *tsGeneration) {
+	if t.document == nil || t.document.Paths == nil {
+		return
+	}
+	for pathName, pathItem := range t.document.Paths.PathItems.FromOldest() {
+		for method, operation := range pathItem.GetOperations().FromOldest() {
+			name := operation.OperationId
+			if name == "" {
+				name = method + " " + pathName
+			}
+			name = tsIdentifier(name)
+			source := fmt.Sprintf("the %s %s operation", strings.ToUpper(method), pathName)
+			var deprecated []string
+			if operation.Deprecated != nil && *operation.Deprecated {
+				deprecated = []string{"@deprecated"}
+			}
+
+			params := slices.Clone(operation.Parameters)
+			for _, p := range pathItem.Parameters {
+				if !slices.ContainsFunc(operation.Parameters, func(op *v3.Parameter) bool {
+					return op.Name == p.Name && op.In == p.In
+				}) {
+					params = append(params, p)
+				}
+			}
+			if len(params) > 0 {
+				typeName := uniqueName(name+"Parameters", gen.used)
+				gen.decls = append(gen.decls, jsDoc(append([]string{"The parameters of " + source + "."},
+					deprecated...), "")+fmt.Sprintf("export interface %s %s\n", typeName, gen.parameters(params, t.mediaTypes)))
+			}
+
+			if operation.RequestBody != nil {
+				if sp := preferredSchema(operation.RequestBody.Content, t.mediaTypes); sp != nil {
+					typeName := uniqueName(name+"Request", gen.used)
+					gen.decls = append(gen.decls, jsDoc(append([]string{"The request body of " + source + "."},
+						deprecated...), "")+fmt.Sprintf("export type %s = %s;\n", typeName, gen.expr(sp, tsRequest, "")))
+				}
+			}
+			if operation.Responses == nil {
+				continue
+			}
+			responses := orderedmap.New[string, *v3.Response]()
+			for code, response := range operation.Responses.Codes.FromOldest() {
+				responses.Set(code, response)
+			}
+			if operation.Responses.Default != nil {
+				responses.Set("default", operation.Responses.Default)
+			}
+			for code, response := range responses.FromOldest() {
+				if sp := preferredSchema(response.Content, t.mediaTypes); sp != nil {
+					typeName := uniqueName(name+pascalCase(code)+"Response", gen.used)
+					gen.decls = append(gen.decls, jsDoc(append([]string{fmt.Sprintf("The %s response of %s.", code,
+						source)}, deprecated...), "")+fmt.Sprintf("export type %s = %s;\n", typeName,
+						gen.expr(sp, tsResponse, "")))
+				}
+			}
+		}
+	}
+}
+
+// tsType holds the names of the types generated for a referenced schema.
+type tsType struct {
+	name     string
+	request  string
+	response string
+	proxy    *highbase.SchemaProxy
+	refs     []string // the schemas referenced by the schema.
+	direct   bool     // true if the schema holds read only or write only properties itself.
+	scanned  bool
+}
+
+type tsGeneration struct {
+	decls      []string
+	used       map[string]bool
+	types      map[string]*tsType // referenced schemas, by name.
+	pending    []string
+	visibility map[string]bool // true if a schema needs request and response types.
+}
+
+// reserve records the type name of a referenced schema, and queues it to be declared.
+func (g *tsGeneration) reserve(schemaName string, sp *highbase.SchemaProxy) *tsType {
+	if t, ok := g.types[schemaName]; ok {
+		return t
+	}
+	t := &tsType{name: uniqueName(tsIdentifier(schemaName), g.used), proxy: sp}
+	g.types[schemaName] = t
+	g.pending = append(g.pending, schemaName)
+	return t
+}
+
+// declarePending declares the referenced schemas that have not been declared yet, including the schemas they
+// reference, such as schemas in other files.
+func (g *tsGeneration) declarePending() {
+	for len(g.pending) > 0 {
+		next := g.pending[0]
+		g.pending = g.pending[1:]
+		g.declare(next)
+	}
+}
+
+// declare generates the types of a referenced schema.
+func (g *tsGeneration) declare(schemaName string) {
+	t := g.types[schemaName]
+	schema := t.proxy.Schema()
+	g.decls = append(g.decls, g.declaration(t.name, schema, tsFull, nil))
+	if !g.needsVariants(schemaName) {
+		return
+	}
+	request, response := g.variants(t)
+	g.decls = append(g.decls, g.declaration(request, schema, tsRequest,
+		[]string{fmt.Sprintf("%s as it is sent in requests, without its read only properties.", t.name)}))
+	g.decls = append(g.decls, g.declaration(response, schema, tsResponse,
+		[]string{fmt.Sprintf("%s as it is sent in responses, without its write only properties.", t.name)}))
+}
+
+// declaration returns an interface for a plain object schema, otherwise a type alias.
+func (g *tsGeneration) declaration(name string, schema *highbase.Schema, mode tsMode, doc []string) string {
+	if doc == nil {
+		doc = schemaDoc(schema)
+	}
+	if isPlainObject(schema) {
+		return jsDoc(doc, "") + fmt.Sprintf("export interface %s %s\n", name, g.objectExpr(schema, mode, ""))
+	}
+	return jsDoc(doc, "") + fmt.Sprintf("export type %s = %s;\n", name, g.schemaExpr(schema, mode, ""))
+}
+
+// variants returns the names of the request and response types of a referenced schema.
+func (g *tsGeneration) variants(t *tsType) (string, string) {
+	if t.request == "" {
+		t.request = uniqueName(t.name+"Request", g.used)
+		t.response = uniqueName(t.name+"Response", g.used)
+	}
+	return t.request, t.response
+}
+
+// needsVariants returns true if a referenced schema holds read only or write only properties, or references a schema
+// that does.
+func (g *tsGeneration) needsVariants(schemaName string) bool {
+	if v, ok := g.visibility[schemaName]; ok {
+		return v
+	}
+	v := g.reaches(schemaName, make(map[string]bool))
+	g.visibility[schemaName] = v
+	return v
+}
+
+func (g *tsGeneration) reaches(schemaName string, seen map[string]bool) bool {
+	if seen[schemaName] {
+		return false
+	}
+	seen[schemaName] = true
+	t := g.types[schemaName]
+	if !t.scanned {
+		t.scanned = true
+		g.scan(t.proxy.Schema(), t)
+	}
+	if t.direct {
+		return true
+	}
+	for _, ref := range t.refs {
+		if g.reaches(ref, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan looks through a schema (without following references) for read only and write only properties, and records
+// the schemas it references.
+func (g *tsGeneration) scan(schema *highbase.Schema, t *tsType) {
+	if schema == nil {
+		return
+	}
+	var proxies []*highbase.SchemaProxy
+	for _, prop := range schema.Properties.FromOldest() {
+		if ps := prop.Schema(); ps != nil &&
+			((ps.ReadOnly != nil && *ps.ReadOnly) || (ps.WriteOnly != nil && *ps.WriteOnly)) {
+			t.direct = true
+		}
+		proxies = append(proxies, prop)
+	}
+	proxies = append(proxies, schema.AllOf...)
+	proxies = append(proxies, schema.OneOf...)
+	proxies = append(proxies, schema.AnyOf...)
+	proxies = append(proxies, schema.PrefixItems...)
+	if schema.Items != nil && schema.Items.IsA() {
+		proxies = append(proxies, schema.Items.A)
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		proxies = append(proxies, schema.AdditionalProperties.A)
+	}
+	for _, sp := range proxies {
+		if sp == nil {
+			continue
+		}
+		if sp.IsReference() {
+			refName := referenceName(sp.GetReference())
+			g.reserve(refName, sp)
+			t.refs = append(t.refs, refName)
+			continue
+		}
+		g.scan(sp.Schema(), t)
+	}
+}
+
+// expr returns the TypeScript type of a schema, the name of a type if the schema is a reference.
+func (g *tsGeneration) expr(sp *highbase.SchemaProxy, mode tsMode, indent string) string {
+	if sp == nil {
+		return "unknown"
+	}
+	if sp.IsReference() {
+		refName := referenceName(sp.GetReference())
+		t := g.reserve(refName, sp)
+		if mode == tsFull || !g.needsVariants(refName) {
+			return t.name
+		}
+		request, response := g.variants(t)
+		if mode == tsRequest {
+			return request
+		}
+		return response
+	}
+	return g.schemaExpr(sp.Schema(), mode, indent)
+}
+
+// schemaExpr returns the TypeScript type of an inline schema.
+func (g *tsGeneration) schemaExpr(schema *highbase.Schema, mode tsMode, indent string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	var members []string
+	switch {
+	case schema.Const != nil:
+		members = []string{tsLiteral(schema.Const)}
+	case len(schema.Enum) > 0:
+		for _, value := range schema.Enum {
+			if literal := tsLiteral(value); !slices.Contains(members, literal) {
+				members = append(members, literal)
+			}
+		}
+	default:
+		var parts []string
+		for _, member := range schema.AllOf {
+			parts = append(parts, g.expr(member, mode, indent))
+		}
+		if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+			parts = append(parts, g.union(schema, mode, indent))
+		}
+		switch {
+		case len(parts) == 0:
+			parts = append(parts, g.typesExpr(schema, mode, indent))
+		case orderedmap.Len(schema.Properties) > 0:
+			parts = append(parts, g.objectExpr(schema, mode, indent))
+		}
+		for i := 0; len(parts) > 1 && i < len(parts); i++ {
+			parts[i] = tsGroup(parts[i], '|')
+		}
+		members = []string{strings.Join(parts, " & ")}
+	}
+	if isNullable(schema) && !slices.Contains(members, "null") {
+		members = append(members, "null")
+	}
+	return strings.Join(members, " | ")
+}
+
+// union returns the union of the oneOf (or anyOf) schemas, narrowing each variant to the values of the discriminator
+// property that select it.
+func (g *tsGeneration) union(schema *highbase.Schema, mode tsMode, indent string) string {
+	proxies := schema.OneOf
+	if len(proxies) == 0 {
+		proxies = schema.AnyOf
+	}
+	var property string
+	if schema.Discriminator != nil {
+		property = schema.Discriminator.PropertyName
+	}
+	var members []string
+	for _, sp := range proxies {
+		member := g.expr(sp, mode, indent)
+		if property != "" && sp != nil && sp.IsReference() {
+			refName := referenceName(sp.GetReference())
+			values := []string{tsString(refName)}
+			for value, target := range schema.Discriminator.Mapping.FromOldest() {
+				if (referenceName(target) == refName || target == refName) && !slices.Contains(values, tsString(value)) {
+					values = append(values, tsString(value))
+				}
+			}
+			member = fmt.Sprintf("%s & { %s: %s }", tsGroup(member, '|'), tsKey(property), strings.Join(values, " | "))
+		}
+		if !slices.Contains(members, member) {
+			members = append(members, member)
+		}
+	}
+	return strings.Join(members, " | ")
+}
+
+// typesExpr returns the TypeScript type for the 'type' of a schema.
+func (g *tsGeneration) typesExpr(schema *highbase.Schema, mode tsMode, indent string) string {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		switch {
+		case orderedmap.Len(schema.Properties) > 0 || schema.AdditionalProperties != nil:
+			types = []string{"object"}
+		case schema.Items != nil || len(schema.PrefixItems) > 0:
+			types = []string{"array"}
+		default:
+			return "unknown"
+		}
+	}
+	var members []string
+	for _, t := range types {
+		switch t {
+		case "string":
+			members = append(members, "string")
+		case "integer", "number":
+			if !slices.Contains(members, "number") {
+				members = append(members, "number")
+			}
+		case "boolean":
+			members = append(members, "boolean")
+		case "array":
+			members = append(members, g.arrayExpr(schema, mode, indent))
+		case "object":
+			members = append(members, g.objectExpr(schema, mode, indent))
+		default:
+			members = append(members, "unknown")
+		}
+	}
+	return strings.Join(members, " | ")
+}
+
+// arrayExpr returns an array type, or a tuple when the schema has prefixItems. Items after minItems are optional.
+func (g *tsGeneration) arrayExpr(schema *highbase.Schema, mode tsMode, indent string) string {
+	if len(schema.PrefixItems) == 0 {
+		if schema.Items != nil && schema.Items.IsA() {
+			return tsGroup(g.expr(schema.Items.A, mode, indent), '|', '&') + "[]"
+		}
+		return "unknown[]"
+	}
+	var minItems int64
+	if schema.MinItems != nil {
+		minItems = *schema.MinItems
+	}
+	var elements []string
+	for i, sp := range schema.PrefixItems {
+		element := g.expr(sp, mode, indent)
+		if int64(i) >= minItems {
+			element = tsGroup(element, '|', '&') + "?"
+		}
+		elements = append(elements, element)
+	}
+	switch {
+	case schema.Items == nil:
+		elements = append(elements, "...unknown[]")
+	case schema.Items.IsA():
+		elements = append(elements, "..."+tsGroup(g.expr(schema.Items.A, mode, indent), '|', '&')+"[]")
+	case schema.Items.B:
+		elements = append(elements, "...unknown[]")
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// objectExpr returns an object type for the properties of a schema, or a record when it only has
+// additionalProperties.
+func (g *tsGeneration) objectExpr(schema *highbase.Schema, mode tsMode, indent string) string {
+	record := "Record<string, unknown>"
+	if schema.AdditionalProperties != nil {
+		switch {
+		case schema.AdditionalProperties.IsA():
+			record = fmt.Sprintf("Record<string, %s>", g.expr(schema.AdditionalProperties.A, mode, indent))
+		case !schema.AdditionalProperties.B:
+			record = "Record<string, never>"
+		}
+	}
+	if orderedmap.Len(schema.Properties) == 0 {
+		return record
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	inner := indent + "  "
+	for name, prop := range schema.Properties.FromOldest() {
+		ps := prop.Schema()
+		readOnly := ps != nil && ps.ReadOnly != nil && *ps.ReadOnly
+		writeOnly := ps != nil && ps.WriteOnly != nil && *ps.WriteOnly
+		if (mode == tsRequest && readOnly) || (mode == tsResponse && writeOnly) {
+			continue
+		}
+		if !prop.IsReference() {
+			sb.WriteString(jsDoc(schemaDoc(ps), inner))
+		}
+		sb.WriteString(inner)
+		if mode == tsFull && readOnly {
+			sb.WriteString("readonly ")
+		}
+		sb.WriteString(tsKey(name))
+		if !slices.Contains(schema.Required, name) {
+			sb.WriteString("?")
+		}
+		sb.WriteString(": " + g.expr(prop, mode, inner) + ";\n")
+	}
+	sb.WriteString(indent + "}")
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA() {
+		return sb.String() + " & " + record
+	}
+	return sb.String()
+}
+
+// parameters returns an object type for the parameters of an operation, grouped by their location.
+func (g *tsGeneration) parameters(params []*v3.Parameter, mediaTypes []string) string {
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, in := range []string{"path", "query", "header", "cookie"} {
+		var fields strings.Builder
+		required := false
+		for _, p := range params {
+			if p == nil || p.In != in {
+				continue
+			}
+			sp := p.Schema
+			if sp == nil {
+				sp = preferredSchema(p.Content, mediaTypes)
+			}
+			optional := in != "path" && (p.Required == nil || !*p.Required)
+			required = required || !optional
+			var doc []string
+			if p.Description != "" {
+				doc = append(doc, p.Description)
+			}
+			if p.Deprecated {
+				doc = append(doc, "@deprecated")
+			}
+			fields.WriteString(jsDoc(doc, "    "))
+			fields.WriteString("    " + tsKey(p.Name))
+			if optional {
+				fields.WriteString("?")
+			}
+			fields.WriteString(": " + g.expr(sp, tsRequest, "    ") + ";\n")
+		}
+		if fields.Len() == 0 {
+			continue
+		}
+		sb.WriteString("  " + in)
+		if !required {
+			sb.WriteString("?")
+		}
+		sb.WriteString(": {\n" + fields.String() + "  };\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// isPlainObject returns true if a schema is an object with properties, and nothing else, so it can be an interface.
+func isPlainObject(schema *highbase.Schema) bool {
+	if schema == nil || orderedmap.Len(schema.Properties) == 0 || isNullable(schema) || schema.Const != nil ||
+		len(schema.Enum) > 0 || len(schema.AllOf) > 0 || len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 ||
+		(schema.AdditionalProperties != nil && schema.AdditionalProperties.IsA()) {
+		return false
+	}
+	types := schemaTypes(schema)
+	return len(types) == 0 || (len(types) == 1 && types[0] == "object")
+}
+
+// schemaDoc returns the lines of the doc comment of a schema.
+func schemaDoc(schema *highbase.Schema) []string {
+	if schema == nil {
+		return nil
+	}
+	var doc []string
+	if schema.Description != "" {
+		doc = append(doc, schema.Description)
+	}
+	if schema.Deprecated != nil && *schema.Deprecated {
+		doc = append(doc, "@deprecated")
+	}
+	return doc
+}
+
+// jsDoc returns a JSDoc comment, on a single line when it is short enough.
+func jsDoc(lines []string, indent string) string {
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return ""
+	}
+	text = strings.ReplaceAll(text, "*/", "*\\/")
+	if !strings.Contains(text, "\n") && len(indent)+len(text) < 110 {
+		return indent + "/** " + text + " */\n"
+	}
+	return indent + "/**\n" + commentLines(text, indent, " * ") + indent + " */\n"
+}
+
+// tsIdentifier turns a name into a TypeScript type name.
+func tsIdentifier(name string) string {
+	id := pascalCase(name)
+	if id == "" {
+		return "Schema"
+	}
+	if unicode.IsDigit([]rune(id)[0]) {
+		id = "N" + id
+	}
+	return id
+}
+
+// tsKey returns a property name, quoted if it isn't an identifier.
+func tsKey(name string) string {
+	if tsPropertyName.MatchString(name) {
+		return name
+	}
+	return tsString(name)
+}
+
+// tsString returns a string literal.
+func tsString(value string) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// tsLiteral returns the literal type of an enum or const value.
+func tsLiteral(node *yaml.Node) string {
+	if node == nil {
+		return "null"
+	}
+	if node.Kind == yaml.ScalarNode {
+		switch node.Tag {
+		case "!!null":
+			return "null"
+		case "!!bool", "!!int", "!!float":
+			return node.Value
+		}
+		return tsString(node.Value)
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return "unknown"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+	return string(b)
+}
+
+// tsGroup wraps a type in parentheses if any of the operators appear in it, outside of brackets and strings.
+func tsGroup(expr string, operators ...byte) string {
+	depth := 0
+	inString := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '(' || c == '{' || c == '[' || c == '<':
+			depth++
+		case c == ')' || c == '}' || c == ']' || c == '>':
+			depth--
+		case depth == 0 && slices.Contains(operators, c):
+			return "(" + expr + ")"
+		}
+	}
+	return expr
+}
@@ -0,0 +1,268 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+var typeScriptSpec = `openapi: 3.1.0
+info:
+  title: pets
+  version: 1.0.0
+paths:
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    put:
+      operationId: updatePet
+      deprecated: true
+      parameters:
+        - name: dry-run
+          in: query
+          description: only validate
+          schema:
+            type: boolean
+        - name: X-Trace
+          in: header
+          required: true
+          schema:
+            type: string
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        default:
+          description: error
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]
+                properties:
+                  message:
+                    type: string
+components:
+  schemas:
+    Pet:
+      oneOf:
+        - $ref: '#/components/schemas/Cat'
+        - $ref: '#/components/schemas/Dog'
+      discriminator:
+        propertyName: petType
+        mapping:
+          cat: '#/components/schemas/Cat'
+    Base:
+      type: object
+      description: The base of every pet.
+      required: [petType, id]
+      properties:
+        id:
+          type: string
+          readOnly: true
+        petType:
+          type: string
+        password:
+          type: string
+          writeOnly: true
+        status:
+          type: [string, "null"]
+          enum: [available, sold, null]
+        location:
+          type: array
+          prefixItems:
+            - type: number
+            - type: number
+          minItems: 2
+          items: false
+        tags:
+          type: array
+          items:
+            oneOf:
+              - type: string
+              - type: integer
+        meta:
+          type: object
+          additionalProperties:
+            type: string
+        kind-of:
+          const: pet
+        owner:
+          type: object
+          deprecated: true
+          properties:
+            name:
+              type: string
+    Cat:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          properties:
+            lives:
+              type: integer
+    Dog:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+      properties:
+        bark:
+          type: boolean
+    Size:
+      type: integer
+      enum: [1, 2, 3]
+      nullable: true`
+
+func TestTypeScriptGenerator_Generate(t *testing.T) {
+	out := string(NewTypeScriptGenerator(buildModel(t, typeScriptSpec)).Generate())
+
+	assert.Contains(t, out, "// Code generated by libopenapi. DO NOT EDIT.\n\nexport type Pet =")
+
+	// a discriminated union, narrowed by the mapping and the name of each schema.
+	assert.Contains(t, out, `export type Pet = Cat & { petType: "Cat" | "cat" } | Dog & { petType: "Dog" };`)
+
+	// plain objects are interfaces, read only properties are readonly.
+	assert.Contains(t, out, "/** The base of every pet. */\nexport interface Base {\n  readonly id: string;\n  petType: string;\n")
+	assert.Contains(t, out, `  status?: "available" | "sold" | null;`)
+	assert.Contains(t, out, "  location?: [number, number];")
+	assert.Contains(t, out, "  tags?: (string | number)[];")
+	assert.Contains(t, out, "  meta?: Record<string, string>;")
+	assert.Contains(t, out, `  "kind-of"?: "pet";`)
+	assert.Contains(t, out, "  /** @deprecated */\n  owner?: {\n    name?: string;\n  };")
+	assert.Contains(t, out, "export type Size = 1 | 2 | 3 | null;")
+
+	// allOf is an intersection.
+	assert.Contains(t, out, "export type Cat = Base & {\n  lives?: number;\n};")
+	assert.Contains(t, out, "export type Dog = Base & {\n  bark?: boolean;\n};")
+
+	// request types leave out read only properties, response types leave out write only properties, and both are used
+	// by every schema that references them.
+	assert.Contains(t, out, "export interface BaseRequest {\n  petType: string;\n  password?: string;\n")
+	assert.Contains(t, out, "export interface BaseResponse {\n  id: string;\n  petType: string;\n  status?:")
+	assert.Contains(t, out, "export type CatRequest = BaseRequest & {")
+	assert.Contains(t, out, `export type PetResponse = CatResponse & { petType: "Cat" | "cat" } | DogResponse & { petType: "Dog" };`)
+	assert.NotContains(t, out, "SizeRequest")
+
+	// operation types, named after the operationId.
+	assert.Contains(t, out, `/**
+ * The parameters of the PUT /pets/{id} operation.
+ * @deprecated
+ */
+export interface UpdatePetParameters {
+  path: {
+    id: string;
+  };
+  query?: {
+    /** only validate */
+    "dry-run"?: boolean;
+  };
+  header: {
+    "X-Trace": string;
+  };
+}`)
+	assert.Contains(t, out, "export type UpdatePetRequest = PetRequest;")
+	assert.Contains(t, out, "export type UpdatePet200Response = PetResponse;")
+	assert.Contains(t, out, "export type UpdatePetDefaultResponse = {\n  message: string;\n};")
+}
+
+func TestTypeScriptGenerator_Circular(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: circular
+  version: 1.0.0
+paths:
+  /trees:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: array
+              items:
+                $ref: '#/components/schemas/Node'
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    Node:
+      type: object
+      properties:
+        children:
+          type: array
+          items:
+            $ref: '#/components/schemas/Leaf'
+    Leaf:
+      type: object
+      properties:
+        parent:
+          $ref: '#/components/schemas/Node'
+        created:
+          type: string
+          readOnly: true
+    Point:
+      type: array
+      prefixItems:
+        - type: number
+        - $ref: '#/components/schemas/Leaf'
+      minItems: 1
+    Record:
+      type: object
+      properties:
+        entries:
+          type: object
+          additionalProperties: false
+        any:
+          type: [string, object]
+      additionalProperties:
+        type: integer`
+	out := string(NewTypeScriptGenerator(buildModel(t, spec)).Generate())
+
+	// the read only property is found through the circular references.
+	assert.Contains(t, out, "export interface NodeRequest {\n  children?: LeafRequest[];\n}")
+	assert.Contains(t, out, "export interface LeafRequest {\n  parent?: NodeRequest;\n}")
+	assert.Contains(t, out, "export type Point = [number, Leaf?, ...unknown[]];")
+
+	// operations without an operationId are named after the method and path, the name of the Record schema doesn't
+	// hide the Record type.
+	assert.Contains(t, out, "export type PostTreesRequest = NodeRequest[];")
+	assert.NotContains(t, out, "PostTrees201Response")
+	assert.Contains(t, out, "export type Record2 = {\n  entries?: Record<string, never>;\n"+
+		"  any?: string | Record<string, unknown>;\n} & Record<string, number>;")
+}
+
+func TestTypeScriptGenerator_Empty(t *testing.T) {
+	assert.Equal(t, "// Code generated by libopenapi. DO NOT EDIT.\n", string(NewTypeScriptGenerator(nil).Generate()))
+}
+
+func TestTsLiteral(t *testing.T) {
+	var node yaml.Node
+	_ = yaml.Unmarshal([]byte(`[one, 2, 2.5, true, null, "say \"hi\"", {a: 1}, 2020-01-01]`), &node)
+	var literals []string
+	for _, n := range node.Content[0].Content {
+		literals = append(literals, tsLiteral(n))
+	}
+	assert.Equal(t, []string{`"one"`, "2", "2.5", "true", "null", `"say \"hi\""`, `{"a":1}`, `"2020-01-01"`}, literals)
+}
+
+func TestTsGroup(t *testing.T) {
+	assert.Equal(t, "(A | B)", tsGroup("A | B", '|'))
+	assert.Equal(t, "A & B", tsGroup("A & B", '|'))
+	assert.Equal(t, "{\n  a: A | B;\n}", tsGroup("{\n  a: A | B;\n}", '|', '&'))
+	assert.Equal(t, `"a | b"`, tsGroup(`"a | b"`, '|'))
+	assert.Equal(t, "Record<string, A | B>", tsGroup("Record<string, A | B>", '|'))
+}
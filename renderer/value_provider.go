@@ -0,0 +1,186 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"math/rand"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// FakerExtension is the schema extension used to select a value provider by name, for example `x-faker: address.city`
+const FakerExtension = "x-faker"
+
+// ValueProvider generates a value for a schema that is being rendered. The propertyName is the name of the property
+// (or key) the value is being rendered for. If the provider cannot supply a value for the schema, it should return
+// false, and the renderer will fall through to the next provider, or to the default rendering behavior.
+type ValueProvider func(schema *base.Schema, propertyName string) (any, bool)
+
+type propertyProvider struct {
+	pattern  *regexp.Regexp
+	provider ValueProvider
+}
+
+// ValueProviderRegistry holds value providers that a SchemaRenderer will consult before generating random values.
+// Providers can be keyed by schema `format`, by a pattern matching the name of a property, or by the value of an
+// `x-` extension on the schema.
+//
+// Providers are consulted in the following order, the first provider to return a value wins:
+//   - extension providers (most specific, the author explicitly asked for it)
+//   - property name providers (in the order they were registered)
+//   - format providers
+//
+// Use NewValueProviderRegistry to create an empty registry, or NewBuiltInValueProviderRegistry to create a registry
+// pre-loaded with providers for names, addresses, phone numbers, currencies, country codes, URLs, durations and
+// int64 / decimal ranges.
+type ValueProviderRegistry struct {
+	formats    map[string]ValueProvider
+	extensions map[string]map[string]ValueProvider
+	properties []*propertyProvider
+	rand       atomic.Pointer[rand.Rand]
+	lock       sync.RWMutex
+}
+
+// NewValueProviderRegistry creates a new, empty ValueProviderRegistry.
+func NewValueProviderRegistry() *ValueProviderRegistry {
+	return &ValueProviderRegistry{
+		formats:    make(map[string]ValueProvider),
+		extensions: make(map[string]map[string]ValueProvider),
+	}
+}
+
+// SetSeed makes the built-in providers of the registry generate the same values, in the same order, every time the
+// registry is seeded with the same value. Providers registered by hand are not affected.
+func (r *ValueProviderRegistry) SetSeed(seed int64) {
+	r.rand.Store(newRandom(seed))
+}
+
+// random returns the random number generator used by the built-in providers of the registry.
+func (r *ValueProviderRegistry) random() *rand.Rand {
+	if random := r.rand.Load(); random != nil {
+		return random
+	}
+	return sharedRandom
+}
+
+// RegisterFormatProvider registers a provider that will be used for any schema with the supplied `format`.
+// Registering a provider for a format that already has one will replace it.
+func (r *ValueProviderRegistry) RegisterFormatProvider(format string, provider ValueProvider) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.formats[format] = provider
+}
+
+// RegisterPropertyProvider registers a provider that will be used for any property with a name matching the supplied
+// regular expression. An error is returned if the pattern cannot be compiled.
+func (r *ValueProviderRegistry) RegisterPropertyProvider(pattern string, provider ValueProvider) error {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.properties = append(r.properties, &propertyProvider{pattern: compiled, provider: provider})
+	return nil
+}
+
+// RegisterExtensionProvider registers a provider that will be used for any schema that has the supplied extension
+// set to the supplied value, for example `x-faker` and `address.city`. If the value is empty, the provider is used
+// for any value of the extension, and is responsible for reading the extension itself.
+func (r *ValueProviderRegistry) RegisterExtensionProvider(extension, value string, provider ValueProvider) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.extensions[extension] == nil {
+		r.extensions[extension] = make(map[string]ValueProvider)
+	}
+	r.extensions[extension][value] = provider
+}
+
+// RegisterFakerProvider is a convenience method that registers an extension provider for the `x-faker` extension.
+func (r *ValueProviderRegistry) RegisterFakerProvider(name string, provider ValueProvider) {
+	r.RegisterExtensionProvider(FakerExtension, name, provider)
+}
+
+// GetFakerProviderNames returns the names of all the providers registered against the `x-faker` extension, sorted.
+func (r *ValueProviderRegistry) GetFakerProviderNames() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var names []string
+	for k := range r.extensions[FakerExtension] {
+		if k != "" {
+			names = append(names, k)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Provide will look up a provider for the supplied schema and property name, and use it to generate a value.
+// If no provider can supply a value, false is returned.
+func (r *ValueProviderRegistry) Provide(schema *base.Schema, propertyName string) (any, bool) {
+	if schema == nil {
+		return nil, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	// extensions first.
+	if schema.Extensions != nil && len(r.extensions) > 0 {
+		for extKey, extNode := range schema.Extensions.FromOldest() {
+			providers := r.extensions[extKey]
+			if providers == nil {
+				continue
+			}
+			if extNode != nil {
+				if p, ok := providers[extNode.Value]; ok && extNode.Value != "" {
+					if v, ok := p(schema, propertyName); ok {
+						return v, true
+					}
+				}
+			}
+			if p, ok := providers[""]; ok {
+				if v, ok := p(schema, propertyName); ok {
+					return v, true
+				}
+			}
+		}
+	}
+
+	// property names only apply to scalar values, an 'address' object should not be replaced with a street name.
+	if propertyName != "" && isScalarSchema(schema) {
+		for _, pp := range r.properties {
+			if pp.pattern.MatchString(propertyName) {
+				if v, ok := pp.provider(schema, propertyName); ok {
+					return v, true
+				}
+			}
+		}
+	}
+
+	if schema.Format != "" {
+		if p, ok := r.formats[schema.Format]; ok {
+			if v, ok := p(schema, propertyName); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func isScalarSchema(schema *base.Schema) bool {
+	if len(schema.Type) == 0 {
+		return schema.Properties == nil && schema.Items == nil
+	}
+	for _, t := range schema.Type {
+		switch t {
+		case objectType, arrayType:
+			return false
+		}
+	}
+	return true
+}
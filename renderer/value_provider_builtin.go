@@ -0,0 +1,296 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// builtInDictionary is a small embedded dictionary, used when no system dictionary is available.
+//
+//go:embed dictionary/words.txt
+var builtInDictionary string
+
+const (
+	int64Type    = "int64"
+	durationType = "duration"
+	urlType      = "url"
+	phoneType    = "phone"
+	currencyType = "currency"
+	countryType  = "country-code"
+)
+
+var (
+	firstNames   = []string{"Alice", "Amara", "Ben", "Carlos", "Chen", "Dave", "Elena", "Farah", "George", "Hana", "Ivan", "Jade", "Kofi", "Lena", "Mateo", "Nina", "Omar", "Priya", "Quinn", "Rosa", "Sam", "Tariq", "Uma", "Victor", "Wen", "Yusuf", "Zoe"}
+	lastNames    = []string{"Anderson", "Brown", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hughes", "Ito", "Johnson", "Kim", "Lopez", "Martin", "Nakamura", "Okafor", "Patel", "Rossi", "Schmidt", "Singh", "Taylor", "Walker", "Wong"}
+	streetNames  = []string{"Acacia Avenue", "Baker Street", "Cedar Lane", "Elm Street", "High Street", "Lake Road", "Maple Drive", "Market Street", "Mill Lane", "Oak Avenue", "Park Road", "River Road", "Station Road", "Sunset Boulevard"}
+	cities       = []string{"Amsterdam", "Auckland", "Austin", "Berlin", "Boston", "Cape Town", "Chicago", "Dublin", "Lisbon", "London", "Madrid", "Melbourne", "Montreal", "Nairobi", "Oslo", "Paris", "Seoul", "Singapore", "Tokyo", "Toronto", "Vienna"}
+	states       = []string{"California", "Colorado", "Florida", "Georgia", "Illinois", "Massachusetts", "New York", "Ohio", "Oregon", "Texas", "Virginia", "Washington"}
+	countries    = []string{"Australia", "Brazil", "Canada", "France", "Germany", "India", "Ireland", "Japan", "Kenya", "Mexico", "Netherlands", "New Zealand", "Norway", "Portugal", "Singapore", "South Africa", "Spain", "United Kingdom", "United States"}
+	countryCodes = []string{"AU", "BR", "CA", "FR", "DE", "IN", "IE", "JP", "KE", "MX", "NL", "NZ", "NO", "PT", "SG", "ZA", "ES", "GB", "US"}
+	currencies   = []string{"AUD", "BRL", "CAD", "CHF", "CNY", "EUR", "GBP", "INR", "JPY", "KES", "MXN", "NOK", "NZD", "SGD", "USD", "ZAR"}
+	topLevels    = []string{"com", "org", "net", "io", "dev"}
+)
+
+// BuiltInDictionary returns the words contained in the dictionary embedded in the renderer. It can be used when there
+// is no system dictionary available (for example on Windows, or in a container).
+func BuiltInDictionary() []string {
+	return strings.Split(builtInDictionary, "\n")
+}
+
+// NewBuiltInValueProviderRegistry creates a new ValueProviderRegistry pre-loaded with the built-in providers.
+// All the built-in providers work offline and do not depend on a dictionary.
+//
+// The following `x-faker` values are registered:
+//   - name.firstName, name.lastName, name.fullName
+//   - address.street, address.city, address.state, address.zipCode, address.country, address.countryCode
+//   - phone.number
+//   - finance.currency, finance.amount
+//   - internet.url, internet.email, internet.domain
+//   - date.duration
+//   - number.int64, number.decimal
+//
+// The following formats are registered: int64, decimal, duration, url, phone, currency, country-code.
+//
+// Common property names (firstName, last_name, city, postcode, phoneNumber, currency, countryCode, website, etc.)
+// are also registered, these only apply to scalar properties.
+func NewBuiltInValueProviderRegistry() *ValueProviderRegistry {
+	r := NewValueProviderRegistry()
+
+	firstName := stringProvider(r, func(random *rand.Rand) string { return pick(random, firstNames) })
+	lastName := stringProvider(r, func(random *rand.Rand) string { return pick(random, lastNames) })
+	fullName := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("%s %s", pick(random, firstNames), pick(random, lastNames))
+	})
+	street := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("%d %s", random.Intn(998)+1, pick(random, streetNames))
+	})
+	city := stringProvider(r, func(random *rand.Rand) string { return pick(random, cities) })
+	state := stringProvider(r, func(random *rand.Rand) string { return pick(random, states) })
+	zipCode := stringProvider(r, func(random *rand.Rand) string { return fmt.Sprintf("%05d", random.Intn(99999)) })
+	country := stringProvider(r, func(random *rand.Rand) string { return pick(random, countries) })
+	countryCode := stringProvider(r, func(random *rand.Rand) string { return pick(random, countryCodes) })
+	phone := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("+1-%03d-%03d-%04d", random.Intn(800)+200, random.Intn(900)+100, random.Intn(10000))
+	})
+	currency := stringProvider(r, func(random *rand.Rand) string { return pick(random, currencies) })
+	domain := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("%s.%s", strings.ToLower(pick(random, lastNames)), pick(random, topLevels))
+	})
+	email := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("%s.%s@%s.%s", strings.ToLower(pick(random, firstNames)), strings.ToLower(pick(random, lastNames)),
+			strings.ToLower(pick(random, lastNames)), pick(random, topLevels))
+	})
+	link := stringProvider(r, func(random *rand.Rand) string {
+		return fmt.Sprintf("https://www.%s.%s/%s", strings.ToLower(pick(random, lastNames)), pick(random, topLevels),
+			strings.ToLower(pick(random, cities)))
+	})
+	duration := stringProvider(r, randomDuration)
+	int64Range := func(schema *base.Schema, _ string) (any, bool) { return randomInt64(r.random(), schema) }
+	decimalRange := func(schema *base.Schema, _ string) (any, bool) { return randomDecimal(r.random(), schema) }
+
+	fakers := map[string]ValueProvider{
+		"name.firstName":      firstName,
+		"name.lastName":       lastName,
+		"name.fullName":       fullName,
+		"address.street":      street,
+		"address.city":        city,
+		"address.state":       state,
+		"address.zipCode":     zipCode,
+		"address.country":     country,
+		"address.countryCode": countryCode,
+		"phone.number":        phone,
+		"finance.currency":    currency,
+		"finance.amount":      decimalRange,
+		"internet.url":        link,
+		"internet.email":      email,
+		"internet.domain":     domain,
+		"date.duration":       duration,
+		"number.int64":        int64Range,
+		"number.decimal":      decimalRange,
+	}
+	for k, v := range fakers {
+		r.RegisterFakerProvider(k, v)
+	}
+
+	r.RegisterFormatProvider(int64Type, int64Range)
+	r.RegisterFormatProvider(decimalType, decimalRange)
+	r.RegisterFormatProvider(durationType, duration)
+	r.RegisterFormatProvider(urlType, link)
+	r.RegisterFormatProvider(phoneType, phone)
+	r.RegisterFormatProvider(currencyType, currency)
+	r.RegisterFormatProvider(countryType, countryCode)
+
+	// the patterns are all static, so they will always compile.
+	properties := []struct {
+		pattern  string
+		provider ValueProvider
+	}{
+		{`(?i)^(first|given)[_-]?name$`, firstName},
+		{`(?i)^((last|family)[_-]?name|surname)$`, lastName},
+		{`(?i)^(full|display)[_-]?name$`, fullName},
+		{`(?i)^(street([_-]?address)?|address[_-]?line[_-]?1?)$`, street},
+		{`(?i)^(city|town)$`, city},
+		{`(?i)^(state|province)$`, state},
+		{`(?i)^(zip|zip[_-]?code|post[_-]?code|postal[_-]?code)$`, zipCode},
+		{`(?i)^country[_-]?code$`, countryCode},
+		{`(?i)^country$`, country},
+		{`(?i)(phone|mobile|fax)([_-]?number)?$`, phone},
+		{`(?i)^currency([_-]?code)?$`, currency},
+		{`(?i)(url|website|homepage)$`, link},
+		{`(?i)^e?-?mail([_-]?address)?$`, email},
+		{`(?i)^duration$`, duration},
+	}
+	for _, p := range properties {
+		_ = r.RegisterPropertyProvider(p.pattern, p.provider)
+	}
+	return r
+}
+
+// Int64RangeProvider generates a random integer that honors the minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum and multipleOf values of the schema. If the schema is a string, the integer is rendered as one.
+func Int64RangeProvider(schema *base.Schema, _ string) (any, bool) {
+	return randomInt64(sharedRandom, schema)
+}
+
+// randomInt64 generates an integer for Int64RangeProvider, using the supplied random number generator.
+func randomInt64(random *rand.Rand, schema *base.Schema) (any, bool) {
+	lower, upper := numericBounds(schema, 1, 10000)
+	if upper < lower {
+		return nil, false
+	}
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	if lower > math.MinInt64 {
+		lo = int64(math.Ceil(lower))
+	}
+	if upper < math.MaxInt64 {
+		hi = int64(math.Floor(upper))
+	}
+	if hi < lo {
+		return nil, false
+	}
+	value := lo
+	if hi > lo {
+		// the span is unsigned, so ranges wider than the largest int64 do not overflow.
+		span := uint64(hi) - uint64(lo)
+		if span < math.MaxInt64 {
+			value = lo + random.Int63n(int64(span)+1)
+		} else {
+			offset := random.Uint64()
+			if span < math.MaxUint64 {
+				offset %= span + 1
+			}
+			value = int64(uint64(lo) + offset)
+		}
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf >= 1 && *schema.MultipleOf < math.MaxInt64 {
+		step := int64(*schema.MultipleOf)
+		stepped := (value / step) * step
+		if stepped < lo && stepped <= math.MaxInt64-step {
+			stepped += step
+		}
+		if stepped >= lo && stepped <= hi {
+			value = stepped
+		}
+	}
+	if slices.Contains(schema.Type, stringType) {
+		return strconv.FormatInt(value, 10), true
+	}
+	return value, true
+}
+
+// DecimalRangeProvider generates a random decimal number with two decimal places, that honors the minimum, maximum,
+// exclusiveMinimum and exclusiveMaximum values of the schema. If the schema is a string, the decimal is rendered as one.
+func DecimalRangeProvider(schema *base.Schema, _ string) (any, bool) {
+	return randomDecimal(sharedRandom, schema)
+}
+
+// randomDecimal generates a decimal for DecimalRangeProvider, using the supplied random number generator.
+func randomDecimal(random *rand.Rand, schema *base.Schema) (any, bool) {
+	lower, upper := numericBounds(schema, 0, 1000)
+	if upper < lower {
+		return nil, false
+	}
+	value := math.Round((lower+random.Float64()*(upper-lower))*100) / 100
+	if value < lower || value > upper {
+		value = lower
+	}
+	if slices.Contains(schema.Type, stringType) {
+		return strconv.FormatFloat(value, 'f', 2, 64), true
+	}
+	return value, true
+}
+
+// numericBounds extracts the inclusive lower and upper bounds of a schema, falling back to the defaults supplied.
+// Exclusive bounds are nudged inwards by a single unit (or by a single cent for non-integers).
+func numericBounds(schema *base.Schema, defaultMin, defaultMax float64) (float64, float64) {
+	lower, upper := defaultMin, defaultMax
+	hasLower, hasUpper := false, false
+	nudge := 0.01
+	if slices.Contains(schema.Type, integerType) || schema.Format == int64Type || schema.Format == int32Type {
+		nudge = 1
+	}
+	if schema.Minimum != nil {
+		lower, hasLower = *schema.Minimum, true
+		if schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsA() && schema.ExclusiveMinimum.A {
+			lower += nudge
+		}
+	}
+	if schema.ExclusiveMinimum != nil && schema.ExclusiveMinimum.IsB() {
+		lower, hasLower = schema.ExclusiveMinimum.B+nudge, true
+	}
+	if schema.Maximum != nil {
+		upper, hasUpper = *schema.Maximum, true
+		if schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsA() && schema.ExclusiveMaximum.A {
+			upper -= nudge
+		}
+	}
+	if schema.ExclusiveMaximum != nil && schema.ExclusiveMaximum.IsB() {
+		upper, hasUpper = schema.ExclusiveMaximum.B-nudge, true
+	}
+
+	// only a single bound supplied? then make sure the default does not fall on the wrong side of it.
+	if hasLower && !hasUpper && upper < lower {
+		upper = lower + (defaultMax - defaultMin)
+	}
+	if hasUpper && !hasLower && lower > upper {
+		lower = upper - (defaultMax - defaultMin)
+	}
+	return lower, upper
+}
+
+// randomDuration generates a random ISO 8601 duration, for example P3DT4H30M
+func randomDuration(random *rand.Rand) string {
+	switch random.Intn(3) {
+	case 0:
+		return fmt.Sprintf("PT%dM", random.Intn(59)+1)
+	case 1:
+		return fmt.Sprintf("PT%dH%dM", random.Intn(23)+1, random.Intn(59)+1)
+	default:
+		return fmt.Sprintf("P%dDT%dH", random.Intn(30)+1, random.Intn(23)+1)
+	}
+}
+
+// stringProvider wraps a generator into a ValueProvider that only supplies values for string (or untyped) schemas.
+// The generator uses the random number generator of the registry.
+func stringProvider(r *ValueProviderRegistry, generate func(random *rand.Rand) string) ValueProvider {
+	return func(schema *base.Schema, _ string) (any, bool) {
+		if len(schema.Type) > 0 && !slices.Contains(schema.Type, stringType) {
+			return nil, false
+		}
+		return generate(r.random()), true
+	}
+}
+
+func pick(random *rand.Rand, values []string) string {
+	return values[random.Intn(len(values))]
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package renderer
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+
+	highbase "github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueProviderRegistry_Format(t *testing.T) {
+	r := NewValueProviderRegistry()
+	r.RegisterFormatProvider("sku", func(schema *highbase.Schema, _ string) (any, bool) {
+		return "SKU-1234", true
+	})
+
+	wr := &SchemaRenderer{}
+	wr.SetValueProviders(r)
+	assert.Equal(t, r, wr.GetValueProviders())
+
+	compiled := getSchema([]byte(`type: object
+properties:
+  code:
+    type: string
+    format: sku`))
+
+	rendered := wr.RenderSchema(compiled).(map[string]any)
+	assert.Equal(t, "SKU-1234", rendered["code"])
+}
+
+func TestValueProviderRegistry_Property(t *testing.T) {
+	r := NewValueProviderRegistry()
+	assert.NoError(t, r.RegisterPropertyProvider(`(?i)^colou?r$`, func(schema *highbase.Schema, _ string) (any, bool) {
+		return "magenta", true
+	}))
+	assert.Error(t, r.RegisterPropertyProvider(`[`, nil))
+
+	wr := &SchemaRenderer{providers: r}
+	compiled := getSchema([]byte(`type: object
+properties:
+  Colour:
+    type: string
+  color:
+    type: object
+    properties:
+      hex:
+        type: string
+        example: '#ff00ff'`))
+
+	rendered := wr.RenderSchema(compiled).(map[string]any)
+	assert.Equal(t, "magenta", rendered["Colour"])
+
+	// objects are never replaced by a property provider.
+	assert.Equal(t, map[string]any{"hex": "#ff00ff"}, rendered["color"])
+}
+
+func TestValueProviderRegistry_Extension(t *testing.T) {
+	r := NewValueProviderRegistry()
+	r.RegisterFakerProvider("pet.name", func(schema *highbase.Schema, _ string) (any, bool) {
+		return "fido", true
+	})
+	r.RegisterExtensionProvider("x-sequence", "", func(schema *highbase.Schema, _ string) (any, bool) {
+		ext := schema.Extensions.GetOrZero("x-sequence")
+		return "seq-" + ext.Value, true
+	})
+	r.RegisterFormatProvider("uuid", func(schema *highbase.Schema, _ string) (any, bool) {
+		return "should-not-be-used", true
+	})
+	assert.Equal(t, []string{"pet.name"}, r.GetFakerProviderNames())
+
+	wr := &SchemaRenderer{providers: r}
+	compiled := getSchema([]byte(`type: object
+properties:
+  name:
+    type: string
+    format: uuid
+    x-faker: pet.name
+  id:
+    type: string
+    x-sequence: abc
+  other:
+    type: string
+    x-faker: not.registered
+    enum: [cat]`))
+
+	rendered := wr.RenderSchema(compiled).(map[string]any)
+	assert.Equal(t, "fido", rendered["name"])
+	assert.Equal(t, "seq-abc", rendered["id"])
+	assert.Equal(t, "cat", rendered["other"])
+}
+
+func TestValueProviderRegistry_FallThrough(t *testing.T) {
+	r := NewValueProviderRegistry()
+	r.RegisterFormatProvider("email", func(schema *highbase.Schema, _ string) (any, bool) {
+		return nil, false
+	})
+	wr := &SchemaRenderer{providers: r}
+	compiled := getSchema([]byte(`type: string
+format: email`))
+
+	assert.Contains(t, wr.RenderSchema(compiled), "@")
+
+	v, ok := r.Provide(nil, "")
+	assert.Nil(t, v)
+	assert.False(t, ok)
+}
+
+func TestBuiltInValueProviders(t *testing.T) {
+	compiled := getSchema([]byte(`type: object
+properties:
+  firstName:
+    type: string
+  last_name:
+    type: string
+  displayName:
+    type: string
+  street:
+    type: string
+  city:
+    type: string
+  postcode:
+    type: string
+  countryCode:
+    type: string
+  phoneNumber:
+    type: string
+  currency:
+    type: string
+  website:
+    type: string
+  timeout:
+    type: string
+    format: duration
+  home:
+    type: string
+    x-faker: address.city
+  count:
+    type: integer
+    format: int64
+    minimum: 500
+    maximum: 510
+  price:
+    type: number
+    format: decimal
+    minimum: 10
+    maximum: 20
+  priceStr:
+    type: string
+    format: decimal
+    minimum: 1
+    maximum: 2`))
+
+	wr := CreateRendererUsingBuiltInProviders()
+	rendered := wr.RenderSchema(compiled).(map[string]any)
+
+	assert.Contains(t, firstNames, rendered["firstName"])
+	assert.Contains(t, lastNames, rendered["last_name"])
+	assert.Regexp(t, `^\w+ \w+$`, rendered["displayName"])
+	assert.Regexp(t, `^\d+ \w+`, rendered["street"])
+	assert.Contains(t, cities, rendered["city"])
+	assert.Contains(t, cities, rendered["home"])
+	assert.Regexp(t, `^\d{5}$`, rendered["postcode"])
+	assert.Contains(t, countryCodes, rendered["countryCode"])
+	assert.Regexp(t, `^\+1-\d{3}-\d{3}-\d{4}$`, rendered["phoneNumber"])
+	assert.Contains(t, currencies, rendered["currency"])
+	assert.Regexp(t, `^https://www\.`, rendered["website"])
+	assert.Regexp(t, `^P`, rendered["timeout"])
+
+	count := rendered["count"].(int64)
+	assert.GreaterOrEqual(t, count, int64(500))
+	assert.LessOrEqual(t, count, int64(510))
+
+	price := rendered["price"].(float64)
+	assert.GreaterOrEqual(t, price, float64(10))
+	assert.LessOrEqual(t, price, float64(20))
+
+	priceStr, err := strconv.ParseFloat(rendered["priceStr"].(string), 64)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, priceStr, float64(1))
+	assert.LessOrEqual(t, priceStr, float64(2))
+
+	_, err = json.Marshal(rendered)
+	assert.NoError(t, err)
+}
+
+func TestBuiltInValueProviders_NotStrings(t *testing.T) {
+	compiled := getSchema([]byte(`type: object
+properties:
+  city:
+    type: integer
+    minimum: 1
+    maximum: 3`))
+
+	wr := CreateRendererUsingBuiltInProviders()
+	rendered := wr.RenderSchema(compiled).(map[string]any)
+	assert.IsType(t, int64(0), rendered["city"])
+}
+
+func TestBuiltInValueProviders_FakerNames(t *testing.T) {
+	r := NewBuiltInValueProviderRegistry()
+	names := r.GetFakerProviderNames()
+	assert.Len(t, names, 18)
+	assert.True(t, slices.IsSorted(names))
+}
+
+func TestInt64RangeProvider(t *testing.T) {
+	exclusive := &highbase.DynamicValue[bool, float64]{N: 0, A: true}
+	min, max, multiple := float64(10), float64(20), float64(5)
+
+	for i := 0; i < 50; i++ {
+		v, ok := Int64RangeProvider(&highbase.Schema{
+			Type:             []string{integerType},
+			Minimum:          &min,
+			Maximum:          &max,
+			ExclusiveMinimum: exclusive,
+			ExclusiveMaximum: exclusive,
+			MultipleOf:       &multiple,
+		}, "")
+		assert.True(t, ok)
+		assert.Equal(t, int64(15), v)
+	}
+
+	// 3.1 style exclusive bounds.
+	v, ok := Int64RangeProvider(&highbase.Schema{
+		Type:             []string{stringType},
+		ExclusiveMinimum: &highbase.DynamicValue[bool, float64]{N: 1, B: 99},
+		ExclusiveMaximum: &highbase.DynamicValue[bool, float64]{N: 1, B: 101},
+	}, "")
+	assert.True(t, ok)
+	assert.Equal(t, "100", v)
+
+	// ranges wider than the largest int64, and bounds beyond the range of an int64.
+	for _, bounds := range [][2]float64{{-9.2e18, 9.2e18}, {-1e20, 1e20}, {9e18, 1e20}} {
+		for i := 0; i < 50; i++ {
+			v, ok = Int64RangeProvider(&highbase.Schema{
+				Type:    []string{integerType},
+				Format:  "int64",
+				Minimum: &bounds[0],
+				Maximum: &bounds[1],
+			}, "")
+			assert.True(t, ok)
+			if bounds[0] > math.MinInt64 {
+				assert.GreaterOrEqual(t, v.(int64), int64(bounds[0]))
+			}
+		}
+	}
+
+	// a full range schema renders.
+	wr := &SchemaRenderer{}
+	wr.SetValueProviders(NewBuiltInValueProviderRegistry())
+	rendered := wr.RenderSchema(getSchema([]byte(`type: integer
+format: int64
+minimum: -9.2e18
+maximum: 9.2e18`)))
+	assert.IsType(t, int64(0), rendered)
+
+	// impossible range.
+	v, ok = Int64RangeProvider(&highbase.Schema{
+		Type:             []string{integerType},
+		ExclusiveMinimum: &highbase.DynamicValue[bool, float64]{N: 1, B: 1},
+		ExclusiveMaximum: &highbase.DynamicValue[bool, float64]{N: 1, B: 2},
+	}, "")
+	assert.False(t, ok)
+	assert.Nil(t, v)
+}
+
+func TestDecimalRangeProvider_SingleBound(t *testing.T) {
+	min := float64(5000)
+	v, ok := DecimalRangeProvider(&highbase.Schema{Type: []string{numberType}, Minimum: &min}, "")
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, v.(float64), min)
+
+	max := float64(-5000)
+	v, ok = DecimalRangeProvider(&highbase.Schema{Type: []string{numberType}, Maximum: &max}, "")
+	assert.True(t, ok)
+	assert.LessOrEqual(t, v.(float64), max)
+}
+
+func TestBuiltInDictionary(t *testing.T) {
+	words := BuiltInDictionary()
+	assert.Greater(t, len(words), 500)
+	assert.NotContains(t, words, "")
+
+	wr := CreateRendererUsingBuiltInProviders()
+	assert.Regexp(t, regexp.MustCompile(`^[a-z]{4,6}$`), wr.RandomWord(4, 6, 0))
+}
+
+func TestMockGenerator_BuiltInProviders(t *testing.T) {
+	compiled := getSchema([]byte(`type: object
+properties:
+  city:
+    type: string`))
+
+	mg := NewMockGeneratorWithBuiltInProviders(JSON)
+	mock, err := mg.GenerateMock(compiled, "")
+	assert.NoError(t, err)
+
+	var decoded map[string]string
+	_ = json.Unmarshal(mock, &decoded)
+	assert.Contains(t, cities, decoded["city"])
+
+	mg.SetValueProviders(nil)
+	mock, err = mg.GenerateMock(compiled, "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, mock)
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package libopenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	v3high "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/index"
+	what_changed "github.com/pb33f/libopenapi/what-changed"
+	"github.com/pb33f/libopenapi/what-changed/model"
+)
+
+// DefaultWatchInterval is how often a DocumentWatcher checks files for changes, if no interval is configured.
+const DefaultWatchInterval = time.Second
+
+// WatcherConfiguration is used to configure a DocumentWatcher.
+type WatcherConfiguration struct {
+	// Interval is how often the watched files are checked for changes. Defaults to DefaultWatchInterval.
+	Interval time.Duration
+
+	// CompareChanges will compare the rebuilt model with the previous version of the model, every time files
+	// change. The changes are available in the Changes property of the WatchEvent.
+	CompareChanges bool
+}
+
+// WatchEvent is delivered to the callback of a DocumentWatcher every time watched files change and the
+// document has been rebuilt.
+type WatchEvent struct {
+	// ChangedFiles holds the full paths of the files that changed (or were removed), sorted.
+	ChangedFiles []string
+
+	// Document is the document being watched.
+	Document Document
+
+	// Model is the rebuilt model, it will be nil if the document could not be rebuilt.
+	Model *DocumentModel[v3high.Document]
+
+	// Changes holds the changes between the previous model and the rebuilt one. It is only set when
+	// CompareChanges is enabled and both models were built. It will be nil if nothing changed in the model.
+	// As with CompareDocuments, references are compared by value, so changes made only inside a referenced
+	// file are not reported.
+	Changes *model.DocumentChanges
+
+	// Errors holds any errors that occurred rebuilding the document.
+	Errors []error
+}
+
+// DocumentWatcher watches every local file loaded by the rolodex of an OpenAPI 3+ document, including the root
+// document and any files pulled in via relative references. When files change, only the changed files (and the files
+// that depend on them) are re-indexed and the model is rebuilt, then the callback is invoked with the result.
+//
+// Files are polled using their modification time and size, so watching is portable and needs no OS
+// notification support. Files that are added to the document by a change are picked up and watched too.
+type DocumentWatcher struct {
+	document ReindexableDocument
+	config   *WatcherConfiguration
+	callback func(event *WatchEvent)
+	model    *DocumentModel[v3high.Document]
+	rootPath string
+	files    map[string]watchedFile
+	lock     sync.Mutex
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// NewDocumentWatcher creates a new DocumentWatcher for an OpenAPI 3+ document. The model is built if it has not
+// already been built, and the callback is invoked every time a change to the watched files is detected.
+//
+// The document must have been created with a BasePath (and ideally a SpecFilePath) in its DocumentConfiguration,
+// otherwise there are no files on disk to watch and an error is returned.
+func NewDocumentWatcher(document Document, config *WatcherConfiguration,
+	callback func(event *WatchEvent),
+) (*DocumentWatcher, error) {
+	if document == nil {
+		return nil, errors.New("a document is required to watch for changes")
+	}
+	if callback == nil {
+		return nil, errors.New("a callback is required to watch for changes")
+	}
+	reindexable, ok := document.(ReindexableDocument)
+	if !ok {
+		return nil, errors.New("only documents that can be re-indexed can be watched for changes")
+	}
+	info := document.GetSpecInfo()
+	if info == nil || (info.SpecFormat != datamodel.OAS3 && info.SpecFormat != datamodel.OAS31) {
+		return nil, errors.New("only OpenAPI 3+ documents can be watched for changes")
+	}
+	if config == nil {
+		config = &WatcherConfiguration{}
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultWatchInterval
+	}
+
+	m, errs := document.BuildV3Model()
+	if m == nil {
+		return nil, fmt.Errorf("unable to build document to watch: %w", errors.Join(errs...))
+	}
+
+	w := &DocumentWatcher{
+		document: reindexable,
+		config:   config,
+		callback: callback,
+		model:    m,
+		files:    make(map[string]watchedFile),
+	}
+	w.trackFiles()
+	if len(w.files) == 0 {
+		return nil, errors.New("there are no local files to watch, the document configuration " +
+			"requires a BasePath")
+	}
+	return w, nil
+}
+
+// GetWatchedFiles returns the full paths of all the files being watched, sorted.
+func (w *DocumentWatcher) GetWatchedFiles() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	files := make([]string, 0, len(w.files))
+	for k := range w.files {
+		files = append(files, k)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// GetModel returns the most recently built model of the document.
+func (w *DocumentWatcher) GetModel() *DocumentModel[v3high.Document] {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.model
+}
+
+// Watch polls the watched files for changes at the configured interval, until the context is cancelled.
+// It blocks, so run it in a goroutine. The error returned is the error of the context.
+func (w *DocumentWatcher) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.Poll()
+		}
+	}
+}
+
+// Poll checks the watched files for changes once. If any have changed, the document is rebuilt, the callback is
+// invoked and the event is returned. If nothing has changed, nil is returned and the callback is not invoked.
+func (w *DocumentWatcher) Poll() *WatchEvent {
+	event := w.rebuild()
+	if event != nil {
+		w.callback(event)
+	}
+	return event
+}
+
+func (w *DocumentWatcher) rebuild() *WatchEvent {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var changed []string
+	for location, previous := range w.files {
+		if current := statWatchedFile(location); current != previous {
+			w.files[location] = current
+			changed = append(changed, location)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	event := &WatchEvent{ChangedFiles: changed, Document: w.document}
+	changes := make([]*index.RolodexFileChange, 0, len(changed))
+	for _, location := range changed {
+		change := &index.RolodexFileChange{Location: location}
+		if location == w.rootPath {
+			// the root document is not held by a file system, so its content is read here.
+			content, err := os.ReadFile(location)
+			if err != nil {
+				event.Errors = []error{fmt.Errorf("unable to read root document: %w", err)}
+				return event
+			}
+			change.Content = content
+		}
+		changes = append(changes, change)
+	}
+
+	previous := w.model
+	event.Model, event.Errors = w.document.ReindexFiles(changes...)
+	if event.Model == nil {
+		return event
+	}
+	if w.config.CompareChanges && previous != nil {
+		event.Changes = what_changed.CompareOpenAPIDocuments(previous.Model.GoLow(), event.Model.Model.GoLow())
+	}
+	w.model = event.Model
+
+	// changes may have pulled new files into the rolodex.
+	w.trackFiles()
+	return event
+}
+
+// trackFiles adds any files loaded by the rolodex that are not already watched. Only files that exist on disk
+// are watched, files held in memory (like the content of archives) cannot change.
+func (w *DocumentWatcher) trackFiles() {
+	rolodex := w.document.GetRolodex()
+	if rolodex == nil {
+		return
+	}
+	if root := rolodex.GetRootIndex(); root != nil {
+		w.rootPath = root.GetSpecAbsolutePath()
+		w.track(w.rootPath)
+	}
+	for location := range rolodex.GetLocalFiles() {
+		w.track(location)
+	}
+}
+
+func (w *DocumentWatcher) track(location string) {
+	if _, ok := w.files[location]; ok {
+		return
+	}
+	if f := statWatchedFile(location); f.exists {
+		w.files[location] = f
+	}
+}
+
+func statWatchedFile(location string) watchedFile {
+	stat, err := os.Stat(location)
+	if err != nil || stat.IsDir() {
+		return watchedFile{}
+	}
+	return watchedFile{modTime: stat.ModTime(), size: stat.Size(), exists: true}
+}
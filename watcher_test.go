@@ -0,0 +1,173 @@
+// Copyright 2023 Princess B33f Heavy Industries / Dave Shanley
+// SPDX-License-Identifier: MIT
+
+package libopenapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchRoot = `openapi: 3.1.0
+info:
+  title: watch
+  version: 1.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'pet.yaml#/components/schemas/Pet'`
+
+const watchPet = `components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string`
+
+// writeWatchedFile writes a file and moves its modification time forward, so the change is seen
+// even when the file system has a coarse timestamp resolution.
+func writeWatchedFile(t *testing.T, location, content string, tick int) {
+	require.NoError(t, os.WriteFile(location, []byte(content), 0o644))
+	mod := time.Now().Add(time.Duration(tick) * time.Minute)
+	require.NoError(t, os.Chtimes(location, mod, mod))
+}
+
+func newWatchedDocument(t *testing.T) (Document, string) {
+	dir := t.TempDir()
+	writeWatchedFile(t, filepath.Join(dir, "root.yaml"), watchRoot, 0)
+	writeWatchedFile(t, filepath.Join(dir, "pet.yaml"), watchPet, 0)
+
+	config := datamodel.NewDocumentConfiguration()
+	config.BasePath = dir
+	config.SpecFilePath = "root.yaml"
+
+	doc, err := NewDocumentWithConfiguration([]byte(watchRoot), config)
+	require.NoError(t, err)
+	return doc, dir
+}
+
+func TestDocumentWatcher_Poll(t *testing.T) {
+	doc, dir := newWatchedDocument(t)
+
+	var events []*WatchEvent
+	watcher, err := NewDocumentWatcher(doc, &WatcherConfiguration{CompareChanges: true}, func(e *WatchEvent) {
+		events = append(events, e)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "pet.yaml"), filepath.Join(dir, "root.yaml")},
+		watcher.GetWatchedFiles())
+
+	// nothing has changed yet.
+	assert.Nil(t, watcher.Poll())
+	assert.Empty(t, events)
+
+	// a property is added to the referenced file.
+	writeWatchedFile(t, filepath.Join(dir, "pet.yaml"), watchPet+"\n        age:\n          type: integer", 1)
+	event := watcher.Poll()
+	require.NotNil(t, event)
+	require.Len(t, events, 1)
+	assert.Empty(t, event.Errors)
+	assert.Equal(t, []string{filepath.Join(dir, "pet.yaml")}, event.ChangedFiles)
+	assert.Equal(t, 2, orderedmap.Len(event.Model.Model.Components.Schemas.GetOrZero("Pet").Schema().Properties))
+	assert.Nil(t, event.Changes, "references are compared by value, the reference has not changed")
+	assert.Same(t, event.Model, watcher.GetModel())
+
+	// the root document now references a new file, which is then watched.
+	writeWatchedFile(t, filepath.Join(dir, "owner.yaml"), "type: string", 0)
+	writeWatchedFile(t, filepath.Join(dir, "root.yaml"), watchRoot+"\n    Owner:\n      $ref: 'owner.yaml'", 2)
+	event = watcher.Poll()
+	require.NotNil(t, event)
+	assert.Empty(t, event.Errors)
+	assert.Equal(t, []string{filepath.Join(dir, "root.yaml")}, event.ChangedFiles)
+	assert.Equal(t, 2, orderedmap.Len(event.Model.Model.Components.Schemas))
+	require.NotNil(t, event.Changes)
+	assert.Equal(t, 1, event.Changes.TotalChanges())
+	assert.Contains(t, watcher.GetWatchedFiles(), filepath.Join(dir, "owner.yaml"))
+
+	writeWatchedFile(t, filepath.Join(dir, "owner.yaml"), "type: integer", 3)
+	event = watcher.Poll()
+	require.NotNil(t, event)
+	assert.Equal(t, []string{filepath.Join(dir, "owner.yaml")}, event.ChangedFiles)
+	assert.Equal(t, "integer",
+		event.Model.Model.Components.Schemas.GetOrZero("Owner").Schema().Type[0])
+	assert.Len(t, events, 3)
+}
+
+func TestDocumentWatcher_Errors(t *testing.T) {
+	doc, dir := newWatchedDocument(t)
+	watcher, err := NewDocumentWatcher(doc, nil, func(*WatchEvent) {})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultWatchInterval, watcher.config.Interval)
+
+	// an invalid root document is reported, and the watcher carries on.
+	writeWatchedFile(t, filepath.Join(dir, "root.yaml"), "swagger: 2.0", 1)
+	event := watcher.Poll()
+	require.NotNil(t, event)
+	assert.Nil(t, event.Model)
+	assert.Len(t, event.Errors, 1)
+
+	writeWatchedFile(t, filepath.Join(dir, "root.yaml"), watchRoot, 2)
+	event = watcher.Poll()
+	require.NotNil(t, event)
+	assert.NotNil(t, event.Model)
+
+	// removing the root is reported too.
+	require.NoError(t, os.Remove(filepath.Join(dir, "root.yaml")))
+	event = watcher.Poll()
+	require.NotNil(t, event)
+	assert.ErrorContains(t, event.Errors[0], "unable to read root document")
+
+	_, err = NewDocumentWatcher(nil, nil, func(*WatchEvent) {})
+	assert.Error(t, err)
+	_, err = NewDocumentWatcher(doc, nil, nil)
+	assert.Error(t, err)
+
+	swagger, _ := NewDocument([]byte("swagger: 2.0"))
+	_, err = NewDocumentWatcher(swagger, nil, func(*WatchEvent) {})
+	assert.EqualError(t, err, "only OpenAPI 3+ documents can be watched for changes")
+
+	inMemory, _ := NewDocument([]byte("openapi: 3.1.0\ninfo:\n  title: memory\n  version: 1.0.0"))
+	_, err = NewDocumentWatcher(inMemory, nil, func(*WatchEvent) {})
+	assert.EqualError(t, err, "there are no local files to watch, the document configuration requires a BasePath")
+
+	// other implementations of Document can't be re-indexed.
+	wrapped := struct{ Document }{inMemory}
+	_, err = NewDocumentWatcher(wrapped, nil, func(*WatchEvent) {})
+	assert.EqualError(t, err, "only documents that can be re-indexed can be watched for changes")
+}
+
+func TestDocumentWatcher_Watch(t *testing.T) {
+	doc, dir := newWatchedDocument(t)
+
+	changed := make(chan *WatchEvent, 1)
+	watcher, err := NewDocumentWatcher(doc, &WatcherConfiguration{Interval: 10 * time.Millisecond},
+		func(e *WatchEvent) {
+			changed <- e
+		})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(ctx)
+	}()
+
+	writeWatchedFile(t, filepath.Join(dir, "pet.yaml"), watchPet+"\n        age:\n          type: integer", 1)
+	select {
+	case e := <-changed:
+		assert.Equal(t, []string{filepath.Join(dir, "pet.yaml")}, e.ChangedFiles)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not detected")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}